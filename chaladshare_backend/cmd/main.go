@@ -2,9 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -15,8 +20,10 @@ import (
 	"chaladshare_backend/internal/connect"
 	"chaladshare_backend/internal/connectdb"
 	"chaladshare_backend/internal/middleware"
+	"chaladshare_backend/internal/ratelimit"
 
 	AuthHandler "chaladshare_backend/internal/auth/handlers"
+	AuthModels "chaladshare_backend/internal/auth/models"
 	AuthRepo "chaladshare_backend/internal/auth/repository"
 	AuthService "chaladshare_backend/internal/auth/service"
 
@@ -25,6 +32,7 @@ import (
 	FileService "chaladshare_backend/internal/files/service"
 
 	PostHandler "chaladshare_backend/internal/posts/handlers"
+	PostModels "chaladshare_backend/internal/posts/models"
 	PostRepo "chaladshare_backend/internal/posts/repository"
 	PostService "chaladshare_backend/internal/posts/service"
 
@@ -43,10 +51,27 @@ import (
 	RecommendHandler "chaladshare_backend/internal/recommend/handlers"
 	RecommendRepo "chaladshare_backend/internal/recommend/repository"
 	RecommendService "chaladshare_backend/internal/recommend/service"
+
+	NotificationHandler "chaladshare_backend/internal/notifications/handlers"
+	NotificationRepo "chaladshare_backend/internal/notifications/repository"
+	NotificationService "chaladshare_backend/internal/notifications/service"
+
+	RealtimeHandler "chaladshare_backend/internal/realtime/handlers"
+	RealtimeService "chaladshare_backend/internal/realtime/service"
 )
 
-func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+// TimeoutMiddleware ตัด context ของคำขอเมื่อเกิน timeout
+// ยกเว้น route ใน skipRoutes (path แบบ c.FullPath() เช่น SSE ที่ต่อค้างไว้นาน ๆ)
+func TimeoutMiddleware(timeout time.Duration, skipRoutes ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipRoutes))
+	for _, p := range skipRoutes {
+		skip[p] = true
+	}
 	return func(c *gin.Context) {
+		if skip[c.FullPath()] {
+			c.Next()
+			return
+		}
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 		c.Request = c.Request.WithContext(ctx)
@@ -62,8 +87,11 @@ func parseAllowOrigins(raw string) []string {
 			"http://127.0.0.1:3000",
 		}
 	}
+	return parseCSV(raw)
+}
 
-	// รองรับได้ทั้ง "a,b,c" หรือ "a"
+// รองรับได้ทั้ง "a,b,c" หรือ "a"
+func parseCSV(raw string) []string {
 	parts := strings.Split(raw, ",")
 	out := make([]string, 0, len(parts))
 	for _, p := range parts {
@@ -84,6 +112,10 @@ func main() {
 		}
 	}
 
+	// ยกเลิกเมื่อได้ SIGINT/SIGTERM: งานเบื้องหลังหยุด แล้วปิด server
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// config
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -117,15 +149,44 @@ func main() {
 	}
 
 	// auth
+	if cfg.TOTPEncryptionKey == "" {
+		log.Println("WARNING: TOTP_ENCRYPTION_KEY is empty, two-factor enrollment is disabled")
+	} else if cfg.TOTPEncryptionKey == cfg.JWTSecret {
+		log.Fatalf("TOTP.ENCRYPTION_KEY must differ from JWT.SECRET")
+	}
 	authRepository := AuthRepo.NewAuthRepository(db.GetDB())
-	authService := AuthService.NewAuthService(authRepository, []byte(cfg.JWTSecret), cfg.TokenTTLMinutes)
+	authService := AuthService.NewAuthService(authRepository, AuthModels.TokenConfig{
+		Secret:         []byte(cfg.JWTSecret),
+		TTLMinutes:     cfg.TokenTTLMinutes,
+		Issuer:         cfg.JWTIssuer,
+		Audience:       cfg.JWTAudience,
+		AccessCacheTTL: time.Duration(cfg.SessionCacheSeconds) * time.Second,
+		TOTPKey:        []byte(cfg.TOTPEncryptionKey),
+	})
 	authHandler := AuthHandler.NewAuthHandler(authService, accessCookieName, refreshCookieName, secureCookie)
 
+	rateLimitStore, err := ratelimit.NewStore(cfg.RateLimitStore, db.GetDB())
+	if err != nil {
+		log.Fatalf("Failed to init rate limit store: %v", err)
+	}
+
+	// realtime hub (SSE) ใช้ร่วมกันทุกโมดูลที่ push event
+	realtimeHub := RealtimeService.NewHub()
+
+	// notifications (สร้างก่อน เพราะ friends/like/comment ส่งเหตุการณ์เข้ามา)
+	notificationRepository := NotificationRepo.NewNotificationRepository(db.GetDB())
+	notificationService := NotificationService.NewNotificationService(notificationRepository, realtimeHub)
+	notificationHandler := NotificationHandler.NewNotificationHandler(notificationService)
+
 	// friends
 	friendsRepo := FriendsRepo.NewFriendRepository(db.GetDB())
-	friendsService := FriendsService.NewFriendService(friendsRepo)
+	friendsService := FriendsService.NewFriendService(friendsRepo, notificationService)
 	friendsHandler := FriendsHandler.NewFriendHandler(friendsService)
 
+	groupRepo := FriendsRepo.NewGroupRepository(db.GetDB())
+	groupService := FriendsService.NewGroupService(groupRepo)
+	groupHandler := FriendsHandler.NewGroupHandler(groupService)
+
 	// AI client (Colab/ngrok)
 	aiClient, err := connect.NewFromEnv()
 	if err != nil {
@@ -138,9 +199,20 @@ func main() {
 	featureHandler := FeatureHandler.NewFeatureHandler(featureService)
 
 	// file
+	storageRegistry, err := FileService.NewStorageRegistryFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to init storage: %v", err)
+	}
+	log.Printf("[STORAGE] default=%s available=%v", storageRegistry.Default().Provider(), storageRegistry.Providers())
+
 	fileRepository := FileRepo.NewFileRepository(db.GetDB())
-	fileService := FileService.NewFileService(fileRepository, featureService)
-	fileHandler := FileHandler.NewFileHandler(fileService)
+	fileService := FileService.NewFileService(fileRepository, featureService, aiClient, storageRegistry)
+	// เอกสารเก่าที่ยังเก็บ URL สาธารณะ ย้ายเข้า backend ของเอกสารแล้วล้าง URL
+	// จากนั้นเติม content_sha256 ให้เอกสารที่อัปก่อนมีระบบไฟล์ซ้ำ
+	go func() {
+		fileService.MigrateLegacyDocuments()
+		fileService.BackfillContentHashes()
+	}()
 
 	if aiClient != nil {
 		// ใช้ interface assertion เพื่อไม่พังแม้ยังไม่ได้เพิ่ม method ใน interface
@@ -154,6 +226,19 @@ func main() {
 		log.Println("[AUTO-CLUSTER] skip bootstrap: aiClient is nil")
 	}
 
+	// worker สกัด feature (หยิบงาน queued/processing ที่ค้างจากรอบก่อนด้วย)
+	if aiClient != nil {
+		featureService.StartWorkers(fileService, cfg.FeatureWorkers, time.Duration(cfg.FeatureLeaseSeconds)*time.Second)
+	} else {
+		log.Println("[FEATURE-WORKER] skip: aiClient is nil")
+	}
+
+	if aiClient != nil {
+		fileService.StartSummaryWorkers(cfg.SummaryWorkers, time.Duration(cfg.SummaryLeaseSeconds)*time.Second)
+	} else {
+		log.Println("[SUMMARY-WORKER] skip: aiClient is nil")
+	}
+
 	// recommend
 	recommendRepo := RecommendRepo.NewRecommendRepo(db.GetDB())
 	recommendService := RecommendService.NewRecommendService(recommendRepo, aiClient)
@@ -161,21 +246,47 @@ func main() {
 
 	// post like save
 	postRepository := PostRepo.NewPostRepository(db.GetDB())
-	postService := PostService.NewPostService(postRepository, friendsService, fileService)
+	var queryEmbedder PostService.QueryEmbedder
+	if aiClient != nil {
+		queryEmbedder = aiClient
+	}
+	postService := PostService.NewPostService(postRepository, friendsService, fileService, queryEmbedder, notificationService, featureService, recommendService, cfg.FeedWindowDays)
+	postService.StartTrendingRefresher(ctx, PostModels.TrendingConfig{
+		HalfLifeHours: cfg.TrendingHalfLifeHours,
+		SaveWeight:    cfg.TrendingSaveWeight,
+	}, time.Duration(cfg.TrendingRefreshMinutes)*time.Minute)
+	postService.StartPublishScheduler(time.Duration(cfg.PublishSchedulerSeconds) * time.Second)
 
 	likeRepository := PostRepo.NewLikeRepository(db.GetDB())
-	likeService := PostService.NewLikeService(likeRepository)
-	likeHandler := PostHandler.NewLikeHandler(likeService, recommendService)
+	likeService := PostService.NewLikeService(likeRepository, postService, notificationService)
+	likeHandler := PostHandler.NewLikeHandler(likeService, recommendService, realtimeHub)
 
 	saveRepository := PostRepo.NewSaveRepository(db.GetDB())
-	saveService := PostService.NewSaveService(saveRepository)
+	saveService := PostService.NewSaveService(saveRepository, postService)
+
+	postHandler := PostHandler.NewPostHandler(postService, saveService, realtimeHub)
+
+	commentRepository := PostRepo.NewCommentRepository(db.GetDB())
+	commentService := PostService.NewCommentService(commentRepository, postService, notificationService)
+	commentHandler := PostHandler.NewCommentHandler(commentService)
+
+	tagRepository := PostRepo.NewTagRepository(db.GetDB())
+	tagService := PostService.NewTagService(tagRepository, postRepository)
+	tagHandler := PostHandler.NewTagHandler(tagService)
 
-	postHandler := PostHandler.NewPostHandler(postService, saveService)
+	collectionRepository := PostRepo.NewCollectionRepository(db.GetDB())
+	collectionService := PostService.NewCollectionService(collectionRepository, postRepository, postService)
+	collectionHandler := PostHandler.NewCollectionHandler(collectionService, realtimeHub)
+
+	realtimeHandler := RealtimeHandler.NewRealtimeHandler(realtimeHub, postService)
+
+	// ดาวน์โหลดไฟล์ต้องเช็คการมองเห็นของโพสต์ จึงสร้างหลัง postService
+	fileHandler := FileHandler.NewFileHandler(fileService, postService)
 
 	// user
 	userRepository := UserRepo.NewUserRepository(db.GetDB())
-	userService := UserService.NewUserService(userRepository)
-	userHandler := UserHandler.NewUserHandler(userService, postService, friendsService)
+	userService := UserService.NewUserService(userRepository, authService)
+	userHandler := UserHandler.NewUserHandler(userService, postService, friendsService, refreshCookieName)
 
 	go func() {
 		for {
@@ -193,6 +304,11 @@ func main() {
 
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
+	if cfg.TrustedProxies != "" {
+		if err := r.SetTrustedProxies(parseCSV(cfg.TrustedProxies)); err != nil {
+			log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+		}
+	}
 
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -215,7 +331,11 @@ func main() {
 		MaxAge:           12 * time.Hour,
 	}))
 
-	r.Use(TimeoutMiddleware(180 * time.Second))
+	// SSE จบเมื่อ client ปิดการเชื่อมต่อ (context ของคำขอ) ไม่ต้องมี timeout
+	r.Use(TimeoutMiddleware(180*time.Second,
+		"/api/v1/files/:document_id/status/stream",
+		"/api/v1/realtime/stream",
+	))
 
 	r.MaxMultipartMemory = 100 << 20
 	uploadDir := os.Getenv("UPLOAD_DIR")
@@ -225,7 +345,9 @@ func main() {
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
 		log.Printf("WARNING: cannot create upload dir (%s): %v", uploadDir, err)
 	}
-	r.Static("/uploads", uploadDir)
+	// public เฉพาะรูป ส่วน documents/ ต้องโหลดผ่าน /files/:document_id/download
+	r.Static("/uploads/covers", filepath.Join(uploadDir, "covers"))
+	r.Static("/uploads/avatars", filepath.Join(uploadDir, "avatars"))
 
 	// r.Static("/uploads", "./uploads")
 
@@ -243,26 +365,75 @@ func main() {
 
 	v1 := r.Group("/api/v1")
 
+	// ลิงก์ดาวน์โหลดของ local storage (ตรวจลายเซ็นแทน JWT)
+	v1.GET("/files/local/*object_path", fileHandler.ServeLocalSigned)
+
+	// rate limit: ต่อ IP นับทุกคำขอ, ต่อ email ของ endpoint ตรวจรหัสนับเฉพาะครั้งที่ผิด (lockout)
+	loginLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitRule{Name: "login", Limit: 20, Window: 15 * time.Minute, Key: middleware.ClientIPKey},
+		middleware.RateLimitRule{Name: "login", Limit: 5, Window: 15 * time.Minute, Key: middleware.EmailIPKey, FailuresOnly: true},
+	)
+	// ขอ OTP ส่งอีเมลทุกครั้ง จึงจำกัดแน่นกว่า
+	resetOTPSendLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitRule{Name: "reset-otp-send", Limit: 10, Window: time.Hour, Key: middleware.ClientIPKey},
+		middleware.RateLimitRule{Name: "reset-otp-send", Limit: 3, Window: 15 * time.Minute, Key: middleware.EmailKey},
+	)
+	resetOTPVerifyLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitRule{Name: "reset-otp-verify", Limit: 30, Window: 15 * time.Minute, Key: middleware.ClientIPKey},
+		middleware.RateLimitRule{Name: "reset-otp-verify", Limit: AuthModels.MaxOTPAttempts, Window: 15 * time.Minute, Key: middleware.EmailIPKey, FailuresOnly: true},
+	)
+	verifyOTPSendLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitRule{Name: "verify-otp-send", Limit: 10, Window: time.Hour, Key: middleware.ClientIPKey},
+		middleware.RateLimitRule{Name: "verify-otp-send", Limit: 3, Window: 15 * time.Minute, Key: middleware.EmailKey},
+	)
+	verifyOTPConfirmLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitRule{Name: "verify-otp-confirm", Limit: 30, Window: 15 * time.Minute, Key: middleware.ClientIPKey},
+		middleware.RateLimitRule{Name: "verify-otp-confirm", Limit: AuthModels.MaxOTPAttempts, Window: 15 * time.Minute, Key: middleware.EmailIPKey, FailuresOnly: true},
+	)
+
+	// 2FA มี lockout ต่อผู้ใช้ใน service อยู่แล้ว อันนี้กันยิงจาก IP เดียว
+	twoFactorLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitRule{Name: "2fa-verify", Limit: 30, Window: 15 * time.Minute, Key: middleware.ClientIPKey},
+	)
+
 	// login register
 	authRoutes := v1.Group("/auth")
 	{
 		authRoutes.POST("/register", authHandler.Register)
-		authRoutes.POST("/login", authHandler.Login)
+		authRoutes.POST("/login", loginLimit, authHandler.Login)
 		authRoutes.POST("/logout", authHandler.Logout)
 		authRoutes.POST("/refresh", authHandler.Refresh)
+		authRoutes.POST("/2fa/verify", twoFactorLimit, authHandler.VerifyTwoFactor)
 
-		authRoutes.POST("/forgot-password", authHandler.ForgotPassword)
-		authRoutes.POST("/forgot-password/verify-otp", authHandler.VerifyForgotPasswordOTP)
-		authRoutes.POST("/reset-password", authHandler.ResetPassword)
+		authRoutes.POST("/forgot-password", resetOTPSendLimit, authHandler.ForgotPassword)
+		authRoutes.POST("/forgot-password/verify-otp", resetOTPVerifyLimit, authHandler.VerifyForgotPasswordOTP)
+		authRoutes.POST("/reset-password", resetOTPVerifyLimit, authHandler.ResetPassword)
 
 		authRoutes.GET("/users", authHandler.GetAllUsers)
-		authRoutes.POST("/register/request-otp", authHandler.RequestRegisterOTP)
-		authRoutes.POST("/register/confirm-otp", authHandler.ConfirmVerifyEmailOTP)
+		authRoutes.POST("/register/request-otp", verifyOTPSendLimit, authHandler.RequestRegisterOTP)
+		authRoutes.POST("/register/confirm-otp", verifyOTPConfirmLimit, authHandler.ConfirmVerifyEmailOTP)
 	}
 	// Protected (ต้องมี JWT)
 	protected := v1.Group("/")
-	protected.Use(middleware.JWT([]byte(cfg.JWTSecret), accessCookieName))
+	protected.Use(middleware.JWT([]byte(cfg.JWTSecret), accessCookieName, cfg.JWTIssuer, cfg.JWTAudience, authService))
 	{
+		// อุปกรณ์ที่ login อยู่
+		sessions := protected.Group("/auth/sessions")
+		{
+			sessions.GET("", authHandler.ListSessions)
+			sessions.DELETE("/others", authHandler.RevokeOtherSessions)
+			sessions.DELETE("/:id", authHandler.RevokeSession)
+		}
+
+		// TOTP 2FA
+		twoFactor := protected.Group("/auth/2fa")
+		{
+			twoFactor.GET("", authHandler.TwoFactorStatus)
+			twoFactor.POST("/enroll", authHandler.EnrollTwoFactor)
+			twoFactor.POST("/confirm", authHandler.ConfirmTwoFactor)
+			twoFactor.POST("/disable", authHandler.DisableTwoFactor)
+		}
+
 		posts := protected.Group("/posts")
 		{
 			posts.GET("", postHandler.GetAllPosts)
@@ -277,7 +448,47 @@ func main() {
 			posts.GET("/save", postHandler.GetSavedPosts)
 			posts.GET("/popular", postHandler.GetPopularPosts)
 			posts.GET("/search", postHandler.SearchPosts)
+			posts.GET("/drafts", postHandler.GetDrafts)
+			posts.POST("/:id/publish", postHandler.PublishPost)
+
+			posts.GET("/:id/comments", commentHandler.ListComments)
+			posts.POST("/:id/comments", commentHandler.CreateComment)
+
+			posts.GET("/:id/collections", collectionHandler.ListPostCollections)
+			posts.PUT("/:id/collections", collectionHandler.SetPostCollections)
+		}
+
+		collections := protected.Group("/collections")
+		{
+			collections.GET("", collectionHandler.ListOwnCollections)
+			collections.POST("", collectionHandler.CreateCollection)
+			collections.PUT("/order", collectionHandler.ReorderCollections)
+			collections.GET("/user/:id", collectionHandler.ListUserCollections)
+			collections.GET("/:id", collectionHandler.GetCollection)
+			collections.PUT("/:id", collectionHandler.UpdateCollection)
+			collections.DELETE("/:id", collectionHandler.DeleteCollection)
+
+			collections.GET("/:id/posts", collectionHandler.GetCollectionPosts)
+			collections.POST("/:id/posts", collectionHandler.AddPost)
+			collections.PUT("/:id/posts/order", collectionHandler.ReorderPosts)
+			collections.DELETE("/:id/posts/:post_id", collectionHandler.RemovePost)
+		}
+
+		tags := protected.Group("/tags")
+		{
+			tags.GET("", tagHandler.SuggestTags)
+			tags.GET("/following", tagHandler.ListFollowedTags)
+			tags.GET("/:name", tagHandler.GetTag)
+			tags.GET("/:name/posts", tagHandler.GetTagPosts)
+			tags.POST("/:name/follow", tagHandler.FollowTag)
+			tags.DELETE("/:name/follow", tagHandler.UnfollowTag)
+		}
 
+		comments := protected.Group("/comments")
+		{
+			comments.GET("/:comment_id/replies", commentHandler.ListReplies)
+			comments.PUT("/:comment_id", commentHandler.UpdateComment)
+			comments.DELETE("/:comment_id", commentHandler.DeleteComment)
 		}
 
 		files := protected.Group("/files")
@@ -285,6 +496,10 @@ func main() {
 			files.POST("/doc", fileHandler.UploadFile)
 			files.GET("/user/:id", fileHandler.GetFilesByUserID)
 			files.GET("/:document_id/summary", fileHandler.GetSummaryByDocumentID)
+			files.POST("/:document_id/summary/regenerate", fileHandler.RegenerateSummary)
+			files.GET("/:document_id/download", fileHandler.DownloadFile)
+			files.GET("/:document_id/status", fileHandler.GetProcessingStatus)
+			files.GET("/:document_id/status/stream", fileHandler.StreamProcessingStatus)
 			files.DELETE("/:document_id", fileHandler.DeleteFile)
 
 			files.POST("/cover", fileHandler.UploadCover)
@@ -296,6 +511,7 @@ func main() {
 			profile.GET("", userHandler.GetOwnProfile)
 			profile.PUT("", userHandler.UpdateOwnProfile)
 			profile.GET("/:id", userHandler.GetViewedUserProfile)
+			profile.GET("/:id/posts", postHandler.GetUserPosts)
 			profile.POST("/change-password", userHandler.ChangePassword)
 
 		}
@@ -321,6 +537,13 @@ func main() {
 			social.DELETE("/friends/:id", friendsHandler.Unfriend)
 			social.GET("/addfriends", friendsHandler.SearchAddFriend)
 
+			// กลุ่มเพื่อน (ใช้เป็นผู้ชมของโพสต์ custom)
+			social.GET("/groups", groupHandler.ListGroups)
+			social.POST("/groups", groupHandler.CreateGroup)
+			social.GET("/groups/:id", groupHandler.GetGroup)
+			social.PUT("/groups/:id", groupHandler.UpdateGroup)
+			social.DELETE("/groups/:id", groupHandler.DeleteGroup)
+
 		}
 
 		docfeatures := protected.Group("/features")
@@ -328,6 +551,21 @@ func main() {
 			docfeatures.GET("/vectors", featureHandler.GetVectors)
 			docfeatures.POST("/clusters/batch_update", featureHandler.BatchUpdateClusters)
 			docfeatures.POST("/clusters/run", featureHandler.RunClustering)
+			docfeatures.POST("/:document_id/retry", featureHandler.RetryExtraction)
+		}
+
+		notifications := protected.Group("/notifications")
+		{
+			notifications.GET("", notificationHandler.ListNotifications)
+			notifications.GET("/unread-count", notificationHandler.UnreadCount)
+			notifications.POST("/read-all", notificationHandler.MarkAllRead)
+			notifications.POST("/:id/read", notificationHandler.MarkRead)
+		}
+
+		realtime := protected.Group("/realtime")
+		{
+			realtime.GET("/stream", realtimeHandler.Stream)
+			realtime.PUT("/watch", realtimeHandler.Watch)
 		}
 
 		recommend := protected.Group("/recommend")
@@ -344,7 +582,18 @@ func main() {
 		port = "8080"
 	}
 
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to run server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err)
 	}
 }
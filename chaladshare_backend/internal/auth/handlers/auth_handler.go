@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 
@@ -114,6 +117,24 @@ func (h *AuthHandler) clearAuthCookies(c *gin.Context) {
 	})
 }
 
+// sessionMeta อุปกรณ์/IP ของคำขอ เก็บไว้แสดงในรายการ session
+func sessionMeta(c *gin.Context) models.SessionMeta {
+	ua := strings.TrimSpace(c.Request.UserAgent())
+	if utf8.RuneCountInString(ua) > models.MaxUserAgentLength {
+		ua = string([]rune(ua)[:models.MaxUserAgentLength])
+	}
+	return models.SessionMeta{UserAgent: ua, IP: c.ClientIP()}
+}
+
+// refreshToken จาก cookie ("" = ไม่มี)
+func (h *AuthHandler) refreshToken(c *gin.Context) string {
+	rt, err := c.Cookie(h.refreshCookieName)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(rt)
+}
+
 // Get all user
 func (h *AuthHandler) GetAllUsers(c *gin.Context) {
 	users, err := h.authService.GetAllUsers()
@@ -139,7 +160,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	// สร้าง access+refresh พร้อม session
-	access, refresh, err := h.authService.IssueSession(user.ID)
+	access, refresh, err := h.authService.IssueSession(user.ID, sessionMeta(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "issue session failed"})
 		return
@@ -166,7 +187,21 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	access, refresh, user, err := h.authService.LoginWithSession(req.Email, req.Password)
+	access, refresh, user, err := h.authService.LoginWithSession(req.Email, req.Password, sessionMeta(c))
+	var twoFactor *models.TwoFactorRequiredError
+	if errors.As(err, &twoFactor) {
+		// รหัสผ่านถูกแล้ว แต่ยังไม่ออก session จนกว่าจะผ่าน POST /auth/2fa/verify
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":             "two-factor authentication required",
+			"two_factor_required": true,
+			"two_factor_token":    twoFactor.Token,
+		})
+		return
+	}
+	if errors.Is(err, models.ErrUserNotActive) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
@@ -204,10 +239,11 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	newAccess, newRefresh, err := h.authService.Refresh(rt)
+	newAccess, newRefresh, err := h.authService.Refresh(rt, sessionMeta(c))
 	if err != nil {
+		// ไม่บอกสาเหตุ (หมดอายุ/ใช้ซ้ำ/ผู้ใช้ถูกระงับ/DB error) ให้ client แค่ login ใหม่
 		h.clearAuthCookies(c)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
 		return
 	}
 
@@ -219,6 +255,66 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	})
 }
 
+// GET /auth/sessions อุปกรณ์ที่ยัง login อยู่
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(uid, h.refreshToken(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": sessions})
+}
+
+// DELETE /auth/sessions/:id ปิด session เดียว (ถ้าเป็นเครื่องนี้จะล้าง cookie ด้วย)
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	current, err := h.authService.RevokeSession(uid, id, h.refreshToken(c))
+	if err != nil {
+		if errors.Is(err, models.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if current {
+		h.clearAuthCookies(c)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// DELETE /auth/sessions/others ออกจากระบบทุกอุปกรณ์ยกเว้นเครื่องนี้
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	n, err := h.authService.RevokeOtherSessions(uid, h.refreshToken(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"revoked": n}})
+}
+
 // ForgotPassword - ขอ OTP เพื่อรีเซ็ตรหัสผ่าน
 // ForgotPassword - ขอ OTP เพื่อรีเซ็ตรหัสผ่าน
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"chaladshare_backend/internal/auth/models"
+)
+
+// writeTwoFactorError แปลง error ของ 2FA เป็น status code
+func writeTwoFactorError(c *gin.Context, err error) {
+	var locked *models.TwoFactorLockedError
+	switch {
+	case errors.As(err, &locked):
+		secs := int(math.Ceil(time.Until(locked.Until).Seconds()))
+		if secs < 1 {
+			secs = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(secs))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retry_after": secs})
+	case errors.Is(err, models.ErrInvalidTwoFactorCode),
+		errors.Is(err, models.ErrInvalidPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidTwoFactorToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrUserNotActive):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrTwoFactorAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrTwoFactorUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GET /auth/2fa
+func (h *AuthHandler) TwoFactorStatus(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	status, err := h.authService.TwoFactorStatus(uid)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": status})
+}
+
+// POST /auth/2fa/enroll สร้าง secret + provisioning URI (ยังไม่เปิดใช้จนกว่าจะ confirm)
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	resp, err := h.authService.EnrollTwoFactor(uid)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"data": resp})
+}
+
+// POST /auth/2fa/confirm กรอกรหัสจากแอปเพื่อเปิดใช้ ได้ recovery code กลับไปครั้งเดียว
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.TwoFactorConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(uid, req.Code)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"data": models.TwoFactorConfirmResponse{RecoveryCodes: codes}})
+}
+
+// POST /auth/2fa/disable ต้องส่ง password หรือ recovery_code (อุปกรณ์อื่นจะถูก logout)
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if req.Password == "" && strings.TrimSpace(req.RecoveryCode) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password or recovery_code required"})
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(uid, req.Password, req.RecoveryCode, h.refreshToken(c), sessionMeta(c)); err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// POST /auth/2fa/verify ขั้นที่สองของ login ผ่านแล้วค่อยออก session + cookie
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req models.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if strings.TrimSpace(req.Token) == "" ||
+		(strings.TrimSpace(req.Code) == "" && strings.TrimSpace(req.RecoveryCode) == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+
+	access, refresh, user, err := h.authService.CompleteTwoFactorLogin(req.Token, req.Code, req.RecoveryCode, sessionMeta(c))
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+
+	h.setAccessCookie(c, access)
+	h.setRefreshCookie(c, refresh)
+
+	resp := models.AuthResponse{
+		ID: user.ID, Email: user.Email, Username: user.Username,
+		CreatedAt: user.CreatedAt, Status: user.Status,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "login successful",
+		"user":    resp,
+	})
+}
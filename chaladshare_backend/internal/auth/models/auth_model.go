@@ -1,6 +1,26 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// MaxOTPAttempts กรอก OTP ผิดครบจำนวนนี้ OTP นั้นใช้ไม่ได้ ต้องขอใหม่
+const MaxOTPAttempts = 5
+
+const MaxUserAgentLength = 512
+
+var (
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	ErrUserNotActive      = errors.New("account is not active")
+)
+
+// ประเภทของ security_events
+const (
+	SecurityEventRefreshReuse      = "refresh_token_reuse"
+	SecurityEventTwoFactorDisabled = "two_factor_disabled"
+)
 
 type User struct {
 	ID           int       `json:"id"`
@@ -9,6 +29,29 @@ type User struct {
 	PasswordHash string    `json:"-"`
 	CreatedAt    time.Time `json:"created_at"`
 	Status       string    `json:"status"`
+	TokenVersion int       `json:"-"`
+}
+
+const UserStatusActive = "active"
+
+// TokenConfig ค่าของ access token (JWT)
+type TokenConfig struct {
+	Secret     []byte
+	TTLMinutes int
+	Issuer     string
+	Audience   string
+	// อายุ cache ผลตรวจ session/สถานะผู้ใช้ของ middleware
+	AccessCacheTTL time.Duration
+	// กุญแจเข้ารหัส secret ของ TOTP ใน DB (แยกจาก Secret ที่ใช้เซ็น JWT)
+	TOTPKey []byte
+}
+
+// AccessState สถานะที่ middleware ใช้ตัดสินว่า access token ยังใช้ได้
+type AccessState struct {
+	UserID        int
+	SessionActive bool
+	UserStatus    string
+	TokenVersion  int
 }
 
 // register
@@ -40,6 +83,7 @@ type PasswordReset struct {
 	OTPHash   string
 	ExpiresAt time.Time
 	UsedAt    *time.Time
+	Attempts  int
 }
 
 type ForgotPasswordRequest struct {
@@ -73,6 +117,7 @@ type EmailVerification struct {
 	ExpiresAt time.Time
 	UsedAt    *time.Time
 	CreatedAt time.Time
+	Attempts  int
 }
 
 type RefreshResponse struct {
@@ -88,4 +133,24 @@ type AuthSession struct {
 	ReplacedByID     *int
 	CreatedAt        time.Time
 	LastUsedAt       *time.Time
+	StartedAt        time.Time
+	UserAgent        *string
+	IP               *string
+}
+
+// SessionMeta ข้อมูลอุปกรณ์ที่บันทึกตอน login/refresh
+type SessionMeta struct {
+	UserAgent string
+	IP        string
+}
+
+// SessionInfo รายการอุปกรณ์ที่ยัง login อยู่ (GET /auth/sessions)
+type SessionInfo struct {
+	SessionID  int        `json:"session_id"`
+	UserAgent  *string    `json:"user_agent"`
+	IP         *string    `json:"ip"`
+	StartedAt  time.Time  `json:"started_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"`
 }
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	TOTPIssuer = "ChaladShare"
+	TOTPDigits = 6
+	TOTPPeriod = 30 // วินาที
+
+	RecoveryCodeCount = 10
+
+	// กรอกรหัส 2FA ผิดติดกันครบแล้วล็อกช่วงหนึ่ง (6 หลักเดาได้ถ้าไม่จำกัด)
+	MaxTwoFactorAttempts = 5
+	TwoFactorLockout     = 15 * time.Minute
+
+	// อายุ token "รอ 2FA" ระหว่างกรอกรหัสผ่านถูกแล้วกับกรอกรหัส TOTP
+	TwoFactorTokenTTL = 5 * time.Minute
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidTwoFactorToken   = errors.New("invalid or expired two-factor token")
+	ErrInvalidPassword         = errors.New("invalid password")
+	// ไม่ได้ตั้ง TOTP.ENCRYPTION_KEY จึงสร้าง/ตรวจรหัสจากแอปไม่ได้ (recovery code ยังใช้ได้)
+	ErrTwoFactorUnavailable = errors.New("two-factor authentication is not configured on this server")
+)
+
+// TwoFactorRequiredError รหัสผ่านถูกแล้วแต่ต้องยืนยัน 2FA ก่อนออก session
+type TwoFactorRequiredError struct {
+	Token string
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return "two-factor authentication required"
+}
+
+// TwoFactorLockedError กรอกผิดครบจำนวนแล้ว ลองใหม่ได้หลัง Until
+type TwoFactorLockedError struct {
+	Until time.Time
+}
+
+func (e *TwoFactorLockedError) Error() string {
+	return "too many invalid two-factor attempts"
+}
+
+type UserTOTP struct {
+	UserID      int
+	Secret      string // เข้ารหัสแล้ว
+	EnabledAt   *time.Time
+	LastCounter int64
+	LockedUntil *time.Time
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorEnrollResponse secret แสดงครั้งเดียวตอน enroll (ใส่แอป authenticator เองได้ถ้าสแกน QR ไม่ได้)
+type TwoFactorEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorVerifyRequest ส่ง code หรือ recovery_code อย่างใดอย่างหนึ่ง
+type TwoFactorVerifyRequest struct {
+	Token        string `json:"two_factor_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// TwoFactorDisableRequest ต้องยืนยันด้วยรหัสผ่านปัจจุบันหรือ recovery code
+type TwoFactorDisableRequest struct {
+	Password     string `json:"password"`
+	RecoveryCode string `json:"recovery_code"`
+}
@@ -12,6 +12,7 @@ import (
 type AuthRepository interface {
 	GetAllUsers() ([]models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
+	GetUserByID(userID int) (*models.User, error)
 	IsEmailTaken(email string) (bool, error)
 	IsUsernameTaken(username string) (bool, error)
 	CreateUser(email, username, passwordHash string) (*models.User, error)
@@ -21,19 +22,46 @@ type AuthRepository interface {
 	GetLatestActivePasswordReset(userID int) (*models.PasswordReset, error)
 	MarkPasswordResetUsed(resetID int) error
 	MarkAllActivePasswordResetsUsed(userID int) error
+	ReservePasswordResetAttempt(resetID int) (bool, error)
+	ReleasePasswordResetAttempt(resetID int) error
 	UpdateUserPasswordHash(userID int, passwordHash string) error
 	// ✅ email verify (ก่อนสมัคร) 88
 	CreateEmailVerification(email string, otpHash string, expiresAt time.Time) error
 	GetLatestActiveEmailVerification(email string) (*models.EmailVerification, error)
 	MarkEmailVerificationUsed(verifyID int) error
 	MarkAllActiveEmailVerificationsUsed(email string) error
+	ReserveEmailVerificationAttempt(verifyID int) (bool, error)
 
 	// sessions refresh
-	CreateSession(userID int, refreshHash string, expiresAt time.Time) (*models.AuthSession, error)
+	CreateSession(userID int, refreshHash string, expiresAt time.Time, meta models.SessionMeta) (*models.AuthSession, error)
 	GetSessionByRefresh(refreshHash string) (*models.AuthSession, error)
 	RevokeSession(sessionID int) error
-	RotateSession(oldSessionID int, newRefreshHash string, newExpiresAt time.Time) (*models.AuthSession, error)
+	RotateSession(oldSessionID int, newRefreshHash string, newExpiresAt time.Time, meta models.SessionMeta) (*models.AuthSession, error)
 	UpdateSessionLastUsed(sessionID int) error
+
+	// จัดการอุปกรณ์ที่ login อยู่
+	ListActiveSessions(userID int) ([]models.AuthSession, error)
+	RevokeUserSession(userID, sessionID int) error
+	RevokeOtherSessions(userID, keepSessionID int) (int, error)
+
+	// ตรวจการใช้ refresh token ซ้ำ
+	FindSessionByRefresh(refreshHash string) (*models.AuthSession, error)
+	RevokeSessionChain(sessionID int) (int, error)
+	GetLiveSuccessor(sessionID int) (*models.AuthSession, error)
+	CreateSecurityEvent(userID int, eventType string, sessionID int, meta models.SessionMeta) error
+
+	// middleware ตรวจ access token
+	GetAccessState(sessionID int) (*models.AccessState, error)
+
+	// TOTP 2FA
+	GetTOTP(userID int) (*models.UserTOTP, error)
+	SavePendingTOTP(userID int, encSecret string) error
+	EnableTOTP(userID int, counter int64, recoveryHashes []string) error
+	DisableTOTP(userID int) error
+	UseTOTPCounter(userID int, counter int64) (bool, error)
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	ReserveTwoFactorAttempt(userID int) (bool, *time.Time, error)
+	CountRecoveryCodes(userID int) (int, error)
 }
 
 type authRepository struct {
@@ -79,12 +107,30 @@ func (r *authRepository) GetAllUsers() ([]models.User, error) {
 func (r *authRepository) GetUserByEmail(email string) (*models.User, error) {
 	var u models.User
 	err := r.db.QueryRow(`
-		SELECT user_id, email, username, password_hash, user_created_at, user_status
+		SELECT user_id, email, username, password_hash, user_created_at, user_status, user_token_version
 		FROM users
 		WHERE LOWER(email) = LOWER($1)
 	`, email).Scan(
 		&u.ID, &u.Email, &u.Username, &u.PasswordHash,
-		&u.CreatedAt, &u.Status,
+		&u.CreatedAt, &u.Status, &u.TokenVersion,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("ไม่พบบัญชีผู้ใช้")
+	} else if err != nil {
+		return nil, fmt.Errorf("เกิดข้อผิดพลาด: %w", err)
+	}
+	return &u, nil
+}
+
+func (r *authRepository) GetUserByID(userID int) (*models.User, error) {
+	var u models.User
+	err := r.db.QueryRow(`
+		SELECT user_id, email, username, password_hash, user_created_at, user_status, user_token_version
+		FROM users
+		WHERE user_id = $1
+	`, userID).Scan(
+		&u.ID, &u.Email, &u.Username, &u.PasswordHash,
+		&u.CreatedAt, &u.Status, &u.TokenVersion,
 	)
 	if err == sql.ErrNoRows {
 		return nil, errors.New("ไม่พบบัญชีผู้ใช้")
@@ -126,14 +172,14 @@ func (r *authRepository) CreatePasswordReset(userID int, otpHash string, expires
 func (r *authRepository) GetLatestActivePasswordReset(userID int) (*models.PasswordReset, error) {
 	var pr models.PasswordReset
 	err := r.db.QueryRow(`
-		SELECT reset_pass_id, reset_pass_user_id, otp_hash, reset_pass_expires_at, used_at
+		SELECT reset_pass_id, reset_pass_user_id, otp_hash, reset_pass_expires_at, used_at, reset_pass_attempts
 		FROM password_resets
 		WHERE reset_pass_user_id = $1
 		  AND used_at IS NULL
 		  AND reset_pass_expires_at > NOW()
 		ORDER BY reset_pass_id DESC
 		LIMIT 1
-	`, userID).Scan(&pr.ID, &pr.UserID, &pr.OTPHash, &pr.ExpiresAt, &pr.UsedAt)
+	`, userID).Scan(&pr.ID, &pr.UserID, &pr.OTPHash, &pr.ExpiresAt, &pr.UsedAt, &pr.Attempts)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("no active otp")
@@ -170,10 +216,47 @@ func (r *authRepository) MarkAllActivePasswordResetsUsed(userID int) error {
 	return nil
 }
 
+// ReservePasswordResetAttempt จองสิทธิ์กรอก OTP หนึ่งครั้งก่อนเทียบ (false = ครบ MaxOTPAttempts/หมดอายุแล้ว)
+// นับใน UPDATE เดียว คำขอที่ยิงพร้อมกันจึงเกินจำนวนครั้งไม่ได้
+func (r *authRepository) ReservePasswordResetAttempt(resetID int) (bool, error) {
+	var attempts int
+	err := r.db.QueryRow(`
+		UPDATE password_resets
+		SET reset_pass_attempts = reset_pass_attempts + 1
+		WHERE reset_pass_id = $1
+		  AND reset_pass_attempts < $2
+		  AND used_at IS NULL
+		  AND reset_pass_expires_at > NOW()
+		RETURNING reset_pass_attempts
+	`, resetID, models.MaxOTPAttempts).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reserve reset otp attempt failed: %w", err)
+	}
+	return true, nil
+}
+
+// ReleasePasswordResetAttempt คืนสิทธิ์เมื่อ OTP ถูก (ตรวจด้วย verify-otp แล้วยังใช้ reset-password ต่อได้)
+func (r *authRepository) ReleasePasswordResetAttempt(resetID int) error {
+	_, err := r.db.Exec(`
+		UPDATE password_resets
+		SET reset_pass_attempts = GREATEST(reset_pass_attempts - 1, 0)
+		WHERE reset_pass_id = $1
+	`, resetID)
+	if err != nil {
+		return fmt.Errorf("release reset otp attempt failed: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserPasswordHash ใช้ตอนรีเซ็ตรหัสผ่าน เพิ่ม token version ให้ access token เดิมใช้ไม่ได้ทันที
 func (r *authRepository) UpdateUserPasswordHash(userID int, passwordHash string) error {
 	_, err := r.db.Exec(`
 		UPDATE users
-		SET password_hash = $2
+		SET password_hash = $2,
+		    user_token_version = user_token_version + 1
 		WHERE user_id = $1
 	`, userID, passwordHash)
 	if err != nil {
@@ -195,14 +278,14 @@ func (r *authRepository) CreateEmailVerification(email string, otpHash string, e
 func (r *authRepository) GetLatestActiveEmailVerification(email string) (*models.EmailVerification, error) {
 	var ev models.EmailVerification
 	err := r.db.QueryRow(`
-		SELECT verify_id, email, otp_hash, expires_at, used_at, created_at
+		SELECT verify_id, email, otp_hash, expires_at, used_at, created_at, verify_attempts
 		FROM email_verifications
 		WHERE lower(email) = lower($1)
 		  AND used_at IS NULL
 		  AND expires_at > NOW()
 		ORDER BY verify_id DESC
 		LIMIT 1
-	`, email).Scan(&ev.ID, &ev.Email, &ev.OTPHash, &ev.ExpiresAt, &ev.UsedAt, &ev.CreatedAt)
+	`, email).Scan(&ev.ID, &ev.Email, &ev.OTPHash, &ev.ExpiresAt, &ev.UsedAt, &ev.CreatedAt, &ev.Attempts)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("no active otp")
@@ -225,6 +308,28 @@ func (r *authRepository) MarkEmailVerificationUsed(verifyID int) error {
 	return nil
 }
 
+// ReserveEmailVerificationAttempt เหมือน ReservePasswordResetAttempt แต่ของ OTP ยืนยันอีเมล
+// (OTP ถูกแล้วถูกปิดทันที จึงไม่ต้องคืนสิทธิ์)
+func (r *authRepository) ReserveEmailVerificationAttempt(verifyID int) (bool, error) {
+	var attempts int
+	err := r.db.QueryRow(`
+		UPDATE email_verifications
+		SET verify_attempts = verify_attempts + 1
+		WHERE verify_id = $1
+		  AND verify_attempts < $2
+		  AND used_at IS NULL
+		  AND expires_at > NOW()
+		RETURNING verify_attempts
+	`, verifyID, models.MaxOTPAttempts).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reserve email otp attempt failed: %w", err)
+	}
+	return true, nil
+}
+
 // 88
 func (r *authRepository) MarkAllActiveEmailVerificationsUsed(email string) error {
 	_, err := r.db.Exec(`
@@ -266,43 +371,62 @@ func (r *authRepository) IsUsernameTaken(username string) (bool, error) {
 	return exists, nil
 }
 
-func (r *authRepository) CreateSession(userID int, refreshHash string, expiresAt time.Time) (*models.AuthSession, error) {
+// sessionColumns ลำดับต้องตรงกับ scanSession
+const sessionColumns = `
+	session_id, session_user_id, refresh_token_hash, session_expires_at,
+	revoked_at, replaced_by_session_id, created_at, last_used_at,
+	session_started_at, session_user_agent, session_ip`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSession(row rowScanner) (*models.AuthSession, error) {
 	var s models.AuthSession
-	err := r.db.QueryRow(`
+	if err := row.Scan(
+		&s.SessionID, &s.UserID, &s.RefreshTokenHash, &s.ExpiresAt,
+		&s.RevokedAt, &s.ReplacedByID, &s.CreatedAt, &s.LastUsedAt,
+		&s.StartedAt, &s.UserAgent, &s.IP,
+	); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// nullIfEmpty ไม่มี user-agent/IP เก็บเป็น NULL
+func nullIfEmpty(v string) any {
+	if v == "" {
+		return nil
+	}
+	return v
+}
+
+func (r *authRepository) CreateSession(userID int, refreshHash string, expiresAt time.Time, meta models.SessionMeta) (*models.AuthSession, error) {
+	s, err := scanSession(r.db.QueryRow(`
 		INSERT INTO auth_sessions (
 			session_user_id, refresh_token_hash, session_expires_at,
-			created_at, last_used_at, revoked_at, replaced_by_session_id
+			created_at, last_used_at, revoked_at, replaced_by_session_id,
+			session_started_at, session_user_agent, session_ip
 		)
-		VALUES ($1, $2, $3, NOW(), NOW(), NULL, NULL)
-		RETURNING
-			session_id, session_user_id, refresh_token_hash, session_expires_at,
-			revoked_at, replaced_by_session_id, created_at, last_used_at
-	`, userID, refreshHash, expiresAt).Scan(
-		&s.SessionID, &s.UserID, &s.RefreshTokenHash, &s.ExpiresAt,
-		&s.RevokedAt, &s.ReplacedByID, &s.CreatedAt, &s.LastUsedAt,
-	)
+		VALUES ($1, $2, $3, NOW(), NOW(), NULL, NULL, NOW(), $4, $5)
+		RETURNING`+sessionColumns,
+		userID, refreshHash, expiresAt, nullIfEmpty(meta.UserAgent), nullIfEmpty(meta.IP)))
 	if err != nil {
 		return nil, fmt.Errorf("create session failed: %w", err)
 	}
-	return &s, nil
+	return s, nil
 }
 
 func (r *authRepository) GetSessionByRefresh(refreshHash string) (*models.AuthSession, error) {
-	var s models.AuthSession
-	err := r.db.QueryRow(`
-  SELECT
-    session_id, session_user_id, refresh_token_hash, session_expires_at,
-    revoked_at, replaced_by_session_id, created_at, last_used_at
+	s, err := scanSession(r.db.QueryRow(`
+  SELECT`+sessionColumns+`
   FROM auth_sessions
   WHERE refresh_token_hash = $1
     AND revoked_at IS NULL
     AND session_expires_at > NOW()
   ORDER BY session_id DESC
   LIMIT 1
-`, refreshHash).Scan(
-		&s.SessionID, &s.UserID, &s.RefreshTokenHash, &s.ExpiresAt,
-		&s.RevokedAt, &s.ReplacedByID, &s.CreatedAt, &s.LastUsedAt,
-	)
+`, refreshHash))
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("session not found or expired")
@@ -310,7 +434,139 @@ func (r *authRepository) GetSessionByRefresh(refreshHash string) (*models.AuthSe
 	if err != nil {
 		return nil, fmt.Errorf("get session by refresh failed: %w", err)
 	}
-	return &s, nil
+	return s, nil
+}
+
+// FindSessionByRefresh เหมือน GetSessionByRefresh แต่รวม session ที่ revoke/rotate ไปแล้ว (ไม่พบ = nil, nil)
+func (r *authRepository) FindSessionByRefresh(refreshHash string) (*models.AuthSession, error) {
+	s, err := scanSession(r.db.QueryRow(`
+		SELECT`+sessionColumns+`
+		FROM auth_sessions
+		WHERE refresh_token_hash = $1
+	`, refreshHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find session by refresh failed: %w", err)
+	}
+	return s, nil
+}
+
+// RevokeSessionChain revoke session นี้และทุก session ที่ rotate ต่อจากมัน
+func (r *authRepository) RevokeSessionChain(sessionID int) (int, error) {
+	res, err := r.db.Exec(`
+		WITH RECURSIVE chain AS (
+			SELECT session_id, replaced_by_session_id
+			FROM auth_sessions
+			WHERE session_id = $1
+			UNION
+			SELECT s.session_id, s.replaced_by_session_id
+			FROM auth_sessions s
+			JOIN chain c ON s.session_id = c.replaced_by_session_id
+		)
+		UPDATE auth_sessions
+		SET revoked_at = NOW()
+		WHERE session_id IN (SELECT session_id FROM chain)
+		  AND revoked_at IS NULL
+	`, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("revoke session chain failed: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// GetLiveSuccessor session ที่ยังใช้ได้ตัวล่าสุดใน chain ที่ rotate ต่อจาก sessionID (ไม่มี = nil, nil)
+func (r *authRepository) GetLiveSuccessor(sessionID int) (*models.AuthSession, error) {
+	s, err := scanSession(r.db.QueryRow(`
+		WITH RECURSIVE chain AS (
+			SELECT session_id, replaced_by_session_id
+			FROM auth_sessions
+			WHERE session_id = $1
+			UNION
+			SELECT s.session_id, s.replaced_by_session_id
+			FROM auth_sessions s
+			JOIN chain c ON s.session_id = c.replaced_by_session_id
+		)
+		SELECT`+sessionColumns+`
+		FROM auth_sessions
+		WHERE session_id IN (SELECT session_id FROM chain)
+		  AND session_id <> $1
+		  AND revoked_at IS NULL
+		  AND session_expires_at > NOW()
+		ORDER BY session_id DESC
+		LIMIT 1
+	`, sessionID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get live successor failed: %w", err)
+	}
+	return s, nil
+}
+
+func (r *authRepository) CreateSecurityEvent(userID int, eventType string, sessionID int, meta models.SessionMeta) error {
+	var sid any
+	if sessionID > 0 {
+		sid = sessionID
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO security_events (event_user_id, event_type, event_session_id, event_ip, event_user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, eventType, sid, nullIfEmpty(meta.IP), nullIfEmpty(meta.UserAgent))
+	if err != nil {
+		return fmt.Errorf("create security event failed: %w", err)
+	}
+	return nil
+}
+
+// GetAccessState สถานะ session + ผู้ใช้ของ access token (ไม่พบ/ผู้ใช้ถูกลบ = nil, nil)
+func (r *authRepository) GetAccessState(sessionID int) (*models.AccessState, error) {
+	var st models.AccessState
+	err := r.db.QueryRow(`
+		SELECT s.session_user_id,
+		       (s.revoked_at IS NULL AND s.session_expires_at > NOW()),
+		       COALESCE(u.user_status, ''),
+		       u.user_token_version
+		FROM auth_sessions s
+		JOIN users u ON u.user_id = s.session_user_id
+		WHERE s.session_id = $1
+	`, sessionID).Scan(&st.UserID, &st.SessionActive, &st.UserStatus, &st.TokenVersion)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get access state failed: %w", err)
+	}
+	return &st, nil
+}
+
+// ListActiveSessions session ที่ยังไม่ revoke และไม่หมดอายุ ใช้ล่าสุดก่อน
+func (r *authRepository) ListActiveSessions(userID int) ([]models.AuthSession, error) {
+	rows, err := r.db.Query(`
+		SELECT`+sessionColumns+`
+		FROM auth_sessions
+		WHERE session_user_id = $1
+		  AND revoked_at IS NULL
+		  AND session_expires_at > NOW()
+		ORDER BY COALESCE(last_used_at, created_at) DESC, session_id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions failed: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.AuthSession
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan session failed: %w", err)
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
 }
 
 func (r *authRepository) RevokeSession(sessionID int) error {
@@ -326,6 +582,41 @@ func (r *authRepository) RevokeSession(sessionID int) error {
 	return nil
 }
 
+// RevokeUserSession revoke เฉพาะ session ของ user นี้ ไม่พบ/ของคนอื่น = ErrSessionNotFound
+func (r *authRepository) RevokeUserSession(userID, sessionID int) error {
+	res, err := r.db.Exec(`
+		UPDATE auth_sessions
+		SET revoked_at = NOW()
+		WHERE session_id = $1
+		  AND session_user_id = $2
+		  AND revoked_at IS NULL
+		  AND session_expires_at > NOW()
+	`, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("revoke session failed: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeOtherSessions revoke ทุก session ของ user ยกเว้น keepSessionID (0 = revoke ทั้งหมด)
+func (r *authRepository) RevokeOtherSessions(userID, keepSessionID int) (int, error) {
+	res, err := r.db.Exec(`
+		UPDATE auth_sessions
+		SET revoked_at = NOW()
+		WHERE session_user_id = $1
+		  AND session_id <> $2
+		  AND revoked_at IS NULL
+	`, userID, keepSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("revoke other sessions failed: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
 func (r *authRepository) UpdateSessionLastUsed(sessionID int) error {
 	_, err := r.db.Exec(`
 		UPDATE auth_sessions
@@ -338,7 +629,8 @@ func (r *authRepository) UpdateSessionLastUsed(sessionID int) error {
 	return nil
 }
 
-func (r *authRepository) RotateSession(oldSessionID int, newRefreshHash string, newExpiresAt time.Time) (*models.AuthSession, error) {
+// RotateSession ออก session ใหม่แทนของเดิม (คงเวลา login เดิม อัปเดตอุปกรณ์/IP ล่าสุด)
+func (r *authRepository) RotateSession(oldSessionID int, newRefreshHash string, newExpiresAt time.Time, meta models.SessionMeta) (*models.AuthSession, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return nil, fmt.Errorf("begin tx failed: %w", err)
@@ -346,15 +638,18 @@ func (r *authRepository) RotateSession(oldSessionID int, newRefreshHash string,
 	defer func() { _ = tx.Rollback() }()
 
 	// ล็อก session เก่า
-	var userID int
+	var (
+		userID    int
+		startedAt time.Time
+	)
 	err = tx.QueryRow(`
-		SELECT session_user_id
+		SELECT session_user_id, session_started_at
 		FROM auth_sessions
 		WHERE session_id = $1
 		  AND revoked_at IS NULL
 		  AND session_expires_at > NOW()
 		FOR UPDATE
-	`, oldSessionID).Scan(&userID)
+	`, oldSessionID).Scan(&userID, &startedAt)
 	if err == sql.ErrNoRows {
 		return nil, errors.New("old session invalid/expired")
 	}
@@ -363,20 +658,15 @@ func (r *authRepository) RotateSession(oldSessionID int, newRefreshHash string,
 	}
 
 	// สร้าง session ใหม่
-	var ns models.AuthSession
-	err = tx.QueryRow(`
+	ns, err := scanSession(tx.QueryRow(`
 		INSERT INTO auth_sessions (
 			session_user_id, refresh_token_hash, session_expires_at,
-			created_at, last_used_at, revoked_at, replaced_by_session_id
+			created_at, last_used_at, revoked_at, replaced_by_session_id,
+			session_started_at, session_user_agent, session_ip
 		)
-		VALUES ($1, $2, $3, NOW(), NOW(), NULL, NULL)
-		RETURNING
-			session_id, session_user_id, refresh_token_hash, session_expires_at,
-			revoked_at, replaced_by_session_id, created_at, last_used_at
-	`, userID, newRefreshHash, newExpiresAt).Scan(
-		&ns.SessionID, &ns.UserID, &ns.RefreshTokenHash, &ns.ExpiresAt,
-		&ns.RevokedAt, &ns.ReplacedByID, &ns.CreatedAt, &ns.LastUsedAt,
-	)
+		VALUES ($1, $2, $3, NOW(), NOW(), NULL, NULL, $4, $5, $6)
+		RETURNING`+sessionColumns,
+		userID, newRefreshHash, newExpiresAt, startedAt, nullIfEmpty(meta.UserAgent), nullIfEmpty(meta.IP)))
 	if err != nil {
 		return nil, fmt.Errorf("insert new session failed: %w", err)
 	}
@@ -396,5 +686,5 @@ func (r *authRepository) RotateSession(oldSessionID int, newRefreshHash string,
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("commit tx failed: %w", err)
 	}
-	return &ns, nil
+	return ns, nil
 }
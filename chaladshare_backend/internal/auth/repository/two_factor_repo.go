@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"chaladshare_backend/internal/auth/models"
+)
+
+// GetTOTP ไม่เคย enroll = nil, nil
+func (r *authRepository) GetTOTP(userID int) (*models.UserTOTP, error) {
+	var t models.UserTOTP
+	err := r.db.QueryRow(`
+		SELECT totp_user_id, totp_secret, totp_enabled_at, totp_last_counter, totp_locked_until
+		FROM user_totp
+		WHERE totp_user_id = $1
+	`, userID).Scan(&t.UserID, &t.Secret, &t.EnabledAt, &t.LastCounter, &t.LockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get totp failed: %w", err)
+	}
+	return &t, nil
+}
+
+// SavePendingTOTP เริ่ม enroll ใหม่ได้เรื่อย ๆ จนกว่าจะยืนยัน เปิดใช้แล้ว = ErrTwoFactorAlreadyEnabled
+func (r *authRepository) SavePendingTOTP(userID int, encSecret string) error {
+	res, err := r.db.Exec(`
+		INSERT INTO user_totp (totp_user_id, totp_secret)
+		VALUES ($1, $2)
+		ON CONFLICT (totp_user_id) DO UPDATE SET
+			totp_secret = EXCLUDED.totp_secret,
+			totp_last_counter = 0,
+			totp_failures = 0,
+			totp_locked_until = NULL,
+			totp_created_at = NOW()
+		WHERE user_totp.totp_enabled_at IS NULL
+	`, userID, encSecret)
+	if err != nil {
+		return fmt.Errorf("save pending totp failed: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrTwoFactorAlreadyEnabled
+	}
+	return nil
+}
+
+// EnableTOTP ยืนยัน enroll และแทนที่ recovery code ทั้งชุด
+func (r *authRepository) EnableTOTP(userID int, counter int64, recoveryHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(`
+		UPDATE user_totp
+		SET totp_enabled_at = NOW(),
+		    totp_last_counter = $2,
+		    totp_failures = 0,
+		    totp_locked_until = NULL
+		WHERE totp_user_id = $1
+		  AND totp_enabled_at IS NULL
+	`, userID, counter)
+	if err != nil {
+		return fmt.Errorf("enable totp failed: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrTwoFactorAlreadyEnabled
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE recovery_user_id = $1`, userID); err != nil {
+		return fmt.Errorf("clear recovery codes failed: %w", err)
+	}
+	for _, h := range recoveryHashes {
+		if _, err := tx.Exec(`
+			INSERT INTO user_recovery_codes (recovery_user_id, recovery_code_hash)
+			VALUES ($1, $2)
+		`, userID, h); err != nil {
+			return fmt.Errorf("insert recovery code failed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx failed: %w", err)
+	}
+	return nil
+}
+
+func (r *authRepository) DisableTOTP(userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx failed: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM user_totp WHERE totp_user_id = $1`, userID); err != nil {
+		return fmt.Errorf("delete totp failed: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE recovery_user_id = $1`, userID); err != nil {
+		return fmt.Errorf("delete recovery codes failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx failed: %w", err)
+	}
+	return nil
+}
+
+// UseTOTPCounter รับรหัสได้เฉพาะ time step ที่ใหม่กว่าครั้งล่าสุด (รหัสเดิมใช้ซ้ำไม่ได้)
+func (r *authRepository) UseTOTPCounter(userID int, counter int64) (bool, error) {
+	res, err := r.db.Exec(`
+		UPDATE user_totp
+		SET totp_last_counter = $2,
+		    totp_failures = 0,
+		    totp_locked_until = NULL
+		WHERE totp_user_id = $1
+		  AND totp_enabled_at IS NOT NULL
+		  AND totp_last_counter < $2
+	`, userID, counter)
+	if err != nil {
+		return false, fmt.Errorf("use totp counter failed: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// UseRecoveryCode ใช้แล้วใช้ซ้ำไม่ได้
+func (r *authRepository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	res, err := r.db.Exec(`
+		UPDATE user_recovery_codes
+		SET recovery_used_at = NOW()
+		WHERE recovery_user_id = $1
+		  AND recovery_code_hash = $2
+		  AND recovery_used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("use recovery code failed: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return false, nil
+	}
+
+	if _, err := r.db.Exec(`
+		UPDATE user_totp SET totp_failures = 0, totp_locked_until = NULL WHERE totp_user_id = $1
+	`, userID); err != nil {
+		return true, fmt.Errorf("reset totp failures failed: %w", err)
+	}
+	return true, nil
+}
+
+// ReserveTwoFactorAttempt นับครั้งก่อนตรวจรหัส (สำเร็จแล้ว UseTOTPCounter/UseRecoveryCode ล้างให้)
+// ครั้งที่ครบ MaxTwoFactorAttempts ล็อกทันที คำขอที่ยิงพร้อมกันจึงเดาได้ไม่เกินจำนวนนี้
+// คืน false + เวลาปลดล็อกถ้ายังล็อกอยู่ (เวลาเป็น nil = ไม่มี TOTP)
+// ถ้าครั้งนี้เป็นครั้งที่ทำให้ล็อก คืน true + เวลาปลดล็อก
+func (r *authRepository) ReserveTwoFactorAttempt(userID int) (bool, *time.Time, error) {
+	var lockedUntil *time.Time
+	err := r.db.QueryRow(`
+		UPDATE user_totp
+		SET totp_failures = CASE WHEN totp_failures + 1 >= $2 THEN 0 ELSE totp_failures + 1 END,
+		    totp_locked_until = CASE WHEN totp_failures + 1 >= $2
+		                             THEN NOW() + make_interval(secs => $3)
+		                             ELSE NULL END
+		WHERE totp_user_id = $1
+		  AND (totp_locked_until IS NULL OR totp_locked_until <= NOW())
+		RETURNING totp_locked_until
+	`, userID, models.MaxTwoFactorAttempts, models.TwoFactorLockout.Seconds()).Scan(&lockedUntil)
+	if err == nil {
+		return true, lockedUntil, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, nil, fmt.Errorf("reserve two-factor attempt failed: %w", err)
+	}
+
+	err = r.db.QueryRow(`
+		SELECT totp_locked_until FROM user_totp WHERE totp_user_id = $1
+	`, userID).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("get two-factor lock failed: %w", err)
+	}
+	return false, lockedUntil, nil
+}
+
+func (r *authRepository) CountRecoveryCodes(userID int) (int, error) {
+	var n int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM user_recovery_codes
+		WHERE recovery_user_id = $1
+		  AND recovery_used_at IS NULL
+	`, userID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count recovery codes failed: %w", err)
+	}
+	return n, nil
+}
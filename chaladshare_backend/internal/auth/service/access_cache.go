@@ -0,0 +1,73 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"chaladshare_backend/internal/auth/models"
+)
+
+const accessCacheSweepInterval = time.Minute
+
+type accessCacheEntry struct {
+	state     *models.AccessState // nil = ไม่พบ session/ผู้ใช้
+	expiresAt time.Time
+}
+
+// accessCache เก็บผลตรวจ session ต่อ session id ไว้สั้น ๆ ไม่ให้ middleware ยิง DB ทุกคำขอ
+// revoke จากที่อื่น (instance อื่น/แก้ใน DB ตรง) จะมีผลภายใน ttl
+type accessCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[int]accessCacheEntry
+	lastSweep time.Time
+}
+
+func newAccessCache(ttl time.Duration) *accessCache {
+	return &accessCache{ttl: ttl, entries: make(map[int]accessCacheEntry), lastSweep: time.Now()}
+}
+
+func (c *accessCache) get(sessionID int) (*models.AccessState, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[sessionID]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, false
+	}
+	return e.state, true
+}
+
+func (c *accessCache) put(sessionID int, state *models.AccessState) {
+	if c.ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastSweep) >= accessCacheSweepInterval {
+		for id, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.entries[sessionID] = accessCacheEntry{state: state, expiresAt: now.Add(c.ttl)}
+}
+
+// invalidateUser ล้าง cache ของผู้ใช้หลัง revoke ใน instance นี้ ให้มีผลทันที
+func (c *accessCache) invalidateUser(userID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, e := range c.entries {
+		if e.state != nil && e.state.UserID == userID {
+			delete(c.entries, id)
+		}
+	}
+}
@@ -26,8 +26,8 @@ type AuthService interface {
 	IsEmailTaken(email string) (bool, error)
 	IsUsernameTaken(username string) (bool, error)
 	Login(email, password string) (*models.User, error)
-	IssueToken(userID int) (string, error)
-	IssueSession(userID int) (accessToken string, refreshToken string, err error)
+	IssueToken(user *models.User, sessionID int) (string, error)
+	IssueSession(userID int, meta models.SessionMeta) (accessToken string, refreshToken string, err error)
 	ForgotPassword(email string) error
 	ResetPassword(email, otp, newPassword string) error
 	//88
@@ -36,19 +36,43 @@ type AuthService interface {
 	ValidateEmailVerifyToken(email, token string) error
 	VerifyForgotOTP(email, otp string) error
 
-	LoginWithSession(email, password string) (accessToken string, refreshToken string, user *models.User, err error)
-	Refresh(refreshToken string) (newAccess string, newRefresh string, err error)
+	LoginWithSession(email, password string, meta models.SessionMeta) (accessToken string, refreshToken string, user *models.User, err error)
+	Refresh(refreshToken string, meta models.SessionMeta) (newAccess string, newRefresh string, err error)
 	Logout(refreshToken string) error
+
+	// อุปกรณ์ที่ login อยู่ (currentRefresh ใช้ระบุ session ของคำขอนี้)
+	ListSessions(userID int, currentRefresh string) ([]models.SessionInfo, error)
+	RevokeSession(userID, sessionID int, currentRefresh string) (wasCurrent bool, err error)
+	SessionRevoker
+
+	// CheckAccess ให้ middleware ตรวจว่า session ยังไม่ถูก revoke ผู้ใช้ยัง active และ token version ตรง
+	CheckAccess(userID, sessionID, tokenVersion int) (bool, error)
+
+	// TOTP 2FA (LoginWithSession คืน *models.TwoFactorRequiredError ถ้าเปิดไว้)
+	TwoFactorStatus(userID int) (*models.TwoFactorStatus, error)
+	EnrollTwoFactor(userID int) (*models.TwoFactorEnrollResponse, error)
+	ConfirmTwoFactor(userID int, code string) (recoveryCodes []string, err error)
+	DisableTwoFactor(userID int, password, recoveryCode, currentRefresh string, meta models.SessionMeta) error
+	CompleteTwoFactorLogin(twoFactorToken, code, recoveryCode string, meta models.SessionMeta) (accessToken string, refreshToken string, user *models.User, err error)
+}
+
+// SessionRevoker ให้โมดูลอื่น (เช่นเปลี่ยนรหัสผ่าน) ปิด session อื่นของผู้ใช้ได้
+type SessionRevoker interface {
+	RevokeOtherSessions(userID int, currentRefresh string) (int, error)
 }
 
 type authService struct {
 	userRepo        repository.AuthRepository
 	jwtSecret       []byte
 	tokenTTLMinutes int
+	issuer          string
+	audience        string
+	access          *accessCache
+	totpKey         []byte
 	mailer          *mail.Mailer
 }
 
-func NewAuthService(userRepo repository.AuthRepository, secret []byte, ttlMin int) AuthService {
+func NewAuthService(userRepo repository.AuthRepository, tokenCfg models.TokenConfig) AuthService {
 	// ถ้าไม่ได้ตั้งค่า SMTP ก็ให้ mailer เป็น nil (กันแอปล้มตอน dev)
 	host := os.Getenv("SMTP_HOST")
 	portStr := os.Getenv("SMTP_PORT")
@@ -65,8 +89,12 @@ func NewAuthService(userRepo repository.AuthRepository, secret []byte, ttlMin in
 
 	return &authService{
 		userRepo:        userRepo,
-		jwtSecret:       secret,
-		tokenTTLMinutes: ttlMin,
+		jwtSecret:       tokenCfg.Secret,
+		tokenTTLMinutes: tokenCfg.TTLMinutes,
+		issuer:          tokenCfg.Issuer,
+		audience:        tokenCfg.Audience,
+		access:          newAccessCache(tokenCfg.AccessCacheTTL),
+		totpKey:         tokenCfg.TOTPKey,
 		mailer:          m,
 	}
 }
@@ -80,10 +108,15 @@ func generateOTP6() (string, error) {
 	return fmt.Sprintf("%06d", nBig.Int64()), nil
 }
 
-func (s *authService) IssueToken(userID int) (string, error) {
+// IssueToken access token ผูกกับ session (sid) และ token version ของผู้ใช้ (ver)
+func (s *authService) IssueToken(user *models.User, sessionID int) (string, error) {
 	now := time.Now()
 	claims := jwt.MapClaims{
-		"user_id": userID,
+		"user_id": user.ID,
+		"sid":     sessionID,
+		"ver":     user.TokenVersion,
+		"iss":     s.issuer,
+		"aud":     s.audience,
 		"iat":     now.Unix(),
 		"exp":     now.Add(time.Duration(s.tokenTTLMinutes) * time.Minute).Unix(),
 	}
@@ -96,12 +129,16 @@ func (s *authService) GetAllUsers() ([]models.User, error) {
 	return s.userRepo.GetAllUsers()
 }
 
-func (s *authService) IssueSession(userID int) (string, string, error) {
-	access, err := s.IssueToken(userID)
+func (s *authService) IssueSession(userID int, meta models.SessionMeta) (string, string, error) {
+	user, err := s.userRepo.GetUserByID(userID)
 	if err != nil {
 		return "", "", err
 	}
+	return s.startSession(user, meta)
+}
 
+// startSession สร้าง session ก่อน แล้วออก access token ที่อ้าง session นั้น
+func (s *authService) startSession(user *models.User, meta models.SessionMeta) (string, string, error) {
 	refresh, err := newRefreshToken()
 	if err != nil {
 		return "", "", err
@@ -110,10 +147,15 @@ func (s *authService) IssueSession(userID int) (string, string, error) {
 	refreshHash := hashToken(refresh)
 	expiresAt := time.Now().Add(30 * 24 * time.Hour)
 
-	if _, err := s.userRepo.CreateSession(userID, refreshHash, expiresAt); err != nil {
+	sess, err := s.userRepo.CreateSession(user.ID, refreshHash, expiresAt, meta)
+	if err != nil {
 		return "", "", err
 	}
 
+	access, err := s.IssueToken(user, sess.SessionID)
+	if err != nil {
+		return "", "", err
+	}
 	return access, refresh, nil
 }
 
@@ -185,6 +227,11 @@ func (s *authService) Login(email, password string) (*models.User, error) {
 		return nil, errors.New("invalid password")
 	}
 
+	// บัญชีที่ถูกระงับ/ปิดไปแล้ว login ไม่ได้
+	if user.Status != models.UserStatusActive {
+		return nil, models.ErrUserNotActive
+	}
+
 	return user, nil
 }
 func (s *authService) ForgotPassword(email string) error {
@@ -258,8 +305,8 @@ func (s *authService) ResetPassword(email, otp, newPassword string) error {
 		return errors.New("invalid otp or expired")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(pr.OTPHash), []byte(otp)); err != nil {
-		return errors.New("invalid otp or expired")
+	if err := s.checkResetOTP(pr, otp); err != nil {
+		return err
 	}
 
 	newHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
@@ -275,6 +322,30 @@ func (s *authService) ResetPassword(email, otp, newPassword string) error {
 		return err
 	}
 
+	// รีเซ็ตรหัสผ่านแล้วให้ทุกอุปกรณ์ login ใหม่
+	if _, err := s.userRepo.RevokeOtherSessions(user.ID, 0); err != nil {
+		return err
+	}
+	s.access.invalidateUser(user.ID)
+
+	return nil
+}
+
+// checkResetOTP จองสิทธิ์กรอกหนึ่งครั้งก่อนเทียบ OTP (ครบ MaxOTPAttempts แล้วใช้ OTP นี้ไม่ได้) ถูกแล้วคืนสิทธิ์
+func (s *authService) checkResetOTP(pr *models.PasswordReset, otp string) error {
+	ok, err := s.userRepo.ReservePasswordResetAttempt(pr.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("invalid otp or expired")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(pr.OTPHash), []byte(otp)); err != nil {
+		return errors.New("invalid otp or expired")
+	}
+	if err := s.userRepo.ReleasePasswordResetAttempt(pr.ID); err != nil {
+		log.Println("release reset otp attempt:", err)
+	}
 	return nil
 }
 
@@ -302,8 +373,8 @@ func (s *authService) VerifyForgotOTP(email, otp string) error {
 		return errors.New("invalid otp or expired")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(pr.OTPHash), []byte(otp)); err != nil {
-		return errors.New("invalid otp or expired")
+	if err := s.checkResetOTP(pr, otp); err != nil {
+		return err
 	}
 
 	// ✅ สำคัญ: “ตรวจอย่างเดียว” ห้าม MarkUsed / ห้ามแก้รหัสผ่าน
@@ -368,6 +439,14 @@ func (s *authService) ConfirmEmailVerifyOTP(email, otp string) (string, error) {
 		return "", errors.New("invalid otp or expired")
 	}
 
+	// จองสิทธิ์ก่อนเทียบ ครบ MaxOTPAttempts แล้วต้องขอ OTP ใหม่
+	ok, err := s.userRepo.ReserveEmailVerificationAttempt(ev.ID)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New("invalid otp or expired")
+	}
 	if err := bcrypt.CompareHashAndPassword([]byte(ev.OTPHash), []byte(otp)); err != nil {
 		return "", errors.New("invalid otp or expired")
 	}
@@ -436,33 +515,33 @@ func (s *authService) IsUsernameTaken(username string) (bool, error) {
 	return s.userRepo.IsUsernameTaken(username)
 }
 
-func (s *authService) LoginWithSession(email, password string) (accessToken string, refreshToken string, user *models.User, err error) {
+func (s *authService) LoginWithSession(email, password string, meta models.SessionMeta) (accessToken string, refreshToken string, user *models.User, err error) {
 	user, err = s.Login(email, password) // ใช้ของเดิม ไม่ต้องแก้
 	if err != nil {
 		return "", "", nil, err
 	}
 
-	accessToken, err = s.IssueToken(user.ID)
+	// เปิด 2FA ไว้ ต้องผ่าน CompleteTwoFactorLogin ก่อนถึงจะออก session
+	totp, err := s.userRepo.GetTOTP(user.ID)
 	if err != nil {
 		return "", "", nil, err
 	}
-
-	refreshToken, err = newRefreshToken()
-	if err != nil {
-		return "", "", nil, err
+	if totp != nil && totp.EnabledAt != nil {
+		token, err := s.issueTwoFactorToken(user)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return "", "", nil, &models.TwoFactorRequiredError{Token: token}
 	}
 
-	refreshHash := hashToken(refreshToken)
-	expiresAt := time.Now().Add(30 * 24 * time.Hour)
-
-	if _, err := s.userRepo.CreateSession(user.ID, refreshHash, expiresAt); err != nil {
+	accessToken, refreshToken, err = s.startSession(user, meta)
+	if err != nil {
 		return "", "", nil, err
 	}
-
 	return accessToken, refreshToken, user, nil
 }
 
-func (s *authService) Refresh(refreshToken string) (newAccess string, newRefresh string, err error) {
+func (s *authService) Refresh(refreshToken string, meta models.SessionMeta) (newAccess string, newRefresh string, err error) {
 	refreshToken = strings.TrimSpace(refreshToken)
 	if refreshToken == "" {
 		return "", "", errors.New("missing refresh token")
@@ -471,7 +550,11 @@ func (s *authService) Refresh(refreshToken string) (newAccess string, newRefresh
 	hash := hashToken(refreshToken)
 	sess, err := s.userRepo.GetSessionByRefresh(hash)
 	if err != nil {
-		return "", "", errors.New("invalid refresh token")
+		// token ถูก rotate ไปแล้ว: ใน grace ต่อจาก session ล่าสุดของ chain, เกิน grace = token หลุด
+		sess, err = s.rotatedRefreshSession(hash, meta)
+		if err != nil {
+			return "", "", err
+		}
 	}
 
 	if time.Now().After(sess.ExpiresAt) {
@@ -479,10 +562,14 @@ func (s *authService) Refresh(refreshToken string) (newAccess string, newRefresh
 		return "", "", errors.New("refresh token expired")
 	}
 
-	// ออก access token ใหม่
-	newAccess, err = s.IssueToken(sess.UserID)
+	// ผู้ใช้ถูกระงับ/ลบระหว่างนั้น ไม่ต่ออายุให้
+	user, err := s.userRepo.GetUserByID(sess.UserID)
 	if err != nil {
-		return "", "", err
+		return "", "", errors.New("invalid refresh token")
+	}
+	if user.Status != models.UserStatusActive {
+		_ = s.userRepo.RevokeSession(sess.SessionID)
+		return "", "", models.ErrUserNotActive
 	}
 
 	// rotate refresh token (ปลอดภัยกว่า)
@@ -493,13 +580,96 @@ func (s *authService) Refresh(refreshToken string) (newAccess string, newRefresh
 	newHash := hashToken(newRefresh)
 	newExpires := time.Now().Add(30 * 24 * time.Hour)
 
-	if _, err := s.userRepo.RotateSession(sess.SessionID, newHash, newExpires); err != nil {
+	ns, err := s.userRepo.RotateSession(sess.SessionID, newHash, newExpires, meta)
+	if err != nil {
 		return "", "", err
 	}
 
+	// ออก access token ใหม่ผูกกับ session ใหม่
+	newAccess, err = s.IssueToken(user, ns.SessionID)
+	if err != nil {
+		return "", "", err
+	}
 	return newAccess, newRefresh, nil
 }
 
+// refreshReuseGrace token ที่เพิ่ง rotate ไม่นานถูกส่งซ้ำ ถือว่าเป็น refresh พร้อมกันหลายแท็บ
+// (หรือ response ของรอบก่อนหาย) ไม่ใช่การขโมย
+const refreshReuseGrace = 10 * time.Second
+
+// rotatedRefreshSession refresh token ที่ไม่ active แล้ว:
+// rotate ไปภายใน grace = คืน session ล่าสุดของ chain ให้ Refresh rotate ต่อ (ผู้เรียกได้ token ที่ใช้ได้จริง)
+// rotate ไปนานกว่านั้น = ใช้ซ้ำ ปิดทั้ง chain; ไม่เคย rotate/หาไม่เจอ = token ใช้ไม่ได้
+func (s *authService) rotatedRefreshSession(refreshHash string, meta models.SessionMeta) (*models.AuthSession, error) {
+	old, err := s.userRepo.FindSessionByRefresh(refreshHash)
+	if err != nil {
+		log.Println("find rotated refresh session:", err)
+		return nil, errors.New("invalid refresh token")
+	}
+	if old == nil || old.ReplacedByID == nil {
+		return nil, errors.New("invalid refresh token")
+	}
+	if old.RevokedAt != nil && time.Since(*old.RevokedAt) < refreshReuseGrace {
+		live, err := s.userRepo.GetLiveSuccessor(old.SessionID)
+		if err != nil {
+			log.Println("get live successor session:", err)
+		}
+		if live == nil {
+			return nil, errors.New("invalid refresh token")
+		}
+		return live, nil
+	}
+
+	s.reportRefreshReuse(old, meta)
+	return nil, models.ErrRefreshTokenReused
+}
+
+// reportRefreshReuse refresh token ที่ rotate ไปแล้วถูกใช้ซ้ำ = token หลุด
+// ปิดทั้ง chain (รวม session ที่ผู้ขโมยอาจถืออยู่) บันทึก security event และแจ้งเจ้าของทางอีเมล
+func (s *authService) reportRefreshReuse(sess *models.AuthSession, meta models.SessionMeta) {
+	revoked, err := s.userRepo.RevokeSessionChain(sess.SessionID)
+	if err != nil {
+		log.Println("revoke reused session chain:", err)
+	}
+	s.access.invalidateUser(sess.UserID)
+	log.Printf("[SECURITY] refresh token reuse user=%d session=%d ip=%s revoked=%d",
+		sess.UserID, sess.SessionID, meta.IP, revoked)
+	if err := s.userRepo.CreateSecurityEvent(sess.UserID, models.SecurityEventRefreshReuse, sess.SessionID, meta); err != nil {
+		log.Println("record security event:", err)
+	}
+
+	go s.sendReuseAlert(sess.UserID, meta)
+}
+
+func (s *authService) sendReuseAlert(userID int, meta models.SessionMeta) {
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		log.Println("reuse alert: get user:", err)
+		return
+	}
+
+	subject := "ChaladShare แจ้งเตือนความปลอดภัยของบัญชี"
+	body := fmt.Sprintf(
+		"สวัสดี, ระบบพบการใช้ข้อมูลเข้าสู่ระบบเก่าของบัญชีคุณซ้ำ (IP: %s, อุปกรณ์: %s)\n\nเพื่อความปลอดภัย ระบบได้ออกจากระบบอุปกรณ์ที่เกี่ยวข้องแล้ว กรุณาเข้าสู่ระบบใหม่และเปลี่ยนรหัสผ่าน\nหากคุณเพิ่งใช้งานจากอุปกรณ์นี้เอง สามารถละเว้นข้อความนี้ได้",
+		orUnknown(meta.IP), orUnknown(meta.UserAgent),
+	)
+
+	if s.mailer != nil {
+		if err := s.mailer.Send(user.Email, subject, body); err != nil {
+			log.Println("send reuse alert email failed:", err)
+		}
+	} else {
+		log.Println("[REFRESH_REUSE] email:", user.Email, "ip:", meta.IP)
+	}
+}
+
+func orUnknown(v string) string {
+	if v == "" {
+		return "ไม่ทราบ"
+	}
+	return v
+}
+
 func (s *authService) Logout(refreshToken string) error {
 	refreshToken = strings.TrimSpace(refreshToken)
 	if refreshToken == "" {
@@ -511,5 +681,97 @@ func (s *authService) Logout(refreshToken string) error {
 	if err != nil {
 		return nil // ไม่เจอก็ถือว่า logout แล้ว
 	}
-	return s.userRepo.RevokeSession(sess.SessionID)
+	if err := s.userRepo.RevokeSession(sess.SessionID); err != nil {
+		return err
+	}
+	s.access.invalidateUser(sess.UserID)
+	return nil
+}
+
+// currentSessionID session ของ refresh token ที่ส่งมา (0 = ไม่มี/ใช้ไม่ได้)
+func (s *authService) currentSessionID(userID int, currentRefresh string) int {
+	currentRefresh = strings.TrimSpace(currentRefresh)
+	if currentRefresh == "" {
+		return 0
+	}
+	sess, err := s.userRepo.GetSessionByRefresh(hashToken(currentRefresh))
+	if err != nil || sess.UserID != userID {
+		return 0
+	}
+	return sess.SessionID
+}
+
+func (s *authService) ListSessions(userID int, currentRefresh string) ([]models.SessionInfo, error) {
+	if userID <= 0 {
+		return nil, errors.New("invalid user id")
+	}
+	sessions, err := s.userRepo.ListActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentID := s.currentSessionID(userID, currentRefresh)
+	out := make([]models.SessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		out = append(out, models.SessionInfo{
+			SessionID:  sess.SessionID,
+			UserAgent:  sess.UserAgent,
+			IP:         sess.IP,
+			StartedAt:  sess.StartedAt,
+			LastUsedAt: sess.LastUsedAt,
+			ExpiresAt:  sess.ExpiresAt,
+			Current:    sess.SessionID == currentID,
+		})
+	}
+	return out, nil
+}
+
+func (s *authService) RevokeSession(userID, sessionID int, currentRefresh string) (bool, error) {
+	if userID <= 0 || sessionID <= 0 {
+		return false, models.ErrSessionNotFound
+	}
+	// หาก่อน revoke เพราะหลัง revoke หา session จาก refresh ไม่เจอแล้ว
+	current := s.currentSessionID(userID, currentRefresh) == sessionID
+	if err := s.userRepo.RevokeUserSession(userID, sessionID); err != nil {
+		return false, err
+	}
+	s.access.invalidateUser(userID)
+	return current, nil
+}
+
+// RevokeOtherSessions "ออกจากระบบทุกอุปกรณ์ยกเว้นเครื่องนี้" ไม่มี refresh ที่ใช้ได้ = ปิดทั้งหมด
+func (s *authService) RevokeOtherSessions(userID int, currentRefresh string) (int, error) {
+	if userID <= 0 {
+		return 0, errors.New("invalid user id")
+	}
+	n, err := s.userRepo.RevokeOtherSessions(userID, s.currentSessionID(userID, currentRefresh))
+	if err != nil {
+		return 0, err
+	}
+	s.access.invalidateUser(userID)
+	return n, nil
+}
+
+func (s *authService) CheckAccess(userID, sessionID, tokenVersion int) (bool, error) {
+	if userID <= 0 || sessionID <= 0 {
+		return false, nil
+	}
+
+	st, ok := s.access.get(sessionID)
+	if !ok {
+		var err error
+		st, err = s.userRepo.GetAccessState(sessionID)
+		if err != nil {
+			return false, err
+		}
+		s.access.put(sessionID, st)
+	}
+
+	if st == nil || st.UserID != userID || !st.SessionActive {
+		return false, nil
+	}
+	if st.UserStatus != models.UserStatusActive || st.TokenVersion != tokenVersion {
+		return false, nil
+	}
+	return true, nil
 }
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"chaladshare_backend/internal/auth/models"
+	"chaladshare_backend/internal/auth/repository"
+)
+
+// fakeSessionRepo จำลองตาราง auth_sessions (rotate/chain) ของผู้ใช้คนเดียว
+type fakeSessionRepo struct {
+	repository.AuthRepository
+
+	mu       sync.Mutex
+	user     models.User
+	sessions map[int]*models.AuthSession
+	nextID   int
+	events   []string
+}
+
+func newFakeSessionRepo() *fakeSessionRepo {
+	return &fakeSessionRepo{
+		user:     models.User{ID: testUserID, Email: "user@example.com", Status: models.UserStatusActive, TokenVersion: 1},
+		sessions: make(map[int]*models.AuthSession),
+	}
+}
+
+func (r *fakeSessionRepo) GetUserByID(userID int) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.user
+	return &u, nil
+}
+
+func (r *fakeSessionRepo) byHash(refreshHash string) *models.AuthSession {
+	for _, sess := range r.sessions {
+		if sess.RefreshTokenHash == refreshHash {
+			return sess
+		}
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) CreateSession(userID int, refreshHash string, expiresAt time.Time, meta models.SessionMeta) (*models.AuthSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	sess := &models.AuthSession{SessionID: r.nextID, UserID: userID, RefreshTokenHash: refreshHash, ExpiresAt: expiresAt}
+	r.sessions[sess.SessionID] = sess
+	c := *sess
+	return &c, nil
+}
+
+func (r *fakeSessionRepo) GetSessionByRefresh(refreshHash string) (*models.AuthSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sess := r.byHash(refreshHash)
+	if sess == nil || sess.RevokedAt != nil || time.Now().After(sess.ExpiresAt) {
+		return nil, errors.New("session not found or expired")
+	}
+	c := *sess
+	return &c, nil
+}
+
+func (r *fakeSessionRepo) FindSessionByRefresh(refreshHash string) (*models.AuthSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sess := r.byHash(refreshHash)
+	if sess == nil {
+		return nil, nil
+	}
+	c := *sess
+	return &c, nil
+}
+
+func (r *fakeSessionRepo) GetLiveSuccessor(sessionID int) (*models.AuthSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var live *models.AuthSession
+	for sess := r.sessions[sessionID]; sess != nil && sess.ReplacedByID != nil; {
+		sess = r.sessions[*sess.ReplacedByID]
+		if sess != nil && sess.RevokedAt == nil {
+			c := *sess
+			live = &c
+		}
+	}
+	return live, nil
+}
+
+func (r *fakeSessionRepo) RotateSession(oldSessionID int, newRefreshHash string, newExpiresAt time.Time, meta models.SessionMeta) (*models.AuthSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.sessions[oldSessionID]
+	if old == nil || old.RevokedAt != nil {
+		return nil, errors.New("old session invalid/expired")
+	}
+	r.nextID++
+	ns := &models.AuthSession{SessionID: r.nextID, UserID: old.UserID, RefreshTokenHash: newRefreshHash, ExpiresAt: newExpiresAt}
+	r.sessions[ns.SessionID] = ns
+	now := time.Now()
+	old.RevokedAt = &now
+	old.ReplacedByID = &ns.SessionID
+	c := *ns
+	return &c, nil
+}
+
+func (r *fakeSessionRepo) RevokeSessionChain(sessionID int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	now := time.Now()
+	for sess := r.sessions[sessionID]; sess != nil; {
+		if sess.RevokedAt == nil {
+			sess.RevokedAt = &now
+			n++
+		}
+		if sess.ReplacedByID == nil {
+			break
+		}
+		sess = r.sessions[*sess.ReplacedByID]
+	}
+	return n, nil
+}
+
+func (r *fakeSessionRepo) CreateSecurityEvent(userID int, eventType string, sessionID int, meta models.SessionMeta) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, eventType)
+	return nil
+}
+
+// ageRotation เลื่อนเวลา rotate ของ session ที่ถือ refresh นี้ไปในอดีต (จำลองเกิน grace)
+func (r *fakeSessionRepo) ageRotation(refresh string, by time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sess := r.byHash(hashToken(refresh))
+	t := sess.RevokedAt.Add(-by)
+	sess.RevokedAt = &t
+}
+
+func (r *fakeSessionRepo) sessionIDOf(refresh string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.byHash(hashToken(refresh)).SessionID
+}
+
+func (r *fakeSessionRepo) active() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, sess := range r.sessions {
+		if sess.RevokedAt == nil {
+			n++
+		}
+	}
+	return n
+}
+
+func startTestSession(t *testing.T) (*authService, *fakeSessionRepo, string) {
+	t.Helper()
+	repo := newFakeSessionRepo()
+	s := newTwoFactorTestService(repo)
+	_, refresh, err := s.IssueSession(testUserID, models.SessionMeta{})
+	if err != nil {
+		t.Fatalf("IssueSession: %v", err)
+	}
+	return s, repo, refresh
+}
+
+func TestRefreshWithinGraceReturnsUsableTokens(t *testing.T) {
+	s, repo, r1 := startTestSession(t)
+
+	_, r2, err := s.Refresh(r1, models.SessionMeta{})
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+
+	// แท็บอื่นส่ง r1 มาพร้อมกัน ต้องได้ token ที่ใช้ต่อได้ ไม่ใช่ 401
+	access, r3, err := s.Refresh(r1, models.SessionMeta{})
+	if err != nil {
+		t.Fatalf("refresh within grace: %v", err)
+	}
+	if access == "" || r3 == "" || r3 == r2 {
+		t.Fatalf("refresh within grace returned access=%q refresh=%q", access, r3)
+	}
+	if _, _, err := s.Refresh(r3, models.SessionMeta{}); err != nil {
+		t.Fatalf("refresh with token from grace path: %v", err)
+	}
+	if n := repo.active(); n != 1 {
+		t.Fatalf("active sessions = %d, want 1", n)
+	}
+	if len(repo.events) != 0 {
+		t.Fatalf("security events = %v, want none", repo.events)
+	}
+}
+
+func TestRefreshReuseAfterGraceRevokesChain(t *testing.T) {
+	s, repo, r1 := startTestSession(t)
+
+	_, r2, err := s.Refresh(r1, models.SessionMeta{})
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	repo.ageRotation(r1, refreshReuseGrace)
+
+	if _, _, err := s.Refresh(r1, models.SessionMeta{}); !errors.Is(err, models.ErrRefreshTokenReused) {
+		t.Fatalf("reuse after grace = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, _, err := s.Refresh(r2, models.SessionMeta{}); err == nil {
+		t.Fatal("successor still usable after reuse was detected")
+	}
+	if n := repo.active(); n != 0 {
+		t.Fatalf("active sessions = %d, want 0", n)
+	}
+	if len(repo.events) != 1 || repo.events[0] != models.SecurityEventRefreshReuse {
+		t.Fatalf("security events = %v, want one refresh reuse", repo.events)
+	}
+}
+
+func TestRefreshWithinGraceAfterLogoutFails(t *testing.T) {
+	s, repo, r1 := startTestSession(t)
+
+	_, r2, err := s.Refresh(r1, models.SessionMeta{})
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	if _, err := repo.RevokeSessionChain(repo.sessionIDOf(r2)); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+
+	if _, _, err := s.Refresh(r1, models.SessionMeta{}); err == nil {
+		t.Fatal("refresh within grace succeeded after the chain was logged out")
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+// key ของชุดทดสอบใน RFC 4226/6238 (SHA-1)
+var rfcKey = []byte("12345678901234567890")
+
+func TestHOTPRFC4226Vectors(t *testing.T) {
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, code := range want {
+		if got := hotp(rfcKey, int64(counter)); got != code {
+			t.Errorf("hotp(counter=%d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestVerifyTOTPRFC6238Vectors(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfcKey)
+
+	// RFC 6238 Appendix B (SHA-1) ตัดเหลือ 6 หลักท้าย
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tc := range cases {
+		counter, ok := verifyTOTP(secret, tc.code, time.Unix(tc.unix, 0))
+		if !ok {
+			t.Errorf("T=%d: code %s rejected", tc.unix, tc.code)
+			continue
+		}
+		if want := tc.unix / 30; counter != want {
+			t.Errorf("T=%d: counter = %d, want %d", tc.unix, counter, want)
+		}
+	}
+}
+
+func TestVerifyTOTPSkew(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfcKey)
+	now := time.Unix(1234567890, 0)
+	current := totpCounter(now)
+
+	cases := []struct {
+		offset int64
+		ok     bool
+	}{
+		{-2, false},
+		{-1, true},
+		{0, true},
+		{1, true},
+		{2, false},
+	}
+	for _, tc := range cases {
+		code := hotp(rfcKey, current+tc.offset)
+		counter, ok := verifyTOTP(secret, code, now)
+		if ok != tc.ok {
+			t.Errorf("offset %d: ok = %v, want %v", tc.offset, ok, tc.ok)
+			continue
+		}
+		if ok && counter != current+tc.offset {
+			t.Errorf("offset %d: counter = %d, want %d", tc.offset, counter, current+tc.offset)
+		}
+	}
+}
+
+func TestVerifyTOTPRejectsMalformed(t *testing.T) {
+	secret := totpEncoding.EncodeToString(rfcKey)
+	now := time.Unix(59, 0)
+
+	for _, code := range []string{"", "28708", "2870822", "abcdef"} {
+		if _, ok := verifyTOTP(secret, code, now); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+	if _, ok := verifyTOTP("not base32!", "287082", now); ok {
+		t.Error("invalid secret accepted")
+	}
+	// ช่องว่างที่ผู้ใช้พิมพ์มาต้องผ่าน
+	if _, ok := verifyTOTP(secret, " 287 082 ", now); !ok {
+		t.Error("code with spaces rejected")
+	}
+}
+
+func TestTOTPSecretEncryption(t *testing.T) {
+	key := []byte("totp-test-key")
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatalf("newTOTPSecret: %v", err)
+	}
+
+	enc1, err := encryptTOTPSecret(key, secret)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	enc2, err := encryptTOTPSecret(key, secret)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if enc1 == enc2 {
+		t.Error("same ciphertext twice, nonce not random")
+	}
+
+	got, err := decryptTOTPSecret(key, enc1)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if got != secret {
+		t.Fatalf("decrypt = %q, want %q", got, secret)
+	}
+
+	if _, err := decryptTOTPSecret([]byte("other-key"), enc1); err == nil {
+		t.Error("decrypt with wrong key succeeded")
+	}
+	if _, err := decryptTOTPSecret(key, "AAAA"); err == nil {
+		t.Error("decrypt of short ciphertext succeeded")
+	}
+	if _, err := encryptTOTPSecret(nil, secret); err == nil {
+		t.Error("encrypt with empty key succeeded")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := newRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("newRecoveryCodes: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("got %d codes, want 10", len(codes))
+	}
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := make(map[string]bool)
+	for _, c := range codes {
+		if !format.MatchString(c) {
+			t.Errorf("code %q has wrong format", c)
+		}
+		if seen[c] {
+			t.Errorf("duplicate code %q", c)
+		}
+		seen[c] = true
+	}
+}
+
+func TestRecoveryCodeHashNormalizes(t *testing.T) {
+	want := recoveryCodeHash("abcde-fghij")
+	for _, in := range []string{"ABCDE-FGHIJ", "abcdefghij", " abcde fghij ", "AbCdE - FgHiJ"} {
+		if got := recoveryCodeHash(in); got != want {
+			t.Errorf("recoveryCodeHash(%q) differs from canonical form", in)
+		}
+	}
+	if recoveryCodeHash("abcde-fghik") == want {
+		t.Error("different codes hash the same")
+	}
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"chaladshare_backend/internal/auth/models"
+)
+
+const twoFactorPurpose = "2fa_pending"
+
+// issueTwoFactorToken token อายุสั้นหลังรหัสผ่านถูก ไม่มี aud/sid จึงใช้เรียก API แทน access token ไม่ได้
+func (s *authService) issueTwoFactorToken(user *models.User) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"purpose": twoFactorPurpose,
+		"ver":     user.TokenVersion,
+		"iss":     s.issuer,
+		"iat":     now.Unix(),
+		"exp":     now.Add(models.TwoFactorTokenTTL).Unix(),
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return t.SignedString(s.jwtSecret)
+}
+
+// parseTwoFactorToken คืนผู้ใช้ของ token (ต้องยัง active และ token version ตรง)
+func (s *authService) parseTwoFactorToken(token string) (*models.User, error) {
+	parsed, err := jwt.Parse(strings.TrimSpace(token), func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("invalid token")
+		}
+		return s.jwtSecret, nil
+	}, jwt.WithIssuer(s.issuer), jwt.WithExpirationRequired())
+	if err != nil || !parsed.Valid {
+		return nil, models.ErrInvalidTwoFactorToken
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, models.ErrInvalidTwoFactorToken
+	}
+	purpose, _ := claims["purpose"].(string)
+	uid, okUID := claims["user_id"].(float64)
+	ver, okVer := claims["ver"].(float64)
+	if purpose != twoFactorPurpose || !okUID || !okVer {
+		return nil, models.ErrInvalidTwoFactorToken
+	}
+
+	user, err := s.userRepo.GetUserByID(int(uid))
+	if err != nil || user.TokenVersion != int(ver) {
+		return nil, models.ErrInvalidTwoFactorToken
+	}
+	if user.Status != models.UserStatusActive {
+		return nil, models.ErrUserNotActive
+	}
+	return user, nil
+}
+
+// enabledTOTP คืน TOTP ที่เปิดใช้อยู่ ไม่ได้เปิด = ErrTwoFactorNotEnabled
+func (s *authService) enabledTOTP(userID int) (*models.UserTOTP, error) {
+	totp, err := s.userRepo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp == nil || totp.EnabledAt == nil {
+		return nil, models.ErrTwoFactorNotEnabled
+	}
+	return totp, nil
+}
+
+// twoFactorAttempt ผลการจองสิทธิ์เดาหนึ่งครั้ง (lockedUntil != nil = ครั้งนี้เป็นครั้งสุดท้ายก่อนล็อก)
+type twoFactorAttempt struct {
+	lockedUntil *time.Time
+}
+
+// reserveTwoFactorAttempt ต้องเรียกก่อนตรวจรหัสทุกครั้ง นับใน UPDATE เดียวกับที่เช็คล็อก
+func (s *authService) reserveTwoFactorAttempt(userID int) (*twoFactorAttempt, error) {
+	ok, lockedUntil, err := s.userRepo.ReserveTwoFactorAttempt(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if lockedUntil == nil {
+			return nil, models.ErrTwoFactorNotEnabled
+		}
+		return nil, &models.TwoFactorLockedError{Until: *lockedUntil}
+	}
+	return &twoFactorAttempt{lockedUntil: lockedUntil}, nil
+}
+
+// failed ครั้งนี้ผิด ถ้าเป็นครั้งที่ทำให้ล็อกคืน TwoFactorLockedError แทน
+func (a *twoFactorAttempt) failed(failure error) error {
+	if a.lockedUntil != nil {
+		return &models.TwoFactorLockedError{Until: *a.lockedUntil}
+	}
+	return failure
+}
+
+// checkSecondFactor ตรวจรหัส TOTP (กันใช้ time step เดิมซ้ำ) หรือ recovery code อย่างใดอย่างหนึ่ง
+func (s *authService) checkSecondFactor(totp *models.UserTOTP, code, recoveryCode string) error {
+	code = strings.TrimSpace(code)
+	recoveryCode = strings.TrimSpace(recoveryCode)
+	if code == "" && recoveryCode == "" {
+		return models.ErrInvalidTwoFactorCode
+	}
+	if code != "" && len(s.totpKey) == 0 {
+		return models.ErrTwoFactorUnavailable
+	}
+
+	attempt, err := s.reserveTwoFactorAttempt(totp.UserID)
+	if err != nil {
+		return err
+	}
+
+	if code != "" {
+		secret, err := decryptTOTPSecret(s.totpKey, totp.Secret)
+		if err != nil {
+			return fmt.Errorf("decrypt totp secret: %w", err)
+		}
+		counter, ok := verifyTOTP(secret, code, time.Now())
+		if !ok {
+			return attempt.failed(models.ErrInvalidTwoFactorCode)
+		}
+		used, err := s.userRepo.UseTOTPCounter(totp.UserID, counter)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return attempt.failed(models.ErrInvalidTwoFactorCode)
+		}
+		return nil
+	}
+
+	used, err := s.userRepo.UseRecoveryCode(totp.UserID, recoveryCodeHash(recoveryCode))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return attempt.failed(models.ErrInvalidTwoFactorCode)
+	}
+	return nil
+}
+
+func (s *authService) TwoFactorStatus(userID int) (*models.TwoFactorStatus, error) {
+	totp, err := s.userRepo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp == nil || totp.EnabledAt == nil {
+		return &models.TwoFactorStatus{}, nil
+	}
+	left, err := s.userRepo.CountRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TwoFactorStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+// EnrollTwoFactor สร้าง secret ใหม่ (ยังไม่มีผลจนกว่าจะ ConfirmTwoFactor)
+func (s *authService) EnrollTwoFactor(userID int) (*models.TwoFactorEnrollResponse, error) {
+	if len(s.totpKey) == 0 {
+		return nil, models.ErrTwoFactorUnavailable
+	}
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	enc, err := encryptTOTPSecret(s.totpKey, secret)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt totp secret: %w", err)
+	}
+	if err := s.userRepo.SavePendingTOTP(userID, enc); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(secret, user.Email),
+	}, nil
+}
+
+// ConfirmTwoFactor กรอกรหัสจากแอปครั้งแรกเพื่อเปิดใช้ คืน recovery code (แสดงได้ครั้งเดียว)
+func (s *authService) ConfirmTwoFactor(userID int, code string) ([]string, error) {
+	totp, err := s.userRepo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp == nil {
+		return nil, models.ErrTwoFactorNotEnabled
+	}
+	if totp.EnabledAt != nil {
+		return nil, models.ErrTwoFactorAlreadyEnabled
+	}
+	if len(s.totpKey) == 0 {
+		return nil, models.ErrTwoFactorUnavailable
+	}
+	attempt, err := s.reserveTwoFactorAttempt(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := decryptTOTPSecret(s.totpKey, totp.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt totp secret: %w", err)
+	}
+	counter, ok := verifyTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, attempt.failed(models.ErrInvalidTwoFactorCode)
+	}
+
+	codes, err := newRecoveryCodes(models.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, recoveryCodeHash(c))
+	}
+	if err := s.userRepo.EnableTOTP(userID, counter, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor ต้องยืนยันด้วยรหัสผ่านปัจจุบันหรือ recovery code (กัน session ที่ถูกขโมยปิด 2FA เอง)
+// ปิดแล้วบันทึก security event และให้อุปกรณ์อื่น login ใหม่ เหมือนตอนเปลี่ยนรหัสผ่าน
+func (s *authService) DisableTwoFactor(userID int, password, recoveryCode, currentRefresh string, meta models.SessionMeta) error {
+	totp, err := s.enabledTOTP(userID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case password != "":
+		attempt, err := s.reserveTwoFactorAttempt(userID)
+		if err != nil {
+			return err
+		}
+		user, err := s.userRepo.GetUserByID(userID)
+		if err != nil {
+			return err
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+			return attempt.failed(models.ErrInvalidPassword)
+		}
+	case strings.TrimSpace(recoveryCode) != "":
+		if err := s.checkSecondFactor(totp, "", recoveryCode); err != nil {
+			return err
+		}
+	default:
+		return models.ErrInvalidPassword
+	}
+
+	if err := s.userRepo.DisableTOTP(userID); err != nil {
+		return err
+	}
+
+	sessionID := s.currentSessionID(userID, currentRefresh)
+	if err := s.userRepo.CreateSecurityEvent(userID, models.SecurityEventTwoFactorDisabled, sessionID, meta); err != nil {
+		log.Println("record security event:", err)
+	}
+	if _, err := s.userRepo.RevokeOtherSessions(userID, sessionID); err != nil {
+		return err
+	}
+	s.access.invalidateUser(userID)
+	return nil
+}
+
+// CompleteTwoFactorLogin ขั้นที่สองของ login: token จาก LoginWithSession + รหัส TOTP/recovery code
+func (s *authService) CompleteTwoFactorLogin(twoFactorToken, code, recoveryCode string, meta models.SessionMeta) (string, string, *models.User, error) {
+	user, err := s.parseTwoFactorToken(twoFactorToken)
+	if err != nil {
+		return "", "", nil, err
+	}
+	totp, err := s.enabledTOTP(user.ID)
+	if err != nil {
+		// ปิด 2FA ไประหว่างนั้น ให้ login ใหม่
+		return "", "", nil, models.ErrInvalidTwoFactorToken
+	}
+	if err := s.checkSecondFactor(totp, code, recoveryCode); err != nil {
+		return "", "", nil, err
+	}
+
+	access, refresh, err := s.startSession(user, meta)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return access, refresh, user, nil
+}
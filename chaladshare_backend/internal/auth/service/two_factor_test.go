@@ -0,0 +1,497 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"chaladshare_backend/internal/auth/models"
+	"chaladshare_backend/internal/auth/repository"
+)
+
+const (
+	testUserID   = 7
+	testPassword = "correct horse"
+	testIssuer   = "chaladshare-test"
+)
+
+var (
+	testJWTSecret = []byte("jwt-test-secret")
+	testTOTPKey   = []byte("totp-test-key")
+)
+
+// fakeTwoFactorRepo จำลองตาราง users/user_totp/user_recovery_codes ของผู้ใช้คนเดียว
+// ReserveTwoFactorAttempt ทำใต้ lock เดียวกับ UPDATE ... WHERE ใน repo จริง
+type fakeTwoFactorRepo struct {
+	repository.AuthRepository
+
+	mu            sync.Mutex
+	user          models.User
+	totp          *models.UserTOTP
+	failures      int
+	recovery      map[string]bool // hash -> ใช้ไปแล้ว
+	recoveryCalls int
+	sessions      int
+	events        []string
+	revokedOthers int
+}
+
+func newFakeTwoFactorRepo(t *testing.T, secret string, recoveryCodes ...string) *fakeTwoFactorRepo {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	enc, err := encryptTOTPSecret(testTOTPKey, secret)
+	if err != nil {
+		t.Fatalf("encrypt secret: %v", err)
+	}
+	enabled := time.Now().Add(-time.Hour)
+	r := &fakeTwoFactorRepo{
+		user: models.User{
+			ID:           testUserID,
+			Email:        "user@example.com",
+			PasswordHash: string(hash),
+			Status:       models.UserStatusActive,
+			TokenVersion: 1,
+		},
+		totp:     &models.UserTOTP{UserID: testUserID, Secret: enc, EnabledAt: &enabled},
+		recovery: make(map[string]bool),
+	}
+	for _, c := range recoveryCodes {
+		r.recovery[recoveryCodeHash(c)] = false
+	}
+	return r
+}
+
+func (r *fakeTwoFactorRepo) GetUserByID(userID int) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if userID != r.user.ID {
+		return nil, errors.New("user not found")
+	}
+	u := r.user
+	return &u, nil
+}
+
+func (r *fakeTwoFactorRepo) GetUserByEmail(email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if email != r.user.Email {
+		return nil, errors.New("user not found")
+	}
+	u := r.user
+	return &u, nil
+}
+
+func (r *fakeTwoFactorRepo) GetTOTP(userID int) (*models.UserTOTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.totp == nil || userID != r.totp.UserID {
+		return nil, nil
+	}
+	t := *r.totp
+	return &t, nil
+}
+
+func (r *fakeTwoFactorRepo) ReserveTwoFactorAttempt(userID int) (bool, *time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.totp == nil || userID != r.totp.UserID {
+		return false, nil, nil
+	}
+	if r.totp.LockedUntil != nil && r.totp.LockedUntil.After(time.Now()) {
+		until := *r.totp.LockedUntil
+		return false, &until, nil
+	}
+	r.totp.LockedUntil = nil
+	r.failures++
+	if r.failures >= models.MaxTwoFactorAttempts {
+		r.failures = 0
+		until := time.Now().Add(models.TwoFactorLockout)
+		r.totp.LockedUntil = &until
+		return true, &until, nil
+	}
+	return true, nil, nil
+}
+
+func (r *fakeTwoFactorRepo) UseTOTPCounter(userID int, counter int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.totp == nil || r.totp.EnabledAt == nil || counter <= r.totp.LastCounter {
+		return false, nil
+	}
+	r.totp.LastCounter = counter
+	r.failures = 0
+	r.totp.LockedUntil = nil
+	return true, nil
+}
+
+func (r *fakeTwoFactorRepo) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recoveryCalls++
+	used, ok := r.recovery[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	r.recovery[codeHash] = true
+	r.failures = 0
+	r.totp.LockedUntil = nil
+	return true, nil
+}
+
+func (r *fakeTwoFactorRepo) DisableTOTP(userID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.totp = nil
+	r.recovery = make(map[string]bool)
+	return nil
+}
+
+func (r *fakeTwoFactorRepo) CreateSession(userID int, refreshHash string, expiresAt time.Time, meta models.SessionMeta) (*models.AuthSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions++
+	return &models.AuthSession{SessionID: r.sessions, UserID: userID, RefreshTokenHash: refreshHash}, nil
+}
+
+func (r *fakeTwoFactorRepo) GetSessionByRefresh(refreshHash string) (*models.AuthSession, error) {
+	return nil, errors.New("session not found")
+}
+
+func (r *fakeTwoFactorRepo) CreateSecurityEvent(userID int, eventType string, sessionID int, meta models.SessionMeta) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, eventType)
+	return nil
+}
+
+func (r *fakeTwoFactorRepo) RevokeOtherSessions(userID, keepSessionID int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revokedOthers++
+	return 0, nil
+}
+
+func newTwoFactorTestService(repo repository.AuthRepository) *authService {
+	return &authService{
+		userRepo:        repo,
+		jwtSecret:       testJWTSecret,
+		tokenTTLMinutes: 15,
+		issuer:          testIssuer,
+		audience:        "chaladshare-test-api",
+		access:          newAccessCache(0),
+		totpKey:         testTOTPKey,
+	}
+}
+
+// setupTwoFactor คืน service, repo และ key ดิบของ secret (ใช้สร้างรหัสด้วย hotp)
+func setupTwoFactor(t *testing.T, recoveryCodes ...string) (*authService, *fakeTwoFactorRepo, []byte) {
+	t.Helper()
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatalf("newTOTPSecret: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	repo := newFakeTwoFactorRepo(t, secret, recoveryCodes...)
+	return newTwoFactorTestService(repo), repo, key
+}
+
+func currentCode(key []byte) string {
+	return hotp(key, totpCounter(time.Now()))
+}
+
+// wrongCode รหัส 6 หลักที่ไม่ตรงกับช่วงเวลาใดที่ verifyTOTP ยอมรับ
+func wrongCode(key []byte) string {
+	now := totpCounter(time.Now())
+	valid := map[string]bool{}
+	for d := int64(-2); d <= 2; d++ {
+		valid[hotp(key, now+d)] = true
+	}
+	for _, c := range []string{"000000", "111111", "222222", "333333"} {
+		if !valid[c] {
+			return c
+		}
+	}
+	return "444444"
+}
+
+func pendingToken(t *testing.T, s *authService) string {
+	t.Helper()
+	user, err := s.userRepo.GetUserByID(testUserID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	token, err := s.issueTwoFactorToken(user)
+	if err != nil {
+		t.Fatalf("issueTwoFactorToken: %v", err)
+	}
+	return token
+}
+
+func signTestToken(t *testing.T, secret []byte, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+func TestLoginWithSessionRequiresSecondFactor(t *testing.T) {
+	s, repo, _ := setupTwoFactor(t)
+
+	_, _, _, err := s.LoginWithSession(repo.user.Email, testPassword, models.SessionMeta{})
+	var required *models.TwoFactorRequiredError
+	if !errors.As(err, &required) {
+		t.Fatalf("LoginWithSession = %v, want TwoFactorRequiredError", err)
+	}
+	if required.Token == "" {
+		t.Fatal("empty two-factor token")
+	}
+	if repo.sessions != 0 {
+		t.Fatalf("created %d sessions before second factor", repo.sessions)
+	}
+	if _, err := s.parseTwoFactorToken(required.Token); err != nil {
+		t.Fatalf("parseTwoFactorToken: %v", err)
+	}
+}
+
+func TestCompleteTwoFactorLoginRejectsReplayedCode(t *testing.T) {
+	s, repo, key := setupTwoFactor(t)
+	code := currentCode(key)
+
+	access, refresh, user, err := s.CompleteTwoFactorLogin(pendingToken(t, s), code, "", models.SessionMeta{})
+	if err != nil {
+		t.Fatalf("CompleteTwoFactorLogin: %v", err)
+	}
+	if access == "" || refresh == "" || user == nil || user.ID != testUserID {
+		t.Fatal("login did not return a session")
+	}
+
+	// รหัสเดิมใน time step เดิมใช้ซ้ำไม่ได้ แม้ token รอ 2FA ยังไม่หมดอายุ
+	if _, _, _, err := s.CompleteTwoFactorLogin(pendingToken(t, s), code, "", models.SessionMeta{}); !errors.Is(err, models.ErrInvalidTwoFactorCode) {
+		t.Fatalf("replayed code = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	if repo.sessions != 1 {
+		t.Fatalf("sessions = %d, want 1", repo.sessions)
+	}
+}
+
+func TestRecoveryCodeWorksOnce(t *testing.T) {
+	s, _, _ := setupTwoFactor(t, "abcde-fghij")
+
+	if _, _, _, err := s.CompleteTwoFactorLogin(pendingToken(t, s), "", "ABCDE FGHIJ", models.SessionMeta{}); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if _, _, _, err := s.CompleteTwoFactorLogin(pendingToken(t, s), "", "abcde-fghij", models.SessionMeta{}); !errors.Is(err, models.ErrInvalidTwoFactorCode) {
+		t.Fatalf("second use = %v, want ErrInvalidTwoFactorCode", err)
+	}
+}
+
+func TestTwoFactorLockout(t *testing.T) {
+	s, _, key := setupTwoFactor(t)
+	token := pendingToken(t, s)
+	bad := wrongCode(key)
+
+	for i := 1; i < models.MaxTwoFactorAttempts; i++ {
+		if _, _, _, err := s.CompleteTwoFactorLogin(token, bad, "", models.SessionMeta{}); !errors.Is(err, models.ErrInvalidTwoFactorCode) {
+			t.Fatalf("attempt %d = %v, want ErrInvalidTwoFactorCode", i, err)
+		}
+	}
+
+	// ครั้งที่ครบจำนวนบอกผู้ใช้ทันทีว่าถูกล็อก
+	_, _, _, err := s.CompleteTwoFactorLogin(token, bad, "", models.SessionMeta{})
+	var locked *models.TwoFactorLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("attempt %d = %v, want TwoFactorLockedError", models.MaxTwoFactorAttempts, err)
+	}
+	if time.Until(locked.Until) <= 0 {
+		t.Fatalf("locked until %v is not in the future", locked.Until)
+	}
+
+	// ระหว่างล็อก รหัสที่ถูกก็ไม่ผ่าน
+	if _, _, _, err := s.CompleteTwoFactorLogin(token, currentCode(key), "", models.SessionMeta{}); !errors.As(err, &locked) {
+		t.Fatalf("correct code while locked = %v, want TwoFactorLockedError", err)
+	}
+}
+
+func TestTwoFactorLockoutUnderConcurrentGuesses(t *testing.T) {
+	s, repo, _ := setupTwoFactor(t, "abcde-fghij")
+	token := pendingToken(t, s)
+
+	const requests = 50
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.CompleteTwoFactorLogin(token, "", "zzzzz-zzzzz", models.SessionMeta{})
+		}()
+	}
+	wg.Wait()
+
+	// จองสิทธิ์ก่อนตรวจรหัส คำขอที่ยิงพร้อมกันจึงเดาได้ไม่เกินจำนวนที่กำหนด
+	if repo.recoveryCalls != models.MaxTwoFactorAttempts {
+		t.Fatalf("recovery code checked %d times, want %d", repo.recoveryCalls, models.MaxTwoFactorAttempts)
+	}
+}
+
+func TestSuccessResetsTwoFactorFailures(t *testing.T) {
+	s, _, key := setupTwoFactor(t, "abcde-fghij")
+	token := pendingToken(t, s)
+	bad := wrongCode(key)
+
+	for i := 1; i < models.MaxTwoFactorAttempts; i++ {
+		s.CompleteTwoFactorLogin(token, bad, "", models.SessionMeta{})
+	}
+	if _, _, _, err := s.CompleteTwoFactorLogin(token, "", "abcde-fghij", models.SessionMeta{}); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	if _, _, _, err := s.CompleteTwoFactorLogin(token, bad, "", models.SessionMeta{}); !errors.Is(err, models.ErrInvalidTwoFactorCode) {
+		t.Fatalf("after success = %v, want ErrInvalidTwoFactorCode (counter reset)", err)
+	}
+}
+
+func TestEmptyCodeDoesNotCountAsAttempt(t *testing.T) {
+	s, repo, _ := setupTwoFactor(t)
+	token := pendingToken(t, s)
+
+	for i := 0; i < models.MaxTwoFactorAttempts*2; i++ {
+		if _, _, _, err := s.CompleteTwoFactorLogin(token, " ", "", models.SessionMeta{}); !errors.Is(err, models.ErrInvalidTwoFactorCode) {
+			t.Fatalf("empty code = %v, want ErrInvalidTwoFactorCode", err)
+		}
+	}
+	if repo.failures != 0 || repo.totp.LockedUntil != nil {
+		t.Fatalf("empty codes counted as attempts (failures=%d)", repo.failures)
+	}
+}
+
+func TestParseTwoFactorTokenRejects(t *testing.T) {
+	s, repo, _ := setupTwoFactor(t)
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"user_id": testUserID,
+			"purpose": twoFactorPurpose,
+			"ver":     repo.user.TokenVersion,
+			"iss":     testIssuer,
+			"iat":     now.Unix(),
+			"exp":     now.Add(time.Minute).Unix(),
+		}
+	}
+
+	if _, err := s.parseTwoFactorToken(signTestToken(t, testJWTSecret, valid())); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	access, err := s.IssueToken(&repo.user, 1)
+	if err != nil {
+		t.Fatalf("IssueToken: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"access token", access},
+		{"wrong purpose", signTestToken(t, testJWTSecret, func() jwt.MapClaims { c := valid(); c["purpose"] = "reset"; return c }())},
+		{"no purpose", signTestToken(t, testJWTSecret, func() jwt.MapClaims { c := valid(); delete(c, "purpose"); return c }())},
+		{"old version", signTestToken(t, testJWTSecret, func() jwt.MapClaims { c := valid(); c["ver"] = repo.user.TokenVersion - 1; return c }())},
+		{"no version", signTestToken(t, testJWTSecret, func() jwt.MapClaims { c := valid(); delete(c, "ver"); return c }())},
+		{"wrong issuer", signTestToken(t, testJWTSecret, func() jwt.MapClaims { c := valid(); c["iss"] = "someone-else"; return c }())},
+		{"expired", signTestToken(t, testJWTSecret, func() jwt.MapClaims { c := valid(); c["exp"] = now.Add(-time.Minute).Unix(); return c }())},
+		{"no expiry", signTestToken(t, testJWTSecret, func() jwt.MapClaims { c := valid(); delete(c, "exp"); return c }())},
+		{"other secret", signTestToken(t, []byte("other-secret"), valid())},
+		{"unknown user", signTestToken(t, testJWTSecret, func() jwt.MapClaims { c := valid(); c["user_id"] = testUserID + 1; return c }())},
+		{"garbage", "not-a-jwt"},
+	}
+	for _, tc := range cases {
+		if _, err := s.parseTwoFactorToken(tc.token); !errors.Is(err, models.ErrInvalidTwoFactorToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidTwoFactorToken", tc.name, err)
+		}
+	}
+}
+
+func TestParseTwoFactorTokenInactiveUser(t *testing.T) {
+	s, repo, _ := setupTwoFactor(t)
+	token := pendingToken(t, s)
+
+	repo.mu.Lock()
+	repo.user.Status = "suspended"
+	repo.mu.Unlock()
+
+	if _, err := s.parseTwoFactorToken(token); !errors.Is(err, models.ErrUserNotActive) {
+		t.Fatalf("err = %v, want ErrUserNotActive", err)
+	}
+}
+
+func TestDisableTwoFactorRevokesOtherSessions(t *testing.T) {
+	s, repo, _ := setupTwoFactor(t)
+
+	if err := s.DisableTwoFactor(testUserID, "wrong", "", "", models.SessionMeta{}); !errors.Is(err, models.ErrInvalidPassword) {
+		t.Fatalf("wrong password = %v, want ErrInvalidPassword", err)
+	}
+	if repo.totp == nil || repo.revokedOthers != 0 {
+		t.Fatal("wrong password disabled 2FA")
+	}
+
+	if err := s.DisableTwoFactor(testUserID, testPassword, "", "", models.SessionMeta{}); err != nil {
+		t.Fatalf("DisableTwoFactor: %v", err)
+	}
+	if repo.totp != nil {
+		t.Fatal("2FA still enabled")
+	}
+	if repo.revokedOthers != 1 {
+		t.Fatalf("RevokeOtherSessions called %d times, want 1", repo.revokedOthers)
+	}
+	if len(repo.events) != 1 || repo.events[0] != models.SecurityEventTwoFactorDisabled {
+		t.Fatalf("security events = %v", repo.events)
+	}
+}
+
+func TestDisableTwoFactorPasswordGuessesLockOut(t *testing.T) {
+	s, repo, _ := setupTwoFactor(t)
+
+	for i := 1; i < models.MaxTwoFactorAttempts; i++ {
+		s.DisableTwoFactor(testUserID, "wrong", "", "", models.SessionMeta{})
+	}
+	var locked *models.TwoFactorLockedError
+	if err := s.DisableTwoFactor(testUserID, "wrong", "", "", models.SessionMeta{}); !errors.As(err, &locked) {
+		t.Fatalf("last guess = %v, want TwoFactorLockedError", err)
+	}
+	if err := s.DisableTwoFactor(testUserID, testPassword, "", "", models.SessionMeta{}); !errors.As(err, &locked) {
+		t.Fatalf("correct password while locked = %v, want TwoFactorLockedError", err)
+	}
+	if repo.totp == nil {
+		t.Fatal("2FA disabled while locked")
+	}
+}
+
+func TestTwoFactorWithoutEncryptionKey(t *testing.T) {
+	s, repo, key := setupTwoFactor(t, "abcde-fghij")
+	s.totpKey = nil
+	token := pendingToken(t, s)
+
+	if _, err := s.EnrollTwoFactor(testUserID); !errors.Is(err, models.ErrTwoFactorUnavailable) {
+		t.Fatalf("EnrollTwoFactor = %v, want ErrTwoFactorUnavailable", err)
+	}
+	// เปิด 2FA ไว้แล้วต้องยังไม่ปล่อยผ่าน และไม่นับเป็นการเดา
+	if _, _, _, err := s.CompleteTwoFactorLogin(token, currentCode(key), "", models.SessionMeta{}); !errors.Is(err, models.ErrTwoFactorUnavailable) {
+		t.Fatalf("TOTP code = %v, want ErrTwoFactorUnavailable", err)
+	}
+	if repo.failures != 0 {
+		t.Fatalf("failures = %d, want 0", repo.failures)
+	}
+	if _, _, _, err := s.CompleteTwoFactorLogin(token, "", "abcde-fghij", models.SessionMeta{}); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+}
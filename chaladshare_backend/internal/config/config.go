@@ -22,6 +22,40 @@ type Config struct {
 	TokenTTLMinutes int
 	CookieName      string
 	AllowOrigin     string
+
+	// iss/aud ของ access token และอายุ cache ผลตรวจ session ใน middleware
+	JWTIssuer           string
+	JWTAudience         string
+	SessionCacheSeconds int
+
+	// worker สกัด feature ของเอกสาร
+	FeatureWorkers      int
+	FeatureLeaseSeconds int
+
+	// worker สรุปเอกสารด้วย AI
+	SummaryWorkers      int
+	SummaryLeaseSeconds int
+
+	// คะแนน trending ของ /posts/popular
+	TrendingHalfLifeHours  float64
+	TrendingSaveWeight     float64
+	TrendingRefreshMinutes int
+
+	// รอบตรวจโพสต์ที่ตั้งเวลาเผยแพร่
+	PublishSchedulerSeconds int
+
+	// ฟีด personal: โพสต์เก่ากว่านี้ (วัน) ไม่เข้าฟีด ยกเว้นที่ถูกแนะนำ
+	FeedWindowDays int
+
+	// rate limit ของ login/OTP: memory | postgres
+	RateLimitStore string
+	// proxy ที่เชื่อ X-Forwarded-For (csv) ว่าง = ค่า default ของ gin
+	TrustedProxies string
+
+	// key เข้ารหัส TOTP secret ใน DB (env TOTP_ENCRYPTION_KEY สุ่มยาว ๆ เช่น openssl rand -base64 32)
+	// ห้ามใช้ค่าเดียวกับ JWT.SECRET และห้ามเปลี่ยนหลังมีคนเปิด 2FA แล้ว (secret เดิมจะถอดไม่ได้)
+	// ว่าง = ปิดการ enroll และตรวจรหัสจากแอป ส่วน login ของคนที่เปิด 2FA ไว้ยังต้องใช้ recovery code
+	TOTPEncryptionKey string
 }
 
 func LoadConfig() (Config, error) {
@@ -43,9 +77,25 @@ func LoadConfig() (Config, error) {
 
 	// ADD THIS PART
 	viper.SetDefault("JWT.TTL_MINUTES", 30)
+	viper.SetDefault("JWT.ISSUER", "chaladshare")
+	viper.SetDefault("JWT.AUDIENCE", "chaladshare-api")
+	viper.SetDefault("JWT.SESSION_CACHE_SECONDS", 30)
 	viper.SetDefault("COOKIE.NAME", "access_token")
 	viper.SetDefault("ALLOW.ORIGIN", "http://localhost:3000")
 
+	viper.SetDefault("FEATURE.WORKERS", 2)
+	viper.SetDefault("FEATURE.LEASE_SECONDS", 900)
+	viper.SetDefault("SUMMARY.WORKERS", 1)
+	viper.SetDefault("SUMMARY.LEASE_SECONDS", 1200)
+	viper.SetDefault("TRENDING.HALF_LIFE_HOURS", 24)
+	viper.SetDefault("TRENDING.SAVE_WEIGHT", 2)
+	viper.SetDefault("TRENDING.REFRESH_MINUTES", 10)
+	viper.SetDefault("PUBLISH.SCHEDULER_SECONDS", 30)
+	viper.SetDefault("FEED.WINDOW_DAYS", 30)
+	viper.SetDefault("RATE_LIMIT.STORE", "memory")
+	viper.SetDefault("TRUSTED.PROXIES", "")
+	viper.SetDefault("TOTP.ENCRYPTION_KEY", "")
+
 	// Set config values
 	config := Config{
 		AppPort:          viper.GetString("APP.PORT"),
@@ -61,6 +111,27 @@ func LoadConfig() (Config, error) {
 		TokenTTLMinutes: viper.GetInt("JWT.TTL_MINUTES"),
 		CookieName:      viper.GetString("COOKIE.NAME"),
 		AllowOrigin:     viper.GetString("ALLOW.ORIGIN"),
+
+		JWTIssuer:           viper.GetString("JWT.ISSUER"),
+		JWTAudience:         viper.GetString("JWT.AUDIENCE"),
+		SessionCacheSeconds: viper.GetInt("JWT.SESSION_CACHE_SECONDS"),
+
+		FeatureWorkers:      viper.GetInt("FEATURE.WORKERS"),
+		FeatureLeaseSeconds: viper.GetInt("FEATURE.LEASE_SECONDS"),
+		SummaryWorkers:      viper.GetInt("SUMMARY.WORKERS"),
+		SummaryLeaseSeconds: viper.GetInt("SUMMARY.LEASE_SECONDS"),
+
+		TrendingHalfLifeHours:  viper.GetFloat64("TRENDING.HALF_LIFE_HOURS"),
+		TrendingSaveWeight:     viper.GetFloat64("TRENDING.SAVE_WEIGHT"),
+		TrendingRefreshMinutes: viper.GetInt("TRENDING.REFRESH_MINUTES"),
+
+		PublishSchedulerSeconds: viper.GetInt("PUBLISH.SCHEDULER_SECONDS"),
+		FeedWindowDays:          viper.GetInt("FEED.WINDOW_DAYS"),
+
+		RateLimitStore: viper.GetString("RATE_LIMIT.STORE"),
+		TrustedProxies: viper.GetString("TRUSTED.PROXIES"),
+
+		TOTPEncryptionKey: viper.GetString("TOTP.ENCRYPTION_KEY"),
 	}
 
 	return config, nil
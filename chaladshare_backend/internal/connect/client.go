@@ -21,7 +21,8 @@ type Client struct {
 	HTTP    *http.Client
 
 	// timeout แยกตามงาน
-	ExtractTimeout time.Duration
+	ExtractTimeout   time.Duration
+	SummarizeTimeout time.Duration
 }
 
 func NewFromEnv() (*Client, error) {
@@ -33,10 +34,11 @@ func NewFromEnv() (*Client, error) {
 	key := os.Getenv("COLAB_API_KEY")
 
 	return &Client{
-		BaseURL:        base,
-		APIKey:         key,
-		HTTP:           &http.Client{},
-		ExtractTimeout: 180 * time.Second, // เท่าของเดิม
+		BaseURL:          base,
+		APIKey:           key,
+		HTTP:             &http.Client{},
+		ExtractTimeout:   180 * time.Second, // เท่าของเดิม
+		SummarizeTimeout: 10 * time.Minute,  // สรุปเอกสารยาวใช้เวลานาน
 	}, nil
 }
 
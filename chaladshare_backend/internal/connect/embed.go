@@ -0,0 +1,90 @@
+package connect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ขนาดเวกเตอร์ต้องตรงกับ document_features.content_embedding vector(768)
+const ContentEmbeddingDim = 768
+
+type EmbedReq struct {
+	Text string `json:"text"`
+}
+
+type EmbedResp struct {
+	Embedding    []float64 `json:"embedding"`
+	EmbeddingAlt []float64 `json:"content_embedding,omitempty"`
+}
+
+// EmbedQuery แปลงข้อความค้นหาเป็น embedding ด้วยโมเดลเดียวกับที่ใช้ตอน /extract
+func (c *Client) EmbedQuery(text string) ([]float64, error) {
+	if c == nil {
+		return nil, fmt.Errorf("connect client is nil")
+	}
+	base := strings.TrimRight(c.BaseURL, "/")
+	if base == "" {
+		return nil, fmt.Errorf("COLAB_URL is empty")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("text is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	b, err := json.Marshal(EmbedReq{Text: text})
+	if err != nil {
+		return nil, fmt.Errorf("marshal embed req: %w", err)
+	}
+
+	url := base + "/embed"
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("ngrok-skip-browser-warning", "true")
+	if c.APIKey != "" {
+		httpReq.Header.Set("X-API-Key", c.APIKey)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("call colab embed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("embed status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var out EmbedResp
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode embed resp: %w", err)
+	}
+
+	vec := out.Embedding
+	if len(vec) == 0 && len(out.EmbeddingAlt) > 0 {
+		vec = out.EmbeddingAlt
+	}
+	if len(vec) != ContentEmbeddingDim {
+		return nil, fmt.Errorf("invalid embedding len=%d (want %d)", len(vec), ContentEmbeddingDim)
+	}
+	return vec, nil
+}
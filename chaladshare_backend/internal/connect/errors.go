@@ -0,0 +1,35 @@
+package connect
+
+import (
+	"errors"
+	"net/http"
+)
+
+// PermanentError คือ error ที่ลองใหม่ก็ไม่หาย (เช่น colab ตอบ 4xx หรือผลลัพธ์ผิดรูปแบบ)
+// ฝั่ง worker ใช้แยกว่าควร retry หรือย้ายไป failed เลย
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+func IsPermanent(err error) bool {
+	var pe *PermanentError
+	return errors.As(err, &pe)
+}
+
+// 4xx ถือว่าถาวร ยกเว้น timeout/rate limit ที่ควรลองใหม่
+func isPermanentStatus(code int) bool {
+	if code == http.StatusRequestTimeout || code == http.StatusTooManyRequests {
+		return false
+	}
+	return code >= 400 && code < 500
+}
@@ -38,7 +38,11 @@ func (c *Client) ExtractFeatures(documentID int, pdfPath string) (*ExtractResp,
 	//เช็ค status code
 	if resp.StatusCode != 200 && resp.StatusCode != 201 {
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("extract status %d: %s", resp.StatusCode, string(b))
+		err := fmt.Errorf("extract status %d: %s", resp.StatusCode, string(b))
+		if isPermanentStatus(resp.StatusCode) {
+			return nil, Permanent(err)
+		}
+		return nil, err
 	}
 
 	//decode JSON
@@ -62,7 +66,7 @@ func (c *Client) ExtractFeatures(documentID int, pdfPath string) (*ExtractResp,
 	out.StyleVectorV16 = vec
 
 	if len(out.StyleVectorV16) != 16 {
-		return nil, fmt.Errorf("invalid style vector v16 len=%d (want 16)", len(out.StyleVectorV16))
+		return nil, Permanent(fmt.Errorf("invalid style vector v16 len=%d (want 16)", len(out.StyleVectorV16)))
 	}
 
 	labelStr := "nil"
@@ -0,0 +1,62 @@
+package connect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+type SummarizeResp struct {
+	SummaryHTML   string `json:"summary_html"`
+	SummaryText   string `json:"summary_text"`
+	SummaryAlt    string `json:"summary,omitempty"`
+	SummaryPDFURL string `json:"summary_pdf_url,omitempty"`
+}
+
+func (c *Client) Summarize(documentID int, pdfPath string) (*SummarizeResp, error) {
+	if c == nil {
+		return nil, fmt.Errorf("connect client is nil")
+	}
+	start := time.Now()
+
+	timeout := c.SummarizeTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := c.postPDFWithField(ctx, "/summarize", documentID, pdfPath, "file")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		err := fmt.Errorf("summarize status %d: %s", resp.StatusCode, string(b))
+		if isPermanentStatus(resp.StatusCode) {
+			return nil, Permanent(err)
+		}
+		return nil, err
+	}
+
+	var out SummarizeResp
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode summarize resp: %w", err)
+	}
+
+	if strings.TrimSpace(out.SummaryText) == "" && strings.TrimSpace(out.SummaryAlt) != "" {
+		out.SummaryText = out.SummaryAlt
+	}
+	if strings.TrimSpace(out.SummaryHTML) == "" && strings.TrimSpace(out.SummaryText) == "" {
+		return nil, Permanent(fmt.Errorf("empty summary from ai"))
+	}
+
+	log.Printf("[COLAB][SUMMARIZE] OK time=%s doc=%d html_len=%d", time.Since(start), documentID, len(out.SummaryHTML))
+	return &out, nil
+}
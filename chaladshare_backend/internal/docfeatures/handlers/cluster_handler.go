@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"chaladshare_backend/internal/docfeatures/models"
 	"chaladshare_backend/internal/docfeatures/service"
+	"chaladshare_backend/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -85,3 +87,37 @@ func (h *FeatureHandler) RunClustering(c *gin.Context) {
 		"updated":          updated,
 	})
 }
+
+// POST /features/:document_id/retry สั่งสกัด feature ใหม่ (เฉพาะเจ้าของ และต้องอยู่ในสถานะ failed)
+func (h *FeatureHandler) RetryExtraction(c *gin.Context) {
+	uid := c.GetInt(middleware.CtxUserID)
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	docID, err := strconv.Atoi(c.Param("document_id"))
+	if err != nil || docID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid document_id"})
+		return
+	}
+
+	if err := h.svc.RetryFailed(docID, uid); err != nil {
+		switch {
+		case errors.Is(err, service.ErrDocumentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		case errors.Is(err, service.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		case errors.Is(err, service.ErrNotFailed):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"document_id":    docID,
+		"feature_status": models.FeatureQueued,
+	})
+}
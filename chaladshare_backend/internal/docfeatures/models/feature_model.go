@@ -19,10 +19,20 @@ type DocumentFeature struct {
 	StyleVector   json.RawMessage `json:"style_vector,omitempty"`
 	ClusterID     *int            `json:"cluster_id,omitempty"`
 	ErrorMessage  *string         `json:"error_message,omitempty"`
+	AttemptCount  int             `json:"attempt_count"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
 	CreatedAt     time.Time       `json:"created_at"`
 	UpdatedAt     time.Time       `json:"updated_at"`
 }
 
+// งานที่ worker claim มาจากคิว
+type ClaimedJob struct {
+	DocumentID  int
+	DocumentURL string
+	Attempt     int   // ครั้งที่เท่าไหร่ (นับรวมครั้งนี้)
+	ClaimID     int64 // ส่งกลับตอนบันทึกผล/ล้มเหลว ถ้างานถูก reclaim ไปแล้วจะไม่ตรง
+}
+
 // ตอนสร้างแถวเริ่มต้น
 type CreateQueuedInput struct {
 	DocumentID int `json:"document_id"`
@@ -35,6 +45,7 @@ type SaveResult struct {
 	ContentText      *string   `json:"content_text,omitempty"`
 	ContentEmbedding []float64 `json:"content_embedding,omitempty"`
 	ClusterID        *int      `json:"cluster_id,omitempty"`
+	ClaimID          int64     `json:"-"` // ClaimedJob.ClaimID ของงานที่ได้ผลนี้
 }
 
 func (df *DocumentFeature) VectorAsFloat64() ([]float64, error) {
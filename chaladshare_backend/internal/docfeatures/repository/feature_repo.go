@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"chaladshare_backend/internal/docfeatures/models"
 
@@ -13,10 +14,18 @@ import (
 type DocFeaturesRepo interface {
 	CreateQueued(documentID int) error
 	MarkProcessing(documentID int) error
-	SaveResult(input models.SaveResult) error
-	MarkFailed(documentID int, msg string) error
+	SaveResult(input models.SaveResult) (bool, error)
+	MarkFailed(documentID int, claimID int64, msg string) (bool, error)
 	GetByDocumentID(documentID int) (*models.DocumentFeature, error)
 
+	// queue
+	ClaimQueued() (*models.ClaimedJob, error)
+	ReclaimStale(lease time.Duration) (int, error)
+	ScheduleRetry(documentID int, claimID int64, msg string, nextAttemptAt time.Time) (bool, error)
+	RequeueFailed(documentID int) (bool, error)
+	GetDocumentOwnerID(documentID int) (int, error)
+	ResolveCanonicalID(documentID int) (int, error)
+
 	//
 	ListVectors(label string, onlyUnclustered bool) ([]models.VectorItem, error)
 	BatchUpdateClusters(updates []models.ClusterUpdate) (int, error)
@@ -46,13 +55,134 @@ func (r *FeatureRepo) CreateQueued(documentID int) error {
 func (r *FeatureRepo) MarkProcessing(documentID int) error {
 	q := `
 		UPDATE document_features
-		SET feature_status = $2, error_message = NULL
+		SET feature_status = $2, error_message = NULL, processing_started_at = NOW(),
+		    feature_claim_id = NULL
 		WHERE document_id = $1;
 	`
 	_, err := r.db.Exec(q, documentID, models.FeatureProcessing)
 	return err
 }
 
+// ClaimQueued หยิบงาน queued ที่เก่าที่สุด 1 งานแล้วเปลี่ยนเป็น processing
+// SKIP LOCKED ทำให้หลาย worker (หรือหลาย instance) ไม่หยิบงานซ้ำกัน
+// ได้ claim id ใหม่ทุกครั้ง ต้องส่งกลับมาตอนบันทึกผล/ล้มเหลว
+func (r *FeatureRepo) ClaimQueued() (*models.ClaimedJob, error) {
+	q := `
+		UPDATE document_features df
+		SET feature_status = $2,
+		    processing_started_at = NOW(),
+		    attempt_count = df.attempt_count + 1,
+		    feature_claim_id = nextval('document_features_claim_seq')
+		FROM documents d
+		WHERE df.document_id = (
+				SELECT document_id
+				FROM document_features
+				WHERE feature_status = $1
+				  AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+				ORDER BY COALESCE(next_attempt_at, created_at) ASC, document_id ASC
+				FOR UPDATE SKIP LOCKED
+				LIMIT 1
+			)
+		  AND d.document_id = df.document_id
+		RETURNING df.document_id, d.document_url, df.attempt_count, df.feature_claim_id;
+	`
+
+	var job models.ClaimedJob
+	err := r.db.QueryRow(q, models.FeatureQueued, models.FeatureProcessing).Scan(&job.DocumentID, &job.DocumentURL, &job.Attempt, &job.ClaimID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ReclaimStale คืนงาน processing ที่เกิน lease (เช่น process ตายกลางทาง) กลับเข้าคิว
+// ล้าง claim ด้วย ถ้า worker เดิมยังไม่ตายแค่ช้า ผลที่ส่งมาทีหลังจะถูกทิ้ง
+func (r *FeatureRepo) ReclaimStale(lease time.Duration) (int, error) {
+	q := `
+		UPDATE document_features
+		SET feature_status = $1, processing_started_at = NULL, feature_claim_id = NULL
+		WHERE feature_status = $2
+		  AND (processing_started_at IS NULL OR processing_started_at < NOW() - make_interval(secs => $3));
+	`
+	res, err := r.db.Exec(q, models.FeatureQueued, models.FeatureProcessing, lease.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// ScheduleRetry คืนงานเข้าคิวพร้อมเวลาที่ลองได้อีกครั้ง (error_message เก็บสาเหตุล่าสุด)
+// false = claim นี้ถูก reclaim ไปแล้ว
+func (r *FeatureRepo) ScheduleRetry(documentID int, claimID int64, msg string, nextAttemptAt time.Time) (bool, error) {
+	q := `
+		UPDATE document_features
+		SET feature_status = $2,
+		    error_message = $3,
+		    next_attempt_at = $4,
+		    processing_started_at = NULL,
+		    feature_claim_id = NULL
+		WHERE document_id = $1 AND feature_claim_id = $5;
+	`
+	res, err := r.db.Exec(q, documentID, models.FeatureQueued, msg, nextAttemptAt, claimID)
+	return claimHeld(res, err)
+}
+
+// claimHeld แปลงผลของ UPDATE ... WHERE feature_claim_id = $n (0 แถว = claim หลุดไปแล้ว)
+func claimHeld(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RequeueFailed เริ่มนับ attempt ใหม่ให้งานที่ failed; false = ไม่ได้อยู่ในสถานะ failed
+func (r *FeatureRepo) RequeueFailed(documentID int) (bool, error) {
+	q := `
+		UPDATE document_features
+		SET feature_status = $2,
+		    attempt_count = 0,
+		    next_attempt_at = NULL,
+		    processing_started_at = NULL,
+		    error_message = NULL
+		WHERE document_id = $1 AND feature_status = $3;
+	`
+	res, err := r.db.Exec(q, documentID, models.FeatureQueued, models.FeatureFailed)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *FeatureRepo) GetDocumentOwnerID(documentID int) (int, error) {
+	var ownerID int
+	err := r.db.QueryRow(`SELECT document_user_id FROM documents WHERE document_id = $1`, documentID).Scan(&ownerID)
+	return ownerID, err
+}
+
+// ResolveCanonicalID ไฟล์ซ้ำ (documents.canonical_document_id) ใช้แถว feature ของเอกสารต้นทาง
+func (r *FeatureRepo) ResolveCanonicalID(documentID int) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
+		SELECT COALESCE(canonical_document_id, document_id) FROM documents WHERE document_id = $1
+	`, documentID).Scan(&id)
+	return id, err
+}
+
 func f64ToF32(a []float64) []float32 {
 	out := make([]float32, len(a))
 	for i, v := range a {
@@ -61,14 +191,15 @@ func f64ToF32(a []float64) []float32 {
 	return out
 }
 
-func (r *FeatureRepo) SaveResult(input models.SaveResult) error {
+// SaveResult บันทึกผลของ claim input.ClaimID เท่านั้น false = claim ถูก reclaim ไปแล้ว
+func (r *FeatureRepo) SaveResult(input models.SaveResult) (bool, error) {
 	if len(input.StyleVectorV16) == 0 {
-		return fmt.Errorf("empty style vector (len=0)")
+		return false, fmt.Errorf("empty style vector (len=0)")
 	}
 
 	vecJSON, err := json.Marshal(input.StyleVectorV16)
 	if err != nil {
-		return fmt.Errorf("marshal style vector: %w", err)
+		return false, fmt.Errorf("marshal style vector: %w", err)
 	}
 
 	sv16 := pgvector.NewVector(f64ToF32(input.StyleVectorV16))
@@ -87,10 +218,13 @@ func (r *FeatureRepo) SaveResult(input models.SaveResult) error {
 		    content_text      = $6,
 		    content_embedding = $7,
 		    cluster_id        = COALESCE($8, cluster_id),
-		    error_message     = NULL
-		WHERE document_id = $1;
+		    error_message     = NULL,
+		    next_attempt_at   = NULL,
+		    processing_started_at = NULL,
+		    feature_claim_id  = NULL
+		WHERE document_id = $1 AND feature_claim_id = $9;
 	`
-	_, err = r.db.Exec(q,
+	res, err := r.db.Exec(q,
 		input.DocumentID,
 		models.FeatureDone,
 		input.StyleLabel,
@@ -99,24 +233,28 @@ func (r *FeatureRepo) SaveResult(input models.SaveResult) error {
 		input.ContentText,
 		emb,
 		input.ClusterID,
+		input.ClaimID,
 	)
-	return err
+	return claimHeld(res, err)
 }
 
-func (r *FeatureRepo) MarkFailed(documentID int, msg string) error {
+// MarkFailed ย้ายงานของ claim นี้ไป failed false = claim ถูก reclaim ไปแล้ว
+func (r *FeatureRepo) MarkFailed(documentID int, claimID int64, msg string) (bool, error) {
 	q := `
 		UPDATE document_features
-		SET feature_status = $2, error_message = $3
-		WHERE document_id = $1;
+		SET feature_status = $2, error_message = $3,
+		    next_attempt_at = NULL, processing_started_at = NULL,
+		    feature_claim_id = NULL
+		WHERE document_id = $1 AND feature_claim_id = $4;
 	`
-	_, err := r.db.Exec(q, documentID, models.FeatureFailed, msg)
-	return err
+	res, err := r.db.Exec(q, documentID, models.FeatureFailed, msg, claimID)
+	return claimHeld(res, err)
 }
 
 func (r *FeatureRepo) GetByDocumentID(documentID int) (*models.DocumentFeature, error) {
 	q := `
 		SELECT document_id, feature_status, style_label, style_vector_raw, cluster_id,
-		       error_message, created_at, updated_at
+		       error_message, attempt_count, next_attempt_at, created_at, updated_at
 		FROM document_features
 		WHERE document_id = $1;
 	`
@@ -129,6 +267,8 @@ func (r *FeatureRepo) GetByDocumentID(documentID int) (*models.DocumentFeature,
 		&out.StyleVector,
 		&out.ClusterID,
 		&out.ErrorMessage,
+		&out.AttemptCount,
+		&out.NextAttemptAt,
 		&out.CreatedAt,
 		&out.UpdatedAt,
 	)
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"chaladshare_backend/internal/connect"
 	"chaladshare_backend/internal/docfeatures/models"
@@ -13,27 +16,47 @@ type FeatureService interface {
 	CreateQueued(documentID int) error
 	MarkProcessing(documentID int) error
 	SaveResult(input models.SaveResult) error
-	MarkFailed(documentID int, msg string) error
+	MarkFailed(documentID int, claimID int64, msg string) error
 	GetByDocumentID(documentID int) (*models.DocumentFeature, error)
-	ProcessDocument(documentID int, pdfPath string)
+	StartWorkers(fetcher DocumentFetcher, workers int, lease time.Duration)
+	RetryFailed(documentID, userID int) error
+	SubscribeStatus(documentID int) (<-chan *models.DocumentFeature, func())
+	NotifyStatus(documentID int)
 
 	//
 	ListVectors(label string, onlyUnclustered bool) ([]models.VectorItem, error)
 	BatchUpdateClusters(updates []models.ClusterUpdate) (int, error)
 	RunClustering(label string, onlyUnclustered bool, k int) (int, error)
 	BootstrapAutoClustering()
+	OnDocumentPublished(documentID int)
 	DeleteByDocumentID(documentID int) error
 }
 
+var (
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrForbidden        = errors.New("forbidden")
+	ErrNotFailed        = errors.New("document features are not in failed state")
+	// ErrClaimLost งานถูก reclaim (เกิน lease) ระหว่างทำ ผลของ worker นี้ถูกทิ้ง
+	ErrClaimLost = errors.New("feature job claim lost")
+)
+
 type featureService struct {
 	featureRepo repository.DocFeaturesRepo
 	aiClient    *connect.Client
+
+	// ปลุก worker เมื่อมีงานเข้าคิว (buffer 1 พอ เพราะ worker จะวน claim จนคิวว่าง)
+	wake chan struct{}
+
+	broker  *statusBroker
+	fetcher DocumentFetcher
 }
 
 func NewFeatureService(featureRepo repository.DocFeaturesRepo, aiClient *connect.Client) FeatureService {
 	return &featureService{
 		featureRepo: featureRepo,
 		aiClient:    aiClient,
+		wake:        make(chan struct{}, 1),
+		broker:      newStatusBroker(),
 	}
 }
 
@@ -41,89 +64,103 @@ func (s *featureService) CreateQueued(documentID int) error {
 	if documentID <= 0 {
 		return fmt.Errorf("invalid documentID")
 	}
-	return s.featureRepo.CreateQueued(documentID)
+	if err := s.featureRepo.CreateQueued(documentID); err != nil {
+		return err
+	}
+	s.notifyStatus(documentID)
+	s.notifyWorkers()
+	return nil
 }
 
 func (s *featureService) MarkProcessing(documentID int) error {
 	if documentID <= 0 {
 		return fmt.Errorf("invalid documentID")
 	}
-	return s.featureRepo.MarkProcessing(documentID)
+	if err := s.featureRepo.MarkProcessing(documentID); err != nil {
+		return err
+	}
+	s.notifyStatus(documentID)
+	return nil
 }
 
 func (s *featureService) SaveResult(input models.SaveResult) error {
 	if input.DocumentID <= 0 {
 		return fmt.Errorf("invalid documentID")
 	}
-	return s.featureRepo.SaveResult(input)
+	held, err := s.featureRepo.SaveResult(input)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrClaimLost
+	}
+	s.notifyStatus(input.DocumentID)
+	return nil
 }
 
-func (s *featureService) MarkFailed(documentID int, msg string) error {
+func (s *featureService) MarkFailed(documentID int, claimID int64, msg string) error {
 	if documentID <= 0 {
 		return fmt.Errorf("invalid documentID")
 	}
 	if msg == "" {
 		msg = "unknown error"
 	}
-	return s.featureRepo.MarkFailed(documentID, msg)
-}
-
-func (s *featureService) GetByDocumentID(documentID int) (*models.DocumentFeature, error) {
-	if documentID <= 0 {
-		return nil, fmt.Errorf("invalid documentID")
+	held, err := s.featureRepo.MarkFailed(documentID, claimID, msg)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return ErrClaimLost
 	}
-	return s.featureRepo.GetByDocumentID(documentID)
+	s.notifyStatus(documentID)
+	return nil
 }
 
-func (s *featureService) ProcessDocument(documentID int, pdfPath string) {
-	if s.aiClient == nil {
-		_ = s.MarkFailed(documentID, "ai client is nil")
-		return
+// RetryFailed ให้เจ้าของเอกสารสั่งสกัด feature ใหม่หลังจากงานตกไปอยู่ failed
+func (s *featureService) RetryFailed(documentID, userID int) error {
+	if documentID <= 0 || userID <= 0 {
+		return fmt.Errorf("invalid documentID or userID")
 	}
 
-	if pdfPath == "" {
-		_ = s.MarkFailed(documentID, "pdfPath is empty")
-		return
+	ownerID, err := s.featureRepo.GetDocumentOwnerID(documentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrDocumentNotFound
 	}
-
-	if err := s.MarkProcessing(documentID); err != nil {
-		_ = s.MarkFailed(documentID, err.Error())
-		return
+	if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return ErrForbidden
 	}
 
-	resp, err := s.aiClient.ExtractFeatures(documentID, pdfPath)
+	featureID := s.canonicalID(documentID)
+	ok, err := s.featureRepo.RequeueFailed(featureID)
 	if err != nil {
-		_ = s.MarkFailed(documentID, err.Error())
-		return
+		return err
 	}
-
-	if resp.StyleLabel == nil || *resp.StyleLabel == "" {
-		_ = s.MarkFailed(documentID, "missing style label ")
-		return
+	if !ok {
+		return ErrNotFailed
 	}
 
-	if len(resp.StyleVectorV16) == 0 {
-		_ = s.MarkFailed(documentID, "empty style_vector_v16 from ai")
-		return
-	}
+	s.notifyStatus(featureID)
+	s.notifyWorkers()
+	return nil
+}
 
-	label := *resp.StyleLabel
-	ct := resp.ContentText
-	if err := s.SaveResult(models.SaveResult{
-		DocumentID:       documentID,
-		StyleLabel:       label,
-		StyleVectorV16:   resp.StyleVectorV16,
-		ContentText:      &ct,
-		ContentEmbedding: resp.Embedding,
-		ClusterID:        resp.ClusterID,
-	}); err != nil {
-		_ = s.MarkFailed(documentID, err.Error())
-		return
+func (s *featureService) GetByDocumentID(documentID int) (*models.DocumentFeature, error) {
+	if documentID <= 0 {
+		return nil, fmt.Errorf("invalid documentID")
 	}
-	if label == "typed" || label == "handwritten" {
-		go s.autoClusterIfReady(label)
-		log.Printf("[AUTO-CLUSTER] trigger from ProcessDocument label=%s", label)
+	return s.featureRepo.GetByDocumentID(s.canonicalID(documentID))
+}
+
+// canonicalID id ที่ถือแถว document_features (ไฟล์ซ้ำชี้ไปเอกสารต้นทาง) หาไม่เจอใช้ id เดิม
+func (s *featureService) canonicalID(documentID int) int {
+	id, err := s.featureRepo.ResolveCanonicalID(documentID)
+	if err != nil {
+		return documentID
 	}
+	return id
 }
 
 func (s *featureService) ListVectors(label string, onlyUnclustered bool) ([]models.VectorItem, error) {
@@ -134,7 +171,18 @@ func (s *featureService) ListVectors(label string, onlyUnclustered bool) ([]mode
 }
 
 func (s *featureService) BatchUpdateClusters(updates []models.ClusterUpdate) (int, error) {
-	return s.featureRepo.BatchUpdateClusters(updates)
+	n, err := s.featureRepo.BatchUpdateClusters(updates)
+	if err != nil {
+		return 0, err
+	}
+	s.notifyClusterUpdates(updates)
+	return n, nil
+}
+
+func (s *featureService) notifyClusterUpdates(updates []models.ClusterUpdate) {
+	for _, u := range updates {
+		s.notifyStatus(u.DocumentID)
+	}
 }
 
 func (s *featureService) RunClustering(label string, onlyUnclustered bool, k int) (int, error) {
@@ -168,7 +216,12 @@ func (s *featureService) RunClustering(label string, onlyUnclustered bool, k int
 		return 0, err
 	}
 
-	return s.featureRepo.BatchUpdateClusters(colabResp.Updates)
+	n, err := s.featureRepo.BatchUpdateClusters(colabResp.Updates)
+	if err != nil {
+		return 0, err
+	}
+	s.notifyClusterUpdates(colabResp.Updates)
+	return n, nil
 }
 
 func (s *featureService) BootstrapAutoClustering() {
@@ -214,6 +267,30 @@ func (s *featureService) autoClusterIfReady(label string) {
 	log.Printf("[AUTO-CLUSTER] DONE label=%s updated=%d", label, updated)
 }
 
+// OnDocumentPublished เอกสารของโพสต์ที่เพิ่งเผยแพร่: ยังไม่มีแถว features = เข้าคิว, ทำเสร็จแต่ยังไม่มี cluster = ลอง auto-cluster
+func (s *featureService) OnDocumentPublished(documentID int) {
+	if documentID <= 0 {
+		return
+	}
+	canonical := s.canonicalID(documentID)
+	f, err := s.featureRepo.GetByDocumentID(canonical)
+	if err != nil {
+		log.Printf("[FEATURE] publish hook doc=%d: %v", documentID, err)
+		return
+	}
+	if f == nil {
+		if err := s.CreateQueued(canonical); err != nil {
+			log.Printf("[FEATURE] publish hook queue doc=%d: %v", documentID, err)
+		}
+		return
+	}
+	if f.FeatureStatus == models.FeatureDone && f.ClusterID == nil && f.StyleLabel != nil {
+		if label := *f.StyleLabel; label == "typed" || label == "handwritten" {
+			go s.autoClusterIfReady(label)
+		}
+	}
+}
+
 func (s *featureService) DeleteByDocumentID(documentID int) error {
 	if documentID <= 0 {
 		return fmt.Errorf("invalid documentID")
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"chaladshare_backend/internal/connect"
+	"chaladshare_backend/internal/docfeatures/models"
+)
+
+const (
+	workerPollInterval = 15 * time.Second // เผื่อพลาด wake และให้งาน retry ที่ถึงเวลาถูกหยิบ
+	workerIdleBackoff  = 5 * time.Second  // พักเมื่อ claim แล้ว error
+
+	// retry: 30s, 1m, 2m, 4m ... สูงสุด 30m แล้วย้ายไป failed เมื่อครบ maxAttempts
+	maxAttempts  = 5
+	retryBackoff = 30 * time.Second
+	retryMaxWait = 30 * time.Minute
+)
+
+// DocumentFetcher เตรียมไฟล์เอกสารในเครื่องให้ส่ง Colab (files service เป็นคน implement)
+type DocumentFetcher interface {
+	FetchDocument(documentID int) (localPath string, cleanup func(), err error)
+}
+
+// StartWorkers เปิด worker pool ที่ดึงงานจาก document_features (queued)
+// เรียกครั้งเดียวตอน start เหมือน BootstrapAutoClustering
+func (s *featureService) StartWorkers(fetcher DocumentFetcher, workers int, lease time.Duration) {
+	s.fetcher = fetcher
+	if workers <= 0 {
+		workers = 1
+	}
+	if lease <= 0 {
+		lease = 15 * time.Minute
+	}
+
+	// งานที่ค้าง processing จากรอบก่อน (process restart) ให้กลับเข้าคิวก่อน
+	s.reclaimStale(lease)
+
+	for i := 1; i <= workers; i++ {
+		go s.runWorker(i)
+	}
+	go s.runReaper(lease)
+
+	log.Printf("[FEATURE-WORKER] started workers=%d lease=%s", workers, lease)
+	s.notifyWorkers()
+}
+
+func (s *featureService) notifyWorkers() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *featureService) runWorker(id int) {
+	ticker := time.NewTicker(workerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := s.featureRepo.ClaimQueued()
+		if err != nil {
+			log.Printf("[FEATURE-WORKER %d] claim error: %v", id, err)
+			time.Sleep(workerIdleBackoff)
+			continue
+		}
+		if job == nil {
+			select {
+			case <-s.wake:
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		// ยังมีงานเหลือ ปลุก worker ตัวอื่นต่อ
+		s.notifyWorkers()
+
+		log.Printf("[FEATURE-WORKER %d] claimed doc=%d", id, job.DocumentID)
+		s.notifyStatus(job.DocumentID)
+		s.processJob(job)
+	}
+}
+
+func (s *featureService) runReaper(lease time.Duration) {
+	interval := lease / 2
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.reclaimStale(lease)
+	}
+}
+
+func (s *featureService) reclaimStale(lease time.Duration) {
+	n, err := s.featureRepo.ReclaimStale(lease)
+	if err != nil {
+		log.Printf("[FEATURE-WORKER] reclaim stale error: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[FEATURE-WORKER] requeued stale jobs=%d", n)
+		s.notifyWorkers()
+	}
+}
+
+// processJob รันงานที่ claim แล้ว (สถานะเป็น processing อยู่แล้ว)
+func (s *featureService) processJob(job *models.ClaimedJob) {
+	if job.Attempt > maxAttempts {
+		// เคย claim ไปแล้วแต่ process ตายกลางทางซ้ำ ๆ
+		_ = s.MarkFailed(job.DocumentID, job.ClaimID, fmt.Sprintf("gave up after %d attempts", maxAttempts))
+		return
+	}
+
+	err := s.extract(job)
+	if errors.Is(err, ErrClaimLost) {
+		log.Printf("[FEATURE-WORKER] doc=%d claim lost, result dropped attempt=%d", job.DocumentID, job.Attempt)
+		return
+	}
+	if err != nil {
+		s.handleJobError(job, err)
+	}
+}
+
+func (s *featureService) extract(job *models.ClaimedJob) error {
+	documentID := job.DocumentID
+
+	if s.aiClient == nil {
+		return fmt.Errorf("ai client is nil")
+	}
+	if s.fetcher == nil {
+		return fmt.Errorf("document fetcher is nil")
+	}
+
+	pdfPath, cleanup, err := s.fetcher.FetchDocument(documentID)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	resp, err := s.aiClient.ExtractFeatures(documentID, pdfPath)
+	if err != nil {
+		return err
+	}
+
+	if resp.StyleLabel == nil || *resp.StyleLabel == "" {
+		return connect.Permanent(fmt.Errorf("missing style label"))
+	}
+
+	if len(resp.StyleVectorV16) == 0 {
+		return connect.Permanent(fmt.Errorf("empty style_vector_v16 from ai"))
+	}
+
+	label := *resp.StyleLabel
+	ct := resp.ContentText
+	if err := s.SaveResult(models.SaveResult{
+		DocumentID:       documentID,
+		StyleLabel:       label,
+		StyleVectorV16:   resp.StyleVectorV16,
+		ContentText:      &ct,
+		ContentEmbedding: resp.Embedding,
+		ClusterID:        resp.ClusterID,
+		ClaimID:          job.ClaimID,
+	}); err != nil {
+		return err
+	}
+	if label == "typed" || label == "handwritten" {
+		go s.autoClusterIfReady(label)
+		log.Printf("[AUTO-CLUSTER] trigger from worker label=%s", label)
+	}
+	return nil
+}
+
+// handleJobError ตัดสินว่าจะ retry หรือย้ายไป failed (dead-letter)
+func (s *featureService) handleJobError(job *models.ClaimedJob, err error) {
+	msg := err.Error()
+
+	if connect.IsPermanent(err) || job.Attempt >= maxAttempts {
+		log.Printf("[FEATURE-WORKER] doc=%d failed attempt=%d: %v", job.DocumentID, job.Attempt, err)
+		if err := s.MarkFailed(job.DocumentID, job.ClaimID, msg); errors.Is(err, ErrClaimLost) {
+			log.Printf("[FEATURE-WORKER] doc=%d claim lost, failure dropped", job.DocumentID)
+		}
+		return
+	}
+
+	wait := retryDelay(job.Attempt)
+	log.Printf("[FEATURE-WORKER] doc=%d retry in %s attempt=%d: %v", job.DocumentID, wait, job.Attempt, err)
+	held, err := s.featureRepo.ScheduleRetry(job.DocumentID, job.ClaimID, msg, time.Now().Add(wait))
+	if err != nil {
+		log.Printf("[FEATURE-WORKER] doc=%d schedule retry error: %v", job.DocumentID, err)
+		return
+	}
+	if !held {
+		log.Printf("[FEATURE-WORKER] doc=%d claim lost, retry dropped", job.DocumentID)
+		return
+	}
+	s.notifyStatus(job.DocumentID)
+}
+
+func retryDelay(attempt int) time.Duration {
+	d := retryBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= retryMaxWait {
+			return retryMaxWait
+		}
+	}
+	return d
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"log"
+	"sync"
+
+	"chaladshare_backend/internal/docfeatures/models"
+)
+
+// statusBroker กระจายสถานะ document_features ให้ผู้ที่ subscribe (SSE) ภายใน process เดียวกัน
+// instance อื่นจะไม่ได้ event นี้ ฝั่ง handler จึงต้อง re-check จาก DB เป็นระยะด้วย
+type statusBroker struct {
+	mu   sync.Mutex
+	subs map[int]map[chan *models.DocumentFeature]struct{}
+}
+
+func newStatusBroker() *statusBroker {
+	return &statusBroker{subs: make(map[int]map[chan *models.DocumentFeature]struct{})}
+}
+
+func (b *statusBroker) subscribe(documentID int) (<-chan *models.DocumentFeature, func()) {
+	ch := make(chan *models.DocumentFeature, 4)
+
+	b.mu.Lock()
+	if b.subs[documentID] == nil {
+		b.subs[documentID] = make(map[chan *models.DocumentFeature]struct{})
+	}
+	b.subs[documentID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs[documentID], ch)
+			if len(b.subs[documentID]) == 0 {
+				delete(b.subs, documentID)
+			}
+			b.mu.Unlock()
+		})
+	}
+	return ch, cancel
+}
+
+func (b *statusBroker) hasSubscribers(documentID int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs[documentID]) > 0
+}
+
+func (b *statusBroker) publish(f *models.DocumentFeature) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[f.DocumentID] {
+		select {
+		case ch <- f:
+		default:
+			// ผู้ฟังช้า ข้ามไป (จะได้สถานะล่าสุดตอน re-check)
+		}
+	}
+}
+
+// SubscribeStatus รับสถานะใหม่ทุกครั้งที่มีการเปลี่ยน ต้องเรียก cancel เมื่อเลิกฟัง
+func (s *featureService) SubscribeStatus(documentID int) (<-chan *models.DocumentFeature, func()) {
+	return s.broker.subscribe(s.canonicalID(documentID))
+}
+
+// NotifyStatus ให้โมดูลอื่นปลุกผู้ฟังเมื่อสถานะส่วนอื่นของเอกสารเปลี่ยน (เช่นสรุปเอกสาร)
+// documentID ต้องเป็นเอกสารต้นทาง (canonical) เหมือนที่ SubscribeStatus ใช้
+func (s *featureService) NotifyStatus(documentID int) {
+	s.notifyStatus(documentID)
+}
+
+// notifyStatus อ่านสถานะล่าสุดจาก DB แล้วส่งให้ผู้ฟัง (ข้ามถ้าไม่มีใครฟัง)
+func (s *featureService) notifyStatus(documentID int) {
+	if !s.broker.hasSubscribers(documentID) {
+		return
+	}
+	f, err := s.featureRepo.GetByDocumentID(documentID)
+	if err != nil {
+		log.Printf("[FEATURE-STATUS] load doc=%d error: %v", documentID, err)
+		return
+	}
+	if f == nil {
+		return
+	}
+	s.broker.publish(f)
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -19,15 +20,21 @@ import (
 	"chaladshare_backend/internal/middleware"
 )
 
+// DocumentAccessChecker เช็คสิทธิ์ดูเอกสารตามการมองเห็นของโพสต์ (posts service)
+type DocumentAccessChecker interface {
+	CanViewDocument(viewerID, documentID int) (bool, error)
+}
+
 type FileHandler struct {
 	fileservice service.FileService
+	access      DocumentAccessChecker
 }
 
-func NewFileHandler(fileservice service.FileService) *FileHandler {
-	return &FileHandler{fileservice: fileservice}
+func NewFileHandler(fileservice service.FileService, access DocumentAccessChecker) *FileHandler {
+	return &FileHandler{fileservice: fileservice, access: access}
 }
 
-// File supabase
+// File (ไปยัง storage ตาม STORAGE_PROVIDER)
 func (h *FileHandler) UploadFile(c *gin.Context) {
 	uid := c.GetInt(middleware.CtxUserID)
 	if uid == 0 {
@@ -40,6 +47,13 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาแนบไฟล์ PDF"})
 		return
 	}
+	if fh.Size > service.MaxPDFBytes {
+		writeUploadError(c, &service.UploadValidationError{
+			Code:    service.UploadErrFileTooLarge,
+			Message: fmt.Sprintf("ไฟล์ใหญ่เกิน %d MB", service.MaxPDFBytes>>20),
+		})
+		return
+	}
 
 	id := uuid.New().String()
 	filename := id + ".pdf"
@@ -55,22 +69,26 @@ func (h *FileHandler) UploadFile(c *gin.Context) {
 		UserID:          uid,
 		DocumentName:    fh.Filename,
 		DocumentURL:     "",
-		StorageProvider: "supabase",
+		StorageProvider: "", // ใช้ STORAGE_PROVIDER
 		LocalPath:       abs,
 	}
 	resp, err := h.fileservice.UploadFile(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeUploadError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"document_id": resp.DocumentID,
-		"pdf_url":     resp.FileURL,
+		"document_id":    resp.DocumentID,
+		"pdf_url":        resp.FileURL,
+		"message":        resp.Message,
+		"duplicate":      resp.Duplicate,
+		"already_shared": resp.AlreadyShared,
+		"shared_post_id": resp.SharedPostID,
 	})
 }
 
-// cover supabase
+// cover
 func (h *FileHandler) UploadCover(c *gin.Context) {
 	uid := c.GetInt(middleware.CtxUserID)
 	if uid == 0 {
@@ -83,15 +101,17 @@ func (h *FileHandler) UploadCover(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาแนบรูปหน้าปก"})
 		return
 	}
-
-	ext := strings.ToLower(filepath.Ext(fh.Filename))
-	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "รองรับเฉพาะ .jpg .jpeg .png"})
+	if fh.Size > service.MaxImageBytes {
+		writeUploadError(c, &service.UploadValidationError{
+			Code:    service.UploadErrFileTooLarge,
+			Message: fmt.Sprintf("รูปใหญ่เกิน %d MB", service.MaxImageBytes>>20),
+		})
 		return
 	}
 
+	// ชนิดไฟล์ตัดสินจากเนื้อไฟล์ใน service และถูก encode ใหม่เป็น JPEG
 	id := uuid.New().String()
-	filename := fmt.Sprintf("cover_%s_%d%s", id, time.Now().UnixNano(), ext)
+	filename := fmt.Sprintf("cover_%s_%d", id, time.Now().UnixNano())
 	abs := filepath.Join(os.TempDir(), filename)
 
 	if err := c.SaveUploadedFile(fh, abs); err != nil {
@@ -99,28 +119,23 @@ func (h *FileHandler) UploadCover(c *gin.Context) {
 		return
 	}
 
-	st, err := service.NewSupabaseStorageFromEnv()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	objectPath := fmt.Sprintf("covers/%d/%s", uid, filename)
-	publicURL, err := st.UploadLocalFile(c.Request.Context(), objectPath, abs)
+	img, err := h.fileservice.UploadImage(c.Request.Context(), "covers", uid, abs)
 	_ = os.Remove(abs)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeUploadError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"cover_url":     publicURL,
-		"cover_storage": "supabase",
+		"cover_url":        img.ImageURL,
+		"cover_medium_url": img.MediumURL,
+		"cover_thumb_url":  img.ThumbURL,
+		"cover_storage":    img.Provider,
 	})
 }
 
-// Avatar supabase
+// Avatar
 func (h *FileHandler) UploadAvatar(c *gin.Context) {
 	uid := c.GetInt(middleware.CtxUserID)
 	if uid == 0 {
@@ -133,15 +148,17 @@ func (h *FileHandler) UploadAvatar(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "กรุณาแนบรูปโปรไฟล์"})
 		return
 	}
-
-	ext := strings.ToLower(filepath.Ext(fh.Filename))
-	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "รองรับเฉพาะ .jpg .jpeg .png"})
+	if fh.Size > service.MaxImageBytes {
+		writeUploadError(c, &service.UploadValidationError{
+			Code:    service.UploadErrFileTooLarge,
+			Message: fmt.Sprintf("รูปใหญ่เกิน %d MB", service.MaxImageBytes>>20),
+		})
 		return
 	}
 
+	// ชนิดไฟล์ตัดสินจากเนื้อไฟล์ใน service และถูก encode ใหม่เป็น JPEG
 	id := uuid.New().String()
-	filename := fmt.Sprintf("avatar_%d_%s_%d%s", uid, id, time.Now().UnixNano(), ext)
+	filename := fmt.Sprintf("avatar_%d_%s_%d", uid, id, time.Now().UnixNano())
 	abs := filepath.Join(os.TempDir(), filename)
 
 	if err := c.SaveUploadedFile(fh, abs); err != nil {
@@ -150,23 +167,36 @@ func (h *FileHandler) UploadAvatar(c *gin.Context) {
 	}
 	defer func() { _ = os.Remove(abs) }()
 
-	st, err := service.NewSupabaseStorageFromEnv()
+	img, err := h.fileservice.UploadImage(c.Request.Context(), "avatars", uid, abs)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeUploadError(c, err)
 		return
 	}
 
-	objectPath := fmt.Sprintf("avatars/%d/%s", uid, filename)
-	publicURL, err := st.UploadLocalFile(c.Request.Context(), objectPath, abs)
-	if err != nil {
+	c.JSON(http.StatusCreated, gin.H{
+		"avatar_url":        img.ImageURL,
+		"avatar_medium_url": img.MediumURL,
+		"avatar_thumb_url":  img.ThumbURL,
+		"avatar_storage":    img.Provider,
+	})
+}
+
+// writeUploadError ไฟล์ไม่ผ่านการตรวจ -> 4xx พร้อม code, อย่างอื่น -> 500
+func writeUploadError(c *gin.Context, err error) {
+	var verr *service.UploadValidationError
+	if !errors.As(err, &verr) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"avatar_url":     publicURL,
-		"avatar_storage": "supabase",
-	})
+	status := http.StatusUnprocessableEntity
+	switch verr.Code {
+	case service.UploadErrFileTooLarge:
+		status = http.StatusRequestEntityTooLarge
+	case service.UploadErrNotPDF, service.UploadErrNotImage:
+		status = http.StatusUnsupportedMediaType
+	}
+	c.JSON(status, gin.H{"error": verr.Message, "code": verr.Code})
 }
 
 // file local
@@ -244,7 +274,7 @@ func (h *FileHandler) UploadAvatar(c *gin.Context) {
 // 	}
 
 // 	id := uuid.New().String()
-// 	filename := fmt.Sprintf("cover_%s_%d%s", id, time.Now().UnixNano(), ext)
+// 	filename := fmt.Sprintf("cover_%s_%d", id, time.Now().UnixNano())
 // 	abs := filepath.Join(baseDir, filename)
 
 // 	publicURL := "/uploads/covers/" + filename
@@ -286,7 +316,7 @@ func (h *FileHandler) UploadAvatar(c *gin.Context) {
 // 	}
 
 // 	id := uuid.New().String()
-// 	filename := fmt.Sprintf("avatar_%d_%s_%d%s", uid, id, time.Now().UnixNano(), ext)
+// 	filename := fmt.Sprintf("avatar_%d_%s_%d", uid, id, time.Now().UnixNano())
 // 	abs := filepath.Join(baseDir, filename)
 
 // 	publicURL := "/uploads/avatars/" + filename
@@ -358,6 +388,95 @@ func (h *FileHandler) GetSummaryByDocumentID(c *gin.Context) {
 	c.JSON(http.StatusOK, summary)
 }
 
+// GET /api/v1/files/:document_id/download
+// เช็คสิทธิ์แบบเดียวกับการดูโพสต์ แล้ว redirect ไปยังลิงก์ที่หมดอายุเอง
+func (h *FileHandler) DownloadFile(c *gin.Context) {
+	authUID := c.GetInt(middleware.CtxUserID)
+	if authUID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	docID, err := strconv.Atoi(c.Param("document_id"))
+	if err != nil || docID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid document_id"})
+		return
+	}
+
+	ok, err := h.access.CanViewDocument(authUID, docID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบไฟล์นี้"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	signedURL, err := h.fileservice.GetDownloadURL(c.Request.Context(), docID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, service.ErrObjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบไฟล์นี้"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, signedURL)
+}
+
+// GET /api/v1/files/local/*object_path?expires=&sig= (ไม่ต้อง login ลิงก์เซ็นไว้แล้ว)
+func (h *FileHandler) ServeLocalSigned(c *gin.Context) {
+	rc, err := h.fileservice.OpenLocalSigned(c.Param("object_path"), c.Query("expires"), c.Query("sig"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSignedURLInvalid):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrObjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "ไม่พบไฟล์นี้"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	defer rc.Close()
+
+	ct := mime.TypeByExtension(strings.ToLower(filepath.Ext(c.Param("object_path"))))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.DataFromReader(http.StatusOK, -1, ct, rc, nil)
+}
+
+// POST /api/v1/files/:document_id/summary/regenerate
+func (h *FileHandler) RegenerateSummary(c *gin.Context) {
+	docID, ok := h.ownerDocumentID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.fileservice.RegenerateSummary(docID); err != nil {
+		if errors.Is(err, service.ErrSummaryUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"document_id":    docID,
+		"summary_status": models.SummaryQueued,
+	})
+}
+
 // DELETE
 func (h *FileHandler) DeleteFile(c *gin.Context) {
 	authUID := c.GetInt(middleware.CtxUserID)
@@ -392,3 +511,122 @@ func (h *FileHandler) DeleteFile(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "ลบไฟล์สำเร็จ"})
 }
+
+// ownerDocumentID อ่าน :document_id แล้วเช็คว่าเป็นเจ้าของ (ตอบ error ให้แล้วถ้าไม่ผ่าน)
+func (h *FileHandler) ownerDocumentID(c *gin.Context) (int, bool) {
+	authUID := c.GetInt(middleware.CtxUserID)
+	if authUID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return 0, false
+	}
+
+	docID, err := strconv.Atoi(c.Param("document_id"))
+	if err != nil || docID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid document_id"})
+		return 0, false
+	}
+
+	ok, err := h.fileservice.IsOwner(docID, authUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return 0, false
+	}
+	return docID, true
+}
+
+// GET /api/v1/files/:document_id/status
+func (h *FileHandler) GetProcessingStatus(c *gin.Context) {
+	docID, ok := h.ownerDocumentID(c)
+	if !ok {
+		return
+	}
+
+	st, err := h.fileservice.GetProcessingStatus(docID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+const statusStreamKeepAlive = 15 * time.Second
+
+// GET /api/v1/files/:document_id/status/stream (Server-Sent Events)
+// ส่ง event "status" ทันทีหนึ่งครั้ง แล้วส่งใหม่ทุกครั้งที่สถานะเปลี่ยน
+func (h *FileHandler) StreamProcessingStatus(c *gin.Context) {
+	docID, ok := h.ownerDocumentID(c)
+	if !ok {
+		return
+	}
+
+	// subscribe ก่อนอ่านสถานะแรก จะได้ไม่พลาด transition ระหว่างนั้น
+	events, cancel := h.fileservice.SubscribeProcessingStatus(docID)
+	defer cancel()
+
+	current, err := h.fileservice.GetProcessingStatus(docID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	send := func(st *models.ProcessingStatus) {
+		c.SSEvent("status", st)
+		c.Writer.Flush()
+	}
+	send(current)
+
+	ticker := time.NewTicker(statusStreamKeepAlive)
+	defer ticker.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case st, open := <-events:
+			if !open {
+				return
+			}
+			current = st
+			send(st)
+		case <-ticker.C:
+			// event จาก instance อื่นไม่ผ่าน broker จึงเช็ค DB ซ้ำไปด้วย
+			st, err := h.fileservice.GetProcessingStatus(docID)
+			if err == nil && statusChanged(current, st) {
+				current = st
+				send(st)
+				continue
+			}
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func statusChanged(a, b *models.ProcessingStatus) bool {
+	if a == nil || b == nil {
+		return a != b
+	}
+	if a.FeatureStatus != b.FeatureStatus {
+		return true
+	}
+	if (a.SummaryStatus == nil) != (b.SummaryStatus == nil) ||
+		(a.SummaryStatus != nil && *a.SummaryStatus != *b.SummaryStatus) {
+		return true
+	}
+	if a.UpdatedAt == nil || b.UpdatedAt == nil {
+		return a.UpdatedAt != b.UpdatedAt
+	}
+	return !a.UpdatedAt.Equal(*b.UpdatedAt)
+}
@@ -1,6 +1,20 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// สถานะเมื่อยังไม่มีแถวใน document_features (เช่น เอกสารเก่าก่อนมีระบบคิว)
+const ProcessingStatusUnknown = "unknown"
+
+// summaries.summary_status
+const (
+	SummaryQueued     = "queued"
+	SummaryProcessing = "processing"
+	SummaryDone       = "done"
+	SummaryFailed     = "failed"
+)
 
 // ข้อมูลไฟล์ที่อัปโหลด
 type Document struct {
@@ -8,24 +22,46 @@ type Document struct {
 	DocumentUserID  int       `json:"document_user_id"`
 	DocumentName    string    `json:"document_name"`
 	DocumentURL     string    `json:"document_url"`
+	DocumentPath    string    `json:"-"` // object key ใน storage
 	StorageProvider string    `json:"storage_provider"`
 	UploadedAt      time.Time `json:"uploaded_at"`
+
+	ContentSHA256       string `json:"-"`
+	CanonicalDocumentID *int   `json:"canonical_document_id,omitempty"` // มีค่า = ไฟล์ซ้ำ ใช้ object/feature ของเอกสารนี้
+}
+
+// DownloadPath endpoint ที่เช็คสิทธิ์แล้ว redirect ไปยัง signed URL
+func DownloadPath(documentID int) string {
+	return fmt.Sprintf("/api/v1/files/%d/download", documentID)
 }
 
 // เก็บข้อมูลจากไฟล์ที่สรุปเนื้อหาด้วย AI
 type Summary struct {
-	SummaryID        int       `json:"summary_id"`
-	SummaryText      string    `json:"summary_text"`
-	SummaryHTML      string    `json:"summary_html"`
-	SummaryPDFURL    string    `json:"summary_pdf_url"`
-	SummaryCreatedAt time.Time `json:"summary_created_at"`
-	DocumentID       int       `json:"document_id"`
+	SummaryID           int        `json:"summary_id"`
+	SummaryStatus       string     `json:"summary_status"`
+	SummaryErrorMessage *string    `json:"summary_error_message,omitempty"`
+	SummaryText         string     `json:"summary_text"`
+	SummaryHTML         string     `json:"summary_html"`
+	SummaryPDFURL       string     `json:"summary_pdf_url"`
+	SummaryCreatedAt    time.Time  `json:"summary_created_at"`
+	SummaryFinishedAt   *time.Time `json:"summary_finished_at,omitempty"`
+	DocumentID          int        `json:"document_id"`
+}
+
+// งานสรุปที่ worker claim มาจากคิว
+type SummaryJob struct {
+	SummaryID   int
+	DocumentID  int
+	DocumentURL string
+	Attempt     int
+	ClaimID     int64 // ส่งกลับตอนบันทึกผล/ล้มเหลว
 }
 
 type UploadRequest struct {
 	UserID          int    `json:"-"`
 	DocumentName    string `json:"document_name"`
 	DocumentURL     string `json:"document_url"`
+	DocumentPath    string `json:"-"`
 	StorageProvider string `json:"storage_provider"`
 	LocalPath       string `json:"-"`
 }
@@ -36,4 +72,33 @@ type UploadResponse struct {
 	File       Document `json:"file"`
 	FileURL    string   `json:"file_url"`
 	DocumentID int      `json:"document_id"`
+
+	Duplicate     bool `json:"duplicate"`                // เนื้อหาซ้ำกับไฟล์ที่มีอยู่แล้ว (ไม่เก็บ/ประมวลผลซ้ำ)
+	AlreadyShared bool `json:"already_shared"`           // มีโพสต์สาธารณะของคนอื่นที่แชร์ไฟล์นี้แล้ว
+	SharedPostID  *int `json:"shared_post_id,omitempty"` // โพสต์แรกที่แชร์ไฟล์นี้
+}
+
+// สถานะการประมวลผลเอกสาร (GET /files/:document_id/status และ SSE)
+type ProcessingStatus struct {
+	DocumentID    int        `json:"document_id"`
+	FeatureStatus string     `json:"feature_status"`
+	StyleLabel    *string    `json:"style_label"`
+	ClusterID     *int       `json:"cluster_id"`
+	ErrorMessage  *string    `json:"error_message"`
+	AttemptCount  int        `json:"attempt_count"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+
+	SummaryStatus       *string `json:"summary_status"`
+	SummaryErrorMessage *string `json:"summary_error_message,omitempty"`
+}
+
+// รูป avatar/cover ที่ประมวลผลแล้ว (table image_uploads) URL = ขนาดใหญ่สุด
+type ImageUpload struct {
+	ImageURL  string `json:"url"`
+	MediumURL string `json:"medium_url"`
+	ThumbURL  string `json:"thumb_url"`
+	Kind      string `json:"kind"`
+	UserID    int    `json:"-"`
+	Provider  string `json:"storage_provider"`
 }
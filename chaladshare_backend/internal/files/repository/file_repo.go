@@ -2,9 +2,12 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
+
 	"chaladshare_backend/internal/files/models"
 )
 
@@ -17,12 +20,42 @@ type FileRepository interface {
 	GetDocumentOwnerID(documentID int) (int, error)
 	GetDocumentByID(documentID int) (*models.Document, error)
 
+	// dedup
+	FindCanonicalByHash(sha256 string) (*models.Document, error)
+	CreateDuplicateDocument(doc *models.Document) (*models.Document, error)
+	ResolveCanonicalID(documentID int) (int, error)
+	PromoteDuplicate(canonicalID int) (int, error)
+	ListUnhashedDocuments(afterID, limit int) ([]models.Document, error)
+	SetContentHash(documentID int, sha256 string) (int, error)
+
+	// เอกสารเก่าที่ยังเก็บ URL ของ storage
+	ListLegacyDocuments(afterID, limit int) ([]models.Document, error)
+	MoveDocumentObject(canonicalID int, provider, objectPath string) error
+	FindSharedPostByHash(sha256 string, excludeUserID int) (int, error)
+
 	// summaries
 	GetSummaryByDocID(docID int) (*models.Summary, error)
 	CreateSummary(summary *models.Summary) (*models.Summary, error)
 	DeleteSummariesByDocID(docID int) error
+
+	// summary queue
+	QueueSummary(docID int) error
+	ClaimQueuedSummary() (*models.SummaryJob, error)
+	ReclaimStaleSummaries(lease time.Duration) (int, error)
+	CompleteSummary(summaryID int, claimID int64, summary *models.Summary) (bool, error)
+	ScheduleSummaryRetry(summaryID int, claimID int64, msg string, nextAttemptAt time.Time) (bool, error)
+	MarkSummaryFailed(summaryID int, claimID int64, msg string) (bool, error)
+
+	// images
+	SaveImageUpload(img *models.ImageUpload) error
 }
 
+// ErrDuplicateContent มีเอกสาร canonical ที่ hash เดียวกันอยู่แล้ว
+var ErrDuplicateContent = errors.New("document content already exists")
+
+// ErrCanonicalGone เอกสาร canonical ถูกลบ/กำลังถูกลบ ระหว่างที่จะลิงก์ไฟล์ซ้ำเข้าไป (หา canonical ใหม่แล้วลองอีกครั้ง)
+var ErrCanonicalGone = errors.New("canonical document is gone")
+
 type fileRepository struct {
 	db *sql.DB
 }
@@ -34,23 +67,77 @@ func NewFileRepository(db *sql.DB) FileRepository {
 // CreateDocument
 func (r *fileRepository) CreateDocument(req *models.Document) (*models.Document, error) {
 	err := r.db.QueryRow(`
-		INSERT INTO documents (document_user_id, document_name, document_url, storage_provider, uploaded_at)
-		VALUES ($1,$2,$3,$4,$5)
+		INSERT INTO documents (document_user_id, document_name, document_url, document_path, storage_provider, uploaded_at,
+		                       content_sha256, canonical_document_id)
+		VALUES ($1,$2,$3,NULLIF($4, ''),$5,$6,NULLIF($7, ''),$8)
 		RETURNING document_id, uploaded_at
 	`,
-		req.DocumentUserID, req.DocumentName, req.DocumentURL, req.StorageProvider, time.Now(),
+		req.DocumentUserID, req.DocumentName, req.DocumentURL, req.DocumentPath, req.StorageProvider, time.Now(),
+		req.ContentSHA256, req.CanonicalDocumentID,
 	).Scan(&req.DocumentID, &req.UploadedAt)
 
 	if err != nil {
+		// ชน ux_documents_canonical_sha256 = มีคนอัปไฟล์เดียวกันเสร็จก่อนพร้อมกัน
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "ux_documents_canonical_sha256" {
+			return nil, ErrDuplicateContent
+		}
 		return nil, fmt.Errorf("ไม่สามารถบันทึกไฟล์ได้: %v", err)
 	}
 	return req, nil
 }
 
+// ListLegacyDocuments เอกสารต้นทางที่ยังมี document_url (ก่อนเปลี่ยนมาดาวน์โหลดผ่าน signed URL) เรียงตาม id
+func (r *fileRepository) ListLegacyDocuments(afterID, limit int) ([]models.Document, error) {
+	rows, err := r.db.Query(`
+		SELECT document_id, document_user_id, document_name, document_url,
+		       COALESCE(document_path, ''), storage_provider, uploaded_at,
+		       COALESCE(content_sha256, ''), canonical_document_id
+		FROM documents
+		WHERE document_id > $1
+		  AND document_url <> ''
+		  AND canonical_document_id IS NULL
+		ORDER BY document_id ASC
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []models.Document
+	for rows.Next() {
+		var d models.Document
+		if err := rows.Scan(&d.DocumentID, &d.DocumentUserID, &d.DocumentName, &d.DocumentURL, &d.DocumentPath, &d.StorageProvider, &d.UploadedAt,
+			&d.ContentSHA256, &d.CanonicalDocumentID); err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
+// MoveDocumentObject ชี้เอกสารต้นทางและไฟล์ซ้ำทั้งหมดไป object ใหม่ แล้วล้าง document_url
+func (r *fileRepository) MoveDocumentObject(canonicalID int, provider, objectPath string) error {
+	_, err := r.db.Exec(`
+		UPDATE documents
+		SET storage_provider = $2,
+		    document_path = $3,
+		    document_url = ''
+		WHERE document_id = $1 OR canonical_document_id = $1
+	`, canonicalID, provider, objectPath)
+	if err != nil {
+		return fmt.Errorf("move document object: %w", err)
+	}
+	return nil
+}
+
 // etListDocByUserID latest
 func (r *fileRepository) GetListDocByUserID(userID int) ([]models.Document, error) {
 	rows, err := r.db.Query(`
-		SELECT document_id, document_user_id, document_name, document_url, storage_provider, uploaded_at
+		SELECT document_id, document_user_id, document_name, document_url,
+		       COALESCE(document_path, ''), storage_provider, uploaded_at,
+		       COALESCE(content_sha256, ''), canonical_document_id
 		FROM documents
 		WHERE document_user_id = $1
 		ORDER BY uploaded_at DESC
@@ -63,7 +150,8 @@ func (r *fileRepository) GetListDocByUserID(userID int) ([]models.Document, erro
 	var docs []models.Document
 	for rows.Next() {
 		var d models.Document
-		if err := rows.Scan(&d.DocumentID, &d.DocumentUserID, &d.DocumentName, &d.DocumentURL, &d.StorageProvider, &d.UploadedAt); err != nil {
+		if err := rows.Scan(&d.DocumentID, &d.DocumentUserID, &d.DocumentName, &d.DocumentURL, &d.DocumentPath, &d.StorageProvider, &d.UploadedAt,
+			&d.ContentSHA256, &d.CanonicalDocumentID); err != nil {
 			return nil, err
 		}
 		docs = append(docs, d)
@@ -71,32 +159,77 @@ func (r *fileRepository) GetListDocByUserID(userID int) ([]models.Document, erro
 	return docs, nil
 }
 
-// CreateSummary
+// CreateSummary บันทึกผลสรุปลงแถวล่าสุดของเอกสาร (แถวที่ถูก queue ไว้) ถ้ายังไม่มีแถวให้สร้างใหม่
 func (r *fileRepository) CreateSummary(summary *models.Summary) (*models.Summary, error) {
 	err := r.db.QueryRow(`
-		INSERT INTO summaries (summary_text, summary_html, summary_pdf_url, summary_created_at, document_id)
-		VALUES ($1,$2,$3,$4,$5)
-		RETURNING summary_id, summary_created_at
-	`, summary.SummaryText, summary.SummaryHTML, summary.SummaryPDFURL, time.Now(), summary.DocumentID).
-		Scan(&summary.SummaryID, &summary.SummaryCreatedAt)
+		WITH upd AS (
+			UPDATE summaries
+			SET summary_text = $1,
+			    summary_html = $2,
+			    summary_pdf_url = $3,
+			    summary_status = $5,
+			    summary_error_message = NULL,
+			    summary_next_attempt_at = NULL,
+			    summary_finished_at = NOW(),
+			    summary_updated_at = NOW()
+			WHERE summary_id = (
+				SELECT MAX(summary_id) FROM summaries WHERE summary_document_id = $4
+			)
+			RETURNING summary_id, summary_created_at, summary_finished_at
+		),
+		ins AS (
+			INSERT INTO summaries (summary_text, summary_html, summary_pdf_url, summary_document_id,
+			                       summary_status, summary_created_at, summary_finished_at)
+			SELECT $1, $2, $3, $4, $5, NOW(), NOW()
+			WHERE NOT EXISTS (SELECT 1 FROM upd)
+			RETURNING summary_id, summary_created_at, summary_finished_at
+		)
+		SELECT summary_id, summary_created_at, summary_finished_at FROM upd
+		UNION ALL
+		SELECT summary_id, summary_created_at, summary_finished_at FROM ins
+	`, summary.SummaryText, summary.SummaryHTML, summary.SummaryPDFURL, summary.DocumentID, models.SummaryDone).
+		Scan(&summary.SummaryID, &summary.SummaryCreatedAt, &summary.SummaryFinishedAt)
 	if err != nil {
 		return nil, err
 	}
+	summary.SummaryStatus = models.SummaryDone
 	return summary, nil
 }
 
-// GetSummaryByDocID
+// GetSummaryByDocID แถวล่าสุดของเอกสาร (รวมสถานะคิว)
 func (r *fileRepository) GetSummaryByDocID(docID int) (*models.Summary, error) {
-	var s models.Summary
+	var (
+		s       models.Summary
+		text    sql.NullString
+		html    sql.NullString
+		pdfURL  sql.NullString
+		created sql.NullTime
+	)
 	err := r.db.QueryRow(`
-		SELECT summary_id, summary_text, summary_html, summary_pdf_url, summary_created_at, document_id
+		SELECT summary_id, summary_status, summary_error_message,
+		       summary_text, summary_html, summary_pdf_url,
+		       summary_created_at, summary_finished_at, summary_document_id
 		FROM summaries
-		WHERE document_id = $1
-	`, docID).Scan(&s.SummaryID, &s.SummaryText, &s.SummaryHTML, &s.SummaryPDFURL, &s.SummaryCreatedAt, &s.DocumentID)
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("ไม่พบสรุปของเอกสารนี้")
+		WHERE summary_document_id = (
+			SELECT COALESCE(canonical_document_id, document_id) FROM documents WHERE document_id = $1
+		)
+		ORDER BY summary_id DESC
+		LIMIT 1
+	`, docID).Scan(&s.SummaryID, &s.SummaryStatus, &s.SummaryErrorMessage,
+		&text, &html, &pdfURL,
+		&created, &s.SummaryFinishedAt, &s.DocumentID)
+	if err != nil {
+		return nil, err
 	}
-	return &s, err
+	// ไฟล์ซ้ำอ่านสรุปของ canonical แต่ตอบกลับด้วย id ที่ถาม
+	s.DocumentID = docID
+	s.SummaryText = text.String
+	s.SummaryHTML = html.String
+	s.SummaryPDFURL = pdfURL.String
+	if created.Valid {
+		s.SummaryCreatedAt = created.Time
+	}
+	return &s, nil
 }
 
 // DeleteDocument
@@ -128,9 +261,12 @@ func (r *fileRepository) GetDocumentOwnerID(documentID int) (int, error) {
 func (r *fileRepository) GetDocumentByID(id int) (*models.Document, error) {
 	var d models.Document
 	err := r.db.QueryRow(
-		`SELECT document_id, document_user_id, document_name, document_url, storage_provider, uploaded_at
+		`SELECT document_id, document_user_id, document_name, document_url,
+		        COALESCE(document_path, ''), storage_provider, uploaded_at,
+		        COALESCE(content_sha256, ''), canonical_document_id
 		FROM documents
-		WHERE document_id = $1`, id).Scan(&d.DocumentID, &d.DocumentUserID, &d.DocumentName, &d.DocumentURL, &d.StorageProvider, &d.UploadedAt)
+		WHERE document_id = $1`, id).Scan(&d.DocumentID, &d.DocumentUserID, &d.DocumentName, &d.DocumentURL, &d.DocumentPath, &d.StorageProvider, &d.UploadedAt,
+		&d.ContentSHA256, &d.CanonicalDocumentID)
 	if err != nil {
 		return nil, err
 	}
@@ -138,6 +274,429 @@ func (r *fileRepository) GetDocumentByID(id int) (*models.Document, error) {
 }
 
 func (r *fileRepository) DeleteSummariesByDocID(docID int) error {
-	_, err := r.db.Exec(`DELETE FROM summaries WHERE document_id = $1`, docID)
+	_, err := r.db.Exec(`DELETE FROM summaries WHERE summary_document_id = $1`, docID)
+	return err
+}
+
+// QueueSummary ตั้งแถวล่าสุดของเอกสารกลับเป็น queued (ข้อความสรุปเดิมยังอยู่จนกว่าจะได้ผลใหม่)
+// ถ้ายังไม่มีแถวจะสร้างใหม่
+func (r *fileRepository) QueueSummary(docID int) error {
+	_, err := r.db.Exec(`
+		WITH upd AS (
+			UPDATE summaries
+			SET summary_status = $2,
+			    summary_error_message = NULL,
+			    summary_attempt_count = 0,
+			    summary_next_attempt_at = NULL,
+			    summary_started_at = NULL,
+			    summary_claim_id = NULL,
+			    summary_updated_at = NOW()
+			WHERE summary_id = (
+				SELECT MAX(summary_id) FROM summaries WHERE summary_document_id = $1
+			)
+			RETURNING summary_id
+		)
+		INSERT INTO summaries (summary_document_id, summary_status)
+		SELECT $1, $2
+		WHERE NOT EXISTS (SELECT 1 FROM upd)
+	`, docID, models.SummaryQueued)
 	return err
 }
+
+// ClaimQueuedSummary หยิบงานสรุปที่ถึงเวลาแล้ว 1 งาน (SKIP LOCKED กันหยิบซ้ำ)
+// ได้ claim id ใหม่ทุกครั้ง ต้องส่งกลับมาตอนบันทึกผล/ล้มเหลว
+func (r *fileRepository) ClaimQueuedSummary() (*models.SummaryJob, error) {
+	var job models.SummaryJob
+	err := r.db.QueryRow(`
+		UPDATE summaries s
+		SET summary_status = $2,
+		    summary_started_at = NOW(),
+		    summary_updated_at = NOW(),
+		    summary_attempt_count = s.summary_attempt_count + 1,
+		    summary_claim_id = nextval('summaries_claim_seq')
+		FROM documents d
+		WHERE s.summary_id = (
+				SELECT summary_id
+				FROM summaries
+				WHERE summary_status = $1
+				  AND (summary_next_attempt_at IS NULL OR summary_next_attempt_at <= NOW())
+				ORDER BY COALESCE(summary_next_attempt_at, summary_created_at) ASC, summary_id ASC
+				FOR UPDATE SKIP LOCKED
+				LIMIT 1
+			)
+		  AND d.document_id = s.summary_document_id
+		RETURNING s.summary_id, s.summary_document_id, d.document_url, s.summary_attempt_count, s.summary_claim_id
+	`, models.SummaryQueued, models.SummaryProcessing).Scan(&job.SummaryID, &job.DocumentID, &job.DocumentURL, &job.Attempt, &job.ClaimID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ReclaimStaleSummaries คืนงานที่เกิน lease กลับเข้าคิว ล้าง claim ด้วย (ผลจาก worker เดิมจะถูกทิ้ง)
+func (r *fileRepository) ReclaimStaleSummaries(lease time.Duration) (int, error) {
+	res, err := r.db.Exec(`
+		UPDATE summaries
+		SET summary_status = $1, summary_started_at = NULL, summary_claim_id = NULL, summary_updated_at = NOW()
+		WHERE summary_status = $2
+		  AND (summary_started_at IS NULL OR summary_started_at < NOW() - make_interval(secs => $3))
+	`, models.SummaryQueued, models.SummaryProcessing, lease.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// CompleteSummary บันทึกผลของ claim นี้ลงแถวที่ claim ไว้ false = claim หลุดไปแล้ว (reclaim/สั่งสรุปใหม่)
+func (r *fileRepository) CompleteSummary(summaryID int, claimID int64, summary *models.Summary) (bool, error) {
+	err := r.db.QueryRow(`
+		UPDATE summaries
+		SET summary_text = $3,
+		    summary_html = $4,
+		    summary_pdf_url = $5,
+		    summary_status = $6,
+		    summary_error_message = NULL,
+		    summary_next_attempt_at = NULL,
+		    summary_claim_id = NULL,
+		    summary_finished_at = NOW(),
+		    summary_updated_at = NOW()
+		WHERE summary_id = $1 AND summary_claim_id = $2
+		RETURNING summary_id, summary_document_id, summary_created_at, summary_finished_at
+	`, summaryID, claimID, summary.SummaryText, summary.SummaryHTML, summary.SummaryPDFURL, models.SummaryDone).
+		Scan(&summary.SummaryID, &summary.DocumentID, &summary.SummaryCreatedAt, &summary.SummaryFinishedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	summary.SummaryStatus = models.SummaryDone
+	return true, nil
+}
+
+func (r *fileRepository) ScheduleSummaryRetry(summaryID int, claimID int64, msg string, nextAttemptAt time.Time) (bool, error) {
+	res, err := r.db.Exec(`
+		UPDATE summaries
+		SET summary_status = $2,
+		    summary_error_message = $3,
+		    summary_next_attempt_at = $4,
+		    summary_started_at = NULL,
+		    summary_claim_id = NULL,
+		    summary_updated_at = NOW()
+		WHERE summary_id = $1 AND summary_claim_id = $5
+	`, summaryID, models.SummaryQueued, msg, nextAttemptAt, claimID)
+	return summaryClaimHeld(res, err)
+}
+
+func (r *fileRepository) MarkSummaryFailed(summaryID int, claimID int64, msg string) (bool, error) {
+	res, err := r.db.Exec(`
+		UPDATE summaries
+		SET summary_status = $2,
+		    summary_error_message = $3,
+		    summary_next_attempt_at = NULL,
+		    summary_claim_id = NULL,
+		    summary_finished_at = NOW(),
+		    summary_updated_at = NOW()
+		WHERE summary_id = $1 AND summary_claim_id = $4
+	`, summaryID, models.SummaryFailed, msg, claimID)
+	return summaryClaimHeld(res, err)
+}
+
+// summaryClaimHeld 0 แถว = claim หลุดไปแล้ว
+func summaryClaimHeld(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SaveImageUpload บันทึก URL ของทุกขนาด ให้โพสต์/โปรไฟล์ดึง thumbnail ไปใช้ตอนบันทึก
+func (r *fileRepository) SaveImageUpload(img *models.ImageUpload) error {
+	_, err := r.db.Exec(`
+		INSERT INTO image_uploads (image_url, image_user_id, image_kind, image_medium_url, image_thumb_url, image_storage)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (image_url) DO UPDATE
+		SET image_medium_url = EXCLUDED.image_medium_url,
+		    image_thumb_url  = EXCLUDED.image_thumb_url
+	`, img.ImageURL, img.UserID, img.Kind, img.MediumURL, img.ThumbURL, img.Provider)
+	if err != nil {
+		return fmt.Errorf("save image upload: %w", err)
+	}
+	return nil
+}
+
+// FindCanonicalByHash หาเอกสารต้นทางที่เนื้อหาตรงกัน ไม่เจอคืน sql.ErrNoRows
+func (r *fileRepository) FindCanonicalByHash(sha256 string) (*models.Document, error) {
+	var d models.Document
+	err := r.db.QueryRow(`
+		SELECT document_id, document_user_id, document_name, document_url,
+		       COALESCE(document_path, ''), storage_provider, uploaded_at, content_sha256
+		FROM documents
+		WHERE content_sha256 = $1 AND canonical_document_id IS NULL
+	`, sha256).Scan(&d.DocumentID, &d.DocumentUserID, &d.DocumentName, &d.DocumentURL,
+		&d.DocumentPath, &d.StorageProvider, &d.UploadedAt, &d.ContentSHA256)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// CreateDuplicateDocument บันทึกไฟล์ซ้ำที่ชี้ doc.CanonicalDocumentID
+// ล็อก canonical แบบ FOR SHARE ระหว่าง insert กัน PromoteDuplicate/ลบเอกสารแทรกกลาง
+// และคัด object path จากแถวที่ล็อกอยู่ ไม่ใช่ค่าที่อ่านไว้ก่อนหน้า
+func (r *fileRepository) CreateDuplicateDocument(doc *models.Document) (*models.Document, error) {
+	if doc.CanonicalDocumentID == nil {
+		return nil, errors.New("duplicate document requires canonical_document_id")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// PromoteDuplicate ปลด content_sha256 ก่อนลบเสมอ hash ที่หายไป = canonical นี้ใช้ต่อไม่ได้แล้ว
+	err = tx.QueryRow(`
+		SELECT document_url, COALESCE(document_path, ''), storage_provider
+		FROM documents
+		WHERE document_id = $1
+		  AND canonical_document_id IS NULL
+		  AND content_sha256 = $2
+		FOR SHARE
+	`, *doc.CanonicalDocumentID, doc.ContentSHA256).Scan(&doc.DocumentURL, &doc.DocumentPath, &doc.StorageProvider)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCanonicalGone
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock canonical: %w", err)
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO documents (document_user_id, document_name, document_url, document_path, storage_provider, uploaded_at,
+		                       content_sha256, canonical_document_id)
+		VALUES ($1,$2,$3,NULLIF($4, ''),$5,$6,$7,$8)
+		RETURNING document_id, uploaded_at
+	`,
+		doc.DocumentUserID, doc.DocumentName, doc.DocumentURL, doc.DocumentPath, doc.StorageProvider, time.Now(),
+		doc.ContentSHA256, *doc.CanonicalDocumentID,
+	).Scan(&doc.DocumentID, &doc.UploadedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert duplicate: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return doc, nil
+}
+
+// ResolveCanonicalID คืน id ที่ถือ document_features/summaries ของเอกสารนี้
+func (r *fileRepository) ResolveCanonicalID(documentID int) (int, error) {
+	var id int
+	err := r.db.QueryRow(`
+		SELECT COALESCE(canonical_document_id, document_id)
+		FROM documents
+		WHERE document_id = $1
+	`, documentID).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// PromoteDuplicate ก่อนลบเอกสาร canonical: ย้าย object/feature/summary ไปให้ไฟล์ซ้ำที่เก่าที่สุด
+// คืน id ที่ถูกเลื่อนขึ้นมา (0 = ไม่มีไฟล์ซ้ำ ลบ object ได้เลย)
+// ล็อก canonical แล้วปลด content_sha256 เสมอ หลัง commit จะไม่มีไฟล์ซ้ำใหม่ลิงก์เข้ามา (CreateDuplicateDocument)
+// และ trigger trg_documents_promote_duplicate ไม่ทำงานซ้ำตอนลบแถว
+func (r *fileRepository) PromoteDuplicate(canonicalID int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked int
+	err = tx.QueryRow(`
+		SELECT document_id FROM documents
+		WHERE document_id = $1 AND canonical_document_id IS NULL
+		FOR UPDATE
+	`, canonicalID).Scan(&locked)
+	if err != nil {
+		return 0, fmt.Errorf("lock canonical: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE documents SET content_sha256 = NULL WHERE document_id = $1`, canonicalID); err != nil {
+		return 0, fmt.Errorf("release hash: %w", err)
+	}
+
+	var newID int
+	err = tx.QueryRow(`
+		SELECT document_id
+		FROM documents
+		WHERE canonical_document_id = $1
+		ORDER BY uploaded_at ASC, document_id ASC
+		LIMIT 1
+		FOR UPDATE
+	`, canonicalID).Scan(&newID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// ไม่มีไฟล์ซ้ำ: commit แค่การปลด hash ให้ผู้เรียกลบ object ต่อได้
+		if err := tx.Commit(); err != nil {
+			return 0, fmt.Errorf("commit: %w", err)
+		}
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("select duplicate: %w", err)
+	}
+
+	steps := []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		{"promote", `UPDATE documents SET canonical_document_id = NULL WHERE document_id = $1`, []any{newID}},
+		{"relink duplicates", `UPDATE documents SET canonical_document_id = $2 WHERE canonical_document_id = $1`, []any{canonicalID, newID}},
+		{"move features", `UPDATE document_features SET document_id = $2 WHERE document_id = $1`, []any{canonicalID, newID}},
+		{"move summaries", `UPDATE summaries SET summary_document_id = $2 WHERE summary_document_id = $1`, []any{canonicalID, newID}},
+	}
+	for _, st := range steps {
+		if _, err := tx.Exec(st.query, st.args...); err != nil {
+			return 0, fmt.Errorf("%s: %w", st.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return newID, nil
+}
+
+// ListUnhashedDocuments เอกสารต้นทางที่ยังไม่มี content_sha256 (อัปก่อนมีระบบไฟล์ซ้ำ) เรียงตาม id
+func (r *fileRepository) ListUnhashedDocuments(afterID, limit int) ([]models.Document, error) {
+	rows, err := r.db.Query(`
+		SELECT document_id, document_user_id, document_name, document_url,
+		       COALESCE(document_path, ''), storage_provider, uploaded_at
+		FROM documents
+		WHERE document_id > $1
+		  AND content_sha256 IS NULL
+		  AND canonical_document_id IS NULL
+		ORDER BY document_id ASC
+		LIMIT $2
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []models.Document
+	for rows.Next() {
+		var d models.Document
+		if err := rows.Scan(&d.DocumentID, &d.DocumentUserID, &d.DocumentName, &d.DocumentURL, &d.DocumentPath, &d.StorageProvider, &d.UploadedAt); err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
+// SetContentHash เก็บ hash ของเอกสารเก่า คืน 0 = เป็น canonical ของ hash นี้
+// ถ้ามี canonical ของ hash เดียวกันอยู่แล้ว แปลงเป็นไฟล์ซ้ำ (ชี้ object ของ canonical ลบ feature/summary ของตัวเอง)
+// แล้วคืน id ของ canonical ให้ผู้เรียกลบ object เดิมทิ้ง
+func (r *fileRepository) SetContentHash(documentID int, sha256 string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked int
+	err = tx.QueryRow(`
+		SELECT document_id FROM documents
+		WHERE document_id = $1 AND content_sha256 IS NULL AND canonical_document_id IS NULL
+		FOR UPDATE
+	`, documentID).Scan(&locked)
+	if err != nil {
+		return 0, fmt.Errorf("lock document: %w", err)
+	}
+
+	var (
+		canonicalID         int
+		url, path, provider string
+	)
+	err = tx.QueryRow(`
+		SELECT document_id, document_url, COALESCE(document_path, ''), storage_provider
+		FROM documents
+		WHERE content_sha256 = $1 AND canonical_document_id IS NULL
+		FOR SHARE
+	`, sha256).Scan(&canonicalID, &url, &path, &provider)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if _, err := tx.Exec(`UPDATE documents SET content_sha256 = $2 WHERE document_id = $1`, documentID, sha256); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "ux_documents_canonical_sha256" {
+				return 0, ErrDuplicateContent
+			}
+			return 0, fmt.Errorf("set hash: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, fmt.Errorf("commit: %w", err)
+		}
+		return 0, nil
+	case err != nil:
+		return 0, fmt.Errorf("find canonical: %w", err)
+	}
+
+	steps := []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		{"link duplicate", `
+			UPDATE documents
+			SET content_sha256 = $2, canonical_document_id = $3,
+			    document_url = $4, document_path = NULLIF($5, ''), storage_provider = $6
+			WHERE document_id = $1`, []any{documentID, sha256, canonicalID, url, path, provider}},
+		{"drop features", `DELETE FROM document_features WHERE document_id = $1`, []any{documentID}},
+		{"drop summaries", `DELETE FROM summaries WHERE summary_document_id = $1`, []any{documentID}},
+	}
+	for _, st := range steps {
+		if _, err := tx.Exec(st.query, st.args...); err != nil {
+			return 0, fmt.Errorf("%s: %w", st.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return canonicalID, nil
+}
+
+// FindSharedPostByHash โพสต์สาธารณะแรกของคนอื่นที่แนบไฟล์เนื้อหาเดียวกัน ไม่เจอคืน sql.ErrNoRows
+func (r *fileRepository) FindSharedPostByHash(sha256 string, excludeUserID int) (int, error) {
+	var postID int
+	err := r.db.QueryRow(`
+		SELECT p.post_id
+		FROM posts p
+		JOIN documents d ON d.document_id = p.post_document_id
+		WHERE d.content_sha256 = $1
+		  AND p.post_status = 'published'
+		  AND p.post_visibility = 'public'
+		  AND p.post_author_user_id <> $2
+		ORDER BY p.post_created_at ASC, p.post_id ASC
+		LIMIT 1
+	`, sha256, excludeUserID).Scan(&postID)
+	if err != nil {
+		return 0, err
+	}
+	return postID, nil
+}
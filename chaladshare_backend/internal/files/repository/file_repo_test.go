@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"chaladshare_backend/internal/files/models"
+)
+
+// ต้องมี Postgres ที่รัน chaladshare_database/docker/init.sql แล้ว:
+// FILES_TEST_DATABASE_URL=postgres://... go test ./internal/files/repository/
+func openTestRepo(t *testing.T) (*sql.DB, FileRepository, int) {
+	t.Helper()
+	dsn := os.Getenv("FILES_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("FILES_TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	name := fmt.Sprintf("filetest%d", time.Now().UnixNano())
+	var userID int
+	if err := db.QueryRow(`
+		INSERT INTO users (email, username, password_hash) VALUES ($1, $2, 'x') RETURNING user_id
+	`, name+"@example.com", name).Scan(&userID); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	// เอกสาร/feature/summary ของผู้ใช้ทดสอบ cascade ตามไปหมด
+	t.Cleanup(func() { _, _ = db.Exec(`DELETE FROM users WHERE user_id = $1`, userID) })
+
+	return db, NewFileRepository(db), userID
+}
+
+func createCanonical(t *testing.T, db *sql.DB, repo FileRepository, userID int, sum string) *models.Document {
+	t.Helper()
+	doc, err := repo.CreateDocument(&models.Document{
+		DocumentUserID:  userID,
+		DocumentName:    "canonical.pdf",
+		DocumentPath:    "documents/test/" + sum + ".pdf",
+		StorageProvider: "local",
+		ContentSHA256:   sum,
+	})
+	if err != nil {
+		t.Fatalf("create canonical: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO document_features (document_id) VALUES ($1)`, doc.DocumentID); err != nil {
+		t.Fatalf("queue features: %v", err)
+	}
+	if err := repo.QueueSummary(doc.DocumentID); err != nil {
+		t.Fatalf("queue summary: %v", err)
+	}
+	return doc
+}
+
+func createDuplicate(t *testing.T, repo FileRepository, userID int, canon *models.Document) *models.Document {
+	t.Helper()
+	canonicalID := canon.DocumentID
+	doc, err := repo.CreateDuplicateDocument(&models.Document{
+		DocumentUserID:      userID,
+		DocumentName:        "copy.pdf",
+		ContentSHA256:       canon.ContentSHA256,
+		CanonicalDocumentID: &canonicalID,
+	})
+	if err != nil {
+		t.Fatalf("create duplicate: %v", err)
+	}
+	if doc.DocumentPath != canon.DocumentPath {
+		t.Fatalf("duplicate path = %q, want canonical path %q", doc.DocumentPath, canon.DocumentPath)
+	}
+	return doc
+}
+
+func testHash() string {
+	return fmt.Sprintf("%064x", time.Now().UnixNano())
+}
+
+func countRows(t *testing.T, db *sql.DB, query string, id int) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, id).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	return n
+}
+
+// ตัวที่ถูกเลื่อนขึ้นมาต้องเป็น canonical ถือ hash/feature/summary และไฟล์ซ้ำอื่นชี้มาที่มัน
+func assertPromoted(t *testing.T, db *sql.DB, repo FileRepository, promotedID, otherID int, sum string) {
+	t.Helper()
+	promoted, err := repo.GetDocumentByID(promotedID)
+	if err != nil {
+		t.Fatalf("get promoted: %v", err)
+	}
+	if promoted.CanonicalDocumentID != nil || promoted.ContentSHA256 != sum {
+		t.Fatalf("promoted = canonical %v hash %q, want canonical with hash", promoted.CanonicalDocumentID, promoted.ContentSHA256)
+	}
+	other, err := repo.GetDocumentByID(otherID)
+	if err != nil {
+		t.Fatalf("get other duplicate: %v", err)
+	}
+	if other.CanonicalDocumentID == nil || *other.CanonicalDocumentID != promotedID {
+		t.Fatalf("other duplicate points at %v, want %d", other.CanonicalDocumentID, promotedID)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM document_features WHERE document_id = $1`, promotedID); n != 1 {
+		t.Fatalf("features of promoted = %d, want 1", n)
+	}
+	if n := countRows(t, db, `SELECT COUNT(*) FROM summaries WHERE summary_document_id = $1`, promotedID); n == 0 {
+		t.Fatal("promoted document has no summary")
+	}
+}
+
+func TestPromoteDuplicate(t *testing.T) {
+	db, repo, userID := openTestRepo(t)
+	sum := testHash()
+	canon := createCanonical(t, db, repo, userID, sum)
+	first := createDuplicate(t, repo, userID, canon)
+	second := createDuplicate(t, repo, userID, canon)
+
+	promotedID, err := repo.PromoteDuplicate(canon.DocumentID)
+	if err != nil {
+		t.Fatalf("PromoteDuplicate: %v", err)
+	}
+	if promotedID != first.DocumentID {
+		t.Fatalf("promoted %d, want oldest duplicate %d", promotedID, first.DocumentID)
+	}
+	if err := repo.DeleteDocument(canon.DocumentID); err != nil {
+		t.Fatalf("delete canonical: %v", err)
+	}
+	assertPromoted(t, db, repo, first.DocumentID, second.DocumentID, sum)
+}
+
+func TestPromoteDuplicateWithoutDuplicatesReleasesHash(t *testing.T) {
+	db, repo, userID := openTestRepo(t)
+	canon := createCanonical(t, db, repo, userID, testHash())
+
+	promotedID, err := repo.PromoteDuplicate(canon.DocumentID)
+	if err != nil {
+		t.Fatalf("PromoteDuplicate: %v", err)
+	}
+	if promotedID != 0 {
+		t.Fatalf("promoted %d, want 0", promotedID)
+	}
+
+	// อัปไฟล์ซ้ำที่เห็น canonical ก่อนถูกลบ ต้องได้ ErrCanonicalGone ไม่ใช่ FK error ตอน commit
+	canonicalID := canon.DocumentID
+	_, err = repo.CreateDuplicateDocument(&models.Document{
+		DocumentUserID:      userID,
+		DocumentName:        "late.pdf",
+		ContentSHA256:       canon.ContentSHA256,
+		CanonicalDocumentID: &canonicalID,
+	})
+	if !errors.Is(err, ErrCanonicalGone) {
+		t.Fatalf("CreateDuplicateDocument after promote = %v, want ErrCanonicalGone", err)
+	}
+}
+
+func TestDeleteCanonicalTriggerPromotesDuplicate(t *testing.T) {
+	db, repo, userID := openTestRepo(t)
+	sum := testHash()
+	canon := createCanonical(t, db, repo, userID, sum)
+	first := createDuplicate(t, repo, userID, canon)
+	second := createDuplicate(t, repo, userID, canon)
+
+	// ลบตรง ๆ ไม่ผ่าน PromoteDuplicate (เช่น cascade จากผู้ใช้) trigger ต้องเลื่อนไฟล์ซ้ำขึ้นมาเอง
+	if _, err := db.Exec(`DELETE FROM documents WHERE document_id = $1`, canon.DocumentID); err != nil {
+		t.Fatalf("delete canonical: %v", err)
+	}
+	assertPromoted(t, db, repo, first.DocumentID, second.DocumentID, sum)
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"chaladshare_backend/internal/files/models"
+)
+
+const contentHashBatch = 100
+
+// BackfillContentHashes คำนวณ content_sha256 ให้เอกสารที่อัปก่อนมีระบบไฟล์ซ้ำ
+// เนื้อหาซ้ำกับ canonical ที่มีอยู่แล้ว = แปลงเป็นไฟล์ซ้ำแล้วลบ object เดิม
+// ทำซ้ำได้ แถวที่มี hash แล้วจะไม่ถูกหยิบอีก เรียกหลัง MigrateLegacyDocuments
+func (s *fileService) BackfillContentHashes() {
+	hashed, linked, failed := 0, 0, 0
+	after := 0
+	for {
+		docs, err := s.filerepo.ListUnhashedDocuments(after, contentHashBatch)
+		if err != nil {
+			log.Printf("[STORAGE] list unhashed documents: %v", err)
+			return
+		}
+		if len(docs) == 0 {
+			break
+		}
+		for i := range docs {
+			doc := &docs[i]
+			after = doc.DocumentID
+
+			wasLinked, err := s.backfillContentHash(doc)
+			switch {
+			case err != nil:
+				failed++
+				log.Printf("[STORAGE] hash document %d: %v", doc.DocumentID, err)
+			case wasLinked:
+				linked++
+			default:
+				hashed++
+			}
+		}
+	}
+	if hashed+linked+failed > 0 {
+		log.Printf("[STORAGE] content hashes hashed=%d linked=%d failed=%d", hashed, linked, failed)
+	}
+}
+
+// backfillContentHash true = เอกสารถูกลิงก์เข้า canonical เดิม
+func (s *fileService) backfillContentHash(doc *models.Document) (bool, error) {
+	localPath, cleanup, err := s.FetchDocument(doc.DocumentID)
+	if err != nil {
+		return false, err
+	}
+	sum, err := fileSHA256(localPath)
+	cleanup()
+	if err != nil {
+		return false, err
+	}
+
+	// ErrDuplicateContent = มีคนอัปไฟล์เดียวกันเป็น canonical ระหว่างนี้ รอบหน้าจะลิงก์ได้
+	canonicalID, err := s.filerepo.SetContentHash(doc.DocumentID, sum)
+	if err != nil {
+		return false, err
+	}
+	if canonicalID == 0 {
+		return false, nil
+	}
+
+	// แถวชี้ object ของ canonical แล้ว ลบ object เดิมทิ้ง ถ้าพลาดแค่ log
+	st, err := s.storage.Get(doc.StorageProvider)
+	if err != nil {
+		log.Printf("[STORAGE] delete object of linked document %d: %v", doc.DocumentID, err)
+		return true, nil
+	}
+	objectPath, ok := objectPathOf(st, doc)
+	if !ok {
+		log.Printf("[STORAGE] delete object of linked document %d: ไม่พบ object path", doc.DocumentID)
+		return true, nil
+	}
+	if err := st.Delete(context.Background(), objectPath); err != nil {
+		log.Printf("[STORAGE] delete object of linked document %d: %v", doc.DocumentID, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"chaladshare_backend/internal/files/models"
+)
+
+const legacyDocumentBatch = 100
+
+// MigrateLegacyDocuments ย้ายเอกสารเก่าที่ยังอยู่ใน bucket public ไป backend ของเอกสาร
+// และล้าง document_url ของทุกแถว (ดาวน์โหลดผ่าน signed URL เท่านั้น)
+// ทำซ้ำได้ แถวที่ย้ายแล้วจะไม่ถูกหยิบอีก เรียกใน goroutine ตอน start
+func (s *fileService) MigrateLegacyDocuments() {
+	moved, cleared, failed := 0, 0, 0
+	after := 0
+	for {
+		docs, err := s.filerepo.ListLegacyDocuments(after, legacyDocumentBatch)
+		if err != nil {
+			log.Printf("[STORAGE] list legacy documents: %v", err)
+			return
+		}
+		if len(docs) == 0 {
+			break
+		}
+		for i := range docs {
+			doc := &docs[i]
+			after = doc.DocumentID
+
+			wasPublic, err := s.migrateLegacyDocument(doc)
+			switch {
+			case err != nil:
+				failed++
+				log.Printf("[STORAGE] migrate document %d: %v", doc.DocumentID, err)
+			case wasPublic:
+				moved++
+			default:
+				cleared++
+			}
+		}
+	}
+	if moved+cleared+failed > 0 {
+		log.Printf("[STORAGE] legacy documents moved=%d cleared=%d failed=%d", moved, cleared, failed)
+	}
+}
+
+// migrateLegacyDocument true = ย้าย object ออกจาก bucket public แล้ว
+func (s *fileService) migrateLegacyDocument(doc *models.Document) (bool, error) {
+	st, err := s.storage.Get(doc.StorageProvider)
+	if err != nil {
+		return false, err
+	}
+	objectPath, ok := objectPathOf(st, doc)
+	if !ok {
+		return false, errors.New("ไม่พบ object path ของเอกสาร")
+	}
+
+	if !isPublicProvider(st.Provider()) {
+		// object อยู่ใน backend ที่ไม่ public อยู่แล้ว แค่เก็บ path แทน URL
+		return false, s.filerepo.MoveDocumentObject(doc.DocumentID, st.Provider(), objectPath)
+	}
+
+	localPath, cleanup, err := s.FetchDocument(doc.DocumentID)
+	if err != nil {
+		return false, err
+	}
+	defer cleanup()
+
+	dst := s.storage.Documents()
+	ctx, cancel := context.WithTimeout(context.Background(), fetchDocumentTimeout)
+	defer cancel()
+	if _, err := dst.UploadLocalFile(ctx, objectPath, localPath); err != nil {
+		return false, err
+	}
+	if err := s.filerepo.MoveDocumentObject(doc.DocumentID, dst.Provider(), objectPath); err != nil {
+		_ = dst.Delete(ctx, objectPath)
+		return false, err
+	}
+	// ลบของเดิมใน bucket public ทีหลัง ถ้าพลาดแค่ log (DB ชี้ที่ใหม่แล้ว)
+	if err := st.Delete(ctx, objectPath); err != nil {
+		log.Printf("[STORAGE] delete public copy of document %d: %v", doc.DocumentID, err)
+	}
+	return true, nil
+}
@@ -2,15 +2,23 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
+	"chaladshare_backend/internal/connect"
 	"chaladshare_backend/internal/files/models"
 	"chaladshare_backend/internal/files/repository"
 
+	docfeaturesModels "chaladshare_backend/internal/docfeatures/models"
 	docfeaturesService "chaladshare_backend/internal/docfeatures/service"
 
 	"github.com/google/uuid"
@@ -18,6 +26,10 @@ import (
 
 type FileService interface {
 	UploadFile(req *models.UploadRequest) (*models.UploadResponse, error)
+	UploadImage(ctx context.Context, kind string, userID int, localPath string) (*models.ImageUpload, error)
+	FetchDocument(documentID int) (localPath string, cleanup func(), err error)
+	GetDownloadURL(ctx context.Context, documentID int) (string, error)
+	OpenLocalSigned(objectPath, expires, sig string) (io.ReadCloser, error)
 	GetFilesByUserID(userID int) ([]models.Document, error)
 	DeleteFile(documentID int) error
 
@@ -25,17 +37,36 @@ type FileService interface {
 
 	SaveSummary(summary *models.Summary) (*models.Summary, error)
 	GetSummaryByDocumentID(docID int) (*models.Summary, error)
+	RegenerateSummary(docID int) error
+	StartSummaryWorkers(workers int, lease time.Duration)
+	MigrateLegacyDocuments()
+	BackfillContentHashes()
 
 	IsOwner(documentID int, userID int) (bool, error)
+
+	GetProcessingStatus(documentID int) (*models.ProcessingStatus, error)
+	SubscribeProcessingStatus(documentID int) (<-chan *models.ProcessingStatus, func())
 }
 
+const fetchDocumentTimeout = 2 * time.Minute
+
 type fileService struct {
 	filerepo   repository.FileRepository
 	featureSvc docfeaturesService.FeatureService
+	aiClient   *connect.Client
+	storage    *StorageRegistry
+
+	summaryWake chan struct{}
 }
 
-func NewFileService(filerepo repository.FileRepository, featureSvc docfeaturesService.FeatureService) FileService {
-	return &fileService{filerepo: filerepo, featureSvc: featureSvc}
+func NewFileService(filerepo repository.FileRepository, featureSvc docfeaturesService.FeatureService, aiClient *connect.Client, storage *StorageRegistry) FileService {
+	return &fileService{
+		filerepo:    filerepo,
+		featureSvc:  featureSvc,
+		aiClient:    aiClient,
+		storage:     storage,
+		summaryWake: make(chan struct{}, 1),
+	}
 }
 
 func (s *fileService) UploadFile(req *models.UploadRequest) (*models.UploadResponse, error) {
@@ -43,75 +74,155 @@ func (s *fileService) UploadFile(req *models.UploadRequest) (*models.UploadRespo
 		return nil, errors.New("ต้องระบุชื่อไฟล์")
 	}
 
-	provider := strings.ToLower(strings.TrimSpace(req.StorageProvider))
-	if provider == "" {
-		provider = "local"
+	if strings.TrimSpace(req.LocalPath) == "" {
+		return nil, errors.New("ต้องมี LocalPath เพื่ออัปโหลดไฟล์")
 	}
+	// worker จะเปิดไฟล์จาก storage เอง ไม่ต้องเก็บ temp ไว้
+	defer func() { _ = os.Remove(req.LocalPath) }()
 
-	// local มี URL เหมือนเดิม
-	if provider != "supabase" && strings.TrimSpace(req.DocumentURL) == "" {
-		return nil, errors.New("ต้องระบุ URL ของไฟล์")
+	// ตรวจเนื้อไฟล์ก่อนเก็บ/ส่ง Colab
+	if err := ValidatePDF(req.LocalPath); err != nil {
+		return nil, err
 	}
 
-	// supabase ใช้ LocalPath อัปก่อน แล้วค่อยได้ URL
-	if provider == "supabase" {
-		if strings.TrimSpace(req.LocalPath) == "" {
-			return nil, errors.New("ต้องมี LocalPath เพื่ออัปขึ้น Supabase")
-		}
+	sum, err := fileSHA256(req.LocalPath)
+	if err != nil {
+		return nil, err
+	}
 
-		st, err := NewSupabaseStorageFromEnv()
-		if err != nil {
-			return nil, fmt.Errorf("supabase storage not configured: %v", err)
+	// เนื้อหาซ้ำกับไฟล์ที่มีอยู่แล้ว: ลิงก์ไปยัง canonical ไม่ต้องอัป/สกัด/สรุปใหม่
+	// ลองใหม่เผื่อมีคนอัปไฟล์เดียวกันเสร็จก่อน หรือ canonical ถูกลบระหว่างลิงก์
+	var savedDoc *models.Document
+	duplicate := false
+	for attempt := 0; attempt < 3 && savedDoc == nil; attempt++ {
+		canon, err := s.filerepo.FindCanonicalByHash(sum)
+		switch {
+		case err == nil:
+			savedDoc, err = s.createDuplicate(req, canon)
+			if errors.Is(err, repository.ErrCanonicalGone) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			duplicate = true
+		case errors.Is(err, sql.ErrNoRows):
+			savedDoc, err = s.createCanonical(req, sum)
+			if errors.Is(err, repository.ErrDuplicateContent) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("ตรวจสอบไฟล์ซ้ำไม่สำเร็จ: %w", err)
 		}
+	}
+	if savedDoc == nil {
+		return nil, errors.New("บันทึกไฟล์ไม่สำเร็จ: ไฟล์เดียวกันถูกอัปพร้อมกัน")
+	}
 
-		ext := strings.ToLower(filepath.Ext(req.LocalPath))
-		if ext == "" {
-			ext = ".pdf"
-		}
-		objectPath := fmt.Sprintf("documents/%d/%s%s", req.UserID, uuid.NewString(), ext)
+	resp := &models.UploadResponse{
+		Message:    "อัปโหลดไฟล์สำเร็จ",
+		File:       *savedDoc,
+		FileURL:    models.DownloadPath(savedDoc.DocumentID),
+		DocumentID: savedDoc.DocumentID,
+		Duplicate:  duplicate,
+	}
+	if postID, err := s.filerepo.FindSharedPostByHash(sum, req.UserID); err == nil {
+		resp.AlreadyShared = true
+		resp.SharedPostID = &postID
+		resp.Message = "อัปโหลดไฟล์สำเร็จ (มีคนแชร์เอกสารนี้แล้ว)"
+	}
+	return resp, nil
+}
 
-		publicURL, err := st.UploadLocalFile(context.Background(), objectPath, req.LocalPath)
-		if err != nil {
-			return nil, fmt.Errorf("อัปขึ้น Supabase ไม่สำเร็จ: %v", err)
+// createCanonical อัปไฟล์ขึ้น storage แล้วเข้าคิว feature/summary ตามปกติ
+func (s *fileService) createCanonical(req *models.UploadRequest, sum string) (*models.Document, error) {
+	// ไม่ระบุ provider = ใช้ backend ของเอกสาร (STORAGE_DOCUMENTS_PROVIDER)
+	st := s.storage.Documents()
+	if strings.TrimSpace(req.StorageProvider) != "" {
+		var err error
+		if st, err = s.storage.Get(req.StorageProvider); err != nil {
+			return nil, err
+		}
+		if isPublicProvider(st.Provider()) {
+			return nil, fmt.Errorf("ไม่สามารถเก็บเอกสารใน %s ได้: bucket เป็น public", st.Provider())
 		}
-		req.DocumentURL = publicURL
 	}
 
+	objectPath := fmt.Sprintf("documents/%d/%s.pdf", req.UserID, uuid.NewString())
+
+	// ไม่เก็บ URL ของ storage ไว้ ดาวน์โหลดผ่าน /files/:document_id/download เท่านั้น
+	if _, err := st.UploadLocalFile(context.Background(), objectPath, req.LocalPath); err != nil {
+		return nil, fmt.Errorf("อัปโหลดไฟล์ไป %s ไม่สำเร็จ: %v", st.Provider(), err)
+	}
+	req.DocumentURL = ""
+	req.DocumentPath = objectPath
+
 	doc := &models.Document{
 		DocumentUserID:  req.UserID,
 		DocumentName:    req.DocumentName,
 		DocumentURL:     req.DocumentURL,
-		StorageProvider: provider,
+		DocumentPath:    req.DocumentPath,
+		StorageProvider: st.Provider(),
+		ContentSHA256:   sum,
 	}
 
 	savedDoc, err := s.filerepo.CreateDocument(doc)
 	if err != nil {
+		_ = st.Delete(context.Background(), objectPath)
+		if errors.Is(err, repository.ErrDuplicateContent) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("บันทึกไฟล์ไม่สำเร็จ: %v", err)
 	}
 
+	// เข้าคิวให้ feature worker (StartWorkers) หยิบไปทำ
 	if err := s.featureSvc.CreateQueued(savedDoc.DocumentID); err != nil {
 		return nil, fmt.Errorf("สร้าง document_features ไม่สำเร็จ: %v", err)
 	}
 
-	pdfPath := req.LocalPath
-	if strings.TrimSpace(pdfPath) == "" {
-		pdfPath = "." + savedDoc.DocumentURL
+	// สรุปเนื้อหาด้วย AI ทำใน summary worker
+	if err := s.filerepo.QueueSummary(savedDoc.DocumentID); err != nil {
+		return nil, fmt.Errorf("สร้างคิวสรุปไม่สำเร็จ: %v", err)
 	}
+	s.notifySummaryWorkers()
+	return savedDoc, nil
+}
 
-	go func(docID int, path string, cleanup bool) {
-		s.featureSvc.ProcessDocument(docID, path)
-		if cleanup {
-			_ = os.Remove(path)
+// createDuplicate สร้างแถวเอกสารของผู้อัปที่ชี้ object เดิมของ canonical
+// ErrCanonicalGone = canonical ถูกลบไปก่อน ให้หา canonical ใหม่
+func (s *fileService) createDuplicate(req *models.UploadRequest, canon *models.Document) (*models.Document, error) {
+	canonicalID := canon.DocumentID
+	doc := &models.Document{
+		DocumentUserID:      req.UserID,
+		DocumentName:        req.DocumentName,
+		ContentSHA256:       canon.ContentSHA256,
+		CanonicalDocumentID: &canonicalID,
+	}
+	savedDoc, err := s.filerepo.CreateDuplicateDocument(doc)
+	if err != nil {
+		if errors.Is(err, repository.ErrCanonicalGone) {
+			return nil, err
 		}
-	}(savedDoc.DocumentID, pdfPath, provider == "supabase")
+		return nil, fmt.Errorf("บันทึกไฟล์ไม่สำเร็จ: %v", err)
+	}
+	return savedDoc, nil
+}
 
-	resp := &models.UploadResponse{
-		Message:    "อัปโหลดไฟล์สำเร็จ",
-		File:       *savedDoc,
-		FileURL:    savedDoc.DocumentURL,
-		DocumentID: savedDoc.DocumentID,
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
 	}
-	return resp, nil
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
 func (s *fileService) GetFilesByUserID(userID int) ([]models.Document, error) {
@@ -132,26 +243,39 @@ func (s *fileService) DeleteFile(documentID int) error {
 		return fmt.Errorf("ไม่พบเอกสาร: %v", err)
 	}
 
-	// local delete
-	if strings.EqualFold(doc.StorageProvider, "local") && strings.TrimSpace(doc.DocumentURL) != "" {
-		p := filepath.Clean("." + doc.DocumentURL)
-		_ = os.Remove(p)
+	// ไฟล์ซ้ำไม่มี object/feature/summary ของตัวเอง ลบแค่แถว
+	if doc.CanonicalDocumentID != nil {
+		if err := s.filerepo.DeleteDocument(documentID); err != nil {
+			return fmt.Errorf("ไม่สามารถลบไฟล์ได้: %v", err)
+		}
+		return nil
 	}
 
-	// supabase delete
-	if strings.EqualFold(doc.StorageProvider, "supabase") && strings.TrimSpace(doc.DocumentURL) != "" {
-		st, err := NewSupabaseStorageFromEnv()
+	// ยังมีไฟล์ซ้ำใช้อยู่: ยก object/feature/summary ให้ตัวที่เก่าที่สุดแทนการลบ
+	promotedID, err := s.filerepo.PromoteDuplicate(documentID)
+	if err != nil {
+		return fmt.Errorf("ย้ายเอกสารซ้ำไม่สำเร็จ: %v", err)
+	}
+	if promotedID != 0 {
+		if err := s.filerepo.DeleteDocument(documentID); err != nil {
+			return fmt.Errorf("ไม่สามารถลบไฟล์ได้: %v", err)
+		}
+		return nil
+	}
+
+	if strings.TrimSpace(doc.DocumentURL) != "" || doc.DocumentPath != "" {
+		st, err := s.storage.Get(doc.StorageProvider)
 		if err != nil {
-			return fmt.Errorf("supabase storage not configured: %v", err)
+			return err
 		}
 
-		objectPath, ok := st.ObjectPathFromPublicURL(doc.DocumentURL)
+		objectPath, ok := objectPathOf(st, doc)
 		if !ok {
-			return errors.New("ลบไฟล์ใน Supabase ไม่ได้: แปลง object path จาก DocumentURL ไม่สำเร็จ (แนะนำเพิ่ม document_path ใน DB)")
+			return fmt.Errorf("ลบไฟล์ใน %s ไม่ได้: ไม่พบ object path ของเอกสาร", st.Provider())
 		}
 
 		if err := st.Delete(context.Background(), objectPath); err != nil {
-			return fmt.Errorf("ลบไฟล์ใน Supabase ไม่สำเร็จ: %v", err)
+			return fmt.Errorf("ลบไฟล์ใน %s ไม่สำเร็จ: %v", st.Provider(), err)
 		}
 	}
 
@@ -173,7 +297,7 @@ func (s *fileService) SaveSummary(summary *models.Summary) (*models.Summary, err
 	if summary.DocumentID == 0 {
 		return nil, errors.New("ต้องระบุ document_id")
 	}
-	if strings.TrimSpace(summary.SummaryText) == "" {
+	if strings.TrimSpace(summary.SummaryText) == "" && strings.TrimSpace(summary.SummaryHTML) == "" {
 		return nil, errors.New("ต้องมีข้อความสรุปก่อนบันทึก")
 	}
 
@@ -191,11 +315,31 @@ func (s *fileService) GetSummaryByDocumentID(docID int) (*models.Summary, error)
 
 	summary, err := s.filerepo.GetSummaryByDocID(docID)
 	if err != nil {
-		return nil, fmt.Errorf("ไม่พบสรุปของไฟล์นี้: %v", err)
+		return nil, fmt.Errorf("ไม่พบสรุปของไฟล์นี้: %w", err)
 	}
 	return summary, nil
 }
 
+// RegenerateSummary สั่งสรุปใหม่ (ผลเดิมยังแสดงได้จนกว่าผลใหม่จะเสร็จ)
+func (s *fileService) RegenerateSummary(docID int) error {
+	if docID <= 0 {
+		return errors.New("document_id ไม่ถูกต้อง")
+	}
+	if s.aiClient == nil {
+		return ErrSummaryUnavailable
+	}
+	// ไฟล์ซ้ำใช้สรุปร่วมกับเอกสารต้นทาง
+	if id, err := s.filerepo.ResolveCanonicalID(docID); err == nil {
+		docID = id
+	}
+	if err := s.filerepo.QueueSummary(docID); err != nil {
+		return fmt.Errorf("สร้างคิวสรุปไม่สำเร็จ: %v", err)
+	}
+	s.notifySummaryStatus(docID)
+	s.notifySummaryWorkers()
+	return nil
+}
+
 // ดึง owner_id ของเอกสารจาก repository
 func (s *fileService) GetDocumentOwnerID(documentID int) (int, error) {
 	if documentID <= 0 {
@@ -203,7 +347,7 @@ func (s *fileService) GetDocumentOwnerID(documentID int) (int, error) {
 	}
 	ownerID, err := s.filerepo.GetDocumentOwnerID(documentID)
 	if err != nil {
-		return 0, fmt.Errorf("ตรวจสอบเจ้าของไฟล์ล้มเหลว: %v", err)
+		return 0, fmt.Errorf("ตรวจสอบเจ้าของไฟล์ล้มเหลว: %w", err)
 	}
 	return ownerID, nil
 }
@@ -219,3 +363,235 @@ func (s *fileService) IsOwner(documentID int, userID int) (bool, error) {
 	}
 	return ownerID == userID, nil
 }
+
+func toProcessingStatus(documentID int, f *docfeaturesModels.DocumentFeature) *models.ProcessingStatus {
+	if f == nil {
+		return &models.ProcessingStatus{DocumentID: documentID, FeatureStatus: models.ProcessingStatusUnknown}
+	}
+	updatedAt := f.UpdatedAt
+	// ไฟล์ซ้ำได้สถานะของเอกสารต้นทาง แต่ตอบด้วย id ที่ถาม
+	return &models.ProcessingStatus{
+		DocumentID:    documentID,
+		FeatureStatus: f.FeatureStatus,
+		StyleLabel:    f.StyleLabel,
+		ClusterID:     f.ClusterID,
+		ErrorMessage:  f.ErrorMessage,
+		AttemptCount:  f.AttemptCount,
+		NextAttemptAt: f.NextAttemptAt,
+		UpdatedAt:     &updatedAt,
+	}
+}
+
+// สถานะการสกัด feature/cluster ของเอกสาร
+func (s *fileService) GetProcessingStatus(documentID int) (*models.ProcessingStatus, error) {
+	if documentID <= 0 {
+		return nil, errors.New("document_id ไม่ถูกต้อง")
+	}
+	f, err := s.featureSvc.GetByDocumentID(documentID)
+	if err != nil {
+		return nil, fmt.Errorf("ดึงสถานะเอกสารไม่สำเร็จ: %v", err)
+	}
+	st := toProcessingStatus(documentID, f)
+	s.attachSummaryStatus(st)
+	return st, nil
+}
+
+func (s *fileService) attachSummaryStatus(st *models.ProcessingStatus) {
+	sum, err := s.filerepo.GetSummaryByDocID(st.DocumentID)
+	if err != nil {
+		return
+	}
+	st.SummaryStatus = &sum.SummaryStatus
+	st.SummaryErrorMessage = sum.SummaryErrorMessage
+}
+
+// SubscribeProcessingStatus ส่งสถานะทุกครั้งที่เปลี่ยน ช่องจะปิดเมื่อเรียก cancel
+func (s *fileService) SubscribeProcessingStatus(documentID int) (<-chan *models.ProcessingStatus, func()) {
+	src, cancelSrc := s.featureSvc.SubscribeStatus(documentID)
+	out := make(chan *models.ProcessingStatus, 4)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-done:
+				return
+			case f := <-src:
+				st := toProcessingStatus(documentID, f)
+				s.attachSummaryStatus(st)
+				select {
+				case out <- st:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			cancelSrc()
+			close(done)
+		})
+	}
+	return out, cancel
+}
+
+// UploadImage ย่อรูป (cover/avatar) เป็นทุกขนาดใน imageVariants แล้วอัปไปยัง storage หลัก
+// ไม่เก็บไฟล์ต้นฉบับ เพื่อไม่ให้ EXIF/GPS หลุดออกไป
+func (s *fileService) UploadImage(ctx context.Context, kind string, userID int, localPath string) (*models.ImageUpload, error) {
+	variants, ok := imageVariants[kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported image kind: %s", kind)
+	}
+	if _, err := ValidateImage(localPath); err != nil {
+		return nil, err
+	}
+
+	encoded, err := processImage(localPath, variants)
+	if err != nil {
+		return nil, err
+	}
+
+	st := s.storage.Default()
+	base := uuid.NewString()
+	urls := make(map[string]string, len(encoded))
+	for _, v := range variants {
+		objectPath := fmt.Sprintf("%s/%d/%s_%s.jpg", kind, userID, base, v.Name)
+		url, err := uploadBytes(ctx, st, objectPath, encoded[v.Name])
+		if err != nil {
+			return nil, fmt.Errorf("อัปโหลดรูป %s ไม่สำเร็จ: %w", v.Name, err)
+		}
+		urls[v.Name] = url
+	}
+
+	img := &models.ImageUpload{
+		ImageURL:  urls["lg"],
+		MediumURL: urls["md"],
+		ThumbURL:  urls["sm"],
+		Kind:      kind,
+		UserID:    userID,
+		Provider:  st.Provider(),
+	}
+	if err := s.filerepo.SaveImageUpload(img); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// uploadBytes เขียนลง temp แล้วอัปผ่าน StorageClient (ทุก backend รับเป็น local path)
+func uploadBytes(ctx context.Context, st StorageClient, objectPath string, data []byte) (string, error) {
+	f, err := os.CreateTemp("", "img_*"+filepath.Ext(objectPath))
+	if err != nil {
+		return "", fmt.Errorf("create temp: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return "", fmt.Errorf("write temp: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("close temp: %w", err)
+	}
+	return st.UploadLocalFile(ctx, objectPath, f.Name())
+}
+
+// FetchDocument คัดลอกไฟล์เอกสารจาก storage ลง temp ให้ worker ส่งต่อไป Colab
+// ต้องเรียก cleanup เมื่อใช้เสร็จ
+func (s *fileService) FetchDocument(documentID int) (string, func(), error) {
+	noop := func() {}
+
+	doc, err := s.filerepo.GetDocumentByID(documentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", noop, connect.Permanent(fmt.Errorf("document %d not found", documentID))
+	}
+	if err != nil {
+		return "", noop, err
+	}
+
+	st, err := s.storage.Get(doc.StorageProvider)
+	if err != nil {
+		return "", noop, connect.Permanent(err)
+	}
+	objectPath, ok := objectPathOf(st, doc)
+	if !ok {
+		return "", noop, connect.Permanent(fmt.Errorf("cannot resolve object path of document %d", documentID))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchDocumentTimeout)
+	defer cancel()
+
+	rc, err := st.Open(ctx, objectPath)
+	if errors.Is(err, ErrObjectNotFound) {
+		return "", noop, connect.Permanent(err)
+	}
+	if err != nil {
+		return "", noop, err
+	}
+	defer rc.Close()
+
+	ext := filepath.Ext(objectPath)
+	if ext == "" {
+		ext = ".pdf"
+	}
+	f, err := os.CreateTemp("", "doc_*"+ext)
+	if err != nil {
+		return "", noop, fmt.Errorf("create temp: %w", err)
+	}
+	path := f.Name()
+	cleanup := func() { _ = os.Remove(path) }
+
+	if _, err := io.Copy(f, rc); err != nil {
+		_ = f.Close()
+		cleanup()
+		return "", noop, fmt.Errorf("copy document: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("copy document: %w", err)
+	}
+	return path, cleanup, nil
+}
+
+// objectPathOf ใช้ document_path ถ้ามี (เอกสารเก่าก่อนมีคอลัมน์นี้ ถอดจาก URL แทน)
+func objectPathOf(st StorageClient, doc *models.Document) (string, bool) {
+	if p := strings.TrimSpace(doc.DocumentPath); p != "" {
+		return p, true
+	}
+	return st.ObjectPathFromPublicURL(doc.DocumentURL)
+}
+
+// GetDownloadURL สร้างลิงก์ดาวน์โหลดอายุสั้น (ผู้เรียกต้องเช็คสิทธิ์ก่อน)
+func (s *fileService) GetDownloadURL(ctx context.Context, documentID int) (string, error) {
+	if documentID <= 0 {
+		return "", errors.New("document_id ไม่ถูกต้อง")
+	}
+
+	doc, err := s.filerepo.GetDocumentByID(documentID)
+	if err != nil {
+		return "", fmt.Errorf("ไม่พบเอกสาร: %w", err)
+	}
+
+	st, err := s.storage.Get(doc.StorageProvider)
+	if err != nil {
+		return "", err
+	}
+	objectPath, ok := objectPathOf(st, doc)
+	if !ok {
+		return "", fmt.Errorf("ไม่พบ object path ของเอกสาร %d", documentID)
+	}
+
+	return st.SignedURL(ctx, objectPath, s.storage.SignedURLTTL())
+}
+
+// OpenLocalSigned ใช้กับ route ดาวน์โหลดของ local storage
+func (s *fileService) OpenLocalSigned(objectPath, expires, sig string) (io.ReadCloser, error) {
+	local := s.storage.Local()
+	if local == nil {
+		return nil, ErrObjectNotFound
+	}
+	return local.OpenSigned(objectPath, expires, sig)
+}
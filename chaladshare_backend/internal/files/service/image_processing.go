@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"os"
+)
+
+// imageVariant ขนาดมาตรฐานที่เก็บต่อรูปหนึ่งรูป
+type imageVariant struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+const imageJPEGQuality = 85
+
+// ถอดรหัสเต็มใช้ RGBA 4 ไบต์ต่อพิกเซล (x2 ตอน toRGBA) จำกัดพิกเซลรวมไว้ ~40MP
+const maxImagePixels = 40_000_000
+
+// avatar เป็นสี่เหลี่ยมจัตุรัส, cover เป็นแนวตั้ง 3:4 ตามหน้ากระดาษชีท
+var imageVariants = map[string][]imageVariant{
+	"avatars": {
+		{Name: "lg", Width: 512, Height: 512},
+		{Name: "md", Width: 256, Height: 256},
+		{Name: "sm", Width: 96, Height: 96},
+	},
+	"covers": {
+		{Name: "lg", Width: 1200, Height: 1600},
+		{Name: "md", Width: 600, Height: 800},
+		{Name: "sm", Width: 300, Height: 400},
+	},
+}
+
+// processImage ถอดรหัสรูป หมุนตาม EXIF ครอปกลางตามสัดส่วน แล้วย่อเป็นทุกขนาดใน variants
+// ผลลัพธ์ encode ใหม่เป็น JPEG จึงไม่มี EXIF (รวม GPS) ติดไปด้วย
+// อ่านขนาดจาก header ก่อน รูปที่ใหญ่เกินจะไม่ถูกถอดรหัสเต็ม (กัน decompression bomb)
+func processImage(path string, variants []imageVariant) (map[string][]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, uploadErr(UploadErrNotImage, "อ่านไฟล์รูปไม่ได้ (ไฟล์อาจเสีย)")
+	}
+	if cfg.Width < 1 || cfg.Height < 1 || cfg.Width > maxImageSide || cfg.Height > maxImageSide ||
+		cfg.Width*cfg.Height > maxImagePixels {
+		return nil, uploadErr(UploadErrImageDimensions, "ขนาดรูปใหญ่เกินไป (%dx%d px)", cfg.Width, cfg.Height)
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, uploadErr(UploadErrNotImage, "อ่านไฟล์รูปไม่ได้ (ไฟล์อาจเสีย)")
+	}
+
+	img := toRGBA(src)
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(raw))
+	}
+
+	out := make(map[string][]byte, len(variants))
+	for _, v := range variants {
+		cropped := centerCrop(img, v.Width, v.Height)
+		w, h := v.Width, v.Height
+		// ไม่ขยายรูปเล็กให้ใหญ่กว่าต้นฉบับ
+		if cb := cropped.Bounds(); cb.Dx() < w {
+			w, h = cb.Dx(), cb.Dy()
+		}
+		resized := resizeArea(cropped, w, h)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: imageJPEGQuality}); err != nil {
+			return nil, fmt.Errorf("encode %s: %w", v.Name, err)
+		}
+		out[v.Name] = buf.Bytes()
+	}
+	return out, nil
+}
+
+// toRGBA แปลงเป็น RGBA บนพื้นขาว (PNG โปร่งใสจะไม่กลายเป็นสีดำตอนเป็น JPEG)
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Over)
+	return dst
+}
+
+// centerCrop ตัดส่วนกลางให้ได้สัดส่วน w:h
+func centerCrop(img *image.RGBA, w, h int) *image.RGBA {
+	b := img.Bounds()
+	cw, ch := b.Dx(), b.Dy()
+	if cw*h > ch*w {
+		cw = ch * w / h
+	} else {
+		ch = cw * h / w
+	}
+	if cw < 1 {
+		cw = 1
+	}
+	if ch < 1 {
+		ch = 1
+	}
+	x0 := b.Min.X + (b.Dx()-cw)/2
+	y0 := b.Min.Y + (b.Dy()-ch)/2
+	return img.SubImage(image.Rect(x0, y0, x0+cw, y0+ch)).(*image.RGBA)
+}
+
+// resizeArea ย่อรูปด้วยการเฉลี่ยพื้นที่ (box filter) ได้ผลดีกว่า nearest ตอนย่อมาก ๆ
+func resizeArea(src *image.RGBA, w, h int) *image.RGBA {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := sb.Min.Y + y*sh/h
+		y1 := sb.Min.Y + (y+1)*sh/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0 := sb.Min.X + x*sw/w
+			x1 := sb.Min.X + (x+1)*sw/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				off := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[off])
+					g += uint32(src.Pix[off+1])
+					bl += uint32(src.Pix[off+2])
+					a += uint32(src.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+			d := dst.PixOffset(x, y)
+			dst.Pix[d] = uint8(r / n)
+			dst.Pix[d+1] = uint8(g / n)
+			dst.Pix[d+2] = uint8(bl / n)
+			dst.Pix[d+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// applyOrientation หมุน/กลับด้านตามค่า EXIF Orientation (1-8)
+func applyOrientation(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// 5-8 สลับแกน
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // flip แนวนอน
+				dx, dy = w-1-x, y
+			case 3: // หมุน 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // flip แนวตั้ง
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // หมุนตามเข็ม 90
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // หมุนทวนเข็ม 90
+				dx, dy = y, w-1-x
+			}
+			so := img.PixOffset(b.Min.X+x, b.Min.Y+y)
+			do := dst.PixOffset(dx, dy)
+			copy(dst.Pix[do:do+4], img.Pix[so:so+4])
+		}
+	}
+	return dst
+}
+
+// jpegOrientation อ่านแท็ก Orientation (0x0112) จาก APP1 Exif ไม่เจอคืน 1
+func jpegOrientation(data []byte) int {
+	r := bytes.NewReader(data)
+	var marker [2]byte
+	if _, err := io.ReadFull(r, marker[:]); err != nil || marker != [2]byte{0xFF, 0xD8} {
+		return 1
+	}
+
+	for {
+		if _, err := io.ReadFull(r, marker[:]); err != nil || marker[0] != 0xFF {
+			return 1
+		}
+		// SOS/EOI = หมดส่วน header แล้ว
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return 1
+		}
+		var sz [2]byte
+		if _, err := io.ReadFull(r, sz[:]); err != nil {
+			return 1
+		}
+		n := int(binary.BigEndian.Uint16(sz[:])) - 2
+		if n < 0 {
+			return 1
+		}
+		seg := make([]byte, n)
+		if _, err := io.ReadFull(r, seg); err != nil {
+			return 1
+		}
+		if marker[1] == 0xE1 && len(seg) > 6 && string(seg[:6]) == "Exif\x00\x00" {
+			return exifOrientation(seg[6:])
+		}
+	}
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(bo.Uint32(tiff[4:8]))
+	if ifd < 0 || ifd+2 > len(tiff) {
+		return 1
+	}
+	count := int(bo.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < count; i++ {
+		e := ifd + 2 + i*12
+		if e+12 > len(tiff) {
+			return 1
+		}
+		if bo.Uint16(tiff[e:e+2]) == 0x0112 {
+			v := int(bo.Uint16(tiff[e+8 : e+10]))
+			if v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeImage(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	return path
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func solidImage(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+// decodeVariant ผลลัพธ์ทุกขนาดต้องเป็น JPEG
+func decodeVariant(t *testing.T, name string, data []byte) image.Image {
+	t.Helper()
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%s: decode output: %v", name, err)
+	}
+	if format != "jpeg" {
+		t.Fatalf("%s: format = %s, want jpeg", name, format)
+	}
+	return img
+}
+
+func TestProcessImageVariantSizes(t *testing.T) {
+	path := writeImage(t, "wide.png", encodePNG(t, solidImage(2000, 1000, color.RGBA{R: 200, A: 255})))
+
+	cases := []struct {
+		kind string
+		want map[string][2]int
+	}{
+		// ครอป 1:1 จาก 1000x1000
+		{"avatars", map[string][2]int{"lg": {512, 512}, "md": {256, 256}, "sm": {96, 96}}},
+		// ครอป 3:4 ได้ 750x1000 เล็กกว่า lg จึงไม่ขยาย
+		{"covers", map[string][2]int{"lg": {750, 1000}, "md": {600, 800}, "sm": {300, 400}}},
+	}
+	for _, tc := range cases {
+		out, err := processImage(path, imageVariants[tc.kind])
+		if err != nil {
+			t.Fatalf("%s: processImage: %v", tc.kind, err)
+		}
+		if len(out) != len(tc.want) {
+			t.Fatalf("%s: got %d variants, want %d", tc.kind, len(out), len(tc.want))
+		}
+		for name, size := range tc.want {
+			b := decodeVariant(t, tc.kind+"/"+name, out[name]).Bounds()
+			if b.Dx() != size[0] || b.Dy() != size[1] {
+				t.Errorf("%s/%s: size = %dx%d, want %dx%d", tc.kind, name, b.Dx(), b.Dy(), size[0], size[1])
+			}
+		}
+	}
+}
+
+func TestProcessImageTransparentBecomesWhite(t *testing.T) {
+	path := writeImage(t, "clear.png", encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 64, 64))))
+
+	out, err := processImage(path, imageVariants["avatars"])
+	if err != nil {
+		t.Fatalf("processImage: %v", err)
+	}
+	r, g, b, _ := decodeVariant(t, "sm", out["sm"]).At(10, 10).RGBA()
+	if r>>8 < 250 || g>>8 < 250 || b>>8 < 250 {
+		t.Fatalf("pixel = (%d,%d,%d), want white", r>>8, g>>8, b>>8)
+	}
+}
+
+// pngWithSize PNG ที่ header อ้างขนาด w x h แต่ข้อมูลจริงเล็ก (DecodeConfig อ่านแค่ IHDR)
+func pngWithSize(t *testing.T, w, h uint32) []byte {
+	t.Helper()
+	data := encodePNG(t, solidImage(1, 1, color.Black))
+	// signature 8 ไบต์ + length 4 + "IHDR" 4 แล้วตามด้วย width/height
+	binary.BigEndian.PutUint32(data[16:20], w)
+	binary.BigEndian.PutUint32(data[20:24], h)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestProcessImageRejectsHugeDimensions(t *testing.T) {
+	cases := []struct {
+		name string
+		w, h uint32
+	}{
+		{"side too long", maxImageSide + 1, 100},
+		{"too many pixels", 7000, 7000},
+		{"bomb", 100000, 100000},
+	}
+	for _, tc := range cases {
+		path := writeImage(t, "huge.png", pngWithSize(t, tc.w, tc.h))
+		_, err := processImage(path, imageVariants["avatars"])
+		var ve *UploadValidationError
+		if !errors.As(err, &ve) || ve.Code != UploadErrImageDimensions {
+			t.Errorf("%s: processImage = %v, want %s", tc.name, err, UploadErrImageDimensions)
+		}
+	}
+}
+
+func TestProcessImageRejectsNonImage(t *testing.T) {
+	path := writeImage(t, "note.png", []byte("not an image at all"))
+	_, err := processImage(path, imageVariants["avatars"])
+	var ve *UploadValidationError
+	if !errors.As(err, &ve) || ve.Code != UploadErrNotImage {
+		t.Fatalf("processImage = %v, want %s", err, UploadErrNotImage)
+	}
+}
+
+// jpegWithOrientation JPEG ที่มี APP1 Exif บอก Orientation
+func jpegWithOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var enc bytes.Buffer
+	if err := jpeg.Encode(&enc, img, nil); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+
+	// TIFF little-endian: IFD เดียวที่มีแท็ก 0x0112
+	tiff := []byte{'I', 'I', 42, 0, 8, 0, 0, 0, 1, 0}
+	entry := make([]byte, 12)
+	binary.LittleEndian.PutUint16(entry[0:2], 0x0112)
+	binary.LittleEndian.PutUint16(entry[2:4], 3) // SHORT
+	binary.LittleEndian.PutUint32(entry[4:8], 1)
+	binary.LittleEndian.PutUint16(entry[8:10], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:4], uint16(len(seg)+2))
+	app1 = append(app1, seg...)
+
+	raw := enc.Bytes()
+	out := append([]byte{}, raw[:2]...)
+	out = append(out, app1...)
+	return append(out, raw[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	img := solidImage(8, 4, color.Black)
+	for _, o := range []uint16{1, 3, 6, 8} {
+		if got := jpegOrientation(jpegWithOrientation(t, img, o)); got != int(o) {
+			t.Errorf("orientation %d: got %d", o, got)
+		}
+	}
+	if got := jpegOrientation([]byte("not a jpeg")); got != 1 {
+		t.Errorf("non-jpeg orientation = %d, want 1", got)
+	}
+}
+
+func TestProcessImageAppliesOrientation(t *testing.T) {
+	// 80x40 แนวนอน + Orientation 6 = แนวตั้ง 40x80 ครอป 3:4 ได้กว้าง 40 (ไม่หมุนจะได้ 30)
+	path := writeImage(t, "rotated.jpg", jpegWithOrientation(t, solidImage(80, 40, color.Gray{Y: 128}), 6))
+
+	out, err := processImage(path, imageVariants["covers"])
+	if err != nil {
+		t.Fatalf("processImage: %v", err)
+	}
+	if b := decodeVariant(t, "lg", out["lg"]).Bounds(); b.Dx() != 40 || b.Dy() != 53 {
+		t.Fatalf("lg size = %dx%d, want 40x53", b.Dx(), b.Dy())
+	}
+}
+
+func TestResizeAreaAveragesBlocks(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	quads := []color.RGBA{{R: 255, A: 255}, {G: 255, A: 255}, {B: 255, A: 255}, {R: 255, G: 255, B: 255, A: 255}}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			src.SetRGBA(x, y, quads[(y/2)*2+x/2])
+		}
+	}
+	dst := resizeArea(src, 2, 2)
+	for i, want := range quads {
+		if got := dst.RGBAAt(i%2, i/2); got != want {
+			t.Errorf("pixel %d = %v, want %v", i, got, want)
+		}
+	}
+
+	// ย่อ 2 สีครึ่งซ้าย/ขวาเป็นพิกเซลเดียว ได้ค่าเฉลี่ย
+	half := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	half.SetRGBA(0, 0, color.RGBA{R: 200, A: 255})
+	half.SetRGBA(1, 0, color.RGBA{R: 100, A: 255})
+	if got := resizeArea(half, 1, 1).RGBAAt(0, 0); got.R != 150 {
+		t.Errorf("averaged red = %d, want 150", got.R)
+	}
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrSignedURLInvalid = errors.New("signed url is invalid or expired")
+
+// LocalSignedPathPrefix route ที่เสิร์ฟไฟล์ local ผ่านลิงก์ที่เซ็นแล้ว (ไม่ต้อง login)
+const LocalSignedPathPrefix = "/api/v1/files/local/"
+
+// LocalStorage เก็บไฟล์ลงดิสก์ใต้ root
+// main.go เสิร์ฟแบบ public เฉพาะ covers/ avatars/ ส่วน documents/ ต้องผ่าน signed URL
+type LocalStorage struct {
+	root       string
+	urlPrefix  string
+	signingKey []byte
+}
+
+// UPLOAD_DIR ต้องตรงกับที่ main.go ใช้ทำ r.Static("/uploads/...", ...)
+// STORAGE_SIGNING_KEY ใช้เซ็นลิงก์ดาวน์โหลด (ไม่ตั้งจะใช้ JWT_SECRET)
+func NewLocalStorageFromEnv() (*LocalStorage, error) {
+	root := strings.TrimSpace(os.Getenv("UPLOAD_DIR"))
+	if root == "" {
+		root = "/tmp/uploads"
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("create upload dir: %w", err)
+	}
+	key := strings.TrimSpace(os.Getenv("STORAGE_SIGNING_KEY"))
+	if key == "" {
+		key = strings.TrimSpace(os.Getenv("JWT_SECRET"))
+	}
+	if key == "" {
+		return nil, errors.New("missing env: STORAGE_SIGNING_KEY (or JWT_SECRET)")
+	}
+	return &LocalStorage{root: root, urlPrefix: "/uploads", signingKey: []byte(key)}, nil
+}
+
+func (s *LocalStorage) Provider() string { return StorageLocal }
+
+// fullPath กัน objectPath ที่พยายามออกนอก root (../)
+func (s *LocalStorage) fullPath(objectPath string) (string, error) {
+	clean := filepath.Clean("/" + filepath.FromSlash(objectPath))
+	if clean == "/" {
+		return "", errors.New("object path is empty")
+	}
+	return filepath.Join(s.root, clean), nil
+}
+
+func (s *LocalStorage) UploadLocalFile(ctx context.Context, objectPath string, localPath string) (string, error) {
+	dst, err := s.fullPath(objectPath)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", fmt.Errorf("mkdir: %w", err)
+	}
+
+	in, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer in.Close()
+
+	// เขียนลง temp ก่อนแล้ว rename กันไฟล์ครึ่ง ๆ กลาง ๆ
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload_*")
+	if err != nil {
+		return "", fmt.Errorf("create temp: %w", err)
+	}
+	if _, err := io.Copy(tmp, in); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("copy file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("copy file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("rename: %w", err)
+	}
+
+	return s.urlPrefix + "/" + strings.TrimPrefix(filepath.ToSlash(objectPath), "/"), nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, objectPath string) error {
+	p, err := s.fullPath(objectPath)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete file: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) Open(ctx context.Context, objectPath string) (io.ReadCloser, error) {
+	p, err := s.fullPath(objectPath)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	return f, nil
+}
+
+func (s *LocalStorage) ObjectPathFromPublicURL(publicURL string) (string, bool) {
+	prefix := s.urlPrefix + "/"
+	if !strings.HasPrefix(publicURL, prefix) {
+		return "", false
+	}
+	p := strings.TrimPrefix(publicURL, prefix)
+	if p == "" {
+		return "", false
+	}
+	return p, true
+}
+
+func (s *LocalStorage) sign(objectPath string, expires int64) string {
+	m := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(m, "%s\n%d", objectPath, expires)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func (s *LocalStorage) SignedURL(ctx context.Context, objectPath string, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	p, err := s.fullPath(objectPath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+		return "", ErrObjectNotFound
+	}
+
+	objectPath = strings.TrimPrefix(filepath.ToSlash(objectPath), "/")
+	expires := time.Now().Add(ttl).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("sig", s.sign(objectPath, expires))
+	return LocalSignedPathPrefix + escapeObjectPath(objectPath) + "?" + q.Encode(), nil
+}
+
+// OpenSigned ตรวจลิงก์จาก SignedURL แล้วเปิดไฟล์
+func (s *LocalStorage) OpenSigned(objectPath, expires, sig string) (io.ReadCloser, error) {
+	objectPath = strings.TrimPrefix(objectPath, "/")
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return nil, ErrSignedURLInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.sign(objectPath, exp))) {
+		return nil, ErrSignedURLInvalid
+	}
+	return s.Open(context.Background(), objectPath)
+}
@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Storage ใช้ได้กับ AWS S3 และ S3-compatible (MinIO ฯลฯ) แบบ path-style: {endpoint}/{bucket}/{key}
+// เซ็น request ด้วย AWS Signature V4 เอง ไม่ต้องพึ่ง SDK
+type S3Storage struct {
+	endpoint   *url.URL
+	region     string
+	bucket     string
+	accessKey  string
+	secretKey  string
+	publicBase string
+	httpClient *http.Client
+}
+
+// env: S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY,
+// S3_REGION (default us-east-1), S3_PUBLIC_URL (default {endpoint}/{bucket})
+func NewS3StorageFromEnv() (*S3Storage, error) {
+	endpoint := strings.TrimRight(strings.TrimSpace(os.Getenv("S3_ENDPOINT")), "/")
+	bucket := strings.TrimSpace(os.Getenv("S3_BUCKET"))
+	accessKey := strings.TrimSpace(os.Getenv("S3_ACCESS_KEY_ID"))
+	secretKey := strings.TrimSpace(os.Getenv("S3_SECRET_ACCESS_KEY"))
+	region := strings.TrimSpace(os.Getenv("S3_REGION"))
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	if endpoint == "" || bucket == "" || accessKey == "" || secretKey == "" {
+		return nil, errors.New("missing env: S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT: %q", endpoint)
+	}
+
+	publicBase := strings.TrimRight(strings.TrimSpace(os.Getenv("S3_PUBLIC_URL")), "/")
+	if publicBase == "" {
+		publicBase = endpoint + "/" + bucket
+	}
+
+	return &S3Storage{
+		endpoint:   u,
+		region:     region,
+		bucket:     bucket,
+		accessKey:  accessKey,
+		secretKey:  secretKey,
+		publicBase: publicBase,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+func (s *S3Storage) Provider() string { return StorageS3 }
+
+func (s *S3Storage) objectURL(objectPath string) *url.URL {
+	u := *s.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + s.bucket + "/" + strings.TrimPrefix(objectPath, "/")
+	u.RawPath = strings.TrimRight(s.endpoint.EscapedPath(), "/") + "/" + s3URIEncode(s.bucket, false) + "/" + s3URIEncode(strings.TrimPrefix(objectPath, "/"), true)
+	u.RawQuery = ""
+	return &u
+}
+
+func (s *S3Storage) UploadLocalFile(ctx context.Context, objectPath string, localPath string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("stat file: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(objectPath).String(), f)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+	req.ContentLength = st.Size()
+
+	ct := mime.TypeByExtension(strings.ToLower(filepath.Ext(localPath)))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	req.Header.Set("Content-Type", ct)
+	s.sign(req, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("upload request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("s3 upload failed: %s - %s", resp.Status, string(b))
+	}
+
+	return s.publicBase + "/" + escapeObjectPath(objectPath), nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, objectPath string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(objectPath).String(), nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	s.sign(req, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("delete request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// S3 ตอบ 204 แม้ object ไม่มีอยู่แล้ว
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("s3 delete failed: %s - %s", resp.Status, string(b))
+	}
+	return nil
+}
+
+func (s *S3Storage) Open(ctx context.Context, objectPath string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(objectPath).String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	s.sign(req, time.Now())
+
+	// ใช้ ctx คุมเวลาแทน client timeout (ไฟล์ใหญ่อ่านนาน)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("s3 download failed: %s - %s", resp.Status, string(b))
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) ObjectPathFromPublicURL(publicURL string) (string, bool) {
+	prefix := s.publicBase + "/"
+	if !strings.HasPrefix(publicURL, prefix) {
+		return "", false
+	}
+	suffix := strings.TrimPrefix(publicURL, prefix)
+
+	decoded, err := url.PathUnescape(suffix)
+	if err == nil && decoded != "" {
+		suffix = decoded
+	}
+	return suffix, suffix != ""
+}
+
+// SignedURL presigned GET (query-string auth) อายุสูงสุดตามสเปกคือ 7 วัน
+func (s *S3Storage) SignedURL(ctx context.Context, objectPath string, ttl time.Duration) (string, error) {
+	secs := int(ttl.Seconds())
+	if secs <= 0 {
+		secs = 60
+	}
+	if secs > 7*24*3600 {
+		secs = 7 * 24 * 3600
+	}
+
+	return s.presignURL(s.objectURL(objectPath), time.Now(), secs).String(), nil
+}
+
+// ---------- AWS Signature V4 ----------
+
+// presignURL ใส่ query-string auth ของ GET ให้ u (เซ็นเฉพาะ host)
+func (s *S3Storage) presignURL(u *url.URL, now time.Time, secs int) *url.URL {
+	t := now.UTC()
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(t))
+	q.Set("X-Amz-Date", t.Format("20060102T150405Z"))
+	q.Set("X-Amz-Expires", strconv.Itoa(secs))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	q.Set("X-Amz-Signature", s.signature(t, s3PresignCanonicalRequest(u, q)))
+	signed := *u
+	signed.RawQuery = s3CanonicalQuery(q)
+	return &signed
+}
+
+func s3PresignCanonicalRequest(u *url.URL, q url.Values) string {
+	return strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		s3CanonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+}
+
+func (s *S3Storage) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+func (s *S3Storage) signingKey(t time.Time) []byte {
+	k := hmacSHA256([]byte("AWS4"+s.secretKey), t.Format("20060102"))
+	k = hmacSHA256(k, s.region)
+	k = hmacSHA256(k, "s3")
+	return hmacSHA256(k, "aws4_request")
+}
+
+// sign ใส่ header Authorization (payload ไม่เซ็น เพื่อ stream ไฟล์ได้โดยไม่ต้องอ่าน 2 รอบ)
+func (s *S3Storage) sign(req *http.Request, now time.Time) {
+	t := now.UTC()
+	amzDate := t.Format("20060102T150405Z")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	signedNames := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("Content-Type") != "" {
+		signedNames = append(signedNames, "content-type")
+	}
+	sort.Strings(signedNames)
+	signedHeaders := strings.Join(signedNames, ";")
+
+	sig := s.signature(t, s3CanonicalRequest(req, signedNames, s3UnsignedPayload))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, s.scope(t), signedHeaders, sig))
+}
+
+// s3CanonicalRequest signedNames ต้องเป็นตัวเล็กและเรียงแล้ว; host เอาจาก URL
+func s3CanonicalRequest(req *http.Request, signedNames []string, payloadHash string) string {
+	var canonHeaders strings.Builder
+	for _, name := range signedNames {
+		v := req.Header.Get(name)
+		if name == "host" {
+			v = req.URL.Host
+		}
+		canonHeaders.WriteString(name + ":" + strings.TrimSpace(v) + "\n")
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		canonHeaders.String(),
+		strings.Join(signedNames, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+func (s *S3Storage) stringToSign(t time.Time, canonReq string) string {
+	sum := sha256.Sum256([]byte(canonReq))
+	return strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		t.Format("20060102T150405Z"),
+		s.scope(t),
+		hex.EncodeToString(sum[:]),
+	}, "\n")
+}
+
+func (s *S3Storage) signature(t time.Time, canonReq string) string {
+	return hex.EncodeToString(hmacSHA256(s.signingKey(t), s.stringToSign(t, canonReq)))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+func s3CanonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, s3URIEncode(k, false)+"="+s3URIEncode(v, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3URIEncode ตามสเปก SigV4: เข้ารหัสทุกตัวยกเว้น A-Z a-z 0-9 - _ . ~ (และ / ถ้า keepSlash)
+func s3URIEncode(v string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StorageLocal           = "local"
+	StorageSupabase        = "supabase"
+	StorageSupabasePrivate = "supabase_private"
+	StorageS3              = "s3"
+)
+
+const defaultSignedURLTTL = 5 * time.Minute
+
+var ErrObjectNotFound = errors.New("storage object not found")
+
+type StorageClient interface {
+	Provider() string
+	UploadLocalFile(ctx context.Context, objectPath string, localPath string) (publicURL string, err error)
+	Delete(ctx context.Context, objectPath string) error
+	Open(ctx context.Context, objectPath string) (io.ReadCloser, error)
+	ObjectPathFromPublicURL(publicURL string) (objectPath string, ok bool)
+	// SignedURL ลิงก์ดาวน์โหลดที่หมดอายุเองหลัง ttl (local = route ที่เซ็นด้วย HMAC)
+	SignedURL(ctx context.Context, objectPath string, ttl time.Duration) (string, error)
+}
+
+// StorageRegistry รวม backend ที่ตั้งค่าไว้ แล้วเลือกตาม documents.storage_provider
+type StorageRegistry struct {
+	backends          map[string]StorageClient
+	defaultProvider   string
+	documentsProvider string
+	signedURLTTL      time.Duration
+}
+
+// isPublicProvider backend ที่ object เปิดอ่านได้ด้วย URL ตรง (bucket supabase หลัก) ห้ามใช้เก็บเอกสาร
+func isPublicProvider(provider string) bool {
+	return provider == StorageSupabase
+}
+
+// NewStorageRegistryFromEnv
+// STORAGE_PROVIDER = local | supabase | s3 (ไม่ระบุ: ใช้ supabase ถ้าตั้งค่าไว้ ไม่งั้น local)
+// STORAGE_DOCUMENTS_PROVIDER = backend ของไฟล์ PDF (ไม่ระบุ: supabase_private ถ้ามี ไม่งั้นใช้ตัวเดียวกับ STORAGE_PROVIDER)
+// เอกสารต้องไม่อยู่ใน bucket public ถ้าไม่มี backend อื่นให้ใช้จะ start ไม่ขึ้น (s3 ต้องตั้ง bucket ให้ไม่ public เอง)
+// STORAGE_SIGNED_URL_TTL_SECONDS = อายุลิงก์ดาวน์โหลด (default 300)
+// local ใช้ได้เสมอ ส่วน supabase/s3 จะถูกเพิ่มเมื่อ env ครบ
+func NewStorageRegistryFromEnv() (*StorageRegistry, error) {
+	r := &StorageRegistry{backends: make(map[string]StorageClient)}
+
+	local, err := NewLocalStorageFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	r.Register(local)
+
+	if sb, err := NewSupabaseStorageFromEnv(); err == nil {
+		r.Register(sb)
+	}
+	if sb, err := NewSupabasePrivateStorageFromEnv(); err == nil {
+		r.Register(sb)
+	}
+	if s3, err := NewS3StorageFromEnv(); err == nil {
+		r.Register(s3)
+	} else if os.Getenv("S3_BUCKET") != "" {
+		log.Printf("WARNING: s3 storage not configured: %v", err)
+	}
+
+	def := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_PROVIDER")))
+	if def == "" {
+		def = StorageLocal
+		if _, ok := r.backends[StorageSupabase]; ok {
+			def = StorageSupabase
+		}
+	}
+	if _, ok := r.backends[def]; !ok {
+		return nil, fmt.Errorf("storage provider %q is not configured (available: %s)", def, strings.Join(r.Providers(), ", "))
+	}
+	r.defaultProvider = def
+
+	docs := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_DOCUMENTS_PROVIDER")))
+	if docs == "" {
+		docs = def
+		if _, ok := r.backends[StorageSupabasePrivate]; ok {
+			docs = StorageSupabasePrivate
+		}
+	}
+	if _, ok := r.backends[docs]; !ok {
+		return nil, fmt.Errorf("documents storage provider %q is not configured (available: %s)", docs, strings.Join(r.Providers(), ", "))
+	}
+	if isPublicProvider(docs) {
+		return nil, fmt.Errorf("documents storage provider %q is a public bucket: set SUPABASE_PRIVATE_BUCKET or STORAGE_DOCUMENTS_PROVIDER=local|s3", docs)
+	}
+	r.documentsProvider = docs
+
+	r.signedURLTTL = defaultSignedURLTTL
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("STORAGE_SIGNED_URL_TTL_SECONDS"))); err == nil && v > 0 {
+		r.signedURLTTL = time.Duration(v) * time.Second
+	}
+	return r, nil
+}
+
+func (r *StorageRegistry) Register(c StorageClient) {
+	r.backends[c.Provider()] = c
+}
+
+// Get คืน backend ตามชื่อ provider (ว่าง = default)
+func (r *StorageRegistry) Get(provider string) (StorageClient, error) {
+	p := strings.ToLower(strings.TrimSpace(provider))
+	if p == "" {
+		p = r.defaultProvider
+	}
+	c, ok := r.backends[p]
+	if !ok {
+		return nil, fmt.Errorf("storage provider %q is not configured", provider)
+	}
+	return c, nil
+}
+
+func (r *StorageRegistry) Default() StorageClient {
+	return r.backends[r.defaultProvider]
+}
+
+// Documents คือ backend ของไฟล์เอกสาร (ควรเป็น bucket ที่ไม่ public)
+func (r *StorageRegistry) Documents() StorageClient {
+	return r.backends[r.documentsProvider]
+}
+
+func (r *StorageRegistry) SignedURLTTL() time.Duration {
+	return r.signedURLTTL
+}
+
+func (r *StorageRegistry) Local() *LocalStorage {
+	l, _ := r.backends[StorageLocal].(*LocalStorage)
+	return l
+}
+
+func (r *StorageRegistry) Providers() []string {
+	out := make([]string, 0, len(r.backends))
+	for p := range r.backends {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"chaladshare_backend/internal/connect"
+	"chaladshare_backend/internal/files/models"
+)
+
+var ErrSummaryUnavailable = errors.New("ai summary is unavailable")
+
+// errSummaryClaimLost งานถูก reclaim หรือถูกสั่งสรุปใหม่ระหว่างทำ ผลของ worker นี้ถูกทิ้ง
+var errSummaryClaimLost = errors.New("summary job claim lost")
+
+const (
+	summaryPollInterval = 30 * time.Second
+	summaryIdleBackoff  = 5 * time.Second
+
+	summaryMaxAttempts  = 3
+	summaryRetryBackoff = time.Minute
+	summaryRetryMaxWait = 30 * time.Minute
+)
+
+// StartSummaryWorkers เปิด worker สรุปเอกสารจากคิวในตาราง summaries
+// เรียกครั้งเดียวตอน start (งาน queued/processing ที่ค้างจากรอบก่อนจะถูกหยิบต่อ)
+func (s *fileService) StartSummaryWorkers(workers int, lease time.Duration) {
+	if workers <= 0 {
+		workers = 1
+	}
+	if lease <= 0 {
+		lease = 20 * time.Minute
+	}
+
+	s.reclaimStaleSummaries(lease)
+
+	for i := 1; i <= workers; i++ {
+		go s.runSummaryWorker(i)
+	}
+	go func() {
+		ticker := time.NewTicker(lease / 2)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.reclaimStaleSummaries(lease)
+		}
+	}()
+
+	log.Printf("[SUMMARY-WORKER] started workers=%d lease=%s", workers, lease)
+	s.notifySummaryWorkers()
+}
+
+func (s *fileService) notifySummaryWorkers() {
+	select {
+	case s.summaryWake <- struct{}{}:
+	default:
+	}
+}
+
+// notifySummaryStatus ส่งสถานะให้ SSE ของเอกสาร (ไม่ต้องรอ re-check จาก DB)
+func (s *fileService) notifySummaryStatus(documentID int) {
+	if s.featureSvc != nil {
+		s.featureSvc.NotifyStatus(documentID)
+	}
+}
+
+func (s *fileService) reclaimStaleSummaries(lease time.Duration) {
+	n, err := s.filerepo.ReclaimStaleSummaries(lease)
+	if err != nil {
+		log.Printf("[SUMMARY-WORKER] reclaim stale error: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[SUMMARY-WORKER] requeued stale jobs=%d", n)
+		s.notifySummaryWorkers()
+	}
+}
+
+func (s *fileService) runSummaryWorker(id int) {
+	ticker := time.NewTicker(summaryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := s.filerepo.ClaimQueuedSummary()
+		if err != nil {
+			log.Printf("[SUMMARY-WORKER %d] claim error: %v", id, err)
+			time.Sleep(summaryIdleBackoff)
+			continue
+		}
+		if job == nil {
+			select {
+			case <-s.summaryWake:
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		s.notifySummaryWorkers()
+		log.Printf("[SUMMARY-WORKER %d] claimed doc=%d summary=%d", id, job.DocumentID, job.SummaryID)
+		s.notifySummaryStatus(job.DocumentID)
+
+		if job.Attempt > summaryMaxAttempts {
+			_, _ = s.filerepo.MarkSummaryFailed(job.SummaryID, job.ClaimID, fmt.Sprintf("gave up after %d attempts", summaryMaxAttempts))
+			s.notifySummaryStatus(job.DocumentID)
+			continue
+		}
+		err = s.summarize(job)
+		if errors.Is(err, errSummaryClaimLost) {
+			log.Printf("[SUMMARY-WORKER %d] doc=%d claim lost, result dropped", id, job.DocumentID)
+		} else if err != nil {
+			s.handleSummaryError(job, err)
+		}
+		s.notifySummaryStatus(job.DocumentID)
+	}
+}
+
+func (s *fileService) summarize(job *models.SummaryJob) error {
+	if s.aiClient == nil {
+		return connect.Permanent(ErrSummaryUnavailable)
+	}
+
+	pdfPath, cleanup, err := s.FetchDocument(job.DocumentID)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	resp, err := s.aiClient.Summarize(job.DocumentID, pdfPath)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(resp.SummaryText) == "" && strings.TrimSpace(resp.SummaryHTML) == "" {
+		return errors.New("ต้องมีข้อความสรุปก่อนบันทึก")
+	}
+	held, err := s.filerepo.CompleteSummary(job.SummaryID, job.ClaimID, &models.Summary{
+		DocumentID:    job.DocumentID,
+		SummaryText:   resp.SummaryText,
+		SummaryHTML:   resp.SummaryHTML,
+		SummaryPDFURL: resp.SummaryPDFURL,
+	})
+	if err != nil {
+		return fmt.Errorf("บันทึกสรุปไม่สำเร็จ: %v", err)
+	}
+	if !held {
+		return errSummaryClaimLost
+	}
+	return nil
+}
+
+func (s *fileService) handleSummaryError(job *models.SummaryJob, err error) {
+	msg := err.Error()
+
+	if connect.IsPermanent(err) || job.Attempt >= summaryMaxAttempts {
+		log.Printf("[SUMMARY-WORKER] doc=%d failed attempt=%d: %v", job.DocumentID, job.Attempt, err)
+		if held, err := s.filerepo.MarkSummaryFailed(job.SummaryID, job.ClaimID, msg); err == nil && !held {
+			log.Printf("[SUMMARY-WORKER] doc=%d claim lost, failure dropped", job.DocumentID)
+		}
+		return
+	}
+
+	wait := summaryRetryBackoff
+	for i := 1; i < job.Attempt && wait < summaryRetryMaxWait; i++ {
+		wait *= 2
+	}
+	if wait > summaryRetryMaxWait {
+		wait = summaryRetryMaxWait
+	}
+
+	log.Printf("[SUMMARY-WORKER] doc=%d retry in %s attempt=%d: %v", job.DocumentID, wait, job.Attempt, err)
+	held, err := s.filerepo.ScheduleSummaryRetry(job.SummaryID, job.ClaimID, msg, time.Now().Add(wait))
+	if err != nil {
+		log.Printf("[SUMMARY-WORKER] doc=%d schedule retry error: %v", job.DocumentID, err)
+		return
+	}
+	if !held {
+		log.Printf("[SUMMARY-WORKER] doc=%d claim lost, retry dropped", job.DocumentID)
+	}
+}
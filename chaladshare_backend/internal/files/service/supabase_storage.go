@@ -1,7 +1,9 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -14,13 +16,8 @@ import (
 	"time"
 )
 
-type StorageClient interface {
-	UploadLocalFile(ctx context.Context, objectPath string, localPath string) (publicURL string, err error)
-	Delete(ctx context.Context, objectPath string) error
-	ObjectPathFromPublicURL(publicURL string) (objectPath string, ok bool)
-}
-
 type SupabaseStorage struct {
+	provider   string
 	baseURL    string
 	serviceKey string
 	bucket     string
@@ -40,6 +37,7 @@ func NewSupabaseStorageFromEnv() (*SupabaseStorage, error) {
 	}
 
 	return &SupabaseStorage{
+		provider:   StorageSupabase,
 		baseURL:    baseURL,
 		serviceKey: key,
 		bucket:     bucket,
@@ -47,6 +45,24 @@ func NewSupabaseStorageFromEnv() (*SupabaseStorage, error) {
 	}, nil
 }
 
+// NewSupabasePrivateStorageFromEnv bucket แยกสำหรับเอกสาร (ตั้งเป็น private ใน Supabase)
+// ดาวน์โหลดได้ผ่าน signed URL เท่านั้น
+func NewSupabasePrivateStorageFromEnv() (*SupabaseStorage, error) {
+	bucket := strings.TrimSpace(os.Getenv("SUPABASE_PRIVATE_BUCKET"))
+	if bucket == "" {
+		return nil, errors.New("missing env: SUPABASE_PRIVATE_BUCKET")
+	}
+	st, err := NewSupabaseStorageFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	st.provider = StorageSupabasePrivate
+	st.bucket = bucket
+	return st, nil
+}
+
+func (s *SupabaseStorage) Provider() string { return s.provider }
+
 func (s *SupabaseStorage) UploadLocalFile(ctx context.Context, objectPath string, localPath string) (string, error) {
 	f, err := os.Open(localPath)
 	if err != nil {
@@ -122,6 +138,39 @@ func (s *SupabaseStorage) Delete(ctx context.Context, objectPath string) error {
 	return nil
 }
 
+// Open อ่านไฟล์ผ่าน endpoint ที่ใช้ service key (ใช้ได้ทั้ง bucket public/private)
+func (s *SupabaseStorage) Open(ctx context.Context, objectPath string) (io.ReadCloser, error) {
+	u := fmt.Sprintf("%s/storage/v1/object/%s/%s",
+		s.baseURL,
+		url.PathEscape(s.bucket),
+		escapeObjectPath(objectPath),
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.serviceKey)
+	req.Header.Set("apikey", s.serviceKey)
+
+	// ไม่ใช้ s.httpClient เพราะ timeout 60s จะตัดการโหลดไฟล์ใหญ่ (ใช้ ctx คุมแทน)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download request: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		// supabase ตอบ object ที่ไม่มีเป็น 400 + "not_found" ได้ด้วย
+		if resp.StatusCode == http.StatusNotFound || strings.Contains(string(b), "not_found") {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("supabase download failed: %s - %s", resp.Status, string(b))
+	}
+	return resp.Body, nil
+}
+
 func (s *SupabaseStorage) ObjectPathFromPublicURL(publicURL string) (string, bool) {
 	prefix := fmt.Sprintf("%s/storage/v1/object/public/%s/", strings.TrimRight(s.baseURL, "/"), s.bucket)
 	if !strings.HasPrefix(publicURL, prefix) {
@@ -136,6 +185,57 @@ func (s *SupabaseStorage) ObjectPathFromPublicURL(publicURL string) (string, boo
 	return suffix, true
 }
 
+func (s *SupabaseStorage) SignedURL(ctx context.Context, objectPath string, ttl time.Duration) (string, error) {
+	u := fmt.Sprintf("%s/storage/v1/object/sign/%s/%s",
+		s.baseURL,
+		url.PathEscape(s.bucket),
+		escapeObjectPath(objectPath),
+	)
+
+	secs := int(ttl.Seconds())
+	if secs <= 0 {
+		secs = 60
+	}
+	body, err := json.Marshal(map[string]int{"expiresIn": secs})
+	if err != nil {
+		return "", fmt.Errorf("marshal sign req: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.serviceKey)
+	req.Header.Set("apikey", s.serviceKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sign request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound || strings.Contains(string(b), "not_found") {
+			return "", ErrObjectNotFound
+		}
+		return "", fmt.Errorf("supabase sign failed: %s - %s", resp.Status, string(b))
+	}
+
+	var out struct {
+		SignedURL string `json:"signedURL"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode sign resp: %w", err)
+	}
+	if out.SignedURL == "" {
+		return "", errors.New("supabase sign: empty signedURL")
+	}
+	// signedURL ที่ได้เป็น path ใต้ /storage/v1
+	return s.baseURL + "/storage/v1" + out.SignedURL, nil
+}
+
 func escapeObjectPath(p string) string {
 	parts := strings.Split(p, "/")
 	for i := range parts {
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// รหัส error ที่ส่งกลับให้ frontend (field "code")
+const (
+	UploadErrFileTooLarge    = "file_too_large"
+	UploadErrEmptyFile       = "empty_file"
+	UploadErrNotPDF          = "not_a_pdf"
+	UploadErrPDFCorrupt      = "pdf_corrupt"
+	UploadErrPDFEncrypted    = "pdf_encrypted"
+	UploadErrTooManyPages    = "too_many_pages"
+	UploadErrNotImage        = "unsupported_image_type"
+	UploadErrImageDimensions = "invalid_image_dimensions"
+)
+
+const (
+	MaxPDFBytes   = 50 << 20
+	MaxPDFPages   = 300
+	MaxImageBytes = 10 << 20
+
+	minImageSide = 32
+	maxImageSide = 8000
+
+	// object stream ที่คลายแล้วรวมกันไม่เกินนี้ (กัน zip bomb) เกินแล้วหยุดอ่าน
+	maxPDFObjStmBytes = 32 << 20
+)
+
+// UploadValidationError ไฟล์ไม่ผ่านการตรวจ (handler แปลงเป็น 4xx พร้อม code)
+type UploadValidationError struct {
+	Code    string
+	Message string
+}
+
+func (e *UploadValidationError) Error() string { return e.Message }
+
+func uploadErr(code, format string, args ...any) error {
+	return &UploadValidationError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+var (
+	pdfEncryptRe  = regexp.MustCompile(`/Encrypt\b`)
+	pdfPagesCount = regexp.MustCompile(`/Type\s*/Pages\b[^>]*?/Count\s+(\d+)|/Count\s+(\d+)[^>]*?/Type\s*/Pages\b`)
+	pdfPageObj    = regexp.MustCompile(`/Type\s*/Page\b`)
+
+	// dictionary ที่ตามด้วย stream (ซ้อน << >> ได้หนึ่งชั้น เช่น /DecodeParms)
+	pdfStreamDict = regexp.MustCompile(`<<((?:[^<>]|<<[^<>]*>>)*)>>\s*stream\r?\n`)
+	pdfObjStm     = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+	pdfFilter     = regexp.MustCompile(`/Filter\s*(\[\s*)?/(\w+)\s*(\])?`)
+)
+
+// ValidatePDF ตรวจจากเนื้อไฟล์: magic bytes, ขนาด, %%EOF, การเข้ารหัส และจำนวนหน้า
+// นับหน้าจาก /Pages /Count ทั้งในเนื้อไฟล์และใน object stream (PDF 1.5+)
+// นับไม่ได้ (เช่น filter ที่ไม่รองรับ) จะไม่ reject แต่ log ไว้ว่าไม่ทราบจำนวนหน้า
+func ValidatePDF(path string) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	if st.Size() == 0 {
+		return uploadErr(UploadErrEmptyFile, "ไฟล์ว่างเปล่า")
+	}
+	if st.Size() > MaxPDFBytes {
+		return uploadErr(UploadErrFileTooLarge, "ไฟล์ใหญ่เกิน %d MB", MaxPDFBytes>>20)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	// สเปกยอมให้มีขยะก่อน header ได้ไม่เกิน 1024 ไบต์
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if !bytes.Contains(head, []byte("%PDF-")) {
+		return uploadErr(UploadErrNotPDF, "ไฟล์นี้ไม่ใช่ PDF")
+	}
+
+	tail := data
+	if len(tail) > 2048 {
+		tail = tail[len(tail)-2048:]
+	}
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return uploadErr(UploadErrPDFCorrupt, "ไฟล์ PDF เสียหรืออัปโหลดไม่ครบ")
+	}
+
+	if pdfEncryptRe.Match(data) {
+		return uploadErr(UploadErrPDFEncrypted, "ไม่รองรับ PDF ที่ตั้งรหัสผ่านหรือเข้ารหัส")
+	}
+
+	pages, known := pdfPageCount(data)
+	if !known {
+		log.Printf("[UPLOAD] PDF page count unknown size=%d", len(data))
+		return nil
+	}
+	if pages > MaxPDFPages {
+		return uploadErr(UploadErrTooManyPages, "PDF มี %d หน้า (สูงสุด %d หน้า)", pages, MaxPDFPages)
+	}
+	return nil
+}
+
+// pdfPageCount คืนจำนวนหน้า และ false ถ้าหา page tree ไม่เจอเลย
+// /Count ของ /Pages ตัวที่มากที่สุดคือ root; ไม่มี /Count ก็นับ /Type /Page แทน
+func pdfPageCount(data []byte) (int, bool) {
+	sources := append([][]byte{data}, pdfObjectStreams(data)...)
+
+	max := 0
+	for _, src := range sources {
+		for _, m := range pdfPagesCount.FindAllSubmatch(src, -1) {
+			for _, g := range m[1:] {
+				if len(g) == 0 {
+					continue
+				}
+				if n, err := strconv.Atoi(string(g)); err == nil && n > max {
+					max = n
+				}
+			}
+		}
+	}
+	if max > 0 {
+		return max, true
+	}
+
+	pages := 0
+	for _, src := range sources {
+		pages += len(pdfPageObj.FindAllIndex(src, -1))
+	}
+	return pages, pages > 0
+}
+
+// pdfObjectStreams คลาย object stream (/Type /ObjStm) ที่ไม่บีบอัดหรือเป็น FlateDecode
+// ตัวที่ใช้ filter อื่นหรือเสียจะถูกข้าม
+func pdfObjectStreams(data []byte) [][]byte {
+	var out [][]byte
+	budget := int64(maxPDFObjStmBytes)
+	for _, loc := range pdfStreamDict.FindAllSubmatchIndex(data, -1) {
+		dict := data[loc[2]:loc[3]]
+		if !pdfObjStm.Match(dict) {
+			continue
+		}
+		body := data[loc[1]:]
+		if end := bytes.Index(body, []byte("endstream")); end >= 0 {
+			body = body[:end]
+		}
+
+		var r io.Reader = bytes.NewReader(body)
+		if f := pdfFilter.FindSubmatch(dict); f != nil {
+			// array ที่มีหลาย filter (มี filter ถัดไปก่อน ]) ไม่รองรับ
+			if string(f[2]) != "FlateDecode" || (len(f[1]) > 0 && len(f[3]) == 0) {
+				continue
+			}
+			zr, err := zlib.NewReader(r)
+			if err != nil {
+				continue
+			}
+			r = zr
+		}
+
+		buf, err := io.ReadAll(io.LimitReader(r, budget+1))
+		if int64(len(buf)) > budget {
+			break
+		}
+		budget -= int64(len(buf))
+		if err != nil && len(buf) == 0 {
+			continue
+		}
+		out = append(out, buf)
+	}
+	return out
+}
+
+// ValidateImage ตรวจชนิดไฟล์จาก magic bytes และขนาดภาพจริง (รองรับ jpeg/png)
+// คืนนามสกุลตามเนื้อไฟล์จริง (.jpg/.png)
+func ValidateImage(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("stat file: %w", err)
+	}
+	if st.Size() == 0 {
+		return "", uploadErr(UploadErrEmptyFile, "ไฟล์ว่างเปล่า")
+	}
+	if st.Size() > MaxImageBytes {
+		return "", uploadErr(UploadErrFileTooLarge, "รูปใหญ่เกิน %d MB", MaxImageBytes>>20)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+	ct := http.DetectContentType(head[:n])
+	ext := ""
+	switch ct {
+	case "image/jpeg":
+		ext = ".jpg"
+	case "image/png":
+		ext = ".png"
+	default:
+		return "", uploadErr(UploadErrNotImage, "รองรับเฉพาะรูป JPEG และ PNG")
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("seek file: %w", err)
+	}
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", uploadErr(UploadErrNotImage, "อ่านไฟล์รูปไม่ได้ (ไฟล์อาจเสีย)")
+	}
+	if cfg.Width < minImageSide || cfg.Height < minImageSide ||
+		cfg.Width > maxImageSide || cfg.Height > maxImageSide {
+		return "", uploadErr(UploadErrImageDimensions, "ขนาดรูปต้องอยู่ระหว่าง %dx%d ถึง %dx%d px (ได้ %dx%d)",
+			minImageSide, minImageSide, maxImageSide, maxImageSide, cfg.Width, cfg.Height)
+	}
+	return ext, nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// classicPDF PDF 1.4 แบบ xref table ปกติ page tree อยู่ในเนื้อไฟล์ตรง ๆ
+func classicPDF(pages int) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	kids := make([]string, pages)
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", i+3)
+	}
+	fmt.Fprintf(&b, "2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), pages)
+	for i := 0; i < pages; i++ {
+		fmt.Fprintf(&b, "%d 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>\nendobj\n", i+3)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+// objStmPDF PDF 1.5 ที่ page tree อยู่ใน object stream และ xref เป็น stream
+// filter = "" ไม่บีบอัด, อื่น ๆ บีบด้วย zlib แล้วติดชื่อ filter นั้น (ไม่ใช่ FlateDecode = อ่านไม่ออก)
+func objStmPDF(pages int, filter string) []byte {
+	var objs bytes.Buffer
+	fmt.Fprintf(&objs, "<< /Type /Pages /Kids [] /Count %d >>\n", pages)
+	for i := 0; i < pages; i++ {
+		objs.WriteString("<< /Type /Page /Parent 2 0 R >>\n")
+	}
+
+	body := objs.Bytes()
+	filterEntry := ""
+	if filter != "" {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(body)
+		zw.Close()
+		body = z.Bytes()
+		filterEntry = " /Filter /" + filter
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n%\xe2\xe3\xcf\xd3\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&b, "5 0 obj\n<< /Type /ObjStm /N %d /First 0 /Length %d%s >>\nstream\n", pages+1, len(body), filterEntry)
+	b.Write(body)
+	b.WriteString("\nendstream\nendobj\n")
+	// xref stream: dictionary ไม่บีบอัด เนื้อจริงไม่สำคัญกับการนับหน้า
+	b.WriteString("6 0 obj\n<< /Type /XRef /Size 7 /W [1 2 1] /Root 1 0 R /Length 4 /Filter /FlateDecode /DecodeParms << /Columns 4 /Predictor 12 >> >>\nstream\n\x00\x00\x00\x00\nendstream\nendobj\n")
+	b.WriteString("startxref\n0\n%%EOF\n")
+	return b.Bytes()
+}
+
+func TestPDFPageCount(t *testing.T) {
+	cases := []struct {
+		name      string
+		data      []byte
+		wantPages int
+		wantKnown bool
+	}{
+		{"classic xref", classicPDF(3), 3, true},
+		{"object stream flate", objStmPDF(350, "FlateDecode"), 350, true},
+		{"object stream uncompressed", objStmPDF(12, ""), 12, true},
+		{"object stream unsupported filter", objStmPDF(5, "LZWDecode"), 0, false},
+		{"no page tree", []byte("%PDF-1.4\n%%EOF\n"), 0, false},
+	}
+	for _, tc := range cases {
+		pages, known := pdfPageCount(tc.data)
+		if pages != tc.wantPages || known != tc.wantKnown {
+			t.Errorf("%s: pdfPageCount = %d, %v; want %d, %v", tc.name, pages, known, tc.wantPages, tc.wantKnown)
+		}
+	}
+}
+
+func TestPDFPageCountWithoutCountEntry(t *testing.T) {
+	// ไม่มี /Count: นับจาก /Type /Page แทน
+	data := bytes.ReplaceAll(classicPDF(4), []byte("/Count 4"), nil)
+	if pages, known := pdfPageCount(data); pages != 4 || !known {
+		t.Fatalf("pdfPageCount = %d, %v; want 4, true", pages, known)
+	}
+}
+
+func validateBytes(t *testing.T, data []byte) error {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "doc.pdf")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	return ValidatePDF(path)
+}
+
+func uploadCode(err error) string {
+	var ve *UploadValidationError
+	if errors.As(err, &ve) {
+		return ve.Code
+	}
+	return ""
+}
+
+func TestValidatePDF(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     []byte
+		wantCode string
+	}{
+		{"classic", classicPDF(3), ""},
+		{"too many pages in object stream", objStmPDF(MaxPDFPages+1, "FlateDecode"), UploadErrTooManyPages},
+		{"unknown page count is accepted", objStmPDF(MaxPDFPages+1, "LZWDecode"), ""},
+		{"not a pdf", []byte("hello world %%EOF"), UploadErrNotPDF},
+		{"truncated", classicPDF(1)[:40], UploadErrPDFCorrupt},
+		{"encrypted", bytes.Replace(classicPDF(1), []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 9 0 R"), 1), UploadErrPDFEncrypted},
+	}
+	for _, tc := range cases {
+		err := validateBytes(t, tc.data)
+		if tc.wantCode == "" {
+			if err != nil {
+				t.Errorf("%s: ValidatePDF = %v, want nil", tc.name, err)
+			}
+			continue
+		}
+		if code := uploadCode(err); code != tc.wantCode {
+			t.Errorf("%s: ValidatePDF = %v (code %q), want code %q", tc.name, err, code, tc.wantCode)
+		}
+	}
+}
@@ -62,6 +62,12 @@ func respondError(c *gin.Context, err error) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 	case models.ErrInvalidSelfAction, models.ErrAlreadyFriends, models.ErrNotFriends:
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case models.ErrGroupInvalidName, models.ErrGroupMemberFriend, models.ErrGroupTooLarge:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case models.ErrGroupNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case models.ErrGroupNameTaken:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+
+	"chaladshare_backend/internal/friends/models"
+	"chaladshare_backend/internal/friends/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GroupHandler struct {
+	groupservice service.GroupService
+}
+
+func NewGroupHandler(groupservice service.GroupService) *GroupHandler {
+	return &GroupHandler{groupservice: groupservice}
+}
+
+func (h *GroupHandler) ListGroups(c *gin.Context) {
+	ownerID, ok := getUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	items, err := h.groupservice.ListGroups(c.Request.Context(), ownerID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": len(items)})
+}
+
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	ownerID, ok := getUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	groupID, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+
+	g, err := h.groupservice.GetGroup(c.Request.Context(), ownerID, groupID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": g})
+}
+
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	ownerID, ok := getUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req models.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	g, err := h.groupservice.CreateGroup(c.Request.Context(), ownerID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": g})
+}
+
+func (h *GroupHandler) UpdateGroup(c *gin.Context) {
+	ownerID, ok := getUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	groupID, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+	var req models.UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	g, err := h.groupservice.UpdateGroup(c.Request.Context(), ownerID, groupID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": g})
+}
+
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	ownerID, ok := getUID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	groupID, ok := parseParamID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.groupservice.DeleteGroup(c.Request.Context(), ownerID, groupID); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
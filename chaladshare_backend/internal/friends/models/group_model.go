@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	MaxGroupNameLength = 50
+	MaxGroupMembers    = 500
+)
+
+var (
+	ErrGroupNotFound     = errors.New("friend group not found")
+	ErrGroupNameTaken    = errors.New("group name already exists")
+	ErrGroupInvalidName  = errors.New("group name is required (max 50 characters)")
+	ErrGroupMemberFriend = errors.New("group members must be your friends")
+	ErrGroupTooLarge     = errors.New("too many group members")
+)
+
+// กลุ่มเพื่อนที่เจ้าของบันทึกไว้ ใช้เลือกผู้ชมของโพสต์แบบ custom
+type FriendGroup struct {
+	GroupID     int       `json:"group_id"`
+	Name        string    `json:"name"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type GroupMember struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+}
+
+type FriendGroupDetail struct {
+	FriendGroup
+	Members []GroupMember `json:"members"`
+}
+
+type CreateGroupRequest struct {
+	Name      string `json:"name" binding:"required"`
+	MemberIDs []int  `json:"member_ids"`
+}
+
+// UpdateGroupRequest ฟิลด์ที่เป็น nil = ไม่แก้, MemberIDs ส่งมา = แทนที่สมาชิกทั้งหมด
+type UpdateGroupRequest struct {
+	Name      *string `json:"name"`
+	MemberIDs *[]int  `json:"member_ids"`
+}
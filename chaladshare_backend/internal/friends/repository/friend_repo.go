@@ -91,7 +91,7 @@ func (r *friendrepo) ListFriends(ctx context.Context, viewerID, userID int, sear
 	SELECT
 	  u.user_id                              AS user_id,
 	  u.username                             AS username,
-	  COALESCE(p.avatar_url,'')              AS avatar,
+	  COALESCE(p.avatar_thumb_url, p.avatar_url, '') AS avatar,
 	  EXISTS (SELECT 1 FROM friendships fs
 	          WHERE fs.user_id=LEAST($1::int, u.user_id) AND fs.friend_id=GREATEST($1::int, u.user_id)) AS is_friend,
 	  EXISTS (SELECT 1 FROM follows f2
@@ -125,7 +125,7 @@ func (r *friendrepo) ListFollowers(ctx context.Context, viewerID, userID int, se
 	SELECT
 	  u.user_id                             AS user_id,
 	  u.username                            AS username,
-	  COALESCE(p.avatar_url,'')             AS avatar,
+	  COALESCE(p.avatar_thumb_url, p.avatar_url, '') AS avatar,
 	  EXISTS (SELECT 1 FROM friendships fs
 	          WHERE fs.user_id=LEAST($1::int, u.user_id) AND fs.friend_id=GREATEST($1::int, u.user_id)) AS is_friend,
 	  EXISTS (SELECT 1 FROM follows f2
@@ -160,7 +160,7 @@ func (r *friendrepo) ListFollowing(ctx context.Context, viewerID, userID int, se
 	SELECT
 	  u.user_id                             AS user_id,
 	  u.username                            AS username,
-	  COALESCE(p.avatar_url,'')             AS avatar,
+	  COALESCE(p.avatar_thumb_url, p.avatar_url, '') AS avatar,
 	  EXISTS (SELECT 1 FROM friendships fs
 	          WHERE fs.user_id=LEAST($1::int, u.user_id) AND fs.friend_id=GREATEST($1::int, u.user_id)) AS is_friend,
 	  EXISTS (SELECT 1 FROM follows f2
@@ -267,7 +267,7 @@ func (r *friendrepo) ListIncomingRequests(ctx context.Context, addresseeID int,
                fr.requester_user_id,
                fr.request_created_at AS requested_at,
                u.username,
-               COALESCE(p.avatar_url,'') AS avatar
+               COALESCE(p.avatar_thumb_url, p.avatar_url, '') AS avatar
         FROM friend_requests fr
         JOIN users u ON u.user_id = fr.requester_user_id
         LEFT JOIN user_profiles p ON p.profile_user_id = u.user_id
@@ -298,7 +298,7 @@ func (r *friendrepo) ListOutgoingRequests(ctx context.Context, requesterID int,
                fr.addressee_user_id      AS target_user_id,
                fr.request_created_at     AS requested_at,
                u.username,
-               COALESCE(p.avatar_url,'') AS avatar
+               COALESCE(p.avatar_thumb_url, p.avatar_url, '') AS avatar
         FROM friend_requests fr
         JOIN users u ON u.user_id = fr.addressee_user_id
         LEFT JOIN user_profiles p ON p.profile_user_id = u.user_id
@@ -403,7 +403,7 @@ func (r *friendrepo) CancelFriendRequest(ctx context.Context, requestID int, req
 	return err
 }
 
-// Unfriend: TX = delete friendship + delete follows A↔B
+// Unfriend: TX = delete friendship + delete follows A↔B + เอาออกจากกลุ่มเพื่อนของกันและกัน
 func (r *friendrepo) Unfriend(ctx context.Context, aID, bID int) (err error) {
 	if aID == bID {
 		return errors.New("cannot unfriend yourself")
@@ -434,6 +434,17 @@ func (r *friendrepo) Unfriend(ctx context.Context, aID, bID int) (err error) {
 		return err
 	}
 
+	// ไม่ลบ = ยังเห็นโพสต์ custom ที่ตั้งผู้ชมเป็นกลุ่มเดิมอยู่
+	if _, err = tx.ExecContext(ctx, `
+		DELETE FROM friend_group_members gm
+		USING friend_groups g
+		WHERE gm.group_id = g.group_id
+		  AND ((g.group_owner_user_id=$1 AND gm.member_user_id=$2)
+		    OR (g.group_owner_user_id=$2 AND gm.member_user_id=$1))
+	`, aID, bID); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
@@ -454,7 +465,7 @@ func (r *friendrepo) SearchAddFriend(ctx context.Context, actorID int, search st
 	SELECT
 	  u.user_id,
 	  u.username,
-	  COALESCE(p.avatar_url,'') AS avatar,
+	  COALESCE(p.avatar_thumb_url, p.avatar_url, '') AS avatar,
 
 	  -- case 2: เป็นเพื่อนแล้ว
 	  EXISTS (
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"chaladshare_backend/internal/friends/models"
+)
+
+type GroupRepository interface {
+	CreateGroup(ctx context.Context, ownerID int, name string, memberIDs []int) (int, error)
+	UpdateGroup(ctx context.Context, ownerID, groupID int, name *string, memberIDs []int, replaceMembers bool) error
+	DeleteGroup(ctx context.Context, ownerID, groupID int) error
+
+	ListGroups(ctx context.Context, ownerID int) ([]models.FriendGroup, error)
+	GetGroup(ctx context.Context, ownerID, groupID int) (*models.FriendGroup, error)
+	ListGroupMembers(ctx context.Context, groupID int) ([]models.GroupMember, error)
+
+	// CountFriendsAmong จำนวนใน userIDs ที่เป็นเพื่อนกับ ownerID
+	CountFriendsAmong(ctx context.Context, ownerID int, userIDs []int) (int, error)
+}
+
+type grouprepo struct {
+	db *sql.DB
+}
+
+func NewGroupRepository(db *sql.DB) GroupRepository {
+	return &grouprepo{db: db}
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func insertMembers(ctx context.Context, tx *sql.Tx, groupID int, memberIDs []int) error {
+	if len(memberIDs) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO friend_group_members (group_id, member_user_id)
+		SELECT $1, UNNEST($2::int[])
+		ON CONFLICT DO NOTHING
+	`, groupID, pq.Array(memberIDs))
+	return err
+}
+
+func (r *grouprepo) CreateGroup(ctx context.Context, ownerID int, name string, memberIDs []int) (groupID int, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = tx.QueryRowContext(ctx, `
+		INSERT INTO friend_groups (group_owner_user_id, group_name)
+		VALUES ($1, $2)
+		RETURNING group_id
+	`, ownerID, name).Scan(&groupID); err != nil {
+		if isUniqueViolation(err) {
+			return 0, models.ErrGroupNameTaken
+		}
+		return 0, err
+	}
+	if err = insertMembers(ctx, tx, groupID, memberIDs); err != nil {
+		return 0, err
+	}
+	return groupID, tx.Commit()
+}
+
+func (r *grouprepo) UpdateGroup(ctx context.Context, ownerID, groupID int, name *string, memberIDs []int, replaceMembers bool) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// เช็คเจ้าของ + แก้ชื่อ (ถ้าส่งมา) ในคำสั่งเดียว
+	res, err := tx.ExecContext(ctx, `
+		UPDATE friend_groups
+		SET group_name = COALESCE($3, group_name),
+		    group_updated_at = now()
+		WHERE group_id = $1 AND group_owner_user_id = $2
+	`, groupID, ownerID, name)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.ErrGroupNameTaken
+		}
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrGroupNotFound
+	}
+
+	if replaceMembers {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM friend_group_members WHERE group_id = $1`, groupID); err != nil {
+			return err
+		}
+		if err = insertMembers(ctx, tx, groupID, memberIDs); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *grouprepo) DeleteGroup(ctx context.Context, ownerID, groupID int) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM friend_groups WHERE group_id = $1 AND group_owner_user_id = $2
+	`, groupID, ownerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrGroupNotFound
+	}
+	return nil
+}
+
+const groupSelect = `
+	SELECT g.group_id, g.group_name,
+	       (SELECT COUNT(*) FROM friend_group_members gm WHERE gm.group_id = g.group_id) AS member_count,
+	       g.group_created_at, g.group_updated_at
+	FROM friend_groups g
+`
+
+func (r *grouprepo) ListGroups(ctx context.Context, ownerID int) ([]models.FriendGroup, error) {
+	rows, err := r.db.QueryContext(ctx, groupSelect+`
+		WHERE g.group_owner_user_id = $1
+		ORDER BY g.group_name ASC, g.group_id ASC
+	`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.FriendGroup
+	for rows.Next() {
+		var g models.FriendGroup
+		if err := rows.Scan(&g.GroupID, &g.Name, &g.MemberCount, &g.CreatedAt, &g.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, g)
+	}
+	return out, rows.Err()
+}
+
+func (r *grouprepo) GetGroup(ctx context.Context, ownerID, groupID int) (*models.FriendGroup, error) {
+	var g models.FriendGroup
+	err := r.db.QueryRowContext(ctx, groupSelect+`
+		WHERE g.group_id = $1 AND g.group_owner_user_id = $2
+	`, groupID, ownerID).Scan(&g.GroupID, &g.Name, &g.MemberCount, &g.CreatedAt, &g.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+func (r *grouprepo) ListGroupMembers(ctx context.Context, groupID int) ([]models.GroupMember, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT u.user_id, u.username, COALESCE(p.avatar_thumb_url, p.avatar_url, '')
+		FROM friend_group_members gm
+		JOIN users u ON u.user_id = gm.member_user_id
+		LEFT JOIN user_profiles p ON p.profile_user_id = u.user_id
+		WHERE gm.group_id = $1
+		ORDER BY u.username ASC, u.user_id ASC
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.GroupMember
+	for rows.Next() {
+		var m models.GroupMember
+		if err := rows.Scan(&m.UserID, &m.Username, &m.Avatar); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (r *grouprepo) CountFriendsAmong(ctx context.Context, ownerID int, userIDs []int) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM UNNEST($2::int[]) AS x(uid)
+		WHERE EXISTS (
+			SELECT 1 FROM friendships f
+			WHERE f.user_id = LEAST($1::int, x.uid) AND f.friend_id = GREATEST($1::int, x.uid)
+		)
+	`, ownerID, pq.Array(userIDs)).Scan(&n)
+	return n, err
+}
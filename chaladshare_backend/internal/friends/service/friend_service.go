@@ -7,6 +7,8 @@ import (
 
 	"chaladshare_backend/internal/friends/models"
 	"chaladshare_backend/internal/friends/repository"
+	notimodels "chaladshare_backend/internal/notifications/models"
+	notiservice "chaladshare_backend/internal/notifications/service"
 )
 
 var (
@@ -46,10 +48,17 @@ type FriendService interface {
 
 type friendsService struct {
 	friendsrepo repository.FriendRepository
+	notifier    notiservice.Notifier
 }
 
-func NewFriendService(friendsrepo repository.FriendRepository) FriendService {
-	return &friendsService{friendsrepo: friendsrepo}
+func NewFriendService(friendsrepo repository.FriendRepository, notifier notiservice.Notifier) FriendService {
+	return &friendsService{friendsrepo: friendsrepo, notifier: notifier}
+}
+
+func (s *friendsService) notify(ev notimodels.Event) {
+	if s.notifier != nil {
+		s.notifier.Notify(ev)
+	}
 }
 
 func normalizeSearch(s string) string {
@@ -77,14 +86,25 @@ func (s *friendsService) FollowUser(ctx context.Context, actorID, targetID int)
 	if actorID == targetID {
 		return models.ErrInvalidSelfAction
 	}
-	return s.friendsrepo.InsertFollow(ctx, actorID, targetID)
+	if err := s.friendsrepo.InsertFollow(ctx, actorID, targetID); err != nil {
+		return err
+	}
+	s.notify(notimodels.Event{Type: notimodels.TypeFollowed, RecipientID: targetID, ActorID: actorID})
+	return nil
 }
 
 func (s *friendsService) UnfollowUser(ctx context.Context, actorID, targetID int) error {
 	if actorID == 0 || targetID == 0 {
 		return ErrBadRequest
 	}
-	return s.friendsrepo.DeleteFollow(ctx, actorID, targetID)
+	if err := s.friendsrepo.DeleteFollow(ctx, actorID, targetID); err != nil {
+		return err
+	}
+	// เลิกติดตามก่อนอีกฝ่ายเปิดอ่าน → ถอนออกจากการแจ้งเตือน
+	if s.notifier != nil {
+		s.notifier.Retract(notimodels.Event{Type: notimodels.TypeFollowed, RecipientID: targetID, ActorID: actorID})
+	}
+	return nil
 }
 
 func (s *friendsService) IsFollowing(ctx context.Context, actorID, targetID int) (bool, error) {
@@ -200,7 +220,12 @@ func (s *friendsService) SendFriendRequest(ctx context.Context, actorID, toUserI
 		return 0, ErrBadRequest
 	}
 
-	return s.friendsrepo.CreateFriendRequest(ctx, actorID, toUserID)
+	requestID, err := s.friendsrepo.CreateFriendRequest(ctx, actorID, toUserID)
+	if err != nil {
+		return 0, err
+	}
+	s.notify(notimodels.Event{Type: notimodels.TypeFriendRequest, RecipientID: toUserID, ActorID: actorID, RequestID: &requestID})
+	return requestID, nil
 }
 
 func (s *friendsService) ListIncomingRequests(ctx context.Context, actorID int, page, size int) ([]models.IncomingReqItem, int, error) {
@@ -246,7 +271,11 @@ func (s *friendsService) AcceptFriendRequest(ctx context.Context, actorID, reque
 	if fr.AddresseeUserID != actorID || fr.RequestStatus != models.FRPending {
 		return ErrForbidden
 	}
-	return s.friendsrepo.AcceptFriendRequest(ctx, requestID, actorID)
+	if err := s.friendsrepo.AcceptFriendRequest(ctx, requestID, actorID); err != nil {
+		return err
+	}
+	s.notify(notimodels.Event{Type: notimodels.TypeFriendAccepted, RecipientID: fr.RequesterUserID, ActorID: actorID, RequestID: &requestID})
+	return nil
 }
 
 func (s *friendsService) DeclineFriendRequest(ctx context.Context, actorID, requestID int) error {
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"chaladshare_backend/internal/friends/models"
+	"chaladshare_backend/internal/friends/repository"
+)
+
+type GroupService interface {
+	CreateGroup(ctx context.Context, ownerID int, req models.CreateGroupRequest) (*models.FriendGroupDetail, error)
+	UpdateGroup(ctx context.Context, ownerID, groupID int, req models.UpdateGroupRequest) (*models.FriendGroupDetail, error)
+	DeleteGroup(ctx context.Context, ownerID, groupID int) error
+
+	ListGroups(ctx context.Context, ownerID int) ([]models.FriendGroup, error)
+	GetGroup(ctx context.Context, ownerID, groupID int) (*models.FriendGroupDetail, error)
+}
+
+type groupService struct {
+	grouprepo repository.GroupRepository
+}
+
+func NewGroupService(grouprepo repository.GroupRepository) GroupService {
+	return &groupService{grouprepo: grouprepo}
+}
+
+func normalizeGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > models.MaxGroupNameLength {
+		return "", models.ErrGroupInvalidName
+	}
+	return name, nil
+}
+
+// normalizeMembers ตัดตัวซ้ำ/ตัวเจ้าของออก และทุกคนต้องเป็นเพื่อนของเจ้าของกลุ่ม
+func (s *groupService) normalizeMembers(ctx context.Context, ownerID int, ids []int) ([]int, error) {
+	seen := make(map[int]struct{}, len(ids))
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			return nil, ErrBadRequest
+		}
+		if id == ownerID {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	if len(out) > models.MaxGroupMembers {
+		return nil, models.ErrGroupTooLarge
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	n, err := s.grouprepo.CountFriendsAmong(ctx, ownerID, out)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(out) {
+		return nil, models.ErrGroupMemberFriend
+	}
+	return out, nil
+}
+
+func (s *groupService) CreateGroup(ctx context.Context, ownerID int, req models.CreateGroupRequest) (*models.FriendGroupDetail, error) {
+	if ownerID == 0 {
+		return nil, ErrBadRequest
+	}
+	name, err := normalizeGroupName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.normalizeMembers(ctx, ownerID, req.MemberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	groupID, err := s.grouprepo.CreateGroup(ctx, ownerID, name, members)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGroup(ctx, ownerID, groupID)
+}
+
+func (s *groupService) UpdateGroup(ctx context.Context, ownerID, groupID int, req models.UpdateGroupRequest) (*models.FriendGroupDetail, error) {
+	if ownerID == 0 || groupID == 0 {
+		return nil, ErrBadRequest
+	}
+
+	var name *string
+	if req.Name != nil {
+		n, err := normalizeGroupName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		name = &n
+	}
+
+	var members []int
+	if req.MemberIDs != nil {
+		m, err := s.normalizeMembers(ctx, ownerID, *req.MemberIDs)
+		if err != nil {
+			return nil, err
+		}
+		members = m
+	}
+
+	if err := s.grouprepo.UpdateGroup(ctx, ownerID, groupID, name, members, req.MemberIDs != nil); err != nil {
+		return nil, err
+	}
+	return s.GetGroup(ctx, ownerID, groupID)
+}
+
+func (s *groupService) DeleteGroup(ctx context.Context, ownerID, groupID int) error {
+	if ownerID == 0 || groupID == 0 {
+		return ErrBadRequest
+	}
+	return s.grouprepo.DeleteGroup(ctx, ownerID, groupID)
+}
+
+func (s *groupService) ListGroups(ctx context.Context, ownerID int) ([]models.FriendGroup, error) {
+	if ownerID == 0 {
+		return nil, ErrBadRequest
+	}
+	groups, err := s.grouprepo.ListGroups(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if groups == nil {
+		groups = []models.FriendGroup{}
+	}
+	return groups, nil
+}
+
+// GetGroup เห็นได้เฉพาะเจ้าของกลุ่ม (กลุ่มของคนอื่นตอบเป็นไม่พบ)
+func (s *groupService) GetGroup(ctx context.Context, ownerID, groupID int) (*models.FriendGroupDetail, error) {
+	if ownerID == 0 || groupID == 0 {
+		return nil, ErrBadRequest
+	}
+	g, err := s.grouprepo.GetGroup(ctx, ownerID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.grouprepo.ListGroupMembers(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if members == nil {
+		members = []models.GroupMember{}
+	}
+	return &models.FriendGroupDetail{FriendGroup: *g, Members: members}, nil
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
@@ -9,9 +10,17 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-const CtxUserID = "user_id"
+const (
+	CtxUserID    = "user_id"
+	CtxSessionID = "session_id"
+)
+
+// AccessChecker ตรวจว่า session ของ token ยังไม่ถูก revoke และผู้ใช้ยังใช้งานได้
+type AccessChecker interface {
+	CheckAccess(userID, sessionID, tokenVersion int) (bool, error)
+}
 
-func JWT(secret []byte, accesscookieName string) gin.HandlerFunc {
+func JWT(secret []byte, accesscookieName, issuer, audience string, checker AccessChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var tokenStr string
 
@@ -30,7 +39,12 @@ func JWT(secret []byte, accesscookieName string) gin.HandlerFunc {
 				return nil, fmt.Errorf("bad alg")
 			}
 			return secret, nil
-		})
+		},
+			jwt.WithIssuer(issuer),
+			jwt.WithAudience(audience),
+			jwt.WithIssuedAt(),
+			jwt.WithExpirationRequired(),
+		)
 
 		if err != nil || !tok.Valid {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
@@ -48,7 +62,31 @@ func JWT(secret []byte, accesscookieName string) gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "bad claims"})
 			return
 		}
+		// token รุ่นเก่าที่ไม่มี sid/ver ต้อง refresh ใหม่
+		sid, ok := claims["sid"].(float64)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "bad claims"})
+			return
+		}
+		ver, ok := claims["ver"].(float64)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "bad claims"})
+			return
+		}
+
+		active, err := checker.CheckAccess(int(f), int(sid), int(ver))
+		if err != nil {
+			log.Printf("[JWT] check access user=%d session=%d: %v", int(f), int(sid), err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "cannot verify session"})
+			return
+		}
+		if !active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+			return
+		}
+
 		c.Set(CtxUserID, int(f))
+		c.Set(CtxSessionID, int(sid))
 		c.Next()
 	}
 }
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"chaladshare_backend/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newLimitedRouter POST /login ตอบ status ตามที่ handler กำหนด
+func newLimitedRouter(store ratelimit.Store, handler gin.HandlerFunc, rules ...RateLimitRule) *gin.Engine {
+	r := gin.New()
+	r.POST("/login", RateLimit(store, rules...), handler)
+	return r
+}
+
+func doLogin(r http.Handler, email, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"email":"`+email+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":12345"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func statusHandler(status *atomic.Int32) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Status(int(status.Load()))
+	}
+}
+
+func TestRateLimitCountsEveryRequest(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	r := newLimitedRouter(ratelimit.NewMemoryStore(), statusHandler(&status),
+		RateLimitRule{Name: "ip", Limit: 2, Window: time.Minute, Key: ClientIPKey},
+	)
+
+	for i := 0; i < 2; i++ {
+		if w := doLogin(r, "a@x.com", "192.0.2.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i+1, w.Code)
+		}
+	}
+	w := doLogin(r, "a@x.com", "192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("over limit: status %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("missing Retry-After")
+	}
+	if w := doLogin(r, "a@x.com", "192.0.2.2"); w.Code != http.StatusOK {
+		t.Fatalf("other ip: status %d", w.Code)
+	}
+}
+
+func TestRateLimitFailuresOnly(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusUnauthorized)
+	r := newLimitedRouter(ratelimit.NewMemoryStore(), statusHandler(&status),
+		RateLimitRule{Name: "login", Limit: 3, Window: time.Minute, Key: EmailIPKey, FailuresOnly: true},
+	)
+
+	// ผิด 2 ครั้งแล้วถูก ตัวนับต้องถูกล้าง
+	for i := 0; i < 2; i++ {
+		doLogin(r, "a@x.com", "192.0.2.1")
+	}
+	status.Store(http.StatusOK)
+	if w := doLogin(r, "a@x.com", "192.0.2.1"); w.Code != http.StatusOK {
+		t.Fatalf("success: status %d", w.Code)
+	}
+
+	status.Store(http.StatusUnauthorized)
+	for i := 0; i < 3; i++ {
+		if w := doLogin(r, "a@x.com", "192.0.2.1"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d after reset: status %d", i+1, w.Code)
+		}
+	}
+
+	// ครบแล้ว แม้รหัสถูกก็ยังโดนล็อก
+	status.Store(http.StatusOK)
+	if w := doLogin(r, "a@x.com", "192.0.2.1"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked: status %d, want 429", w.Code)
+	}
+	// อีเมลเดียวกันจาก IP อื่นไม่โดนล็อกตาม (กันคนอื่นล็อกบัญชีเรา)
+	if w := doLogin(r, "a@x.com", "192.0.2.9"); w.Code != http.StatusOK {
+		t.Fatalf("same email other ip: status %d", w.Code)
+	}
+	// body ต้องยังอ่านได้ใน handler
+	if w := doLogin(r, "b@x.com", "192.0.2.1"); w.Code != http.StatusOK {
+		t.Fatalf("other email: status %d", w.Code)
+	}
+}
+
+func TestRateLimitFailuresOnlyConcurrent(t *testing.T) {
+	const limit = 3
+	var handled atomic.Int32
+	release := make(chan struct{})
+	r := newLimitedRouter(ratelimit.NewMemoryStore(), func(c *gin.Context) {
+		handled.Add(1)
+		<-release
+		c.Status(http.StatusUnauthorized)
+	}, RateLimitRule{Name: "login", Limit: limit, Window: time.Minute, Key: EmailIPKey, FailuresOnly: true})
+
+	var wg sync.WaitGroup
+	var limited atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w := doLogin(r, "a@x.com", "192.0.2.1"); w.Code == http.StatusTooManyRequests {
+				limited.Add(1)
+			}
+		}()
+	}
+
+	// รอให้คำขอที่เกินถูกปฏิเสธก่อนปล่อย handler (ถ้านับหลัง handler ทุกคำขอจะค้างอยู่ตรงนี้)
+	deadline := time.Now().Add(2 * time.Second)
+	for limited.Load() < 10-limit && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := handled.Load(); got != limit {
+		t.Fatalf("handler ran %d times, want %d", got, limit)
+	}
+}
+
+func TestEmailIPKey(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"email":" A@X.com "}`))
+	c.Request.RemoteAddr = "192.0.2.1:1"
+	if got, want := EmailIPKey(c), "email:a@x.com:ip:192.0.2.1"; got != want {
+		t.Fatalf("EmailIPKey = %q, want %q", got, want)
+	}
+
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+	if got := EmailIPKey(c); got != "" {
+		t.Fatalf("EmailIPKey without email = %q, want empty", got)
+	}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"chaladshare_backend/internal/notifications/service"
+)
+
+type NotificationHandler struct {
+	notificationService service.NotificationService
+}
+
+func NewNotificationHandler(notificationService service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+func writeNotificationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotificationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GET /notifications?page=&size=
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+
+	items, total, err := h.notificationService.List(c.Request.Context(), uid, page, size)
+	if err != nil {
+		writeNotificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items, "total": total, "page": page, "size": size,
+	})
+}
+
+// GET /notifications/unread-count
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	n, err := h.notificationService.UnreadCount(c.Request.Context(), uid)
+	if err != nil {
+		writeNotificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread_count": n})
+}
+
+// POST /notifications/:id/read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.notificationService.MarkRead(c.Request.Context(), uid, id); err != nil {
+		writeNotificationError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// POST /notifications/read-all
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	n, err := h.notificationService.MarkAllRead(c.Request.Context(), uid)
+	if err != nil {
+		writeNotificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": n})
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type NotificationType string
+
+const (
+	TypePostLiked      NotificationType = "post_liked"
+	TypePostCommented  NotificationType = "post_commented"
+	TypeCommentReplied NotificationType = "comment_replied"
+	TypeFollowed       NotificationType = "followed"
+	TypeFriendRequest  NotificationType = "friend_request"
+	TypeFriendAccepted NotificationType = "friend_accepted"
+	TypeNewPost        NotificationType = "new_post" // คนที่ติดตามเผยแพร่โพสต์ใหม่
+)
+
+// Event คือเหตุการณ์ที่โมดูลอื่นส่งเข้ามา (ActorID ทำอะไรบางอย่างกับ RecipientID)
+type Event struct {
+	Type        NotificationType
+	RecipientID int
+	ActorID     int
+	PostID      *int
+	CommentID   *int
+	RequestID   *int
+}
+
+// GroupKey เหตุการณ์ที่ key ตรงกันและยังไม่อ่านจะรวมเป็นแถวเดียว
+func (e Event) GroupKey() string {
+	switch e.Type {
+	case TypePostLiked, TypePostCommented, TypeNewPost:
+		if e.PostID != nil {
+			return fmt.Sprintf("%s:post:%d", e.Type, *e.PostID)
+		}
+	case TypeCommentReplied:
+		if e.CommentID != nil {
+			return fmt.Sprintf("%s:comment:%d", e.Type, *e.CommentID)
+		}
+	case TypeFollowed:
+		return string(e.Type)
+	case TypeFriendRequest, TypeFriendAccepted:
+		if e.RequestID != nil {
+			return fmt.Sprintf("%s:request:%d", e.Type, *e.RequestID)
+		}
+	}
+	return ""
+}
+
+type Actor struct {
+	UserID    int     `json:"user_id"`
+	Username  string  `json:"username"`
+	AvatarURL *string `json:"avatar_url"`
+}
+
+type Notification struct {
+	NotificationID int              `json:"notification_id"`
+	Type           NotificationType `json:"type"`
+	PostID         *int             `json:"post_id"`
+	CommentID      *int             `json:"comment_id"`
+	RequestID      *int             `json:"request_id"`
+	ActorCount     int              `json:"actor_count"`
+	Actors         []Actor          `json:"actors"` // ล่าสุดไม่เกิน MaxActorsShown คน
+	Message        string           `json:"message"`
+	IsRead         bool             `json:"is_read"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+const MaxActorsShown = 3
@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"chaladshare_backend/internal/notifications/models"
+)
+
+type NotificationRepository interface {
+	AddActor(ctx context.Context, ev models.Event, groupKey string) (notificationID int, err error)
+	RemoveActor(ctx context.Context, ev models.Event, groupKey string) error
+
+	Get(ctx context.Context, userID, notificationID int) (*models.Notification, error)
+	List(ctx context.Context, userID, limit, offset int) ([]models.Notification, error)
+	Count(ctx context.Context, userID int) (int, error)
+	CountUnread(ctx context.Context, userID int) (int, error)
+	MarkRead(ctx context.Context, userID, notificationID int) error
+	MarkAllRead(ctx context.Context, userID int) (int, error)
+}
+
+type notificationRepo struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) NotificationRepository {
+	return &notificationRepo{db: db}
+}
+
+// AddActor รวมเข้าแถวที่ยังไม่อ่านของกลุ่มเดียวกัน (ถ้าไม่มีสร้างใหม่) แล้วนับคนใหม่
+func (r *notificationRepo) AddActor(ctx context.Context, ev models.Event, groupKey string) (id int, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = tx.QueryRowContext(ctx, `
+		INSERT INTO notifications (
+			recipient_user_id, notif_type, notif_group_key,
+			notif_post_id, notif_comment_id, notif_request_id, last_actor_user_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (recipient_user_id, notif_group_key) WHERE read_at IS NULL
+		DO UPDATE SET last_actor_user_id = EXCLUDED.last_actor_user_id,
+		              notif_updated_at   = now()
+		RETURNING notification_id
+	`, ev.RecipientID, string(ev.Type), groupKey, ev.PostID, ev.CommentID, ev.RequestID, ev.ActorID).Scan(&id); err != nil {
+		return 0, fmt.Errorf("upsert notification: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		INSERT INTO notification_actors (notification_id, actor_user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (notification_id, actor_user_id) DO UPDATE SET acted_at = now()
+	`, id, ev.ActorID); err != nil {
+		return 0, fmt.Errorf("insert notification actor: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE notifications
+		SET actor_count = (SELECT COUNT(*) FROM notification_actors WHERE notification_id = $1)
+		WHERE notification_id = $1
+	`, id); err != nil {
+		return 0, fmt.Errorf("count notification actors: %w", err)
+	}
+
+	return id, tx.Commit()
+}
+
+// RemoveActor ถอนคนออกจากแถวที่ยังไม่อ่าน (เช่น unlike) ถ้าไม่เหลือใครก็ลบแถวทิ้ง
+func (r *notificationRepo) RemoveActor(ctx context.Context, ev models.Event, groupKey string) (err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		SELECT notification_id FROM notifications
+		WHERE recipient_user_id = $1 AND notif_group_key = $2 AND read_at IS NULL
+		FOR UPDATE
+	`, ev.RecipientID, groupKey).Scan(&id)
+	if err == sql.ErrNoRows {
+		_ = tx.Rollback()
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("find notification: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		DELETE FROM notification_actors WHERE notification_id = $1 AND actor_user_id = $2
+	`, id, ev.ActorID); err != nil {
+		return fmt.Errorf("delete notification actor: %w", err)
+	}
+
+	var remaining int
+	if err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notification_actors WHERE notification_id = $1
+	`, id).Scan(&remaining); err != nil {
+		return fmt.Errorf("count notification actors: %w", err)
+	}
+
+	if remaining == 0 {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM notifications WHERE notification_id = $1`, id); err != nil {
+			return fmt.Errorf("delete notification: %w", err)
+		}
+		return tx.Commit()
+	}
+
+	if _, err = tx.ExecContext(ctx, `
+		UPDATE notifications
+		SET actor_count = $2,
+		    last_actor_user_id = (
+		        SELECT actor_user_id FROM notification_actors
+		        WHERE notification_id = $1
+		        ORDER BY acted_at DESC LIMIT 1
+		    )
+		WHERE notification_id = $1
+	`, id, remaining); err != nil {
+		return fmt.Errorf("update notification: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+const notificationSelect = `
+	SELECT notification_id, notif_type, notif_post_id, notif_comment_id, notif_request_id,
+	       actor_count, read_at IS NOT NULL, notif_created_at, notif_updated_at
+	FROM notifications
+`
+
+func (r *notificationRepo) Get(ctx context.Context, userID, notificationID int) (*models.Notification, error) {
+	items, err := r.query(ctx, notificationSelect+`
+		WHERE notification_id = $1 AND recipient_user_id = $2
+	`, notificationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &items[0], nil
+}
+
+// List เรียงตามเหตุการณ์ล่าสุดของแต่ละกลุ่ม
+func (r *notificationRepo) List(ctx context.Context, userID, limit, offset int) ([]models.Notification, error) {
+	return r.query(ctx, notificationSelect+`
+		WHERE recipient_user_id = $1
+		ORDER BY notif_updated_at DESC, notification_id DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+}
+
+// query อ่านแถวแจ้งเตือน พร้อมรายชื่อคนล่าสุดไม่เกิน MaxActorsShown คน
+func (r *notificationRepo) query(ctx context.Context, query string, args ...any) ([]models.Notification, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.Notification
+	index := map[int]int{}
+	ids := []int64{}
+	for rows.Next() {
+		var (
+			n                        models.Notification
+			postID, commentID, reqID sql.NullInt64
+		)
+		if err := rows.Scan(&n.NotificationID, &n.Type, &postID, &commentID, &reqID,
+			&n.ActorCount, &n.IsRead, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			return nil, err
+		}
+		n.PostID = nullIntPtr(postID)
+		n.CommentID = nullIntPtr(commentID)
+		n.RequestID = nullIntPtr(reqID)
+		n.Actors = []models.Actor{}
+
+		index[n.NotificationID] = len(out)
+		ids = append(ids, int64(n.NotificationID))
+		out = append(out, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	actorRows, err := r.db.QueryContext(ctx, `
+		SELECT notification_id, user_id, username, avatar_url
+		FROM (
+			SELECT na.notification_id, u.user_id, u.username,
+			       COALESCE(up.avatar_thumb_url, up.avatar_url) AS avatar_url,
+			       ROW_NUMBER() OVER (PARTITION BY na.notification_id ORDER BY na.acted_at DESC) AS rn
+			FROM notification_actors na
+			JOIN users u ON u.user_id = na.actor_user_id
+			LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
+			WHERE na.notification_id = ANY($1)
+		) a
+		WHERE rn <= $2
+		ORDER BY notification_id, rn
+	`, pq.Array(ids), models.MaxActorsShown)
+	if err != nil {
+		return nil, fmt.Errorf("list notification actors: %w", err)
+	}
+	defer actorRows.Close()
+
+	for actorRows.Next() {
+		var (
+			notifID int
+			a       models.Actor
+			avatar  sql.NullString
+		)
+		if err := actorRows.Scan(&notifID, &a.UserID, &a.Username, &avatar); err != nil {
+			return nil, err
+		}
+		if avatar.Valid {
+			a.AvatarURL = &avatar.String
+		}
+		i := index[notifID]
+		out[i].Actors = append(out[i].Actors, a)
+	}
+	return out, actorRows.Err()
+}
+
+func (r *notificationRepo) Count(ctx context.Context, userID int) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notifications WHERE recipient_user_id = $1
+	`, userID).Scan(&n)
+	return n, err
+}
+
+func (r *notificationRepo) CountUnread(ctx context.Context, userID int) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM notifications WHERE recipient_user_id = $1 AND read_at IS NULL
+	`, userID).Scan(&n)
+	return n, err
+}
+
+// MarkRead คืน sql.ErrNoRows ถ้าไม่ใช่การแจ้งเตือนของ userID (อ่านไปแล้วถือว่าสำเร็จ)
+func (r *notificationRepo) MarkRead(ctx context.Context, userID, notificationID int) error {
+	var id int
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, now())
+		WHERE notification_id = $1 AND recipient_user_id = $2
+		RETURNING notification_id
+	`, notificationID, userID).Scan(&id)
+	return err
+}
+
+func (r *notificationRepo) MarkAllRead(ctx context.Context, userID int) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = now()
+		WHERE recipient_user_id = $1 AND read_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("mark all read: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"chaladshare_backend/internal/notifications/models"
+	"chaladshare_backend/internal/notifications/repository"
+	rtmodels "chaladshare_backend/internal/realtime/models"
+	rtservice "chaladshare_backend/internal/realtime/service"
+)
+
+var (
+	ErrBadRequest           = errors.New("bad request")
+	ErrNotificationNotFound = errors.New("notification not found")
+)
+
+// เวลาสูงสุดที่ยอมให้การเขียนแจ้งเตือนใช้ (ไม่ผูกกับ request ของผู้ทำ)
+const notifyTimeout = 5 * time.Second
+
+// Notifier ให้โมดูลอื่นส่งเหตุการณ์เข้ามา ความผิดพลาดจะถูก log ไว้ ไม่ทำให้ action หลักล้ม
+type Notifier interface {
+	Notify(ev models.Event)
+	Retract(ev models.Event)
+}
+
+type NotificationService interface {
+	Notifier
+
+	List(ctx context.Context, userID, page, size int) ([]models.Notification, int, error)
+	UnreadCount(ctx context.Context, userID int) (int, error)
+	MarkRead(ctx context.Context, userID, notificationID int) error
+	MarkAllRead(ctx context.Context, userID int) (int, error)
+}
+
+type notificationService struct {
+	repo      repository.NotificationRepository
+	publisher rtservice.Publisher
+}
+
+// publisher เป็น nil ได้ (ไม่มี push แบบ realtime)
+func NewNotificationService(repo repository.NotificationRepository, publisher rtservice.Publisher) NotificationService {
+	return &notificationService{repo: repo, publisher: publisher}
+}
+
+func validEvent(ev models.Event) (string, bool) {
+	// ทำกับตัวเองไม่ต้องแจ้ง
+	if ev.RecipientID <= 0 || ev.ActorID <= 0 || ev.RecipientID == ev.ActorID {
+		return "", false
+	}
+	key := ev.GroupKey()
+	return key, key != ""
+}
+
+func (s *notificationService) Notify(ev models.Event) {
+	key, ok := validEvent(ev)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	id, err := s.repo.AddActor(ctx, ev, key)
+	if err != nil {
+		log.Printf("[NOTIFY] add %s for user %d: %v", key, ev.RecipientID, err)
+		return
+	}
+	if s.publisher == nil {
+		return
+	}
+
+	n, err := s.repo.Get(ctx, ev.RecipientID, id)
+	if err != nil {
+		log.Printf("[NOTIFY] load notification %d: %v", id, err)
+		return
+	}
+	n.Message = message(*n)
+	s.publisher.PublishToUser(ev.RecipientID, rtmodels.Event{Type: rtmodels.EventNotification, Data: n})
+	if ev.Type == models.TypeFriendRequest && ev.RequestID != nil {
+		s.publisher.PublishToUser(ev.RecipientID, rtmodels.Event{
+			Type: rtmodels.EventFriendRequest,
+			Data: rtmodels.FriendRequestData{RequestID: *ev.RequestID, FromUserID: ev.ActorID},
+		})
+	}
+	s.publishUnreadCount(ctx, ev.RecipientID)
+}
+
+// publishUnreadCount ส่งจำนวนยังไม่อ่านล่าสุดให้ทุกการเชื่อมต่อของผู้ใช้
+func (s *notificationService) publishUnreadCount(ctx context.Context, userID int) {
+	if s.publisher == nil {
+		return
+	}
+	n, err := s.repo.CountUnread(ctx, userID)
+	if err != nil {
+		log.Printf("[NOTIFY] unread count for user %d: %v", userID, err)
+		return
+	}
+	s.publisher.PublishToUser(userID, rtmodels.Event{
+		Type: rtmodels.EventUnreadCount,
+		Data: rtmodels.UnreadCountData{UnreadCount: n},
+	})
+}
+
+func (s *notificationService) Retract(ev models.Event) {
+	key, ok := validEvent(ev)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := s.repo.RemoveActor(ctx, ev, key); err != nil {
+		log.Printf("[NOTIFY] retract %s for user %d: %v", key, ev.RecipientID, err)
+		return
+	}
+	s.publishUnreadCount(ctx, ev.RecipientID)
+}
+
+func (s *notificationService) List(ctx context.Context, userID, page, size int) ([]models.Notification, int, error) {
+	if userID <= 0 {
+		return nil, 0, ErrBadRequest
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 || size > 100 {
+		size = 20
+	}
+
+	items, err := s.repo.List(ctx, userID, size, (page-1)*size)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range items {
+		items[i].Message = message(items[i])
+	}
+	if items == nil {
+		items = []models.Notification{}
+	}
+
+	total, err := s.repo.Count(ctx, userID)
+	return items, total, err
+}
+
+func (s *notificationService) UnreadCount(ctx context.Context, userID int) (int, error) {
+	if userID <= 0 {
+		return 0, ErrBadRequest
+	}
+	return s.repo.CountUnread(ctx, userID)
+}
+
+func (s *notificationService) MarkRead(ctx context.Context, userID, notificationID int) error {
+	if userID <= 0 || notificationID <= 0 {
+		return ErrBadRequest
+	}
+	err := s.repo.MarkRead(ctx, userID, notificationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotificationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("mark read: %w", err)
+	}
+	s.publishUnreadCount(ctx, userID)
+	return nil
+}
+
+func (s *notificationService) MarkAllRead(ctx context.Context, userID int) (int, error) {
+	if userID <= 0 {
+		return 0, ErrBadRequest
+	}
+	n, err := s.repo.MarkAllRead(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		s.publishUnreadCount(ctx, userID)
+	}
+	return n, nil
+}
+
+// message ประกอบข้อความจากชื่อคนล่าสุด เช่น "alice and 4 others liked your post"
+func message(n models.Notification) string {
+	who := "Someone"
+	if len(n.Actors) > 0 {
+		who = n.Actors[0].Username
+	}
+	switch others := n.ActorCount - 1; {
+	case others == 1:
+		who += " and 1 other"
+	case others > 1:
+		who += fmt.Sprintf(" and %d others", others)
+	}
+
+	switch n.Type {
+	case models.TypePostLiked:
+		return who + " liked your post"
+	case models.TypePostCommented:
+		return who + " commented on your post"
+	case models.TypeCommentReplied:
+		return who + " replied to your comment"
+	case models.TypeFollowed:
+		return who + " started following you"
+	case models.TypeFriendRequest:
+		return who + " sent you a friend request"
+	case models.TypeFriendAccepted:
+		return who + " accepted your friend request"
+	case models.TypeNewPost:
+		return who + " published a new post"
+	}
+	return who
+}
@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"chaladshare_backend/internal/posts/models"
+	"chaladshare_backend/internal/posts/service"
+	rtmodels "chaladshare_backend/internal/realtime/models"
+	rtservice "chaladshare_backend/internal/realtime/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CollectionHandler struct {
+	collectionService service.CollectionService
+	publisher         rtservice.Publisher
+}
+
+func NewCollectionHandler(collectionService service.CollectionService, publisher rtservice.Publisher) *CollectionHandler {
+	return &CollectionHandler{collectionService: collectionService, publisher: publisher}
+}
+
+func writeCollectionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidCollection):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+	case errors.Is(err, models.ErrCollectionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "collection not found"})
+	case errors.Is(err, service.ErrPostNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+	case errors.Is(err, service.ErrPostNotVisible):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	case errors.Is(err, models.ErrCollectionNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func paramID(c *gin.Context, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(name))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+// GET /collections คอลเลกชันของตัวเอง
+func (h *CollectionHandler) ListOwnCollections(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	items, err := h.collectionService.ListCollections(uid, uid)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+// GET /collections/user/:id คอลเลกชันของคนอื่นที่แชร์ให้เห็น
+func (h *CollectionHandler) ListUserCollections(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	ownerID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	items, err := h.collectionService.ListCollections(uid, ownerID)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+// POST /collections {name, description?, visibility?}
+func (h *CollectionHandler) CreateCollection(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req models.CreateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	col, err := h.collectionService.CreateCollection(uid, req)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": col})
+}
+
+// GET /collections/:id
+func (h *CollectionHandler) GetCollection(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	col, err := h.collectionService.GetCollection(uid, id)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": col})
+}
+
+// PUT /collections/:id {name?, description?, visibility?}
+func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req models.UpdateCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	col, err := h.collectionService.UpdateCollection(uid, id, req)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": col})
+}
+
+// DELETE /collections/:id (โพสต์ข้างในยังถูกบันทึกอยู่)
+func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.collectionService.DeleteCollection(uid, id); err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PUT /collections/order {collection_ids}
+func (h *CollectionHandler) ReorderCollections(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req models.CollectionOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	items, err := h.collectionService.ReorderCollections(uid, req.CollectionIDs)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": items})
+}
+
+// GET /collections/:id/posts?cursor=&size=
+func (h *CollectionHandler) GetCollectionPosts(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	size, _ := strconv.Atoi(c.Query("size"))
+	page, err := h.collectionService.GetCollectionPosts(uid, id, c.Query("cursor"), size)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// POST /collections/:id/posts {post_id} บันทึกโพสต์ให้ด้วยถ้ายังไม่ได้บันทึก
+func (h *CollectionHandler) AddPost(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req models.AddCollectionPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	saveCount, err := h.collectionService.AddPost(uid, id, req.PostID)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	publishPostStats(h.publisher, rtmodels.PostStatsData{PostID: req.PostID, SaveCount: &saveCount})
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"collection_id": id,
+			"post_id":       req.PostID,
+			"is_saved":      true,
+			"save_count":    saveCount,
+		},
+	})
+}
+
+// DELETE /collections/:id/posts/:post_id
+func (h *CollectionHandler) RemovePost(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	postID, ok := paramID(c, "post_id")
+	if !ok {
+		return
+	}
+
+	if err := h.collectionService.RemovePost(uid, id, postID); err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PUT /collections/:id/posts/order {post_ids}
+func (h *CollectionHandler) ReorderPosts(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req models.CollectionPostsOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := h.collectionService.ReorderPosts(uid, id, req.PostIDs); err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GET /posts/:id/collections คอลเลกชันของตัวเองที่มีโพสต์นี้
+func (h *CollectionHandler) ListPostCollections(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	postID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	ids, err := h.collectionService.ListPostCollectionIDs(uid, postID)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"post_id": postID, "collection_ids": ids}})
+}
+
+// PUT /posts/:id/collections {collection_ids} ตั้งคอลเลกชันของโพสต์นี้ทั้งชุด
+func (h *CollectionHandler) SetPostCollections(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	postID, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req models.PostCollectionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	ids, saveCount, err := h.collectionService.SetPostCollections(uid, postID, req.CollectionIDs)
+	if err != nil {
+		writeCollectionError(c, err)
+		return
+	}
+	publishPostStats(h.publisher, rtmodels.PostStatsData{PostID: postID, SaveCount: &saveCount})
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"post_id":        postID,
+			"collection_ids": ids,
+			"save_count":     saveCount,
+		},
+	})
+}
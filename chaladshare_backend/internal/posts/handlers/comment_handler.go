@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"chaladshare_backend/internal/posts/models"
+	"chaladshare_backend/internal/posts/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommentHandler struct {
+	commentService service.CommentService
+}
+
+func NewCommentHandler(commentService service.CommentService) *CommentHandler {
+	return &CommentHandler{commentService: commentService}
+}
+
+// map error ของ comment service เป็น status code
+func writeCommentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidComment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+	case errors.Is(err, service.ErrPostNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+	case errors.Is(err, service.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "comment not found"})
+	case errors.Is(err, service.ErrPostNotVisible), errors.Is(err, service.ErrCommentForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GET /posts/:id/comments?cursor=&size=
+func (h *CommentHandler) ListComments(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || postID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	size, _ := strconv.Atoi(c.Query("size"))
+	page, err := h.commentService.ListComments(uid, postID, c.Query("cursor"), size)
+	if err != nil {
+		writeCommentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// POST /posts/:id/comments {body, parent_id?}
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || postID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req models.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(uid, postID, req)
+	if err != nil {
+		writeCommentError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": comment})
+}
+
+// GET /comments/:comment_id/replies?cursor=&size=
+func (h *CommentHandler) ListReplies(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	commentID, err := strconv.Atoi(c.Param("comment_id"))
+	if err != nil || commentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment_id"})
+		return
+	}
+
+	size, _ := strconv.Atoi(c.Query("size"))
+	page, err := h.commentService.ListReplies(uid, commentID, c.Query("cursor"), size)
+	if err != nil {
+		writeCommentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// PUT /comments/:comment_id {body}
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	commentID, err := strconv.Atoi(c.Param("comment_id"))
+	if err != nil || commentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment_id"})
+		return
+	}
+
+	var req models.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	comment, err := h.commentService.UpdateComment(uid, commentID, req.Body)
+	if err != nil {
+		writeCommentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": comment})
+}
+
+// DELETE /comments/:comment_id
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	commentID, err := strconv.Atoi(c.Param("comment_id"))
+	if err != nil || commentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment_id"})
+		return
+	}
+
+	if err := h.commentService.DeleteComment(uid, commentID); err != nil {
+		writeCommentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "comment deleted successfully"})
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"chaladshare_backend/internal/posts/service"
+	rtmodels "chaladshare_backend/internal/realtime/models"
+	rtservice "chaladshare_backend/internal/realtime/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,15 +19,25 @@ type RecommendHook interface {
 type LikeHandler struct {
 	likeService      service.LikeService
 	recommendService RecommendHook
+	publisher        rtservice.Publisher
 }
 
-func NewLikeHandler(likeService service.LikeService, recommendService RecommendHook) *LikeHandler {
+func NewLikeHandler(likeService service.LikeService, recommendService RecommendHook, publisher rtservice.Publisher) *LikeHandler {
 	return &LikeHandler{
 		likeService:      likeService,
 		recommendService: recommendService,
+		publisher:        publisher,
 	}
 }
 
+// publishPostStats ส่งตัวเลขใหม่ให้ทุกคนที่กำลังดูโพสต์นี้อยู่
+func publishPostStats(p rtservice.Publisher, stats rtmodels.PostStatsData) {
+	if p == nil {
+		return
+	}
+	p.PublishToPost(stats.PostID, rtmodels.Event{Type: rtmodels.EventPostStats, Data: stats})
+}
+
 func (h *LikeHandler) ToggleLike(c *gin.Context) {
 	uid := c.GetInt("user_id")
 	if uid == 0 {
@@ -39,6 +52,11 @@ func (h *LikeHandler) ToggleLike(c *gin.Context) {
 	}
 
 	isLiked, likeCount, err := h.likeService.ToggleLike(uid, postID)
+	if errors.Is(err, service.ErrPostNotFound) || errors.Is(err, service.ErrPostNotVisible) {
+		// ไม่บอกว่าโพสต์มีอยู่แต่ดูไม่ได้
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -48,6 +66,7 @@ func (h *LikeHandler) ToggleLike(c *gin.Context) {
 	if h.recommendService != nil {
 		h.recommendService.OnLikeHook(uid)
 	}
+	publishPostStats(h.publisher, rtmodels.PostStatsData{PostID: postID, LikeCount: &likeCount})
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"chaladshare_backend/internal/posts/models"
 	"chaladshare_backend/internal/posts/service"
+	rtmodels "chaladshare_backend/internal/realtime/models"
+	rtservice "chaladshare_backend/internal/realtime/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,12 +18,25 @@ import (
 type PostHandler struct {
 	postService service.PostService
 	saveService service.SaveService
+	publisher   rtservice.Publisher
 }
 
-func NewPostHandler(postService service.PostService, saveService service.SaveService) *PostHandler {
+func NewPostHandler(postService service.PostService, saveService service.SaveService, publisher rtservice.Publisher) *PostHandler {
 	return &PostHandler{
 		postService: postService,
 		saveService: saveService,
+		publisher:   publisher,
+	}
+}
+
+// writePostWriteError ข้อมูลผู้ชม/การมองเห็นไม่ถูกต้องตอบ 400 ที่เหลือ 500
+func writePostWriteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidAudience), errors.Is(err, service.ErrUnsupportedVisibility),
+		errors.Is(err, service.ErrInvalidPublishState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
 }
 
@@ -32,18 +49,21 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 	}
 
 	var req struct {
-		Title       string   `json:"post_title" binding:"required"`
-		Description string   `json:"post_description"`
-		Visibility  string   `json:"post_visibility" binding:"required"` // ตอนนี้รองรับ "public" เท่านั้น
-		DocumentID  *int     `json:"document_id"`
-		CoverURL    *string  `json:"cover_url"`
-		Tags        []string `json:"tags"`
+		Title       string               `json:"post_title" binding:"required"`
+		Description string               `json:"post_description"`
+		Visibility  string               `json:"post_visibility" binding:"required"` // public | friends | private | custom
+		Audience    *models.PostAudience `json:"audience"`                           // เฉพาะ custom
+		DocumentID  *int                 `json:"document_id"`
+		CoverURL    *string              `json:"cover_url"`
+		Tags        []string             `json:"tags"`
+		Status      string               `json:"post_status"` // draft | scheduled | published (ว่าง = เดาจาก publish_at)
+		PublishAt   *time.Time           `json:"publish_at"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
-	if req.Visibility != models.VisibilityPublic && req.Visibility != models.VisibilityFriends {
+	if !models.IsValidVisibility(req.Visibility) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported visibility"})
 		return
 	}
@@ -55,18 +75,25 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		Visibility:   req.Visibility,
 		DocumentID:   req.DocumentID,
 		CoverURL:     req.CoverURL,
+		Audience:     req.Audience,
+		Status:       req.Status,
+		PublishAt:    req.PublishAt,
 	}
 
 	postID, err := h.postService.CreatePost(post, req.Tags)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writePostWriteError(c, err)
 		return
 	}
 	c.Header("Location", "/api/v1/posts/"+strconv.Itoa(postID))
-	c.JSON(http.StatusCreated, gin.H{"data": gin.H{"post_id": postID}})
+	c.JSON(http.StatusCreated, gin.H{"data": gin.H{
+		"post_id":     postID,
+		"post_status": post.Status,
+		"publish_at":  post.PublishAt,
+	}})
 }
 
-// ดึงโพสต์ทั้งหมด (ต้องล็อกอิน)
+// ฟีดหน้าแรก (ต้องล็อกอิน) ?mode=latest|personal&cursor=&size= (ไม่ระบุ mode = latest)
 func (h *PostHandler) GetAllPosts(c *gin.Context) {
 	uid := c.GetInt("user_id")
 	if uid == 0 {
@@ -74,12 +101,14 @@ func (h *PostHandler) GetAllPosts(c *gin.Context) {
 		return
 	}
 
-	posts, err := h.postService.GetFeedPosts(uid)
+	size, _ := strconv.Atoi(c.Query("size"))
+	mode := strings.ToLower(strings.TrimSpace(c.Query("mode")))
+	page, err := h.postService.GetFeedPosts(uid, mode, c.Query("cursor"), size)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writePageError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"data": posts})
+	c.JSON(http.StatusOK, page)
 }
 
 // รายละเอียดโพสต์ (ต้องล็อกอิน)
@@ -103,7 +132,8 @@ func (h *PostHandler) GetPostByID(c *gin.Context) {
 	}
 	if !ok {
 		switch reason {
-		case "not_found":
+		case "not_found", "private", "not_in_audience":
+			// ไม่บอกว่ามีโพสต์ส่วนตัว/เฉพาะกลุ่มอยู่
 			c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
 		case "friends_only", "denied":
 			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
@@ -149,10 +179,13 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 	}
 
 	var req struct {
-		Title       string   `json:"post_title"`
-		Description string   `json:"post_description"`
-		Visibility  *string  `json:"post_visibility"`
-		Tags        []string `json:"tags"`
+		Title       string               `json:"post_title"`
+		Description string               `json:"post_description"`
+		Visibility  *string              `json:"post_visibility"`
+		Audience    *models.PostAudience `json:"audience"` // ส่งมา = แทนที่ผู้ชมเดิม (เฉพาะ custom)
+		Tags        []string             `json:"tags"`
+		Status      *string              `json:"post_status"` // ไม่ส่งทั้งคู่ = คงสถานะเดิม
+		PublishAt   *time.Time           `json:"publish_at"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
@@ -161,7 +194,7 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 	vis := ""
 	if req.Visibility != nil {
 		v := strings.ToLower(strings.TrimSpace(*req.Visibility))
-		if v != models.VisibilityPublic && v != models.VisibilityFriends && v != "" {
+		if v != "" && !models.IsValidVisibility(v) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported visibility"})
 			return
 		}
@@ -173,9 +206,14 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		Title:       req.Title,
 		Description: req.Description,
 		Visibility:  vis,
+		Audience:    req.Audience,
+		PublishAt:   req.PublishAt,
+	}
+	if req.Status != nil {
+		post.Status = *req.Status
 	}
 	if err := h.postService.UpdatePost(post, req.Tags); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writePostWriteError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "post updated successfully"})
@@ -219,13 +257,94 @@ func (h *PostHandler) GetSavedPosts(c *gin.Context) {
 		return
 	}
 
-	posts, err := h.postService.GetSavedPosts(uid)
+	size, _ := strconv.Atoi(c.Query("size"))
+	page, err := h.postService.GetSavedPosts(uid, c.Query("cursor"), size)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writePageError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"data": posts})
+	c.JSON(http.StatusOK, page)
+}
+
+// ฉบับร่างและโพสต์ที่ตั้งเวลาไว้ของตัวเอง GET /posts/drafts?cursor=&size=
+func (h *PostHandler) GetDrafts(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	size, _ := strconv.Atoi(c.Query("size"))
+	page, err := h.postService.GetDrafts(uid, c.Query("cursor"), size)
+	if err != nil {
+		writePageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// เผยแพร่ฉบับร่าง/โพสต์ที่ตั้งเวลาไว้ทันที POST /posts/:id/publish
+func (h *PostHandler) PublishPost(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || postID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.postService.PublishPost(uid, postID); err != nil {
+		if errors.Is(err, service.ErrPostNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+			return
+		}
+		writePostWriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"post_id": postID, "post_status": models.StatusPublished}})
+}
+
+// โพสต์ในหน้าโปรไฟล์ GET /profile/:id/posts?cursor=&size=
+func (h *PostHandler) GetUserPosts(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	authorID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || authorID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	size, _ := strconv.Atoi(c.Query("size"))
+	page, err := h.postService.GetUserPosts(uid, authorID, c.Query("cursor"), size)
+	if err != nil {
+		writePageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func writePageError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidFeedMode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mode"})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidTrendingWindow) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid window"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }
 
 // toggle save
@@ -243,10 +362,16 @@ func (h *PostHandler) ToggleSave(c *gin.Context) {
 	}
 
 	isSaved, saveCount, err := h.saveService.ToggleSave(uid, postID)
+	if errors.Is(err, service.ErrPostNotFound) || errors.Is(err, service.ErrPostNotVisible) {
+		// ไม่บอกว่าโพสต์มีอยู่แต่ดูไม่ได้
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	publishPostStats(h.publisher, rtmodels.PostStatsData{PostID: postID, SaveCount: &saveCount})
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
@@ -257,6 +382,7 @@ func (h *PostHandler) ToggleSave(c *gin.Context) {
 	})
 }
 
+// GET /posts/popular?window=day|week|all&limit=3 (ค่าเริ่มต้น window=week)
 func (h *PostHandler) GetPopularPosts(c *gin.Context) {
 	uid := c.GetInt("user_id")
 	if uid == 0 {
@@ -271,9 +397,9 @@ func (h *PostHandler) GetPopularPosts(c *gin.Context) {
 		return
 	}
 
-	posts, err := h.postService.GetPopularPosts(uid, limit)
+	posts, err := h.postService.GetPopularPosts(uid, c.Query("window"), limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writePageError(c, err)
 		return
 	}
 
@@ -288,6 +414,7 @@ func (h *PostHandler) SearchPosts(c *gin.Context) {
 	}
 
 	search := strings.TrimSpace(c.Query("search"))
+	mode := strings.ToLower(strings.TrimSpace(c.DefaultQuery("mode", models.SearchModeKeyword)))
 
 	if search == "" {
 		c.JSON(http.StatusOK, gin.H{
@@ -314,8 +441,19 @@ func (h *PostHandler) SearchPosts(c *gin.Context) {
 		size = 20
 	}
 
-	items, total, err := h.postService.SearchPosts(uid, search, page, size)
+	switch mode {
+	case models.SearchModeKeyword, models.SearchModeSemantic, models.SearchModeHybrid:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mode"})
+		return
+	}
+
+	items, total, err := h.postService.SearchPosts(uid, search, mode, page, size)
 	if err != nil {
+		if errors.Is(err, service.ErrSemanticUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -327,6 +465,7 @@ func (h *PostHandler) SearchPosts(c *gin.Context) {
 			"page":   page,
 			"size":   size,
 			"search": search,
+			"mode":   mode,
 		},
 	})
 }
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"chaladshare_backend/internal/posts/models"
+	"chaladshare_backend/internal/posts/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TagHandler struct {
+	tagService service.TagService
+}
+
+func NewTagHandler(tagService service.TagService) *TagHandler {
+	return &TagHandler{tagService: tagService}
+}
+
+func writeTagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidTag):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag"})
+	case errors.Is(err, service.ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+	case errors.Is(err, models.ErrTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GET /tags?prefix=go&limit=10
+func (h *TagHandler) SuggestTags(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	tags, err := h.tagService.SuggestTags(uid, c.Query("prefix"), limit)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+// GET /tags/:name
+func (h *TagHandler) GetTag(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tag, err := h.tagService.GetTag(uid, c.Param("name"))
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tag})
+}
+
+// GET /tags/:name/posts?cursor=&size=
+func (h *TagHandler) GetTagPosts(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	size, _ := strconv.Atoi(c.Query("size"))
+	page, err := h.tagService.GetTagPosts(uid, c.Param("name"), c.Query("cursor"), size)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GET /tags/following
+func (h *TagHandler) ListFollowedTags(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tags, err := h.tagService.ListFollowedTags(uid)
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tags})
+}
+
+// POST /tags/:name/follow
+func (h *TagHandler) FollowTag(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	tag, err := h.tagService.FollowTag(uid, c.Param("name"))
+	if err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": tag})
+}
+
+// DELETE /tags/:name/follow
+func (h *TagHandler) UnfollowTag(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.tagService.UnfollowTag(uid, c.Param("name")); err != nil {
+		writeTagError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
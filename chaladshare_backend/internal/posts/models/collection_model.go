@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// การมองเห็นของคอลเลกชัน (ไม่เกี่ยวกับการมองเห็นของโพสต์ข้างใน ซึ่งยังกรองตามโพสต์แต่ละอัน)
+const (
+	CollectionPrivate = "private"
+	CollectionFriends = "friends"
+	CollectionPublic  = "public"
+)
+
+const (
+	MaxCollectionNameLength        = 80
+	MaxCollectionDescriptionLength = 500
+)
+
+var (
+	ErrCollectionNotFound  = errors.New("collection not found")
+	ErrCollectionNameTaken = errors.New("collection name already exists")
+	ErrInvalidCollection   = errors.New("invalid collection")
+)
+
+func IsValidCollectionVisibility(v string) bool {
+	switch v {
+	case CollectionPrivate, CollectionFriends, CollectionPublic:
+		return true
+	}
+	return false
+}
+
+type Collection struct {
+	CollectionID int       `json:"collection_id"`
+	OwnerID      int       `json:"owner_id"`
+	OwnerName    string    `json:"owner_name"`
+	Name         string    `json:"name"`
+	Description  *string   `json:"description"`
+	Visibility   string    `json:"visibility"`
+	Position     int       `json:"position"`
+	PostCount    int       `json:"post_count"` // นับเฉพาะโพสต์ที่ผู้ชมมองเห็น
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type CreateCollectionRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	Visibility  string  `json:"visibility"`
+}
+
+// ฟิลด์ที่เป็น nil = ไม่แก้; description = "" คือลบคำอธิบาย
+type UpdateCollectionRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Visibility  *string `json:"visibility"`
+}
+
+// ต้องส่งครบทุกรายการตามลำดับใหม่
+type CollectionOrderRequest struct {
+	CollectionIDs []int `json:"collection_ids"`
+}
+
+type CollectionPostsOrderRequest struct {
+	PostIDs []int `json:"post_ids"`
+}
+
+type AddCollectionPostRequest struct {
+	PostID int `json:"post_id"`
+}
+
+// PostCollectionsRequest คอลเลกชันทั้งหมดที่โพสต์นี้ควรอยู่ (ของผู้ใช้เอง)
+type PostCollectionsRequest struct {
+	CollectionIDs []int `json:"collection_ids"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+const MaxCommentLength = 2000
+
+// ความคิดเห็นใต้โพสต์ (ParentID = nil คือความคิดเห็นหลัก)
+type Comment struct {
+	CommentID int       `json:"comment_id"`
+	PostID    int       `json:"post_id"`
+	UserID    int       `json:"user_id"`
+	ParentID  *int      `json:"parent_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CommentResponse struct {
+	CommentID  int       `json:"comment_id"`
+	PostID     int       `json:"post_id"`
+	ParentID   *int      `json:"parent_id"`
+	AuthorID   int       `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	AvatarURL  *string   `json:"avatar_url"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	IsEdited   bool      `json:"is_edited"`
+	ReplyCount int       `json:"reply_count"`
+}
+
+type CommentRequest struct {
+	Body     string `json:"body" binding:"required"`
+	ParentID *int   `json:"parent_id"`
+}
+
+// CommentCursor ต่อจากความคิดเห็นสุดท้ายของหน้าก่อน (เรียงเก่าไปใหม่)
+type CommentCursor struct {
+	CreatedAt time.Time `json:"t"`
+	CommentID int       `json:"id"`
+}
+
+type CommentPage struct {
+	Items      []CommentResponse `json:"data"`
+	NextCursor *string           `json:"next_cursor"`
+}
@@ -1,12 +1,76 @@
 package models
 
 import (
+	"errors"
 	"time"
 )
 
 const (
 	VisibilityPublic  = "public"
 	VisibilityFriends = "friends"
+	VisibilityPrivate = "private" // เห็นเฉพาะเจ้าของ
+	VisibilityCustom  = "custom"  // เห็นเฉพาะผู้ใช้/กลุ่มเพื่อนใน Audience
+)
+
+// ผู้ชมของโพสต์ custom รวมกันได้ไม่เกินนี้ (นับรายคน + กลุ่ม)
+const MaxAudienceEntries = 500
+
+var ErrInvalidAudience = errors.New("invalid audience")
+
+// ErrAlreadyPublished โพสต์ถูกเผยแพร่ไปแล้ว (เช่น scheduler ชิงเผยแพร่ก่อน)
+var ErrAlreadyPublished = errors.New("post is already published")
+
+// ErrTrendingBusy instance อื่นกำลังคำนวณ trending ของช่วงเดียวกันอยู่
+var ErrTrendingBusy = errors.New("trending refresh already running")
+
+func IsValidVisibility(v string) bool {
+	switch v {
+	case VisibilityPublic, VisibilityFriends, VisibilityPrivate, VisibilityCustom:
+		return true
+	}
+	return false
+}
+
+// สถานะการเผยแพร่: draft/scheduled เห็นได้เฉพาะเจ้าของ และไม่อยู่ในฟีด/ค้นหา/ยอดนิยมใดๆ
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled" // เผยแพร่อัตโนมัติเมื่อถึง PublishAt
+	StatusPublished = "published"
+)
+
+// PostAudience ผู้ชมของโพสต์ custom: ผู้ใช้ที่ระบุ และ/หรือ กลุ่มเพื่อนของเจ้าของโพสต์
+type PostAudience struct {
+	UserIDs  []int `json:"user_ids"`
+	GroupIDs []int `json:"group_ids"`
+}
+
+// โหมดของฟีด GET /posts
+const (
+	FeedModePersonal = "personal" // จัดอันดับตามความสัมพันธ์/engagement/ความใหม่
+	FeedModeLatest   = "latest"   // ใหม่ไปเก่าทั้งหมดที่มองเห็นได้
+)
+
+// ช่วงเวลาของ trending บน GET /posts/popular
+const (
+	TrendingDay  = "day"  // like/save ใน 24 ชม.ล่าสุด
+	TrendingWeek = "week" // like/save ใน 7 วันล่าสุด
+	TrendingAll  = "all"  // ทั้งหมด (ยังลดน้ำหนักตามอายุ)
+)
+
+var TrendingWindows = []string{TrendingDay, TrendingWeek, TrendingAll}
+
+// TrendingConfig คะแนน = Σ weight * 0.5^(อายุของ like/save / HalfLifeHours)
+// like มี weight 1, save มี weight SaveWeight
+type TrendingConfig struct {
+	HalfLifeHours float64
+	SaveWeight    float64
+}
+
+// โหมดค้นหาของ /posts/search
+const (
+	SearchModeKeyword  = "keyword"
+	SearchModeSemantic = "semantic"
+	SearchModeHybrid   = "hybrid"
 )
 
 // post
@@ -20,6 +84,13 @@ type Post struct {
 	CoverURL     *string   `json:"post_cover_url"`
 	CreatedAt    time.Time `json:"post_created_at"`
 	UpdatedAt    time.Time `json:"post_updated_at"`
+
+	// ใช้เมื่อ Visibility = custom (nil ตอนแก้ไข = คงผู้ชมเดิม)
+	Audience *PostAudience `json:"audience,omitempty"`
+
+	// Status ว่าง = published (ตอนแก้ไข = คงเดิม); PublishAt ใช้กับ scheduled
+	Status    string     `json:"post_status"`
+	PublishAt *time.Time `json:"publish_at"`
 }
 
 // each tag
@@ -75,8 +146,29 @@ type PostResponse struct {
 	LikeCount int      `json:"like_count"`
 	SaveCount int      `json:"save_count"`
 
+	CommentCount int `json:"comment_count"`
+
 	IsLiked bool `json:"is_liked"`
 	IsSaved bool `json:"is_saved"`
+
+	// คะแนนความใกล้เคียง (เฉพาะผลค้นหาแบบ semantic/hybrid)
+	SearchScore *float64 `json:"search_score,omitempty"`
+
+	// คะแนนจัดอันดับ (เฉพาะฟีดโหมด personal)
+	FeedScore *float64 `json:"feed_score,omitempty"`
+
+	// คะแนน trending (เฉพาะ /posts/popular)
+	TrendingScore *float64 `json:"trending_score,omitempty"`
+
+	// ลำดับในคอลเลกชัน (เฉพาะ /collections/:id/posts)
+	CollectionPosition *int `json:"collection_position,omitempty"`
+
+	// ผู้ชมของโพสต์ custom (ส่งให้เจ้าของโพสต์เท่านั้น)
+	Audience *PostAudience `json:"audience,omitempty"`
+
+	// สถานะการเผยแพร่ (ส่งในรายละเอียดโพสต์และรายการฉบับร่าง)
+	Status    string     `json:"post_status,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
 }
 
 type UpdatePostRequest struct {
@@ -92,3 +184,34 @@ type PostQueryParam struct {
 	Sort   string   `form:"sort"`
 	Limit  int      `form:"limit"`
 }
+
+// FeedCursor ตำแหน่งต่อจากโพสต์สุดท้ายของหน้าก่อน
+// latest: keyset บน (post_created_at, post_id); personal: (score, post_id) โดยคิดคะแนน ณ เวลา AsOf เดิมทุกหน้า
+// collection: (item_position, post_id)
+type FeedCursor struct {
+	CreatedAt time.Time  `json:"t"`
+	PostID    int        `json:"id"`
+	Score     *float64   `json:"s,omitempty"`
+	Position  *int       `json:"p,omitempty"`
+	AsOf      *time.Time `json:"at,omitempty"`
+	Seen      []int      `json:"seen,omitempty"` // ฟีด personal: post_id ที่ส่งไปแล้วในหน้าก่อน ๆ
+}
+
+// FeedRanking น้ำหนักของฟีดโหมด personal
+// score = (1 + ความสัมพันธ์ + แท็กที่ติดตาม + recommendation + engagement) * 0.5^(อายุโพสต์/HalfLifeHours)
+type FeedRanking struct {
+	OwnWeight        float64
+	FriendWeight     float64
+	FollowWeight     float64
+	TagWeight        float64 // โพสต์ที่มีแท็กที่ผู้ชมติดตาม
+	RecommendWeight  float64 // คูณกับ recommendations.score
+	EngagementWeight float64 // คูณกับ ln(1 + likes + 2*saves)
+	HalfLifeHours    float64
+	WindowDays       int // โพสต์เก่ากว่านี้ไม่เข้าฟีด (ยกเว้นที่ถูกแนะนำ)
+}
+
+// PostPage หนึ่งหน้าของฟีด; NextCursor = nil คือหมดแล้ว
+type PostPage struct {
+	Items      []PostResponse `json:"data"`
+	NextCursor *string        `json:"next_cursor"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	MaxTagsPerPost   = 10
+	MaxTagLength     = 30
+	MaxTagSuggestion = 20 // จำนวนสูงสุดของ GET /tags
+)
+
+var (
+	ErrInvalidTag  = errors.New("invalid tag")
+	ErrTagNotFound = errors.New("tag not found")
+)
+
+// TagSummary แท็กพร้อมจำนวนโพสต์ที่ผู้ชมมองเห็น
+type TagSummary struct {
+	Tag
+	PostCount int `json:"post_count"`
+}
+
+// TagDetail ข้อมูลหน้าแท็ก
+type TagDetail struct {
+	TagSummary
+	FollowerCount int  `json:"follower_count"`
+	IsFollowing   bool `json:"is_following"`
+}
+
+// FollowedTag แท็กที่ผู้ใช้ติดตาม
+type FollowedTag struct {
+	Tag
+	FollowedAt time.Time `json:"followed_at"`
+}
@@ -0,0 +1,407 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"chaladshare_backend/internal/posts/models"
+)
+
+type CollectionRepository interface {
+	CreateCollection(ownerID int, name string, description *string, visibility string) (int, error)
+	UpdateCollection(ownerID, collectionID int, name, description, visibility *string) error
+	DeleteCollection(ownerID, collectionID int) error
+	ReorderCollections(ownerID int, collectionIDs []int) error
+
+	GetCollection(viewerID, collectionID int) (*models.Collection, error)
+	ListCollections(viewerID, ownerID int) ([]models.Collection, error)
+
+	// AddPost บันทึกโพสต์ให้ด้วยถ้ายังไม่ได้บันทึก คืนจำนวน save ล่าสุดของโพสต์
+	AddPost(ownerID, collectionID, postID int) (int, error)
+	RemovePost(ownerID, collectionID, postID int) error
+	ReorderPosts(ownerID, collectionID int, postIDs []int) error
+	SetPostCollections(ownerID, postID int, collectionIDs []int) (int, error)
+	ListPostCollectionIDs(ownerID, postID int) ([]int, error)
+}
+
+type collectionRepository struct {
+	db *sql.DB
+}
+
+func NewCollectionRepository(db *sql.DB) CollectionRepository {
+	return &collectionRepository{db: db}
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// collectionVisibleSQL เงื่อนไขว่า viewer ($1) เห็นคอลเลกชัน c ได้ไหม
+const collectionVisibleSQL = `(
+	c.collection_owner_user_id = $1
+	OR c.collection_visibility = 'public'
+	OR (
+		c.collection_visibility = 'friends'
+		AND EXISTS (
+			SELECT 1 FROM friendships f
+			WHERE f.user_id = LEAST(c.collection_owner_user_id, $1)
+			  AND f.friend_id = GREATEST(c.collection_owner_user_id, $1)
+		)
+	)
+)`
+
+// collectionSelect post_count นับเฉพาะโพสต์ที่ viewer ($1) มองเห็น
+const collectionSelect = `
+	SELECT c.collection_id, c.collection_owner_user_id, u.username,
+		c.collection_name, c.collection_description, c.collection_visibility, c.collection_position,
+		(
+			SELECT COUNT(*)
+			FROM collection_items ci
+			JOIN posts p ON p.post_id = ci.item_post_id
+			WHERE ci.item_collection_id = c.collection_id
+			  AND ` + VisibleToViewerSQL + `
+		) AS post_count,
+		c.collection_created_at, c.collection_updated_at
+	FROM collections c
+	JOIN users u ON u.user_id = c.collection_owner_user_id`
+
+func scanCollection(s rowScanner) (models.Collection, error) {
+	var (
+		c    models.Collection
+		desc sql.NullString
+	)
+	err := s.Scan(
+		&c.CollectionID, &c.OwnerID, &c.OwnerName,
+		&c.Name, &desc, &c.Visibility, &c.Position,
+		&c.PostCount, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if desc.Valid {
+		c.Description = &desc.String
+	}
+	return c, err
+}
+
+func (r *collectionRepository) CreateCollection(ownerID int, name string, description *string, visibility string) (int, error) {
+	// คอลเลกชันใหม่ต่อท้ายสุด
+	var id int
+	err := r.db.QueryRow(`
+		INSERT INTO collections (collection_owner_user_id, collection_name, collection_description,
+			collection_visibility, collection_position)
+		SELECT $1, $2, $3, $4, COALESCE(MAX(collection_position) + 1, 0)
+		FROM collections
+		WHERE collection_owner_user_id = $1
+		RETURNING collection_id
+	`, ownerID, name, description, visibility).Scan(&id)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, models.ErrCollectionNameTaken
+		}
+		return 0, fmt.Errorf("create collection: %w", err)
+	}
+	return id, nil
+}
+
+func (r *collectionRepository) UpdateCollection(ownerID, collectionID int, name, description, visibility *string) error {
+	res, err := r.db.Exec(`
+		UPDATE collections
+		SET collection_name        = COALESCE($3, collection_name),
+		    collection_description = CASE WHEN $4::text IS NULL THEN collection_description ELSE NULLIF($4::text, '') END,
+		    collection_visibility  = COALESCE($5, collection_visibility),
+		    collection_updated_at  = now()
+		WHERE collection_id = $1 AND collection_owner_user_id = $2
+	`, collectionID, ownerID, name, description, visibility)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return models.ErrCollectionNameTaken
+		}
+		return fmt.Errorf("update collection: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrCollectionNotFound
+	}
+	return nil
+}
+
+// DeleteCollection โพสต์ข้างในยังถูกบันทึกอยู่เหมือนเดิม
+func (r *collectionRepository) DeleteCollection(ownerID, collectionID int) error {
+	res, err := r.db.Exec(`
+		DELETE FROM collections WHERE collection_id = $1 AND collection_owner_user_id = $2
+	`, collectionID, ownerID)
+	if err != nil {
+		return fmt.Errorf("delete collection: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return models.ErrCollectionNotFound
+	}
+	return nil
+}
+
+// ReorderCollections collectionIDs ต้องเป็นคอลเลกชันทั้งหมดของเจ้าของ (ไม่ซ้ำ)
+func (r *collectionRepository) ReorderCollections(ownerID int, collectionIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var total int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM collections WHERE collection_owner_user_id = $1
+	`, ownerID).Scan(&total); err != nil {
+		return fmt.Errorf("count collections: %w", err)
+	}
+	if total != len(collectionIDs) {
+		return fmt.Errorf("%w: order must list all %d collections", models.ErrInvalidCollection, total)
+	}
+
+	res, err := tx.Exec(`
+		UPDATE collections c
+		SET collection_position = o.ord - 1
+		FROM UNNEST($2::int[]) WITH ORDINALITY AS o(id, ord)
+		WHERE c.collection_id = o.id AND c.collection_owner_user_id = $1
+	`, ownerID, pq.Array(collectionIDs))
+	if err != nil {
+		return fmt.Errorf("reorder collections: %w", err)
+	}
+	if n, _ := res.RowsAffected(); int(n) != len(collectionIDs) {
+		return fmt.Errorf("%w: unknown collection in order", models.ErrInvalidCollection)
+	}
+	return tx.Commit()
+}
+
+func (r *collectionRepository) GetCollection(viewerID, collectionID int) (*models.Collection, error) {
+	row := r.db.QueryRow(collectionSelect+`
+	WHERE c.collection_id = $2
+	  AND `+collectionVisibleSQL, viewerID, collectionID)
+
+	c, err := scanCollection(row)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrCollectionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get collection: %w", err)
+	}
+	return &c, nil
+}
+
+// ListCollections คอลเลกชันของ ownerID ที่ viewer มองเห็น
+func (r *collectionRepository) ListCollections(viewerID, ownerID int) ([]models.Collection, error) {
+	rows, err := r.db.Query(collectionSelect+`
+	WHERE c.collection_owner_user_id = $2
+	  AND `+collectionVisibleSQL+`
+	ORDER BY c.collection_position ASC, c.collection_id ASC`, viewerID, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("list collections: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.Collection
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// lockOwnedCollection กัน AddPost พร้อมกันในคอลเลกชันเดียวกันได้ตำแหน่งซ้ำ
+func lockOwnedCollection(tx *sql.Tx, ownerID, collectionID int) error {
+	var id int
+	err := tx.QueryRow(`
+		SELECT collection_id FROM collections
+		WHERE collection_id = $1 AND collection_owner_user_id = $2
+		FOR UPDATE
+	`, collectionID, ownerID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return models.ErrCollectionNotFound
+	}
+	return err
+}
+
+func ensureSaved(tx *sql.Tx, userID, postID int) error {
+	_, err := tx.Exec(`
+		INSERT INTO saved_posts (save_user_id, save_post_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, userID, postID)
+	if err != nil {
+		return fmt.Errorf("save post: %w", err)
+	}
+	return nil
+}
+
+func refreshSaveCount(tx *sql.Tx, postID int) (int, error) {
+	var n int
+	if err := tx.QueryRow(upsertSaveCountSQL+`
+		RETURNING post_save_count`, postID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("update save count: %w", err)
+	}
+	return n, nil
+}
+
+// โพสต์ที่เพิ่มใหม่ขึ้นบนสุดของคอลเลกชัน
+const insertCollectionItemSQL = `
+	INSERT INTO collection_items (item_collection_id, item_user_id, item_post_id, item_position)
+	SELECT x.id, $1, $2,
+		COALESCE((SELECT MIN(ci.item_position) FROM collection_items ci WHERE ci.item_collection_id = x.id) - 1, 0)
+	FROM UNNEST($3::int[]) AS x(id)
+	ON CONFLICT (item_collection_id, item_post_id) DO NOTHING`
+
+func (r *collectionRepository) AddPost(ownerID, collectionID, postID int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := lockOwnedCollection(tx, ownerID, collectionID); err != nil {
+		return 0, err
+	}
+	if err := ensureSaved(tx, ownerID, postID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(insertCollectionItemSQL, ownerID, postID, pq.Array([]int{collectionID})); err != nil {
+		return 0, fmt.Errorf("add collection post: %w", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE collections SET collection_updated_at = now() WHERE collection_id = $1
+	`, collectionID); err != nil {
+		return 0, err
+	}
+	count, err := refreshSaveCount(tx, postID)
+	if err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// RemovePost เอาออกจากคอลเลกชันเท่านั้น โพสต์ยังถูกบันทึกอยู่
+func (r *collectionRepository) RemovePost(ownerID, collectionID, postID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOwnedCollection(tx, ownerID, collectionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM collection_items WHERE item_collection_id = $1 AND item_post_id = $2
+	`, collectionID, postID); err != nil {
+		return fmt.Errorf("remove collection post: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ReorderPosts postIDs ต้องเป็นโพสต์ทั้งหมดในคอลเลกชัน (ไม่ซ้ำ)
+func (r *collectionRepository) ReorderPosts(ownerID, collectionID int, postIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockOwnedCollection(tx, ownerID, collectionID); err != nil {
+		return err
+	}
+
+	var total int
+	if err := tx.QueryRow(`
+		SELECT COUNT(*) FROM collection_items WHERE item_collection_id = $1
+	`, collectionID).Scan(&total); err != nil {
+		return fmt.Errorf("count collection posts: %w", err)
+	}
+	if total != len(postIDs) {
+		return fmt.Errorf("%w: order must list all %d posts", models.ErrInvalidCollection, total)
+	}
+
+	res, err := tx.Exec(`
+		UPDATE collection_items ci
+		SET item_position = o.ord - 1
+		FROM UNNEST($2::int[]) WITH ORDINALITY AS o(id, ord)
+		WHERE ci.item_collection_id = $1 AND ci.item_post_id = o.id
+	`, collectionID, pq.Array(postIDs))
+	if err != nil {
+		return fmt.Errorf("reorder collection posts: %w", err)
+	}
+	if n, _ := res.RowsAffected(); int(n) != len(postIDs) {
+		return fmt.Errorf("%w: unknown post in order", models.ErrInvalidCollection)
+	}
+	return tx.Commit()
+}
+
+// SetPostCollections ให้โพสต์อยู่ในคอลเลกชันตาม collectionIDs พอดี (ว่าง = ออกจากทุกคอลเลกชันแต่ยังบันทึกอยู่)
+func (r *collectionRepository) SetPostCollections(ownerID, postID int, collectionIDs []int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if len(collectionIDs) > 0 {
+		var owned int
+		if err := tx.QueryRow(`
+			SELECT COUNT(*) FROM collections
+			WHERE collection_owner_user_id = $1 AND collection_id = ANY($2::int[])
+		`, ownerID, pq.Array(collectionIDs)).Scan(&owned); err != nil {
+			return 0, fmt.Errorf("check collections: %w", err)
+		}
+		if owned != len(collectionIDs) {
+			return 0, models.ErrCollectionNotFound
+		}
+	}
+
+	if _, err := tx.Exec(`
+		DELETE FROM collection_items
+		WHERE item_user_id = $1 AND item_post_id = $2
+		  AND NOT (item_collection_id = ANY($3::int[]))
+	`, ownerID, postID, pq.Array(collectionIDs)); err != nil {
+		return 0, fmt.Errorf("clear post collections: %w", err)
+	}
+
+	if len(collectionIDs) > 0 {
+		if err := ensureSaved(tx, ownerID, postID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(insertCollectionItemSQL, ownerID, postID, pq.Array(collectionIDs)); err != nil {
+			return 0, fmt.Errorf("add post collections: %w", err)
+		}
+	}
+
+	count, err := refreshSaveCount(tx, postID)
+	if err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+func (r *collectionRepository) ListPostCollectionIDs(ownerID, postID int) ([]int, error) {
+	rows, err := r.db.Query(`
+		SELECT ci.item_collection_id
+		FROM collection_items ci
+		JOIN collections c ON c.collection_id = ci.item_collection_id
+		WHERE ci.item_user_id = $1 AND ci.item_post_id = $2
+		ORDER BY c.collection_position ASC, c.collection_id ASC
+	`, ownerID, postID)
+	if err != nil {
+		return nil, fmt.Errorf("list post collections: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"chaladshare_backend/internal/posts/models"
+)
+
+type CommentRepository interface {
+	CreateComment(c *models.Comment) (*models.Comment, error)
+	GetComment(commentID int) (*models.Comment, error)
+	UpdateComment(commentID int, body string) (*models.Comment, error)
+	DeleteComment(commentID int) error
+	GetCommentResponse(commentID int) (*models.CommentResponse, error)
+
+	// parentID = nil คือความคิดเห็นหลักของโพสต์
+	ListComments(postID int, parentID *int, cursor *models.CommentCursor, limit int) ([]models.CommentResponse, error)
+	UpdateCommentCount(postID int) error
+}
+
+type commentRepository struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+func (r *commentRepository) CreateComment(c *models.Comment) (*models.Comment, error) {
+	err := r.db.QueryRow(`
+		INSERT INTO comments (comment_post_id, comment_user_id, comment_parent_id, comment_body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING comment_id, comment_created_at, comment_updated_at
+	`, c.PostID, c.UserID, c.ParentID, c.Body).Scan(&c.CommentID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("create comment: %w", err)
+	}
+	if err := r.UpdateCommentCount(c.PostID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r *commentRepository) GetComment(commentID int) (*models.Comment, error) {
+	var c models.Comment
+	err := r.db.QueryRow(`
+		SELECT comment_id, comment_post_id, comment_user_id, comment_parent_id,
+		       comment_body, comment_created_at, comment_updated_at
+		FROM comments
+		WHERE comment_id = $1
+	`, commentID).Scan(&c.CommentID, &c.PostID, &c.UserID, &c.ParentID,
+		&c.Body, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *commentRepository) UpdateComment(commentID int, body string) (*models.Comment, error) {
+	var c models.Comment
+	err := r.db.QueryRow(`
+		UPDATE comments
+		SET comment_body = $2, comment_updated_at = now()
+		WHERE comment_id = $1
+		RETURNING comment_id, comment_post_id, comment_user_id, comment_parent_id,
+		          comment_body, comment_created_at, comment_updated_at
+	`, commentID, body).Scan(&c.CommentID, &c.PostID, &c.UserID, &c.ParentID,
+		&c.Body, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// DeleteComment ลบความคิดเห็น (ตอบกลับถูกลบตามด้วย on delete cascade) แล้วนับใหม่
+func (r *commentRepository) DeleteComment(commentID int) error {
+	var postID int
+	err := r.db.QueryRow(`
+		DELETE FROM comments WHERE comment_id = $1 RETURNING comment_post_id
+	`, commentID).Scan(&postID)
+	if err != nil {
+		return err
+	}
+	return r.UpdateCommentCount(postID)
+}
+
+const commentResponseSelect = `
+	SELECT c.comment_id, c.comment_post_id, c.comment_parent_id,
+	       c.comment_user_id, u.username, COALESCE(up.avatar_thumb_url, up.avatar_url),
+	       c.comment_body, c.comment_created_at, c.comment_updated_at,
+	       (SELECT COUNT(*) FROM comments r WHERE r.comment_parent_id = c.comment_id) AS reply_count
+	FROM comments c
+	JOIN users u ON u.user_id = c.comment_user_id
+	LEFT JOIN user_profiles up ON up.profile_user_id = c.comment_user_id`
+
+func scanCommentResponse(s rowScanner) (models.CommentResponse, error) {
+	var (
+		c      models.CommentResponse
+		avatar sql.NullString
+	)
+	err := s.Scan(&c.CommentID, &c.PostID, &c.ParentID,
+		&c.AuthorID, &c.AuthorName, &avatar,
+		&c.Body, &c.CreatedAt, &c.UpdatedAt, &c.ReplyCount)
+	if err != nil {
+		return c, err
+	}
+	if avatar.Valid {
+		c.AvatarURL = &avatar.String
+	}
+	c.IsEdited = c.UpdatedAt.After(c.CreatedAt)
+	return c, nil
+}
+
+func (r *commentRepository) GetCommentResponse(commentID int) (*models.CommentResponse, error) {
+	c, err := scanCommentResponse(r.db.QueryRow(commentResponseSelect+`
+	WHERE c.comment_id = $1`, commentID))
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListComments เรียงเก่าไปใหม่ ทีละ limit แถวต่อจาก cursor
+func (r *commentRepository) ListComments(postID int, parentID *int, cursor *models.CommentCursor, limit int) ([]models.CommentResponse, error) {
+	var ct, cid any
+	if cursor != nil {
+		ct, cid = cursor.CreatedAt, cursor.CommentID
+	}
+
+	// แยกเงื่อนไขหลัก/ตอบกลับ ให้ใช้ index ได้ตรงตัว
+	where := `c.comment_post_id = $1 AND c.comment_parent_id IS NULL`
+	args := []any{postID, ct, cid, limit}
+	if parentID != nil {
+		where = `c.comment_post_id = $1 AND c.comment_parent_id = $5`
+		args = append(args, *parentID)
+	}
+
+	rows, err := r.db.Query(commentResponseSelect+`
+	WHERE `+where+`
+	  AND ($2::timestamptz IS NULL OR (c.comment_created_at, c.comment_id) > ($2::timestamptz, $3::int))
+	ORDER BY c.comment_created_at ASC, c.comment_id ASC
+	LIMIT $4`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list comments: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.CommentResponse
+	for rows.Next() {
+		c, err := scanCommentResponse(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan comment: %w", err)
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// อัปเดตจำนวนความคิดเห็นใน post_stats (นับรวมตอบกลับ)
+func (r *commentRepository) UpdateCommentCount(postID int) error {
+	_, err := r.db.Exec(`
+		INSERT INTO post_stats (post_stats_post_id, post_comment_count, post_last_activity_at)
+		VALUES (
+			$1,
+			(SELECT COUNT(*) FROM comments WHERE comment_post_id = $1),
+			NOW()
+		)
+		ON CONFLICT (post_stats_post_id)
+		DO UPDATE SET
+			post_comment_count    = EXCLUDED.post_comment_count,
+			post_last_activity_at = EXCLUDED.post_last_activity_at;
+	`, postID)
+	if err != nil {
+		return fmt.Errorf("update comment count: %w", err)
+	}
+	return nil
+}
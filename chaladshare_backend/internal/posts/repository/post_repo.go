@@ -4,27 +4,44 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
+	"github.com/pgvector/pgvector-go"
 
 	"chaladshare_backend/internal/posts/models"
 )
 
 type PostRepository interface {
 	CreatePost(post *models.Post, tags []string) (int, error)
-	UpdatePost(post *models.Post, tags []string) error
+	UpdatePost(post *models.Post, tags []string) (bool, error)
+	PublishPost(postID, authorID int) (bool, error)
 	DeletePost(postID int) error
 
 	GetAllPosts() ([]models.PostResponse, error)
 	GetPostByID(postID int) (*models.PostResponse, error)
 	GetPostByIDForViewer(viewerID, postID int) (*models.PostResponse, error)
-	GetFeedPosts(viewerID int) ([]models.PostResponse, error)
+	GetFeedPosts(viewerID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error)
+	GetUserPosts(viewerID, authorID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error)
+	GetTagPosts(viewerID int, tagName string, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error)
+	GetCollectionPosts(viewerID, collectionID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error)
+	GetPersonalFeed(viewerID int, rank models.FeedRanking, asOf time.Time, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error)
 	GetPostOwnerID(postID int) (int, error)
+	GetDrafts(authorID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error)
+	PublishDue(limit int) ([]models.PostResponse, error)
+	ListFollowersWhoCanView(postID, limit int) ([]int, error)
 	CountByUserID(userID int) (int, error)
 
-	GetSavedPosts(userID int) ([]models.PostResponse, error)
-	GetPopularPosts(viewerID, limit int) ([]models.PostResponse, error)
+	GetAudience(postID int) (*models.PostAudience, error)
+	CanView(viewerID, postID int) (bool, error)
+
+	GetSavedPosts(userID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error)
+	GetPopularPosts(viewerID int, window string, limit int) ([]models.PostResponse, error)
+	RefreshTrending(window string, since *time.Time, cfg models.TrendingConfig) (int, error)
 	SearchPosts(viewerID int, search string, page, size int) ([]models.PostResponse, int, error)
+	ListPostIDsByDocumentID(documentID int) ([]int, error)
+	SearchPostsSemantic(viewerID int, embedding []float64, maxDistance float64, page, size int) ([]models.PostResponse, int, error)
+	SearchPostsHybrid(viewerID int, search string, embedding []float64, keywordWeight, minSimilarity float64, page, size int) ([]models.PostResponse, int, error)
 }
 
 type postRepository struct {
@@ -52,9 +69,13 @@ func (r *postRepository) CreatePost(post *models.Post, tags []string) (int, erro
 		coverArg = *post.CoverURL
 	}
 
+	// cover ที่อัปผ่าน /files/cover จะมี thumbnail ใน image_uploads
 	query := `INSERT INTO posts (post_author_user_id, post_title, post_description,
-			  post_visibility, post_document_id, post_cover_url) 
-			  SELECT $1, $2, $3, $4, $5, $6
+			  post_visibility, post_document_id, post_cover_url, post_cover_thumb_url,
+			  post_status, post_publish_at) 
+			  SELECT $1, $2, $3, $4, $5, $6,
+			         (SELECT iu.image_thumb_url FROM image_uploads iu WHERE iu.image_url = $6),
+			         $7, $8
 			  FROM documents d
 			  WHERE d.document_id = $5 AND d.document_user_id = $1
 			  RETURNING post_id;`
@@ -64,6 +85,7 @@ func (r *postRepository) CreatePost(post *models.Post, tags []string) (int, erro
 		query,
 		post.AuthorUserID, post.Title, post.Description,
 		post.Visibility, docArg, coverArg,
+		post.Status, post.PublishAt,
 	).Scan(&postID); err != nil {
 		if err == sql.ErrNoRows {
 			return 0, fmt.Errorf("invalid document_id or not owned by user")
@@ -71,6 +93,12 @@ func (r *postRepository) CreatePost(post *models.Post, tags []string) (int, erro
 		return 0, fmt.Errorf("create post: %w", err)
 	}
 
+	if post.Visibility == models.VisibilityCustom {
+		if err := replaceAudience(tx, postID, post.AuthorUserID, post.Audience); err != nil {
+			return 0, err
+		}
+	}
+
 	if len(tags) > 0 {
 		upsertTag := `INSERT INTO tags (tag_name) VALUES ($1) ON CONFLICT (tag_name) DO UPDATE
 					  SET tag_name = EXCLUDED.tag_name RETURNING tag_id;`
@@ -101,27 +129,54 @@ func (r *postRepository) CreatePost(post *models.Post, tags []string) (int, erro
 	return postID, nil
 }
 
-func (r *postRepository) UpdatePost(post *models.Post, tags []string) error {
+// UpdatePost คืน true เมื่อการแก้ไขนี้เป็นตัวที่เปลี่ยนโพสต์เป็น published (คนเรียกเป็นคนส่งแจ้งเตือน)
+// ล็อกแถวก่อนอ่านสถานะเดิม กัน scheduler เผยแพร่ไปแล้วแต่ถูกเขียนทับกลับเป็น scheduled/draft
+func (r *postRepository) UpdatePost(post *models.Post, tags []string) (bool, error) {
 	tx, err := r.db.Begin()
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer tx.Rollback()
 
+	var prevStatus string
+	err = tx.QueryRow(`SELECT post_status FROM posts WHERE post_id = $1 FOR UPDATE`, post.PostID).Scan(&prevStatus)
+	if err != nil {
+		return false, err
+	}
+	if prevStatus == models.StatusPublished && post.Status != models.StatusPublished {
+		return false, models.ErrAlreadyPublished
+	}
+
+	// เผยแพร่ฉบับร่างตอนนี้ = ใช้เวลานี้เป็น post_created_at (ฟีดเรียงตามเวลาเผยแพร่)
 	res, err := tx.Exec(`UPDATE posts SET post_title = $1,
-        				 post_description = $2, post_visibility = $3, post_updated_at = now()
+        				 post_description = $2, post_visibility = $3, post_updated_at = now(),
+        				 post_created_at = CASE WHEN post_status <> 'published' AND $5::varchar = 'published'
+        				                        THEN now() ELSE post_created_at END,
+        				 post_status = $5::varchar, post_publish_at = $6
     					 WHERE post_id = $4;`,
-		post.Title, post.Description, post.Visibility, post.PostID)
+		post.Title, post.Description, post.Visibility, post.PostID,
+		post.Status, post.PublishAt)
 	if err != nil {
-		return fmt.Errorf("update post: %w", err)
+		return false, fmt.Errorf("update post: %w", err)
 	}
 	if n, _ := res.RowsAffected(); n == 0 {
-		return sql.ErrNoRows
+		return false, sql.ErrNoRows
+	}
+
+	// ไม่ใช่ custom แล้วล้างผู้ชมเดิมทิ้ง, custom + ส่ง Audience มา = แทนที่ทั้งหมด
+	if post.Visibility != models.VisibilityCustom {
+		if err := replaceAudience(tx, post.PostID, post.AuthorUserID, nil); err != nil {
+			return false, err
+		}
+	} else if post.Audience != nil {
+		if err := replaceAudience(tx, post.PostID, post.AuthorUserID, post.Audience); err != nil {
+			return false, err
+		}
 	}
 
 	if tags != nil {
 		if _, err := tx.Exec(`DELETE FROM post_tags WHERE post_tag_post_id = $1`, post.PostID); err != nil {
-			return fmt.Errorf("clear old tags: %w", err)
+			return false, fmt.Errorf("clear old tags: %w", err)
 		}
 
 		if len(tags) > 0 {
@@ -136,21 +191,119 @@ func (r *postRepository) UpdatePost(post *models.Post, tags []string) error {
 			for _, t := range tags {
 				var tagID int
 				if err := tx.QueryRow(upsertTag, t).Scan(&tagID); err != nil {
-					return fmt.Errorf("upsert tag %q: %w", t, err)
+					return false, fmt.Errorf("upsert tag %q: %w", t, err)
 				}
 				if _, err := tx.Exec(link, post.PostID, tagID); err != nil {
-					return fmt.Errorf("link tag %q: %w", t, err)
+					return false, fmt.Errorf("link tag %q: %w", t, err)
 				}
 			}
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit: %w", err)
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	return prevStatus != models.StatusPublished && post.Status == models.StatusPublished, nil
+}
+
+// PublishPost เผยแพร่ฉบับร่าง/โพสต์ที่ตั้งเวลาไว้ทันที คืน false ถ้าโพสต์ถูกเผยแพร่ไปแล้ว (เช่น PublishDue ชิงไปก่อน)
+func (r *postRepository) PublishPost(postID, authorID int) (bool, error) {
+	res, err := r.db.Exec(`
+		UPDATE posts
+		SET post_status = 'published',
+		    post_publish_at = NULL,
+		    post_created_at = now(),
+		    post_updated_at = now()
+		WHERE post_id = $1 AND post_author_user_id = $2
+		  AND post_status IN ('draft', 'scheduled')
+	`, postID, authorID)
+	if err != nil {
+		return false, fmt.Errorf("publish post: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// replaceAudience เขียนผู้ชมของโพสต์ใหม่ทั้งชุด (aud = nil คือล้าง)
+// ผู้ใช้ต้องมีอยู่จริง และกลุ่มต้องเป็นของเจ้าของโพสต์ ไม่งั้นคืน ErrInvalidAudience
+func replaceAudience(tx *sql.Tx, postID, authorID int, aud *models.PostAudience) error {
+	if _, err := tx.Exec(`DELETE FROM post_audience_users WHERE audience_post_id = $1`, postID); err != nil {
+		return fmt.Errorf("clear audience users: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM post_audience_groups WHERE audience_post_id = $1`, postID); err != nil {
+		return fmt.Errorf("clear audience groups: %w", err)
+	}
+	if aud == nil {
+		return nil
+	}
+
+	if len(aud.UserIDs) > 0 {
+		res, err := tx.Exec(`
+			INSERT INTO post_audience_users (audience_post_id, audience_user_id)
+			SELECT $1, u.user_id FROM users u WHERE u.user_id = ANY($2::int[])
+			ON CONFLICT DO NOTHING
+		`, postID, pq.Array(aud.UserIDs))
+		if err != nil {
+			return fmt.Errorf("insert audience users: %w", err)
+		}
+		if n, _ := res.RowsAffected(); int(n) != len(aud.UserIDs) {
+			return fmt.Errorf("%w: unknown user in audience", models.ErrInvalidAudience)
+		}
+	}
+
+	if len(aud.GroupIDs) > 0 {
+		res, err := tx.Exec(`
+			INSERT INTO post_audience_groups (audience_post_id, audience_group_id)
+			SELECT $1, g.group_id FROM friend_groups g
+			WHERE g.group_id = ANY($2::int[]) AND g.group_owner_user_id = $3
+			ON CONFLICT DO NOTHING
+		`, postID, pq.Array(aud.GroupIDs), authorID)
+		if err != nil {
+			return fmt.Errorf("insert audience groups: %w", err)
+		}
+		if n, _ := res.RowsAffected(); int(n) != len(aud.GroupIDs) {
+			return fmt.Errorf("%w: friend group not found", models.ErrInvalidAudience)
+		}
 	}
 	return nil
 }
 
+func (r *postRepository) GetAudience(postID int) (*models.PostAudience, error) {
+	aud := &models.PostAudience{UserIDs: []int{}, GroupIDs: []int{}}
+
+	var users, groups pq.Int64Array
+	err := r.db.QueryRow(`
+		SELECT
+			ARRAY(SELECT audience_user_id FROM post_audience_users WHERE audience_post_id = $1 ORDER BY audience_user_id),
+			ARRAY(SELECT audience_group_id FROM post_audience_groups WHERE audience_post_id = $1 ORDER BY audience_group_id)
+	`, postID).Scan(&users, &groups)
+	if err != nil {
+		return nil, fmt.Errorf("get audience: %w", err)
+	}
+	for _, id := range users {
+		aud.UserIDs = append(aud.UserIDs, int(id))
+	}
+	for _, id := range groups {
+		aud.GroupIDs = append(aud.GroupIDs, int(id))
+	}
+	return aud, nil
+}
+
+// CanView ใช้เงื่อนไขเดียวกับฟีด/ค้นหา (VisibleToViewerSQL) ไม่พบโพสต์คืน false
+func (r *postRepository) CanView(viewerID, postID int) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM posts p
+			WHERE p.post_id = $2 AND `+VisibleToViewerSQL+`
+		)
+	`, viewerID, postID).Scan(&ok)
+	return ok, err
+}
+
 func (r *postRepository) DeletePost(postID int) error {
 	query := `DELETE FROM posts WHERE post_id = $1`
 	res, err := r.db.Exec(query, postID)
@@ -169,9 +322,10 @@ func (r *postRepository) GetAllPosts() ([]models.PostResponse, error) {
 		p.post_document_id, p.post_created_at, p.post_updated_at,
 		COALESCE(ps.post_like_count, 0) AS post_like_count,
 		COALESCE(ps.post_save_count, 0) AS post_save_count,
-		d.document_url AS document_file_url,
+		COALESCE(ps.post_comment_count, 0) AS post_comment_count,
+		('/api/v1/files/' || p.post_document_id || '/download') AS document_file_url,
 		d.document_name AS document_name,
-		p.post_cover_url, up.avatar_url,
+		COALESCE(p.post_cover_thumb_url, p.post_cover_url), COALESCE(up.avatar_thumb_url, up.avatar_url),
 		ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags
 	FROM posts p
 	JOIN users u ON u.user_id = p.post_author_user_id
@@ -180,7 +334,8 @@ func (r *postRepository) GetAllPosts() ([]models.PostResponse, error) {
 	LEFT JOIN tags t ON t.tag_id = pt.post_tag_tag_id
 	LEFT JOIN documents d ON d.document_id = p.post_document_id
 	LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
-	GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, d.document_url, d.document_name, p.post_cover_url, up.avatar_url
+	WHERE p.post_status = 'published'
+	GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, ps.post_comment_count, d.document_url, d.document_name, p.post_cover_url, up.avatar_url, p.post_cover_thumb_url, up.avatar_thumb_url
 	ORDER BY p.post_created_at DESC;`
 
 	rows, err := r.db.Query(query)
@@ -205,7 +360,7 @@ func (r *postRepository) GetAllPosts() ([]models.PostResponse, error) {
 			&p.PostID, &p.AuthorID, &p.AuthorName,
 			&p.Title, &p.Description, &p.Visibility,
 			&docID, &p.CreatedAt, &p.UpdatedAt,
-			&p.LikeCount, &p.SaveCount,
+			&p.LikeCount, &p.SaveCount, &p.CommentCount,
 			&fileURL, &docName, &coverURL, &avatarURL, &tags,
 		); err != nil {
 			return nil, err
@@ -238,52 +393,89 @@ func (r *postRepository) GetAllPosts() ([]models.PostResponse, error) {
 	return posts, nil
 }
 
-func (r *postRepository) GetFeedPosts(viewerID int) ([]models.PostResponse, error) {
-	query := `
-		SELECT p.post_id, p.post_author_user_id, u.username AS author_name,
-			p.post_title, p.post_description, p.post_visibility,
-			p.post_document_id, p.post_created_at, p.post_updated_at,
-			COALESCE(ps.post_like_count, 0) AS post_like_count,
-			COALESCE(ps.post_save_count, 0) AS post_save_count,
-			d.document_url AS document_file_url,
-			d.document_name AS document_name,
-			p.post_cover_url, up.avatar_url,
-			ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags,
-
-			EXISTS (
-				SELECT 1 FROM likes l
-				WHERE l.like_user_id = $1 AND l.like_post_id = p.post_id
-			) AS is_liked,
-			EXISTS (
-				SELECT 1 FROM saved_posts sp
-				WHERE sp.save_user_id = $1 AND sp.save_post_id = p.post_id
-			) AS is_saved
-
-		FROM posts p
-		JOIN users u ON u.user_id = p.post_author_user_id
-		LEFT JOIN post_stats ps ON ps.post_stats_post_id = p.post_id
-		LEFT JOIN post_tags pt ON pt.post_tag_post_id = p.post_id
-		LEFT JOIN tags t ON t.tag_id = pt.post_tag_tag_id
-		LEFT JOIN documents d ON d.document_id = p.post_document_id
-		LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
-		WHERE
-			p.post_author_user_id = $1
-			OR p.post_visibility = 'public'
-			OR ( p.post_visibility = 'friends'
-				AND EXISTS (
+// VisibleToViewerSQL เงื่อนไขการมองเห็นโพสต์ p ของ viewer ($1) ใช้ร่วมกันทุก query ที่กรองการมองเห็น
+// private เห็นเฉพาะเจ้าของ, custom เห็นเฉพาะผู้ใช้ที่ระบุหรือสมาชิกกลุ่มเพื่อนที่เลือก
+// draft/scheduled ไม่ผ่านเงื่อนไขนี้แม้เป็นเจ้าของ (เจ้าของดูผ่าน GetDrafts)
+const VisibleToViewerSQL = `(
+	p.post_status = 'published'
+	AND (
+		p.post_author_user_id = $1
+		OR p.post_visibility = 'public'
+		OR (
+			p.post_visibility = 'friends'
+			AND EXISTS (
+				SELECT 1
+				FROM friendships f
+				WHERE
+					f.user_id = LEAST(p.post_author_user_id, $1)
+					AND f.friend_id = GREATEST(p.post_author_user_id, $1)
+			)
+		)
+		OR (
+			p.post_visibility = 'custom'
+			AND (
+				EXISTS (
+					SELECT 1 FROM post_audience_users au
+					WHERE au.audience_post_id = p.post_id AND au.audience_user_id = $1
+				)
+				OR EXISTS (
 					SELECT 1
-					FROM friendships f
-					WHERE
-						f.user_id  = LEAST(p.post_author_user_id, $1)
-						AND f.friend_id = GREATEST(p.post_author_user_id, $1)
+					FROM post_audience_groups ag
+					JOIN friend_group_members gm ON gm.group_id = ag.audience_group_id
+					WHERE ag.audience_post_id = p.post_id AND gm.member_user_id = $1
 				)
 			)
-		GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count,
-				 d.document_url, d.document_name, p.post_cover_url, up.avatar_url
-		ORDER BY p.post_created_at DESC;
-	`
+		)
+	)
+)`
+
+// keysetPageSQL เงื่อนไข cursor ($2 = created_at, $3 = post_id; NULL = หน้าแรก) และ LIMIT $4
+const keysetPageSQL = `
+	AND ($2::timestamptz IS NULL OR (p.post_created_at, p.post_id) < ($2::timestamptz, $3::int))
+	GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, ps.post_comment_count,
+			 d.document_url, d.document_name, p.post_cover_url, up.avatar_url, p.post_cover_thumb_url, up.avatar_thumb_url
+	ORDER BY p.post_created_at DESC, p.post_id DESC
+	LIMIT $4`
+
+// viewerPostSelect คอลัมน์ชุดเดียวกับ scanViewerPost; ต้องต่อด้วย FROM ที่มี posts p
+const viewerPostSelect = `
+	SELECT p.post_id, p.post_author_user_id, u.username AS author_name,
+		p.post_title, p.post_description, p.post_visibility,
+		p.post_document_id, p.post_created_at, p.post_updated_at,
+		COALESCE(ps.post_like_count, 0) AS post_like_count,
+		COALESCE(ps.post_save_count, 0) AS post_save_count,
+		COALESCE(ps.post_comment_count, 0) AS post_comment_count,
+		('/api/v1/files/' || p.post_document_id || '/download') AS document_file_url,
+		d.document_name AS document_name,
+		COALESCE(p.post_cover_thumb_url, p.post_cover_url), COALESCE(up.avatar_thumb_url, up.avatar_url),
+		ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags,
+		EXISTS (
+			SELECT 1 FROM likes l
+			WHERE l.like_user_id = $1 AND l.like_post_id = p.post_id
+		) AS is_liked,
+		EXISTS (
+			SELECT 1 FROM saved_posts sv
+			WHERE sv.save_user_id = $1 AND sv.save_post_id = p.post_id
+		) AS is_saved`
+
+const viewerPostJoins = `
+	JOIN users u ON u.user_id = p.post_author_user_id
+	LEFT JOIN post_stats ps ON ps.post_stats_post_id = p.post_id
+	LEFT JOIN post_tags pt ON pt.post_tag_post_id = p.post_id
+	LEFT JOIN tags t ON t.tag_id = pt.post_tag_tag_id
+	LEFT JOIN documents d ON d.document_id = p.post_document_id
+	LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id`
 
-	rows, err := r.db.Query(query, viewerID)
+// cursorArgs แปลง cursor เป็น $2, $3 (nil ทั้งคู่ = หน้าแรก)
+func cursorArgs(c *models.FeedCursor) (any, any) {
+	if c == nil {
+		return nil, nil
+	}
+	return c.CreatedAt, c.PostID
+}
+
+func (r *postRepository) queryViewerPosts(query string, args ...any) ([]models.PostResponse, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -291,68 +483,43 @@ func (r *postRepository) GetFeedPosts(viewerID int) ([]models.PostResponse, erro
 
 	var posts []models.PostResponse
 	for rows.Next() {
-		var (
-			p         models.PostResponse
-			tags      pq.StringArray
-			fileURL   sql.NullString
-			docName   sql.NullString
-			coverURL  sql.NullString
-			avatarURL sql.NullString
-			docID     sql.NullInt64
-			isLiked   bool
-			isSaved   bool
-		)
-		if err := rows.Scan(
-			&p.PostID, &p.AuthorID, &p.AuthorName,
-			&p.Title, &p.Description, &p.Visibility,
-			&docID, &p.CreatedAt, &p.UpdatedAt,
-			&p.LikeCount, &p.SaveCount,
-			&fileURL, &docName, &coverURL, &avatarURL, &tags,
-			&isLiked, &isSaved,
-		); err != nil {
+		p, err := scanViewerPost(rows)
+		if err != nil {
 			return nil, err
 		}
-
-		if docID.Valid {
-			v := int(docID.Int64)
-			p.DocumentID = &v
-		}
-		if fileURL.Valid {
-			p.FileURL = &fileURL.String
-		}
-		if docName.Valid {
-			p.DocumentName = &docName.String
-		}
-		if coverURL.Valid {
-			p.CoverURL = &coverURL.String
-		}
-		if avatarURL.Valid {
-			p.AvatarURL = &avatarURL.String
-		}
-
-		p.IsLiked = isLiked
-		p.IsSaved = isSaved
-
-		p.Tags = []string(tags)
 		posts = append(posts, p)
 	}
-
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return posts, nil
 }
 
+// GetFeedPosts ฟีดหน้าแรก เรียงใหม่ไปเก่า ทีละ limit แถวต่อจาก cursor
+func (r *postRepository) GetFeedPosts(viewerID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+	query := viewerPostSelect + `
+	FROM posts p` + viewerPostJoins + `
+	WHERE ` + VisibleToViewerSQL + keysetPageSQL
+
+	t, id := cursorArgs(cursor)
+	posts, err := r.queryViewerPosts(query, viewerID, t, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get feed posts: %w", err)
+	}
+	return posts, nil
+}
 func (r *postRepository) GetPostByID(postID int) (*models.PostResponse, error) {
 	query := `SELECT p.post_id, p.post_author_user_id, u.username AS author_name,
 		p.post_title, p.post_description, p.post_visibility, p.post_document_id,
 		p.post_created_at, p.post_updated_at,
 		COALESCE(ps.post_like_count, 0)  AS post_like_count,
 		COALESCE(ps.post_save_count, 0)  AS post_save_count,
-		d.document_url AS document_file_url,
+		COALESCE(ps.post_comment_count, 0)  AS post_comment_count,
+		('/api/v1/files/' || p.post_document_id || '/download') AS document_file_url,
 		d.document_name AS document_name,
 		p.post_cover_url, up.avatar_url,
-		ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags
+		ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags,
+		p.post_status, p.post_publish_at
 	FROM posts p
 	JOIN users u ON u.user_id = p.post_author_user_id
 	LEFT JOIN post_stats ps ON ps.post_stats_post_id = p.post_id
@@ -361,7 +528,7 @@ func (r *postRepository) GetPostByID(postID int) (*models.PostResponse, error) {
 	LEFT JOIN documents d ON d.document_id = p.post_document_id
 	LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
 	WHERE p.post_id = $1
-	GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, d.document_url, d.document_name, up.avatar_url;`
+	GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, ps.post_comment_count, d.document_url, d.document_name, up.avatar_url;`
 
 	row := r.db.QueryRow(query, postID)
 	var (
@@ -372,14 +539,16 @@ func (r *postRepository) GetPostByID(postID int) (*models.PostResponse, error) {
 		coverURL  sql.NullString
 		avatarURL sql.NullString
 		docID     sql.NullInt64
+		publishAt sql.NullTime
 	)
 
 	if err := row.Scan(
 		&p.PostID, &p.AuthorID, &p.AuthorName,
 		&p.Title, &p.Description, &p.Visibility,
 		&docID, &p.CreatedAt, &p.UpdatedAt,
-		&p.LikeCount, &p.SaveCount,
+		&p.LikeCount, &p.SaveCount, &p.CommentCount,
 		&fileURL, &docName, &coverURL, &avatarURL, &tags,
+		&p.Status, &publishAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, sql.ErrNoRows
@@ -403,6 +572,10 @@ func (r *postRepository) GetPostByID(postID int) (*models.PostResponse, error) {
 		p.AvatarURL = &avatarURL.String
 	}
 
+	if publishAt.Valid {
+		p.PublishAt = &publishAt.Time
+	}
+
 	p.Tags = []string(tags)
 	return &p, nil
 }
@@ -414,7 +587,8 @@ func (r *postRepository) GetPostByIDForViewer(viewerID, postID int) (*models.Pos
 		p.post_created_at, p.post_updated_at,
 		COALESCE(ps.post_like_count, 0) AS post_like_count,
 		COALESCE(ps.post_save_count, 0) AS post_save_count,
-		d.document_url AS document_file_url,
+		COALESCE(ps.post_comment_count, 0) AS post_comment_count,
+		('/api/v1/files/' || p.post_document_id || '/download') AS document_file_url,
 		d.document_name AS document_name,
 		p.post_cover_url, up.avatar_url,
 		ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags,
@@ -426,7 +600,8 @@ func (r *postRepository) GetPostByIDForViewer(viewerID, postID int) (*models.Pos
 		EXISTS (
 			SELECT 1 FROM saved_posts sp
 			WHERE sp.save_user_id = $1 AND sp.save_post_id = p.post_id
-		) AS is_saved
+		) AS is_saved,
+		p.post_status, p.post_publish_at
 
 	FROM posts p
 	JOIN users u ON u.user_id = p.post_author_user_id
@@ -436,7 +611,7 @@ func (r *postRepository) GetPostByIDForViewer(viewerID, postID int) (*models.Pos
 	LEFT JOIN documents d ON d.document_id = p.post_document_id
 	LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
 	WHERE p.post_id = $2
-	GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count,
+	GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, ps.post_comment_count,
 			 d.document_url, d.document_name, p.post_cover_url, up.avatar_url;
 	`
 
@@ -452,15 +627,17 @@ func (r *postRepository) GetPostByIDForViewer(viewerID, postID int) (*models.Pos
 		docID     sql.NullInt64
 		isLiked   bool
 		isSaved   bool
+		publishAt sql.NullTime
 	)
 
 	if err := row.Scan(
 		&p.PostID, &p.AuthorID, &p.AuthorName,
 		&p.Title, &p.Description, &p.Visibility,
 		&docID, &p.CreatedAt, &p.UpdatedAt,
-		&p.LikeCount, &p.SaveCount,
+		&p.LikeCount, &p.SaveCount, &p.CommentCount,
 		&fileURL, &docName, &coverURL, &avatarURL, &tags,
 		&isLiked, &isSaved,
+		&p.Status, &publishAt,
 	); err != nil {
 		return nil, err
 	}
@@ -482,6 +659,10 @@ func (r *postRepository) GetPostByIDForViewer(viewerID, postID int) (*models.Pos
 		p.AvatarURL = &avatarURL.String
 	}
 
+	if publishAt.Valid {
+		p.PublishAt = &publishAt.Time
+	}
+
 	p.Tags = []string(tags)
 	p.IsLiked = isLiked
 	p.IsSaved = isSaved
@@ -499,126 +680,399 @@ func (r *postRepository) GetPostOwnerID(postID int) (int, error) {
 
 func (r *postRepository) CountByUserID(userID int) (int, error) {
 	var cnt int
-	err := r.db.QueryRow(`SELECT COUNT(*) FROM posts WHERE post_author_user_id = $1`, userID).Scan(&cnt)
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM posts WHERE post_author_user_id = $1 AND post_status = 'published'`, userID).Scan(&cnt)
 	return cnt, err
 }
 
-func (r *postRepository) GetSavedPosts(userID int) ([]models.PostResponse, error) {
-	query := `
-        SELECT p.post_id, p.post_author_user_id, u.username AS author_name,
-               p.post_title, p.post_description, p.post_visibility,
-               p.post_document_id, p.post_created_at, p.post_updated_at,
-               COALESCE(ps.post_like_count, 0) AS post_like_count,
-               COALESCE(ps.post_save_count, 0) AS post_save_count,
-               d.document_url AS document_file_url,
-			   d.document_name AS document_name,
-               p.post_cover_url, up.avatar_url,
-               ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags,
-
-			   EXISTS (
-					SELECT 1 FROM likes l
-					WHERE l.like_user_id = $1 AND l.like_post_id = p.post_id
-			   ) AS is_liked,
-			   EXISTS (
-					SELECT 1 FROM saved_posts sp2
-					WHERE sp2.save_user_id = $1 AND sp2.save_post_id = p.post_id
-			   ) AS is_saved
-
-        FROM saved_posts sp
-        JOIN posts p ON p.post_id = sp.save_post_id
-        JOIN users u ON u.user_id = p.post_author_user_id
-        LEFT JOIN post_stats ps ON ps.post_stats_post_id = p.post_id
-        LEFT JOIN post_tags pt ON pt.post_tag_post_id = p.post_id
-        LEFT JOIN tags t ON t.tag_id = pt.post_tag_tag_id
-        LEFT JOIN documents d ON d.document_id = p.post_document_id
-        LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
-        WHERE sp.save_user_id = $1
-          AND (
-              p.post_author_user_id = $1
-              OR p.post_visibility = 'public'
-              OR ( p.post_visibility = 'friends'
-                AND EXISTS (
-                    SELECT 1
-                    FROM friendships f
-                    WHERE
-                        f.user_id  = LEAST(p.post_author_user_id, $1)
-                        AND f.friend_id = GREATEST(p.post_author_user_id, $1)
-                )
-              )
-          )
-        GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count,
-                 d.document_url, d.document_name, p.post_cover_url, up.avatar_url
-        ORDER BY p.post_created_at DESC;
-    `
-
-	rows, err := r.db.Query(query, userID)
+// GetDrafts ฉบับร่างและโพสต์ที่ตั้งเวลาไว้ของ authorID (เห็นเฉพาะเจ้าของ)
+func (r *postRepository) GetDrafts(authorID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+	query := viewerPostSelect + `,
+		p.post_status, p.post_publish_at
+	FROM posts p` + viewerPostJoins + `
+	WHERE p.post_author_user_id = $1
+	  AND p.post_status <> 'published'` + keysetPageSQL
+
+	t, id := cursorArgs(cursor)
+	rows, err := r.db.Query(query, authorID, t, id, limit)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("get drafts: %w", err)
 	}
 	defer rows.Close()
 
 	var posts []models.PostResponse
 	for rows.Next() {
 		var (
-			p         models.PostResponse
-			tags      pq.StringArray
-			fileURL   sql.NullString
-			docName   sql.NullString
-			coverURL  sql.NullString
-			avatarURL sql.NullString
-			docID     sql.NullInt64
-			isLiked   bool
-			isSaved   bool
+			status    string
+			publishAt sql.NullTime
 		)
+		p, err := scanViewerPost(rows, &status, &publishAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan drafts: %w", err)
+		}
+		p.Status = status
+		if publishAt.Valid {
+			p.PublishAt = &publishAt.Time
+		}
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
 
-		if err := rows.Scan(
-			&p.PostID, &p.AuthorID, &p.AuthorName,
-			&p.Title, &p.Description, &p.Visibility,
-			&docID, &p.CreatedAt, &p.UpdatedAt,
-			&p.LikeCount, &p.SaveCount,
-			&fileURL, &docName, &coverURL, &avatarURL, &tags,
-			&isLiked, &isSaved,
-		); err != nil {
+// PublishDue เผยแพร่โพสต์ scheduled ที่ถึงเวลาแล้ว (ไม่เกิน limit ต่อรอบ) คืน post_id กับผู้เขียนของโพสต์ที่เผยแพร่
+// post_created_at = เวลาที่ตั้งไว้ ฟีดจึงเรียงตามเวลาเผยแพร่; SKIP LOCKED ให้รันหลาย instance พร้อมกันได้
+// เช็ค post_status ซ้ำที่ UPDATE ชั้นนอก แถวที่ PublishPost เผยแพร่ไปแล้วจะไม่ถูกนับซ้ำ
+func (r *postRepository) PublishDue(limit int) ([]models.PostResponse, error) {
+	rows, err := r.db.Query(`
+		UPDATE posts p
+		SET post_status = 'published',
+		    post_created_at = p.post_publish_at,
+		    post_updated_at = now()
+		WHERE p.post_id IN (
+			SELECT post_id FROM posts
+			WHERE post_status = 'scheduled' AND post_publish_at <= now()
+			ORDER BY post_publish_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		  AND p.post_status = 'scheduled'
+		RETURNING p.post_id, p.post_author_user_id, p.post_visibility, p.post_created_at, p.post_document_id
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("publish due posts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.PostResponse
+	for rows.Next() {
+		p := models.PostResponse{Status: models.StatusPublished}
+		var docID sql.NullInt64
+		if err := rows.Scan(&p.PostID, &p.AuthorID, &p.Visibility, &p.CreatedAt, &docID); err != nil {
 			return nil, err
 		}
 		if docID.Valid {
 			v := int(docID.Int64)
 			p.DocumentID = &v
 		}
-		if fileURL.Valid {
-			p.FileURL = &fileURL.String
-		}
-		if docName.Valid {
-			p.DocumentName = &docName.String
-		}
-		if coverURL.Valid {
-			p.CoverURL = &coverURL.String
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// followerVisibleSQL เงื่อนไขเดียวกับ VisibleToViewerSQL แต่ผู้ชมคือผู้ติดตามแต่ละแถว (fo.follower_user_id)
+var followerVisibleSQL = strings.ReplaceAll(VisibleToViewerSQL, "$1", "fo.follower_user_id")
+
+// ListFollowersWhoCanView ผู้ติดตามของผู้เขียนที่มองเห็นโพสต์ $1 (ใช้แจ้งเตือนโพสต์ใหม่)
+func (r *postRepository) ListFollowersWhoCanView(postID, limit int) ([]int, error) {
+	rows, err := r.db.Query(`
+		SELECT fo.follower_user_id
+		FROM posts p
+		JOIN follows fo ON fo.followed_user_id = p.post_author_user_id
+		WHERE p.post_id = $1
+		  AND fo.follower_user_id <> p.post_author_user_id
+		  AND `+followerVisibleSQL+`
+		ORDER BY fo.follower_user_id
+		LIMIT $2
+	`, postID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list followers who can view: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
 		}
-		if avatarURL.Valid {
-			p.AvatarURL = &avatarURL.String
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// personalFeedCTE ให้คะแนนโพสต์ที่มองเห็นได้ ณ เวลา $2 (ตัวแปรอื่นดู GetPersonalFeed)
+// ไลก์/บันทึกและ recommendation ที่เกิดหลัง $2 ไม่นับ คะแนนจึงไม่ขยับระหว่างเลื่อนหน้าเมื่อผู้ใช้กดไลก์
+const personalFeedCTE = `
+	WITH scored AS (
+		SELECT p.post_id,
+			(
+				1.0
+				+ CASE
+					WHEN p.post_author_user_id = $1 THEN $6::float8
+					WHEN EXISTS (
+						SELECT 1 FROM friendships fr
+						WHERE fr.user_id = LEAST(p.post_author_user_id, $1)
+						  AND fr.friend_id = GREATEST(p.post_author_user_id, $1)
+					) THEN $7::float8
+					WHEN EXISTS (
+						SELECT 1 FROM follows fo
+						WHERE fo.follower_user_id = $1 AND fo.followed_user_id = p.post_author_user_id
+					) THEN $8::float8
+					ELSE 0
+				  END
+				+ CASE
+					WHEN EXISTS (
+						SELECT 1 FROM post_tags fpt
+						JOIN tag_follows tf ON tf.tag_follow_tag_id = fpt.post_tag_tag_id
+						WHERE fpt.post_tag_post_id = p.post_id AND tf.tag_follow_user_id = $1
+					) THEN $13::float8
+					ELSE 0
+				  END
+				+ $9::float8 * COALESCE(rec.score, 0)
+				+ $10::float8 * LN(1
+					+ GREATEST(COALESCE(ps.post_like_count, 0) - COALESCE(nl.n, 0), 0)
+					+ 2 * GREATEST(COALESCE(ps.post_save_count, 0) - COALESCE(ns.n, 0), 0))
+			) * POWER(0.5, GREATEST(EXTRACT(EPOCH FROM ($2::timestamptz - p.post_created_at)), 0) / 3600.0 / $11::float8)
+			AS score
+		FROM posts p
+		LEFT JOIN post_stats ps ON ps.post_stats_post_id = p.post_id
+		LEFT JOIN (
+			SELECT like_post_id AS post_id, COUNT(*) AS n
+			FROM likes WHERE like_created_at > $2::timestamptz
+			GROUP BY like_post_id
+		) nl ON nl.post_id = p.post_id
+		LEFT JOIN (
+			SELECT save_post_id AS post_id, COUNT(*) AS n
+			FROM saved_posts WHERE save_created_at > $2::timestamptz
+			GROUP BY save_post_id
+		) ns ON ns.post_id = p.post_id
+		LEFT JOIN recommendations rec ON rec.rec_user_id = $1 AND rec.rec_post_id = p.post_id
+			AND rec.created_at <= $2::timestamptz
+		WHERE ` + VisibleToViewerSQL + `
+		  AND p.post_created_at <= $2::timestamptz
+		  AND (p.post_created_at >= $2::timestamptz - make_interval(days => $12::int) OR rec.rec_post_id IS NOT NULL)
+	),
+	ranked AS (
+		SELECT post_id, score
+		FROM scored
+		WHERE ($3::float8 IS NULL OR (score, post_id) < ($3::float8, $4::int))
+		  AND NOT (post_id = ANY($14::int[]))
+		ORDER BY score DESC, post_id DESC
+		LIMIT $5
+	)`
+
+// GetPersonalFeed ฟีดจัดอันดับ: เพื่อน/คนที่ติดตามมาก่อน แล้วผสมโพสต์ที่แนะนำและโพสต์ยอดนิยม
+// โพสต์ที่สร้างหลัง asOf จะไม่อยู่ในชุดนี้ และคะแนนนับ engagement/recommendation ถึง asOf เท่านั้น
+// ยังไม่นิ่ง 100%: unlike/ยกเลิกบันทึก หรือ recommendation ที่ถูกคำนวณใหม่หลัง asOf (แถวเดิมถูกแทน)
+// ทำให้คะแนนเปลี่ยนได้ โพสต์ใน cursor.Seen (ส่งไปแล้วในหน้าก่อน) จึงถูกตัดออก
+func (r *postRepository) GetPersonalFeed(viewerID int, rank models.FeedRanking, asOf time.Time, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+	query := personalFeedCTE + viewerPostSelect + `,
+		rk.score
+	FROM ranked rk
+	JOIN posts p ON p.post_id = rk.post_id` + viewerPostJoins + `
+	GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, ps.post_comment_count,
+			 d.document_url, d.document_name, p.post_cover_url, up.avatar_url, p.post_cover_thumb_url, up.avatar_thumb_url, rk.score
+	ORDER BY rk.score DESC, p.post_id DESC`
+
+	var cs, cid any
+	seen := []int{}
+	if cursor != nil && cursor.Score != nil {
+		cs, cid = *cursor.Score, cursor.PostID
+		seen = append(seen, cursor.Seen...)
+	}
+
+	rows, err := r.db.Query(query,
+		viewerID, asOf, cs, cid, limit,
+		rank.OwnWeight, rank.FriendWeight, rank.FollowWeight,
+		rank.RecommendWeight, rank.EngagementWeight, rank.HalfLifeHours, rank.WindowDays,
+		rank.TagWeight, pq.Array(seen),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get personal feed: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []models.PostResponse
+	for rows.Next() {
+		var score float64
+		p, err := scanViewerPost(rows, &score)
+		if err != nil {
+			return nil, fmt.Errorf("scan personal feed: %w", err)
 		}
-		p.IsLiked = isLiked
-		p.IsSaved = isSaved
-		p.Tags = []string(tags)
+		p.FeedScore = &score
 		posts = append(posts, p)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
 
+// GetSavedPosts โพสต์ที่ userID บันทึกไว้ (ที่ยังมองเห็นได้) แบ่งหน้าแบบเดียวกับฟีด
+func (r *postRepository) GetSavedPosts(userID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+	query := viewerPostSelect + `
+	FROM saved_posts sp
+	JOIN posts p ON p.post_id = sp.save_post_id` + viewerPostJoins + `
+	WHERE sp.save_user_id = $1
+	  AND ` + VisibleToViewerSQL + keysetPageSQL
+
+	t, id := cursorArgs(cursor)
+	posts, err := r.queryViewerPosts(query, userID, t, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get saved posts: %w", err)
+	}
+	return posts, nil
+}
+
+// GetUserPosts โพสต์ของ authorID ที่ viewer มองเห็น (หน้าโปรไฟล์)
+func (r *postRepository) GetUserPosts(viewerID, authorID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+	query := viewerPostSelect + `
+	FROM posts p` + viewerPostJoins + `
+	WHERE p.post_author_user_id = $5
+	  AND ` + VisibleToViewerSQL + keysetPageSQL
+
+	t, id := cursorArgs(cursor)
+	posts, err := r.queryViewerPosts(query, viewerID, t, id, limit, authorID)
+	if err != nil {
+		return nil, fmt.Errorf("get user posts: %w", err)
+	}
+	return posts, nil
+}
+
+// GetTagPosts โพสต์ที่มีแท็ก tagName และ viewer มองเห็น (ใช้ EXISTS เพื่อให้ tags ในผลลัพธ์ครบทุกแท็ก)
+func (r *postRepository) GetTagPosts(viewerID int, tagName string, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+	query := viewerPostSelect + `
+	FROM posts p` + viewerPostJoins + `
+	WHERE EXISTS (
+		SELECT 1 FROM post_tags xpt
+		JOIN tags xt ON xt.tag_id = xpt.post_tag_tag_id
+		WHERE xpt.post_tag_post_id = p.post_id AND xt.tag_name = $5
+	)
+	  AND ` + VisibleToViewerSQL + keysetPageSQL
+
+	t, id := cursorArgs(cursor)
+	posts, err := r.queryViewerPosts(query, viewerID, t, id, limit, tagName)
+	if err != nil {
+		return nil, fmt.Errorf("get tag posts: %w", err)
+	}
+	return posts, nil
+}
+
+// GetCollectionPosts โพสต์ในคอลเลกชันที่ viewer มองเห็น เรียงตาม (item_position, post_id)
+// การมองเห็นของตัวคอลเลกชันต้องเช็คก่อนเรียก
+func (r *postRepository) GetCollectionPosts(viewerID, collectionID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+	query := viewerPostSelect + `,
+		ci.item_position
+	FROM collection_items ci
+	JOIN posts p ON p.post_id = ci.item_post_id` + viewerPostJoins + `
+	WHERE ci.item_collection_id = $2
+	  AND ` + VisibleToViewerSQL + `
+	  AND ($3::int IS NULL OR (ci.item_position, ci.item_post_id) > ($3::int, $4::int))
+	GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, ps.post_comment_count,
+			 d.document_url, d.document_name, p.post_cover_url, up.avatar_url, p.post_cover_thumb_url, up.avatar_thumb_url,
+			 ci.item_position, ci.item_post_id
+	ORDER BY ci.item_position ASC, ci.item_post_id ASC
+	LIMIT $5`
+
+	var pos, id any
+	if cursor != nil && cursor.Position != nil {
+		pos, id = *cursor.Position, cursor.PostID
+	}
+
+	rows, err := r.db.Query(query, viewerID, collectionID, pos, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get collection posts: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []models.PostResponse
+	for rows.Next() {
+		var position int
+		p, err := scanViewerPost(rows, &position)
+		if err != nil {
+			return nil, fmt.Errorf("scan collection posts: %w", err)
+		}
+		p.CollectionPosition = &position
+		posts = append(posts, p)
+	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return posts, nil
 }
 
-func (r *postRepository) GetPopularPosts(viewerID, limit int) ([]models.PostResponse, error) {
+// RefreshTrending คำนวณคะแนน trending ของช่วง window ใหม่ทั้งหมดแทนที่ของเดิม (since = nil คือไม่จำกัดช่วง)
+// like/save ที่ไม่มีเวลาไม่นับ; exponent ถูก clamp ไว้กัน POWER underflow
+// หลาย instance: ถือ advisory lock ของ window ไว้จน commit ใครไม่ได้ lock คืน ErrTrendingBusy
+func (r *postRepository) RefreshTrending(window string, since *time.Time, cfg models.TrendingConfig) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRow(`SELECT pg_try_advisory_xact_lock(hashtext('post_trending:' || $1))`, window).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("lock trending: %w", err)
+	}
+	if !locked {
+		return 0, models.ErrTrendingBusy
+	}
+
+	if _, err := tx.Exec(`DELETE FROM post_trending WHERE trending_window = $1`, window); err != nil {
+		return 0, fmt.Errorf("clear trending: %w", err)
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO post_trending (trending_post_id, trending_window, trending_score,
+			trending_like_count, trending_save_count, trending_computed_at)
+		SELECT e.post_id, $1,
+			SUM(e.weight * POWER(0.5, LEAST(
+				GREATEST(EXTRACT(EPOCH FROM (now() - e.acted_at)) / 3600.0, 0) / $3::float8, 1000))),
+			COUNT(*) FILTER (WHERE e.is_save = false),
+			COUNT(*) FILTER (WHERE e.is_save),
+			now()
+		FROM (
+			SELECT l.like_post_id AS post_id, l.like_created_at AS acted_at, 1.0::float8 AS weight, false AS is_save
+			FROM likes l
+			WHERE l.like_created_at IS NOT NULL
+			  AND ($2::timestamptz IS NULL OR l.like_created_at >= $2)
+			UNION ALL
+			SELECT sp.save_post_id, sp.save_created_at, $4::float8, true
+			FROM saved_posts sp
+			WHERE sp.save_created_at IS NOT NULL
+			  AND ($2::timestamptz IS NULL OR sp.save_created_at >= $2)
+		) e
+		GROUP BY e.post_id
+	`, window, since, cfg.HalfLifeHours, cfg.SaveWeight)
+	if err != nil {
+		return 0, fmt.Errorf("compute trending: %w", err)
+	}
+	n, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// GetPopularPosts โพสต์สาธารณะเรียงตามคะแนน trending ที่คำนวณไว้ของช่วง window
+// โพสต์ที่ยังไม่มีคะแนน (refresher ยังไม่รัน/ไม่มี like ในช่วงนี้) ต่อท้ายตาม like+save รวม แล้วใหม่ก่อน
+func (r *postRepository) GetPopularPosts(viewerID int, window string, limit int) ([]models.PostResponse, error) {
 	query := `
+		WITH ranked AS (
+			SELECT p.post_id, tr.trending_score,
+				COALESCE(ps.post_like_count, 0) + COALESCE(ps.post_save_count, 0) AS engagement
+			FROM posts p
+			LEFT JOIN post_trending tr
+				ON tr.trending_post_id = p.post_id AND tr.trending_window = $2
+			LEFT JOIN post_stats ps ON ps.post_stats_post_id = p.post_id
+			WHERE p.post_visibility = 'public'
+			  AND p.post_status = 'published'
+			ORDER BY tr.trending_score DESC NULLS LAST, engagement DESC, p.post_created_at DESC, p.post_id DESC
+			LIMIT $3
+		)
 		SELECT p.post_id, p.post_author_user_id, u.username AS author_name,
 			p.post_title, p.post_description, p.post_visibility,
 			p.post_document_id, p.post_created_at, p.post_updated_at,
 			COALESCE(ps.post_like_count, 0) AS post_like_count,
 			COALESCE(ps.post_save_count, 0) AS post_save_count,
-			d.document_url AS document_file_url,
+			COALESCE(ps.post_comment_count, 0) AS post_comment_count,
+			('/api/v1/files/' || p.post_document_id || '/download') AS document_file_url,
 			d.document_name AS document_name,
-			p.post_cover_url, up.avatar_url,
+			COALESCE(p.post_cover_thumb_url, p.post_cover_url), COALESCE(up.avatar_thumb_url, up.avatar_url),
 			ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags,
 
 			-- สถานะของผู้ชม (คนที่ล็อกอิน)
@@ -629,24 +1083,25 @@ func (r *postRepository) GetPopularPosts(viewerID, limit int) ([]models.PostResp
 			EXISTS (
 				SELECT 1 FROM saved_posts sp
 				WHERE sp.save_user_id = $1 AND sp.save_post_id = p.post_id
-			) AS is_saved
+			) AS is_saved,
+			rk.trending_score
 
-		FROM posts p
+		FROM ranked rk
+		JOIN posts p ON p.post_id = rk.post_id
 		JOIN users u ON u.user_id = p.post_author_user_id
 		LEFT JOIN post_stats ps ON ps.post_stats_post_id = p.post_id
 		LEFT JOIN post_tags pt ON pt.post_tag_post_id = p.post_id
 		LEFT JOIN tags t ON t.tag_id = pt.post_tag_tag_id
 		LEFT JOIN documents d ON d.document_id = p.post_document_id
 		LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
-		WHERE p.post_visibility = 'public'
-		GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count,
-				 d.document_url, d.document_name, p.post_cover_url, up.avatar_url
+		GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, ps.post_comment_count,
+				 d.document_url, d.document_name, p.post_cover_url, up.avatar_url, p.post_cover_thumb_url, up.avatar_thumb_url,
+				 rk.trending_score, rk.engagement
 
-		ORDER BY COALESCE(ps.post_like_count, 0) DESC, p.post_created_at DESC
-		LIMIT $2;
+		ORDER BY rk.trending_score DESC NULLS LAST, rk.engagement DESC, p.post_created_at DESC, p.post_id DESC;
 	`
 
-	rows, err := r.db.Query(query, viewerID, limit)
+	rows, err := r.db.Query(query, viewerID, window, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -664,15 +1119,16 @@ func (r *postRepository) GetPopularPosts(viewerID, limit int) ([]models.PostResp
 			docID     sql.NullInt64
 			isLiked   bool
 			isSaved   bool
+			score     sql.NullFloat64
 		)
 
 		if err := rows.Scan(
 			&p.PostID, &p.AuthorID, &p.AuthorName,
 			&p.Title, &p.Description, &p.Visibility,
 			&docID, &p.CreatedAt, &p.UpdatedAt,
-			&p.LikeCount, &p.SaveCount,
+			&p.LikeCount, &p.SaveCount, &p.CommentCount,
 			&fileURL, &docName, &coverURL, &avatarURL, &tags,
-			&isLiked, &isSaved,
+			&isLiked, &isSaved, &score,
 		); err != nil {
 			return nil, err
 		}
@@ -697,6 +1153,9 @@ func (r *postRepository) GetPopularPosts(viewerID, limit int) ([]models.PostResp
 		p.Tags = []string(tags)
 		p.IsLiked = isLiked
 		p.IsSaved = isSaved
+		if score.Valid {
+			p.TrendingScore = &score.Float64
+		}
 
 		posts = append(posts, p)
 	}
@@ -724,20 +1183,7 @@ func (r *postRepository) SearchPosts(viewerID int, search string, page, size int
 		SELECT COUNT(DISTINCT p.post_id)
 		FROM posts p
 		WHERE
-			(
-				p.post_author_user_id = $1
-				OR p.post_visibility = 'public'
-				OR (
-					p.post_visibility = 'friends'
-					AND EXISTS (
-						SELECT 1
-						FROM friendships f
-						WHERE
-							f.user_id = LEAST(p.post_author_user_id, $1)
-							AND f.friend_id = GREATEST(p.post_author_user_id, $1)
-					)
-				)
-			)
+			` + VisibleToViewerSQL + `
 			AND (
 				$2 = ''
 				OR p.post_title ILIKE $3
@@ -763,9 +1209,10 @@ func (r *postRepository) SearchPosts(viewerID int, search string, page, size int
 			p.post_document_id, p.post_created_at, p.post_updated_at,
 			COALESCE(ps.post_like_count, 0) AS post_like_count,
 			COALESCE(ps.post_save_count, 0) AS post_save_count,
-			d.document_url AS document_file_url,
+			COALESCE(ps.post_comment_count, 0) AS post_comment_count,
+			('/api/v1/files/' || p.post_document_id || '/download') AS document_file_url,
 			d.document_name AS document_name,
-			p.post_cover_url, up.avatar_url,
+			COALESCE(p.post_cover_thumb_url, p.post_cover_url), COALESCE(up.avatar_thumb_url, up.avatar_url),
 			ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags,
 
 			EXISTS (
@@ -786,20 +1233,7 @@ func (r *postRepository) SearchPosts(viewerID int, search string, page, size int
 		LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
 
 		WHERE
-			(
-				p.post_author_user_id = $1
-				OR p.post_visibility = 'public'
-				OR (
-					p.post_visibility = 'friends'
-					AND EXISTS (
-						SELECT 1
-						FROM friendships f
-						WHERE
-							f.user_id = LEAST(p.post_author_user_id, $1)
-							AND f.friend_id = GREATEST(p.post_author_user_id, $1)
-					)
-				)
-			)
+			` + VisibleToViewerSQL + `
 			AND (
 				$2 = ''
 				OR p.post_title ILIKE $3
@@ -812,8 +1246,8 @@ func (r *postRepository) SearchPosts(viewerID int, search string, page, size int
 				)
 			)
 
-		GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count,
-				 d.document_url, d.document_name, p.post_cover_url, up.avatar_url
+		GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, ps.post_comment_count,
+				 d.document_url, d.document_name, p.post_cover_url, up.avatar_url, p.post_cover_thumb_url, up.avatar_thumb_url
 		ORDER BY p.post_created_at DESC
 		LIMIT $4 OFFSET $5;
 	`
@@ -842,7 +1276,7 @@ func (r *postRepository) SearchPosts(viewerID int, search string, page, size int
 			&p.PostID, &p.AuthorID, &p.AuthorName,
 			&p.Title, &p.Description, &p.Visibility,
 			&docID, &p.CreatedAt, &p.UpdatedAt,
-			&p.LikeCount, &p.SaveCount,
+			&p.LikeCount, &p.SaveCount, &p.CommentCount,
 			&fileURL, &docName, &coverURL, &avatarURL, &tags,
 			&isLiked, &isSaved,
 		); err != nil {
@@ -879,3 +1313,283 @@ func (r *postRepository) SearchPosts(viewerID int, search string, page, size int
 
 	return posts, total, nil
 }
+
+// rowScanner ใช้ได้ทั้ง *sql.Row และ *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanViewerPost อ่านคอลัมน์ชุดเดียวกับ GetFeedPosts (รวม is_liked/is_saved) แล้วตามด้วยคอลัมน์เพิ่มเติมใน extra
+func scanViewerPost(s rowScanner, extra ...any) (models.PostResponse, error) {
+	var (
+		p         models.PostResponse
+		tags      pq.StringArray
+		fileURL   sql.NullString
+		docName   sql.NullString
+		coverURL  sql.NullString
+		avatarURL sql.NullString
+		docID     sql.NullInt64
+	)
+
+	dest := []any{
+		&p.PostID, &p.AuthorID, &p.AuthorName,
+		&p.Title, &p.Description, &p.Visibility,
+		&docID, &p.CreatedAt, &p.UpdatedAt,
+		&p.LikeCount, &p.SaveCount, &p.CommentCount,
+		&fileURL, &docName, &coverURL, &avatarURL, &tags,
+		&p.IsLiked, &p.IsSaved,
+	}
+	dest = append(dest, extra...)
+
+	if err := s.Scan(dest...); err != nil {
+		return p, err
+	}
+
+	if docID.Valid {
+		v := int(docID.Int64)
+		p.DocumentID = &v
+	}
+	if fileURL.Valid {
+		p.FileURL = &fileURL.String
+	}
+	if docName.Valid {
+		p.DocumentName = &docName.String
+	}
+	if coverURL.Valid {
+		p.CoverURL = &coverURL.String
+	}
+	if avatarURL.Valid {
+		p.AvatarURL = &avatarURL.String
+	}
+	p.Tags = []string(tags)
+	return p, nil
+}
+
+func f64ToF32(a []float64) []float32 {
+	out := make([]float32, len(a))
+	for i, v := range a {
+		out[i] = float32(v)
+	}
+	return out
+}
+
+// ค้นหาตามความหมายของเนื้อหาเอกสาร (cosine distance บน content_embedding)
+func (r *postRepository) SearchPostsSemantic(viewerID int, embedding []float64, maxDistance float64, page, size int) ([]models.PostResponse, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size <= 0 || size > 100 {
+		size = 20
+	}
+	offset := (page - 1) * size
+	qvec := pgvector.NewVector(f64ToF32(embedding))
+
+	countQ := `
+		SELECT COUNT(*)
+		FROM posts p
+		JOIN documents fd ON fd.document_id = p.post_document_id
+		JOIN document_features df ON df.document_id = COALESCE(fd.canonical_document_id, fd.document_id)
+		WHERE df.content_embedding IS NOT NULL
+			AND ` + VisibleToViewerSQL + `
+			AND (df.content_embedding <=> $2) <= $3;
+	`
+
+	var total int
+	if err := r.db.QueryRow(countQ, viewerID, qvec, maxDistance).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count semantic search: %w", err)
+	}
+
+	listQ := `
+		WITH ranked AS (
+			SELECT p.post_id, (df.content_embedding <=> $2) AS distance
+			FROM posts p
+			JOIN documents fd ON fd.document_id = p.post_document_id
+			JOIN document_features df ON df.document_id = COALESCE(fd.canonical_document_id, fd.document_id)
+			WHERE df.content_embedding IS NOT NULL
+				AND ` + VisibleToViewerSQL + `
+				AND (df.content_embedding <=> $2) <= $3
+			ORDER BY distance ASC, p.post_id DESC
+			LIMIT $4 OFFSET $5
+		)
+		SELECT p.post_id, p.post_author_user_id, u.username AS author_name,
+			p.post_title, p.post_description, p.post_visibility,
+			p.post_document_id, p.post_created_at, p.post_updated_at,
+			COALESCE(ps.post_like_count, 0) AS post_like_count,
+			COALESCE(ps.post_save_count, 0) AS post_save_count,
+			COALESCE(ps.post_comment_count, 0) AS post_comment_count,
+			('/api/v1/files/' || p.post_document_id || '/download') AS document_file_url,
+			d.document_name AS document_name,
+			COALESCE(p.post_cover_thumb_url, p.post_cover_url), COALESCE(up.avatar_thumb_url, up.avatar_url),
+			ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags,
+
+			EXISTS (
+				SELECT 1 FROM likes l
+				WHERE l.like_user_id = $1 AND l.like_post_id = p.post_id
+			) AS is_liked,
+			EXISTS (
+				SELECT 1 FROM saved_posts sp
+				WHERE sp.save_user_id = $1 AND sp.save_post_id = p.post_id
+			) AS is_saved,
+
+			1 - rk.distance AS search_score
+
+		FROM ranked rk
+		JOIN posts p ON p.post_id = rk.post_id
+		JOIN users u ON u.user_id = p.post_author_user_id
+		LEFT JOIN post_stats ps ON ps.post_stats_post_id = p.post_id
+		LEFT JOIN post_tags pt ON pt.post_tag_post_id = p.post_id
+		LEFT JOIN tags t ON t.tag_id = pt.post_tag_tag_id
+		LEFT JOIN documents d ON d.document_id = p.post_document_id
+		LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
+		GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, ps.post_comment_count,
+				 d.document_url, d.document_name, p.post_cover_url, up.avatar_url, p.post_cover_thumb_url, up.avatar_thumb_url, rk.distance
+		ORDER BY rk.distance ASC, p.post_id DESC;
+	`
+
+	rows, err := r.db.Query(listQ, viewerID, qvec, maxDistance, size, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("semantic search: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []models.PostResponse
+	for rows.Next() {
+		var score float64
+		p, err := scanViewerPost(rows, &score)
+		if err != nil {
+			return nil, 0, err
+		}
+		p.SearchScore = &score
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// hybridSearchCTE ให้คะแนนรวมจาก ILIKE (title/tag) กับความใกล้เคียงของ embedding
+// $1 viewer, $2 pattern, $3 query vector, $4 น้ำหนัก keyword, $5 similarity ขั้นต่ำ
+const hybridSearchCTE = `
+	WITH scored AS (
+		SELECT p.post_id,
+			CASE WHEN p.post_title ILIKE $2
+				OR EXISTS (
+					SELECT 1
+					FROM post_tags pt2
+					JOIN tags t2 ON t2.tag_id = pt2.post_tag_tag_id
+					WHERE pt2.post_tag_post_id = p.post_id
+					  AND t2.tag_name ILIKE $2
+				)
+			THEN 1.0 ELSE 0.0 END AS keyword_score,
+			COALESCE(1 - (df.content_embedding <=> $3), 0) AS semantic_score
+		FROM posts p
+		LEFT JOIN documents fd ON fd.document_id = p.post_document_id
+		LEFT JOIN document_features df ON df.document_id = COALESCE(fd.canonical_document_id, fd.document_id)
+		WHERE ` + VisibleToViewerSQL + `
+	),
+	matched AS (
+		SELECT post_id, ($4 * keyword_score + (1 - $4) * semantic_score) AS score
+		FROM scored
+		WHERE keyword_score > 0 OR semantic_score >= $5
+	)
+`
+
+func (r *postRepository) SearchPostsHybrid(viewerID int, search string, embedding []float64, keywordWeight, minSimilarity float64, page, size int) ([]models.PostResponse, int, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size <= 0 || size > 100 {
+		size = 20
+	}
+	offset := (page - 1) * size
+	pattern := "%" + strings.TrimSpace(search) + "%"
+	qvec := pgvector.NewVector(f64ToF32(embedding))
+
+	var total int
+	if err := r.db.QueryRow(hybridSearchCTE+`SELECT COUNT(*) FROM matched;`,
+		viewerID, pattern, qvec, keywordWeight, minSimilarity).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count hybrid search: %w", err)
+	}
+
+	listQ := hybridSearchCTE + `,
+	ranked AS (
+		SELECT post_id, score
+		FROM matched
+		ORDER BY score DESC, post_id DESC
+		LIMIT $6 OFFSET $7
+	)
+	SELECT p.post_id, p.post_author_user_id, u.username AS author_name,
+		p.post_title, p.post_description, p.post_visibility,
+		p.post_document_id, p.post_created_at, p.post_updated_at,
+		COALESCE(ps.post_like_count, 0) AS post_like_count,
+		COALESCE(ps.post_save_count, 0) AS post_save_count,
+		COALESCE(ps.post_comment_count, 0) AS post_comment_count,
+		('/api/v1/files/' || p.post_document_id || '/download') AS document_file_url,
+		d.document_name AS document_name,
+		COALESCE(p.post_cover_thumb_url, p.post_cover_url), COALESCE(up.avatar_thumb_url, up.avatar_url),
+		ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags,
+
+		EXISTS (
+			SELECT 1 FROM likes l
+			WHERE l.like_user_id = $1 AND l.like_post_id = p.post_id
+		) AS is_liked,
+		EXISTS (
+			SELECT 1 FROM saved_posts sp
+			WHERE sp.save_user_id = $1 AND sp.save_post_id = p.post_id
+		) AS is_saved,
+
+		rk.score AS search_score
+
+	FROM ranked rk
+	JOIN posts p ON p.post_id = rk.post_id
+	JOIN users u ON u.user_id = p.post_author_user_id
+	LEFT JOIN post_stats ps ON ps.post_stats_post_id = p.post_id
+	LEFT JOIN post_tags pt ON pt.post_tag_post_id = p.post_id
+	LEFT JOIN tags t ON t.tag_id = pt.post_tag_tag_id
+	LEFT JOIN documents d ON d.document_id = p.post_document_id
+	LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
+	GROUP BY p.post_id, u.username, ps.post_like_count, ps.post_save_count, ps.post_comment_count,
+			 d.document_url, d.document_name, p.post_cover_url, up.avatar_url, p.post_cover_thumb_url, up.avatar_thumb_url, rk.score
+	ORDER BY rk.score DESC, p.post_id DESC;
+	`
+
+	rows, err := r.db.Query(listQ, viewerID, pattern, qvec, keywordWeight, minSimilarity, size, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("hybrid search: %w", err)
+	}
+	defer rows.Close()
+
+	var posts []models.PostResponse
+	for rows.Next() {
+		var score float64
+		p, err := scanViewerPost(rows, &score)
+		if err != nil {
+			return nil, 0, err
+		}
+		p.SearchScore = &score
+		posts = append(posts, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+func (r *postRepository) ListPostIDsByDocumentID(documentID int) ([]int, error) {
+	rows, err := r.db.Query(`SELECT post_id FROM posts WHERE post_document_id = $1 ORDER BY post_id`, documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -58,9 +58,8 @@ func (r *saveRepository) IsPostSaved(userID, postID int) (bool, error) {
 	return saved, nil
 }
 
-// อัปเดตจำนวนบันทึกใน post_stat
-func (r *saveRepository) UpdateSaveCount(postID int) error {
-	query := `
+// upsertSaveCountSQL นับ saved_posts ของโพสต์ $1 ใหม่ลง post_stats (ใช้ร่วมกับ collection repo)
+const upsertSaveCountSQL = `
 		INSERT INTO post_stats (post_stats_post_id, post_save_count, post_last_activity_at)
 		VALUES (
 			$1,
@@ -70,9 +69,11 @@ func (r *saveRepository) UpdateSaveCount(postID int) error {
 		ON CONFLICT (post_stats_post_id)
 		DO UPDATE SET
 			post_save_count       = EXCLUDED.post_save_count,
-			post_last_activity_at = EXCLUDED.post_last_activity_at;
-	`
-	_, err := r.db.Exec(query, postID)
+			post_last_activity_at = EXCLUDED.post_last_activity_at`
+
+// อัปเดตจำนวนบันทึกใน post_stat
+func (r *saveRepository) UpdateSaveCount(postID int) error {
+	_, err := r.db.Exec(upsertSaveCountSQL, postID)
 	return err
 }
 
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"chaladshare_backend/internal/posts/models"
+)
+
+type TagRepository interface {
+	// SuggestTags แท็กที่ขึ้นต้นด้วย prefix เรียงตามจำนวนโพสต์ที่ viewer มองเห็น
+	SuggestTags(viewerID int, prefix string, limit int) ([]models.TagSummary, error)
+	GetTag(viewerID int, name string) (*models.TagDetail, error)
+
+	FollowTag(userID int, name string) (*models.FollowedTag, error)
+	UnfollowTag(userID int, name string) error
+	ListFollowedTags(userID int) ([]models.FollowedTag, error)
+}
+
+type tagRepository struct {
+	db *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+// likeEscaper กัน _ และ % ใน prefix ไม่ให้เป็น wildcard
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *tagRepository) SuggestTags(viewerID int, prefix string, limit int) ([]models.TagSummary, error) {
+	query := `
+	SELECT t.tag_id, t.tag_name, COUNT(p.post_id) AS post_count
+	FROM tags t
+	JOIN post_tags pt ON pt.post_tag_tag_id = t.tag_id
+	JOIN posts p ON p.post_id = pt.post_tag_post_id
+	WHERE t.tag_name LIKE $2 || '%'
+	  AND ` + VisibleToViewerSQL + `
+	GROUP BY t.tag_id, t.tag_name
+	ORDER BY post_count DESC, t.tag_name ASC
+	LIMIT $3`
+
+	rows, err := r.db.Query(query, viewerID, likeEscaper.Replace(prefix), limit)
+	if err != nil {
+		return nil, fmt.Errorf("suggest tags: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.TagSummary
+	for rows.Next() {
+		var t models.TagSummary
+		if err := rows.Scan(&t.TagID, &t.TagName, &t.PostCount); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (r *tagRepository) GetTag(viewerID int, name string) (*models.TagDetail, error) {
+	query := `
+	SELECT t.tag_id, t.tag_name,
+		(
+			SELECT COUNT(*)
+			FROM post_tags pt
+			JOIN posts p ON p.post_id = pt.post_tag_post_id
+			WHERE pt.post_tag_tag_id = t.tag_id
+			  AND ` + VisibleToViewerSQL + `
+		) AS post_count,
+		(SELECT COUNT(*) FROM tag_follows tf WHERE tf.tag_follow_tag_id = t.tag_id) AS follower_count,
+		EXISTS (
+			SELECT 1 FROM tag_follows tf
+			WHERE tf.tag_follow_tag_id = t.tag_id AND tf.tag_follow_user_id = $1
+		) AS is_following
+	FROM tags t
+	WHERE t.tag_name = $2`
+
+	var d models.TagDetail
+	err := r.db.QueryRow(query, viewerID, name).Scan(
+		&d.TagID, &d.TagName, &d.PostCount, &d.FollowerCount, &d.IsFollowing,
+	)
+	if err == sql.ErrNoRows {
+		return nil, models.ErrTagNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get tag: %w", err)
+	}
+	return &d, nil
+}
+
+// FollowTag สร้างแท็กถ้ายังไม่มี (ติดตามไว้ก่อนมีโพสต์ได้) กดซ้ำได้
+func (r *tagRepository) FollowTag(userID int, name string) (*models.FollowedTag, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var f models.FollowedTag
+	if err := tx.QueryRow(`
+		INSERT INTO tags (tag_name) VALUES ($1)
+		ON CONFLICT (tag_name) DO UPDATE SET tag_name = EXCLUDED.tag_name
+		RETURNING tag_id, tag_name
+	`, name).Scan(&f.TagID, &f.TagName); err != nil {
+		return nil, fmt.Errorf("upsert tag %q: %w", name, err)
+	}
+
+	if err := tx.QueryRow(`
+		INSERT INTO tag_follows (tag_follow_user_id, tag_follow_tag_id)
+		VALUES ($1, $2)
+		ON CONFLICT (tag_follow_user_id, tag_follow_tag_id)
+		DO UPDATE SET tag_followed_at = tag_follows.tag_followed_at
+		RETURNING tag_followed_at
+	`, userID, f.TagID).Scan(&f.FollowedAt); err != nil {
+		return nil, fmt.Errorf("follow tag %q: %w", name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// UnfollowTag เลิกติดตาม (ไม่ได้ติดตามอยู่ก็ไม่ถือว่าผิด)
+func (r *tagRepository) UnfollowTag(userID int, name string) error {
+	_, err := r.db.Exec(`
+		DELETE FROM tag_follows tf
+		USING tags t
+		WHERE t.tag_id = tf.tag_follow_tag_id
+		  AND tf.tag_follow_user_id = $1
+		  AND t.tag_name = $2
+	`, userID, name)
+	if err != nil {
+		return fmt.Errorf("unfollow tag %q: %w", name, err)
+	}
+	return nil
+}
+
+func (r *tagRepository) ListFollowedTags(userID int) ([]models.FollowedTag, error) {
+	rows, err := r.db.Query(`
+		SELECT t.tag_id, t.tag_name, tf.tag_followed_at
+		FROM tag_follows tf
+		JOIN tags t ON t.tag_id = tf.tag_follow_tag_id
+		WHERE tf.tag_follow_user_id = $1
+		ORDER BY t.tag_name ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list followed tags: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.FollowedTag
+	for rows.Next() {
+		var f models.FollowedTag
+		if err := rows.Scan(&f.TagID, &f.TagName, &f.FollowedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
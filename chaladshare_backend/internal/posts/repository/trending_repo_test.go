@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"chaladshare_backend/internal/posts/models"
+)
+
+// ต้องมี Postgres ที่รัน chaladshare_database/docker/init.sql แล้ว:
+// POSTS_TEST_DATABASE_URL=postgres://... go test ./internal/posts/repository/
+func openTrendingRepo(t *testing.T) (*sql.DB, PostRepository, int) {
+	t.Helper()
+	dsn := os.Getenv("POSTS_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("POSTS_TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	name := fmt.Sprintf("trendtest%d", time.Now().UnixNano())
+	var userID int
+	if err := db.QueryRow(`
+		INSERT INTO users (email, username, password_hash) VALUES ($1, $2, 'x') RETURNING user_id
+	`, name+"@example.com", name).Scan(&userID); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	// โพสต์/like/post_trending ของผู้ใช้ทดสอบ cascade ตามไปหมด
+	t.Cleanup(func() { _, _ = db.Exec(`DELETE FROM users WHERE user_id = $1`, userID) })
+
+	return db, NewPostRepository(db), userID
+}
+
+func createPublicPost(t *testing.T, db *sql.DB, userID int, title string) int {
+	t.Helper()
+	var postID int
+	if err := db.QueryRow(`
+		INSERT INTO posts (post_author_user_id, post_title, post_visibility) VALUES ($1, $2, 'public') RETURNING post_id
+	`, userID, title).Scan(&postID); err != nil {
+		t.Fatalf("create post: %v", err)
+	}
+	return postID
+}
+
+func TestRefreshTrendingConcurrentInstances(t *testing.T) {
+	db, repo, userID := openTrendingRepo(t)
+	postID := createPublicPost(t, db, userID, "liked")
+	if _, err := db.Exec(`INSERT INTO likes (like_user_id, like_post_id) VALUES ($1, $2)`, userID, postID); err != nil {
+		t.Fatalf("like: %v", err)
+	}
+
+	// สอง instance refresh window เดียวกันพร้อมกัน: ต้องสำเร็จหรือได้ ErrTrendingBusy ไม่ชน primary key
+	cfg := models.TrendingConfig{HalfLifeHours: 24, SaveWeight: 2}
+	since := time.Now().Add(-7 * 24 * time.Hour)
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = repo.RefreshTrending(models.TrendingWeek, &since, cfg)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, models.ErrTrendingBusy) {
+			t.Fatalf("concurrent refresh: %v", err)
+		}
+	}
+
+	var score float64
+	if err := db.QueryRow(`
+		SELECT trending_score FROM post_trending WHERE trending_window = $1 AND trending_post_id = $2
+	`, models.TrendingWeek, postID).Scan(&score); err != nil {
+		t.Fatalf("trending row of liked post: %v", err)
+	}
+	if score <= 0 {
+		t.Fatalf("score = %v, want > 0", score)
+	}
+}
+
+func TestGetPopularPostsFallsBackWithoutTrendingRows(t *testing.T) {
+	db, repo, userID := openTrendingRepo(t)
+	postID := createPublicPost(t, db, userID, "not refreshed yet")
+
+	// ไม่มีแถวใน post_trending: ยังต้องเห็นโพสต์ (ไม่มีคะแนน) แทนที่จะว่าง
+	posts, err := repo.GetPopularPosts(userID, models.TrendingDay, 100000)
+	if err != nil {
+		t.Fatalf("GetPopularPosts: %v", err)
+	}
+	for _, p := range posts {
+		if p.PostID == postID {
+			if p.TrendingScore != nil {
+				t.Fatalf("trending score = %v, want nil", *p.TrendingScore)
+			}
+			return
+		}
+	}
+	t.Fatal("post without a trending row is missing from popular posts")
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"chaladshare_backend/internal/posts/models"
+	"chaladshare_backend/internal/posts/repository"
+)
+
+type CollectionService interface {
+	CreateCollection(ownerID int, req models.CreateCollectionRequest) (*models.Collection, error)
+	UpdateCollection(ownerID, collectionID int, req models.UpdateCollectionRequest) (*models.Collection, error)
+	DeleteCollection(ownerID, collectionID int) error
+	ReorderCollections(ownerID int, collectionIDs []int) ([]models.Collection, error)
+
+	GetCollection(viewerID, collectionID int) (*models.Collection, error)
+	ListCollections(viewerID, ownerID int) ([]models.Collection, error)
+	GetCollectionPosts(viewerID, collectionID int, cursor string, size int) (*models.PostPage, error)
+
+	// AddPost / SetPostCollections คืนจำนวน save ล่าสุดของโพสต์ (อาจถูกบันทึกให้อัตโนมัติ)
+	AddPost(ownerID, collectionID, postID int) (int, error)
+	RemovePost(ownerID, collectionID, postID int) error
+	ReorderPosts(ownerID, collectionID int, postIDs []int) error
+	SetPostCollections(ownerID, postID int, collectionIDs []int) ([]int, int, error)
+	ListPostCollectionIDs(ownerID, postID int) ([]int, error)
+}
+
+type collectionService struct {
+	collectionRepo repository.CollectionRepository
+	postRepo       repository.PostRepository
+	postSvc        PostService
+}
+
+func NewCollectionService(collectionRepo repository.CollectionRepository, postRepo repository.PostRepository, postSvc PostService) CollectionService {
+	return &collectionService{collectionRepo: collectionRepo, postRepo: postRepo, postSvc: postSvc}
+}
+
+func normalizeCollectionName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > models.MaxCollectionNameLength {
+		return "", fmt.Errorf("%w: name must be 1-%d characters", models.ErrInvalidCollection, models.MaxCollectionNameLength)
+	}
+	return name, nil
+}
+
+// normalizeCollectionDescription "" หลัง trim = ไม่มีคำอธิบาย
+func normalizeCollectionDescription(desc string) (string, error) {
+	desc = strings.TrimSpace(desc)
+	if utf8.RuneCountInString(desc) > models.MaxCollectionDescriptionLength {
+		return "", fmt.Errorf("%w: description is longer than %d characters", models.ErrInvalidCollection, models.MaxCollectionDescriptionLength)
+	}
+	return desc, nil
+}
+
+func normalizeCollectionVisibility(v string) (string, error) {
+	vis := strings.ToLower(strings.TrimSpace(v))
+	if vis == "" {
+		return models.CollectionPrivate, nil
+	}
+	if !models.IsValidCollectionVisibility(vis) {
+		return "", fmt.Errorf("%w: unsupported visibility %s", models.ErrInvalidCollection, v)
+	}
+	return vis, nil
+}
+
+// uniqueIDs ใช้กับรายการลำดับใหม่ ห้ามซ้ำและต้องเป็นเลขบวก
+func uniqueIDs(ids []int) error {
+	seen := make(map[int]struct{}, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			return fmt.Errorf("%w: invalid id %d", models.ErrInvalidCollection, id)
+		}
+		if _, dup := seen[id]; dup {
+			return fmt.Errorf("%w: duplicate id %d", models.ErrInvalidCollection, id)
+		}
+		seen[id] = struct{}{}
+	}
+	return nil
+}
+
+func (s *collectionService) CreateCollection(ownerID int, req models.CreateCollectionRequest) (*models.Collection, error) {
+	if ownerID <= 0 {
+		return nil, fmt.Errorf("invalid user id")
+	}
+	name, err := normalizeCollectionName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	vis, err := normalizeCollectionVisibility(req.Visibility)
+	if err != nil {
+		return nil, err
+	}
+	var desc *string
+	if req.Description != nil {
+		d, err := normalizeCollectionDescription(*req.Description)
+		if err != nil {
+			return nil, err
+		}
+		if d != "" {
+			desc = &d
+		}
+	}
+
+	id, err := s.collectionRepo.CreateCollection(ownerID, name, desc, vis)
+	if err != nil {
+		return nil, err
+	}
+	return s.collectionRepo.GetCollection(ownerID, id)
+}
+
+func (s *collectionService) UpdateCollection(ownerID, collectionID int, req models.UpdateCollectionRequest) (*models.Collection, error) {
+	if ownerID <= 0 || collectionID <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", models.ErrInvalidCollection)
+	}
+
+	var name, desc, vis *string
+	if req.Name != nil {
+		n, err := normalizeCollectionName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		name = &n
+	}
+	if req.Description != nil {
+		d, err := normalizeCollectionDescription(*req.Description)
+		if err != nil {
+			return nil, err
+		}
+		desc = &d
+	}
+	if req.Visibility != nil {
+		v, err := normalizeCollectionVisibility(*req.Visibility)
+		if err != nil {
+			return nil, err
+		}
+		vis = &v
+	}
+
+	if err := s.collectionRepo.UpdateCollection(ownerID, collectionID, name, desc, vis); err != nil {
+		return nil, err
+	}
+	return s.collectionRepo.GetCollection(ownerID, collectionID)
+}
+
+func (s *collectionService) DeleteCollection(ownerID, collectionID int) error {
+	if ownerID <= 0 || collectionID <= 0 {
+		return fmt.Errorf("%w: invalid id", models.ErrInvalidCollection)
+	}
+	return s.collectionRepo.DeleteCollection(ownerID, collectionID)
+}
+
+func (s *collectionService) ReorderCollections(ownerID int, collectionIDs []int) ([]models.Collection, error) {
+	if ownerID <= 0 {
+		return nil, fmt.Errorf("invalid user id")
+	}
+	if err := uniqueIDs(collectionIDs); err != nil {
+		return nil, err
+	}
+	if err := s.collectionRepo.ReorderCollections(ownerID, collectionIDs); err != nil {
+		return nil, err
+	}
+	return s.ListCollections(ownerID, ownerID)
+}
+
+// GetCollection คอลเลกชันที่มองไม่เห็นตอบเป็นไม่พบ
+func (s *collectionService) GetCollection(viewerID, collectionID int) (*models.Collection, error) {
+	if viewerID <= 0 || collectionID <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", models.ErrInvalidCollection)
+	}
+	return s.collectionRepo.GetCollection(viewerID, collectionID)
+}
+
+func (s *collectionService) ListCollections(viewerID, ownerID int) ([]models.Collection, error) {
+	if viewerID <= 0 || ownerID <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", models.ErrInvalidCollection)
+	}
+	items, err := s.collectionRepo.ListCollections(viewerID, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []models.Collection{}
+	}
+	return items, nil
+}
+
+func (s *collectionService) GetCollectionPosts(viewerID, collectionID int, cursor string, size int) (*models.PostPage, error) {
+	if _, err := s.GetCollection(viewerID, collectionID); err != nil {
+		return nil, err
+	}
+	c, err := DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil && c.Position == nil {
+		return nil, ErrInvalidCursor
+	}
+	return paginateFrom(c, size, collectionCursor, func(c *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+		return s.postRepo.GetCollectionPosts(viewerID, collectionID, c, limit)
+	})
+}
+
+// checkPostSavable เก็บได้เฉพาะโพสต์ที่ตัวเองมองเห็น (กติกาเดียวกับ ViewPost)
+func (s *collectionService) checkPostSavable(userID, postID int) error {
+	if postID <= 0 {
+		return fmt.Errorf("%w: invalid post id", models.ErrInvalidCollection)
+	}
+	ok, reason, err := s.postSvc.ViewPost(userID, postID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if reason == "not_found" {
+			return ErrPostNotFound
+		}
+		return ErrPostNotVisible
+	}
+	return nil
+}
+
+func (s *collectionService) AddPost(ownerID, collectionID, postID int) (int, error) {
+	if ownerID <= 0 || collectionID <= 0 {
+		return 0, fmt.Errorf("%w: invalid id", models.ErrInvalidCollection)
+	}
+	if err := s.checkPostSavable(ownerID, postID); err != nil {
+		return 0, err
+	}
+	return s.collectionRepo.AddPost(ownerID, collectionID, postID)
+}
+
+func (s *collectionService) RemovePost(ownerID, collectionID, postID int) error {
+	if ownerID <= 0 || collectionID <= 0 || postID <= 0 {
+		return fmt.Errorf("%w: invalid id", models.ErrInvalidCollection)
+	}
+	return s.collectionRepo.RemovePost(ownerID, collectionID, postID)
+}
+
+func (s *collectionService) ReorderPosts(ownerID, collectionID int, postIDs []int) error {
+	if ownerID <= 0 || collectionID <= 0 {
+		return fmt.Errorf("%w: invalid id", models.ErrInvalidCollection)
+	}
+	if err := uniqueIDs(postIDs); err != nil {
+		return err
+	}
+	return s.collectionRepo.ReorderPosts(ownerID, collectionID, postIDs)
+}
+
+func (s *collectionService) SetPostCollections(ownerID, postID int, collectionIDs []int) ([]int, int, error) {
+	if ownerID <= 0 {
+		return nil, 0, fmt.Errorf("invalid user id")
+	}
+	if err := uniqueIDs(collectionIDs); err != nil {
+		return nil, 0, err
+	}
+	if err := s.checkPostSavable(ownerID, postID); err != nil {
+		return nil, 0, err
+	}
+	// body {} / null ได้ nil ซึ่ง pq.Array ส่งเป็น NULL ทำให้ลบไม่ออก ต้องเป็น array ว่าง
+	if collectionIDs == nil {
+		collectionIDs = []int{}
+	}
+
+	count, err := s.collectionRepo.SetPostCollections(ownerID, postID, collectionIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	ids, err := s.collectionRepo.ListPostCollectionIDs(ownerID, postID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ids, count, nil
+}
+
+func (s *collectionService) ListPostCollectionIDs(ownerID, postID int) ([]int, error) {
+	if ownerID <= 0 || postID <= 0 {
+		return nil, fmt.Errorf("%w: invalid id", models.ErrInvalidCollection)
+	}
+	return s.collectionRepo.ListPostCollectionIDs(ownerID, postID)
+}
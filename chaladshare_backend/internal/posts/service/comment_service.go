@@ -0,0 +1,239 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	notimodels "chaladshare_backend/internal/notifications/models"
+	notiservice "chaladshare_backend/internal/notifications/service"
+	"chaladshare_backend/internal/posts/models"
+	"chaladshare_backend/internal/posts/repository"
+)
+
+type CommentService interface {
+	CreateComment(userID, postID int, req models.CommentRequest) (*models.CommentResponse, error)
+	UpdateComment(userID, commentID int, body string) (*models.CommentResponse, error)
+	DeleteComment(userID, commentID int) error
+
+	ListComments(viewerID, postID int, cursor string, size int) (*models.CommentPage, error)
+	ListReplies(viewerID, commentID int, cursor string, size int) (*models.CommentPage, error)
+}
+
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrCommentForbidden = errors.New("forbidden")
+	ErrPostNotFound     = errors.New("post not found")
+	ErrPostNotVisible   = errors.New("post is not visible")
+	ErrInvalidComment   = errors.New("invalid comment")
+)
+
+type commentService struct {
+	commentRepo repository.CommentRepository
+	postSvc     PostService
+	notifier    notiservice.Notifier
+}
+
+func NewCommentService(commentRepo repository.CommentRepository, postSvc PostService, notifier notiservice.Notifier) CommentService {
+	return &commentService{commentRepo: commentRepo, postSvc: postSvc, notifier: notifier}
+}
+
+func normalizeCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", fmt.Errorf("%w: body is required", ErrInvalidComment)
+	}
+	if utf8.RuneCountInString(body) > models.MaxCommentLength {
+		return "", fmt.Errorf("%w: body is longer than %d characters", ErrInvalidComment, models.MaxCommentLength)
+	}
+	return body, nil
+}
+
+// checkVisible ใช้กติกาเดียวกับ ViewPost: ดูโพสต์ไม่ได้ก็ดู/เขียนความคิดเห็นไม่ได้
+func (s *commentService) checkVisible(viewerID, postID int) error {
+	ok, reason, err := s.postSvc.ViewPost(viewerID, postID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if reason == "not_found" {
+			return ErrPostNotFound
+		}
+		return ErrPostNotVisible
+	}
+	return nil
+}
+
+func (s *commentService) getComment(commentID int) (*models.Comment, error) {
+	c, err := s.commentRepo.GetComment(commentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get comment: %w", err)
+	}
+	return c, nil
+}
+
+func (s *commentService) CreateComment(userID, postID int, req models.CommentRequest) (*models.CommentResponse, error) {
+	if userID <= 0 || postID <= 0 {
+		return nil, fmt.Errorf("invalid user or post id")
+	}
+	body, err := normalizeCommentBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkVisible(userID, postID); err != nil {
+		return nil, err
+	}
+
+	c := &models.Comment{PostID: postID, UserID: userID, Body: body}
+	var parentAuthorID int
+	if req.ParentID != nil {
+		parent, err := s.getComment(*req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.PostID != postID {
+			return nil, fmt.Errorf("%w: parent comment belongs to another post", ErrInvalidComment)
+		}
+		// ตอบกลับได้ชั้นเดียว: ตอบ reply = ตอบความคิดเห็นหลักของมัน
+		parentID := parent.CommentID
+		if parent.ParentID != nil {
+			parentID = *parent.ParentID
+		}
+		c.ParentID = &parentID
+		parentAuthorID = parent.UserID
+	}
+
+	saved, err := s.commentRepo.CreateComment(c)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyComment(saved, parentAuthorID)
+	return s.commentRepo.GetCommentResponse(saved.CommentID)
+}
+
+// notifyComment แจ้งเจ้าของโพสต์ และผู้เขียนความคิดเห็นที่ถูกตอบ (ไม่แจ้งซ้ำถ้าเป็นคนเดียวกัน)
+func (s *commentService) notifyComment(c *models.Comment, parentAuthorID int) {
+	if s.notifier == nil {
+		return
+	}
+	ownerID, err := s.postSvc.GetOwnerID(c.PostID)
+	if err != nil {
+		log.Printf("[NOTIFY] comment: get owner of post %d: %v", c.PostID, err)
+		return
+	}
+	postID := c.PostID
+	if c.ParentID != nil && parentAuthorID != ownerID {
+		parentID := *c.ParentID
+		s.notifier.Notify(notimodels.Event{
+			Type: notimodels.TypeCommentReplied, RecipientID: parentAuthorID, ActorID: c.UserID,
+			PostID: &postID, CommentID: &parentID,
+		})
+	}
+	s.notifier.Notify(notimodels.Event{
+		Type: notimodels.TypePostCommented, RecipientID: ownerID, ActorID: c.UserID, PostID: &postID,
+	})
+}
+
+// UpdateComment แก้ได้เฉพาะผู้เขียน
+func (s *commentService) UpdateComment(userID, commentID int, body string) (*models.CommentResponse, error) {
+	body, err := normalizeCommentBody(body)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.getComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserID != userID {
+		return nil, ErrCommentForbidden
+	}
+	// เผื่อโพสต์เปลี่ยนการมองเห็นไปแล้ว
+	if err := s.checkVisible(userID, c.PostID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.commentRepo.UpdateComment(commentID, body); err != nil {
+		return nil, fmt.Errorf("update comment: %w", err)
+	}
+	return s.commentRepo.GetCommentResponse(commentID)
+}
+
+// DeleteComment ผู้เขียนหรือเจ้าของโพสต์ลบได้ (ตอบกลับถูกลบตาม)
+func (s *commentService) DeleteComment(userID, commentID int) error {
+	c, err := s.getComment(commentID)
+	if err != nil {
+		return err
+	}
+	if c.UserID != userID {
+		isOwner, err := s.postSvc.IsOwner(c.PostID, userID)
+		if err != nil {
+			return err
+		}
+		if !isOwner {
+			return ErrCommentForbidden
+		}
+	}
+
+	if err := s.commentRepo.DeleteComment(commentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCommentNotFound
+		}
+		return fmt.Errorf("delete comment: %w", err)
+	}
+	return nil
+}
+
+func (s *commentService) ListComments(viewerID, postID int, cursor string, size int) (*models.CommentPage, error) {
+	if err := s.checkVisible(viewerID, postID); err != nil {
+		return nil, err
+	}
+	return s.listPage(postID, nil, cursor, size)
+}
+
+func (s *commentService) ListReplies(viewerID, commentID int, cursor string, size int) (*models.CommentPage, error) {
+	parent, err := s.getComment(commentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkVisible(viewerID, parent.PostID); err != nil {
+		return nil, err
+	}
+	return s.listPage(parent.PostID, &parent.CommentID, cursor, size)
+}
+
+func (s *commentService) listPage(postID int, parentID *int, cursor string, size int) (*models.CommentPage, error) {
+	var c *models.CommentCursor
+	if cursor != "" {
+		c = &models.CommentCursor{}
+		if err := decodeCursor(cursor, c); err != nil {
+			return nil, err
+		}
+		if c.CommentID <= 0 || c.CreatedAt.IsZero() {
+			return nil, ErrInvalidCursor
+		}
+	}
+	size = normalizePageSize(size)
+
+	items, err := s.commentRepo.ListComments(postID, parentID, c, size+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.CommentPage{Items: items}
+	if len(items) > size {
+		page.Items = items[:size]
+		last := page.Items[size-1]
+		next := encodeCursor(models.CommentCursor{CreatedAt: last.CreatedAt, CommentID: last.CommentID})
+		page.NextCursor = &next
+	}
+	if page.Items == nil {
+		page.Items = []models.CommentResponse{}
+	}
+	return page, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"chaladshare_backend/internal/posts/models"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 50
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor แปลงเป็นสตริง opaque ให้ client ส่งกลับมาตามเดิม
+func encodeCursor(v any) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func EncodeCursor(c models.FeedCursor) string {
+	return encodeCursor(c)
+}
+
+// DecodeCursor สตริงว่าง = หน้าแรก (คืน nil)
+func DecodeCursor(s string) (*models.FeedCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var c models.FeedCursor
+	if err := decodeCursor(s, &c); err != nil {
+		return nil, err
+	}
+	if c.PostID <= 0 || (c.CreatedAt.IsZero() && c.Score == nil && c.Position == nil) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func normalizePageSize(size int) int {
+	if size <= 0 {
+		return defaultPageSize
+	}
+	if size > maxPageSize {
+		return maxPageSize
+	}
+	return size
+}
+
+// latestCursor cursor ของรายการที่เรียงตาม (post_created_at, post_id)
+func latestCursor(p models.PostResponse) models.FeedCursor {
+	return models.FeedCursor{CreatedAt: p.CreatedAt, PostID: p.PostID}
+}
+
+// collectionCursor cursor ของโพสต์ในคอลเลกชันที่เรียงตาม (item_position, post_id)
+func collectionCursor(p models.PostResponse) models.FeedCursor {
+	return models.FeedCursor{PostID: p.PostID, Position: p.CollectionPosition}
+}
+
+// paginate ดึงเกินมาหนึ่งแถวเพื่อรู้ว่ายังมีหน้าถัดไปไหม
+func paginate(cursor string, size int, fetch func(c *models.FeedCursor, limit int) ([]models.PostResponse, error)) (*models.PostPage, error) {
+	c, err := DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	return paginateFrom(c, size, latestCursor, fetch)
+}
+
+func paginateFrom(c *models.FeedCursor, size int, cursorOf func(models.PostResponse) models.FeedCursor,
+	fetch func(c *models.FeedCursor, limit int) ([]models.PostResponse, error)) (*models.PostPage, error) {
+	size = normalizePageSize(size)
+
+	items, err := fetch(c, size+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.PostPage{Items: items}
+	if len(items) > size {
+		page.Items = items[:size]
+		last := page.Items[size-1]
+		next := EncodeCursor(cursorOf(last))
+		page.NextCursor = &next
+	}
+	if page.Items == nil {
+		page.Items = []models.PostResponse{}
+	}
+	return page, nil
+}
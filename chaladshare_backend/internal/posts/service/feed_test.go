@@ -0,0 +1,130 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"chaladshare_backend/internal/posts/models"
+	"chaladshare_backend/internal/posts/repository"
+)
+
+// fakeFeedRepo ฟีด personal ที่คะแนนขยับระหว่างหน้า: โพสต์ที่ส่งไปแล้วยังอยู่ใต้ cursor
+type fakeFeedRepo struct {
+	repository.PostRepository
+
+	scores     map[int]float64
+	latestHits int
+	lastRank   models.FeedRanking
+}
+
+func (r *fakeFeedRepo) GetFeedPosts(viewerID int, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+	r.latestHits++
+	return nil, nil
+}
+
+func (r *fakeFeedRepo) GetPersonalFeed(viewerID int, rank models.FeedRanking, asOf time.Time, cursor *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+	r.lastRank = rank
+	seen := make(map[int]bool)
+	if cursor != nil {
+		for _, id := range cursor.Seen {
+			seen[id] = true
+		}
+	}
+	var out []models.PostResponse
+	// id 1..n เริ่มที่คะแนน n..1 วนตาม id แล้วกรองด้วยคะแนนของ cursor
+	for id := 1; id <= len(r.scores) && len(out) < limit; id++ {
+		score := r.scores[id]
+		if seen[id] || (cursor != nil && cursor.Score != nil && score >= *cursor.Score) {
+			continue
+		}
+		sc := score
+		out = append(out, models.PostResponse{PostID: id, FeedScore: &sc})
+	}
+	return out, nil
+}
+
+func newFeedRepo(n int) *fakeFeedRepo {
+	r := &fakeFeedRepo{scores: make(map[int]float64)}
+	for id := 1; id <= n; id++ {
+		r.scores[id] = float64(n - id + 1)
+	}
+	return r
+}
+
+func TestFeedDefaultModeIsLatest(t *testing.T) {
+	repo := newFeedRepo(3)
+	s := &postService{postRepo: repo, feedRanking: personalFeedRanking}
+
+	if _, err := s.GetFeedPosts(1, "", "", 10); err != nil {
+		t.Fatalf("GetFeedPosts: %v", err)
+	}
+	if repo.latestHits != 1 {
+		t.Fatalf("default mode did not use the latest feed")
+	}
+	if _, err := s.GetFeedPosts(1, "bogus", "", 10); err != ErrInvalidFeedMode {
+		t.Fatalf("unknown mode = %v, want ErrInvalidFeedMode", err)
+	}
+}
+
+func TestPersonalFeedSkipsPostsFromEarlierPages(t *testing.T) {
+	repo := newFeedRepo(6)
+	s := &postService{postRepo: repo, feedRanking: personalFeedRanking}
+
+	first, err := s.GetFeedPosts(1, models.FeedModePersonal, "", 2)
+	if err != nil {
+		t.Fatalf("page 1: %v", err)
+	}
+	if first.NextCursor == nil {
+		t.Fatal("page 1 has no next_cursor")
+	}
+
+	// post 1 ถูก unlike คะแนนตกลงมาอยู่ใต้ cursor
+	repo.scores[1] = 0.5
+
+	second, err := s.GetFeedPosts(1, models.FeedModePersonal, *first.NextCursor, 2)
+	if err != nil {
+		t.Fatalf("page 2: %v", err)
+	}
+	for _, p := range second.Items {
+		if p.PostID == 1 || p.PostID == 2 {
+			t.Fatalf("page 2 repeats post %d from page 1", p.PostID)
+		}
+	}
+	c, err := DecodeCursor(*second.NextCursor)
+	if err != nil {
+		t.Fatalf("decode page 2 cursor: %v", err)
+	}
+	if len(c.Seen) != 4 {
+		t.Fatalf("seen after two pages = %v, want 4 ids", c.Seen)
+	}
+}
+
+func TestSeenAfterKeepsMostRecent(t *testing.T) {
+	prev := &models.FeedCursor{}
+	for id := 1; id <= maxFeedSeen; id++ {
+		prev.Seen = append(prev.Seen, id)
+	}
+	seen := seenAfter(prev, []models.PostResponse{{PostID: 1001}, {PostID: 1002}})
+	if len(seen) != maxFeedSeen {
+		t.Fatalf("len(seen) = %d, want %d", len(seen), maxFeedSeen)
+	}
+	if seen[0] != 3 || seen[len(seen)-1] != 1002 {
+		t.Fatalf("seen = [%d ... %d], want [3 ... 1002]", seen[0], seen[len(seen)-1])
+	}
+}
+
+func TestFeedWindowDaysIsConfigurable(t *testing.T) {
+	repo := newFeedRepo(1)
+	s := NewPostService(repo, nil, nil, nil, nil, nil, nil, 7).(*postService)
+	if _, err := s.GetFeedPosts(1, models.FeedModePersonal, "", 10); err != nil {
+		t.Fatalf("GetFeedPosts: %v", err)
+	}
+	if repo.lastRank.WindowDays != 7 {
+		t.Fatalf("WindowDays = %d, want 7", repo.lastRank.WindowDays)
+	}
+
+	s = NewPostService(repo, nil, nil, nil, nil, nil, nil, 0).(*postService)
+	if s.feedRanking.WindowDays != personalFeedRanking.WindowDays {
+		t.Fatalf("default WindowDays = %d, want %d", s.feedRanking.WindowDays, personalFeedRanking.WindowDays)
+	}
+}
@@ -1,6 +1,12 @@
 package service
 
-import "chaladshare_backend/internal/posts/repository"
+import (
+	"log"
+
+	notimodels "chaladshare_backend/internal/notifications/models"
+	notiservice "chaladshare_backend/internal/notifications/service"
+	"chaladshare_backend/internal/posts/repository"
+)
 
 type LikeService interface {
 	ToggleLike(userID, postID int) (isLiked bool, likeCount int, err error)
@@ -9,10 +15,12 @@ type LikeService interface {
 
 type likeService struct {
 	likeRepo repository.LikeRepository
+	postSvc  PostService
+	notifier notiservice.Notifier
 }
 
-func NewLikeService(likeRepo repository.LikeRepository) LikeService {
-	return &likeService{likeRepo: likeRepo}
+func NewLikeService(likeRepo repository.LikeRepository, postSvc PostService, notifier notiservice.Notifier) LikeService {
+	return &likeService{likeRepo: likeRepo, postSvc: postSvc, notifier: notifier}
 }
 
 func (s *likeService) ToggleLike(userID, postID int) (bool, int, error) {
@@ -29,6 +37,9 @@ func (s *likeService) ToggleLike(userID, postID int) (bool, int, error) {
 		}
 		liked = false
 	} else {
+		if err := s.checkVisible(userID, postID); err != nil {
+			return false, 0, err
+		}
 		if err := s.likeRepo.LikePost(userID, postID); err != nil {
 			return false, 0, err
 		}
@@ -41,9 +52,43 @@ func (s *likeService) ToggleLike(userID, postID int) (bool, int, error) {
 		return false, 0, err
 	}
 
+	s.notifyLike(userID, postID, liked)
 	return liked, count, nil
 }
 
+// checkVisible ไลก์ได้เฉพาะโพสต์ที่มองเห็น (กติกาเดียวกับ ViewPost) ส่วน unlike ทำได้เสมอ
+func (s *likeService) checkVisible(viewerID, postID int) error {
+	ok, reason, err := s.postSvc.ViewPost(viewerID, postID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if reason == "not_found" {
+			return ErrPostNotFound
+		}
+		return ErrPostNotVisible
+	}
+	return nil
+}
+
+// notifyLike แจ้งเจ้าของโพสต์ (รวมเป็นกลุ่มต่อโพสต์) และถอนออกเมื่อ unlike
+func (s *likeService) notifyLike(userID, postID int, liked bool) {
+	if s.notifier == nil {
+		return
+	}
+	ownerID, err := s.postSvc.GetOwnerID(postID)
+	if err != nil {
+		log.Printf("[NOTIFY] like: get owner of post %d: %v", postID, err)
+		return
+	}
+	ev := notimodels.Event{Type: notimodels.TypePostLiked, RecipientID: ownerID, ActorID: userID, PostID: &postID}
+	if liked {
+		s.notifier.Notify(ev)
+	} else {
+		s.notifier.Retract(ev)
+	}
+}
+
 // ตรวจสอบ
 func (s *likeService) IsPostLiked(userID, postID int) (bool, error) {
 	return s.likeRepo.IsPostLiked(userID, postID)
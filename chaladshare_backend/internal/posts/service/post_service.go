@@ -2,11 +2,16 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	fileservice "chaladshare_backend/internal/files/service"
 	friendservice "chaladshare_backend/internal/friends/service"
+	notiservice "chaladshare_backend/internal/notifications/service"
 
 	"chaladshare_backend/internal/posts/models"
 	"chaladshare_backend/internal/posts/repository"
@@ -18,40 +23,172 @@ type PostService interface {
 	DeletePost(postID int) error
 
 	GetAllPosts() ([]models.PostResponse, error)
-	GetFeedPosts(viewerID int) ([]models.PostResponse, error)
+	GetFeedPosts(viewerID int, mode, cursor string, size int) (*models.PostPage, error)
+	GetUserPosts(viewerID, authorID int, cursor string, size int) (*models.PostPage, error)
 	GetPostByID(postID int) (*models.PostResponse, error)
 	GetPostByIDForViewer(viewerID, postID int) (*models.PostResponse, error)
 	CountByUserID(userID int) (int, error)
 
+	GetDrafts(authorID int, cursor string, size int) (*models.PostPage, error)
+	PublishPost(authorID, postID int) error
+	StartPublishScheduler(interval time.Duration)
+
 	IsOwner(postID int, userID int) (bool, error)
+	GetOwnerID(postID int) (int, error)
 	ViewPost(viewerID, postID int) (bool, string, error)
+	CanViewDocument(viewerID, documentID int) (bool, error)
 	Friends(viewerID, authorID int) (bool, error)
 
-	GetSavedPosts(userID int) ([]models.PostResponse, error)
-	GetPopularPosts(viewerID, limit int) ([]models.PostResponse, error)
-	SearchPosts(viewerID int, search string, page, size int) ([]models.PostResponse, int, error)
+	GetSavedPosts(userID int, cursor string, size int) (*models.PostPage, error)
+	GetPopularPosts(viewerID int, window string, limit int) ([]models.PostResponse, error)
+	StartTrendingRefresher(ctx context.Context, cfg models.TrendingConfig, interval time.Duration)
+	SearchPosts(viewerID int, search, mode string, page, size int) ([]models.PostResponse, int, error)
+}
+
+// QueryEmbedder แปลงข้อความค้นหาเป็น embedding (connect.Client)
+type QueryEmbedder interface {
+	EmbedQuery(text string) ([]float64, error)
+}
+
+// FeatureHook ให้เอกสารของโพสต์ที่เพิ่งเผยแพร่เข้าคิว feature/cluster (docfeatures FeatureService)
+type FeatureHook interface {
+	OnDocumentPublished(documentID int)
+}
+
+// RecommendHook คำนวณ recommendation ใหม่ให้ผู้ชมของโพสต์ที่เพิ่งเผยแพร่ (recommend RecommendService)
+type RecommendHook interface {
+	OnPostPublished(postID int, viewerIDs []int)
 }
 
+var (
+	ErrSemanticUnavailable   = errors.New("semantic search is unavailable")
+	ErrUnsupportedVisibility = errors.New("unsupported visibility")
+	ErrInvalidPublishState   = errors.New("invalid publish state")
+)
+
+const (
+	semanticMaxDistance = 0.6  // cosine distance สูงสุดที่ยังนับว่าเกี่ยวข้อง
+	hybridKeywordWeight = 0.4  // น้ำหนักของ ILIKE ในโหมด hybrid
+	hybridMinSimilarity = 0.45 // similarity ขั้นต่ำของผลที่ไม่ตรง keyword
+)
+
+// น้ำหนักฟีดโหมด personal: โพสต์เพื่อนอายุ 2 half-life ยังเทียบเท่าโพสต์ใหม่ของคนแปลกหน้า
+// WindowDays ปรับได้ด้วย FEED.WINDOW_DAYS
+var personalFeedRanking = models.FeedRanking{
+	OwnWeight:        1.0,
+	FriendWeight:     3.0,
+	FollowWeight:     2.0,
+	TagWeight:        2.0,
+	RecommendWeight:  2.0,
+	EngagementWeight: 0.5,
+	HalfLifeHours:    48,
+	WindowDays:       30,
+}
+
+var (
+	ErrInvalidFeedMode       = errors.New("invalid feed mode")
+	ErrInvalidTrendingWindow = errors.New("invalid trending window")
+)
+
 type postService struct {
 	postRepo  repository.PostRepository
 	friendSvc friendservice.FriendService
 	fileSvc   fileservice.FileService
+	embedder  QueryEmbedder
+	notifier  notiservice.Notifier
+	features  FeatureHook
+	recommend RecommendHook
+
+	feedRanking models.FeedRanking
 }
 
-func NewPostService(postRepo repository.PostRepository, friendSvc friendservice.FriendService, fileSvc fileservice.FileService) PostService {
-	return &postService{postRepo: postRepo, friendSvc: friendSvc, fileSvc: fileSvc}
+// feedWindowDays <= 0 ใช้ค่า default ของ personalFeedRanking
+func NewPostService(postRepo repository.PostRepository, friendSvc friendservice.FriendService, fileSvc fileservice.FileService, embedder QueryEmbedder, notifier notiservice.Notifier, features FeatureHook, recommend RecommendHook, feedWindowDays int) PostService {
+	rank := personalFeedRanking
+	if feedWindowDays > 0 {
+		rank.WindowDays = feedWindowDays
+	}
+	return &postService{postRepo: postRepo, friendSvc: friendSvc, fileSvc: fileSvc, embedder: embedder, notifier: notifier,
+		features: features, recommend: recommend, feedRanking: rank}
+}
+
+// normalizePublishState status ว่าง = เดาจาก publishAt (อนาคต = scheduled, ไม่มี/ผ่านไปแล้ว = published)
+func normalizePublishState(status string, publishAt *time.Time, now time.Time) (string, *time.Time, error) {
+	st := strings.ToLower(strings.TrimSpace(status))
+	future := publishAt != nil && publishAt.After(now)
+
+	switch st {
+	case "":
+		if future {
+			return models.StatusScheduled, publishAt, nil
+		}
+		return models.StatusPublished, nil, nil
+	case models.StatusPublished:
+		if future {
+			return "", nil, fmt.Errorf("%w: use scheduled for a future publish_at", ErrInvalidPublishState)
+		}
+		return models.StatusPublished, nil, nil
+	case models.StatusScheduled:
+		if !future {
+			return "", nil, fmt.Errorf("%w: scheduled requires a future publish_at", ErrInvalidPublishState)
+		}
+		return models.StatusScheduled, publishAt, nil
+	case models.StatusDraft:
+		if publishAt != nil {
+			return "", nil, fmt.Errorf("%w: draft cannot have publish_at", ErrInvalidPublishState)
+		}
+		return models.StatusDraft, nil, nil
+	}
+	return "", nil, fmt.Errorf("%w: unsupported status %s", ErrInvalidPublishState, status)
 }
 
 func normalizeVisibility(v string) (string, error) {
 	vis := strings.ToLower(strings.TrimSpace(v))
-	switch vis {
-	case "", models.VisibilityPublic:
+	if vis == "" {
 		return models.VisibilityPublic, nil
-	case models.VisibilityFriends:
-		return models.VisibilityFriends, nil
-	default:
-		return "", fmt.Errorf("unsupported visibility: %s", v)
 	}
+	if !models.IsValidVisibility(vis) {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedVisibility, v)
+	}
+	return vis, nil
+}
+
+// normalizeAudience ตัดตัวซ้ำและตัวเจ้าของโพสต์ออก ต้องเหลืออย่างน้อย 1 รายการ
+func normalizeAudience(aud *models.PostAudience, authorID int) (*models.PostAudience, error) {
+	if aud == nil {
+		return nil, fmt.Errorf("%w: custom visibility requires an audience", models.ErrInvalidAudience)
+	}
+	dedupe := func(ids []int, skip int) ([]int, error) {
+		seen := make(map[int]struct{}, len(ids))
+		out := make([]int, 0, len(ids))
+		for _, id := range ids {
+			if id <= 0 {
+				return nil, fmt.Errorf("%w: invalid id %d", models.ErrInvalidAudience, id)
+			}
+			if _, ok := seen[id]; ok || id == skip {
+				continue
+			}
+			seen[id] = struct{}{}
+			out = append(out, id)
+		}
+		return out, nil
+	}
+
+	users, err := dedupe(aud.UserIDs, authorID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := dedupe(aud.GroupIDs, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(users)+len(groups) == 0 {
+		return nil, fmt.Errorf("%w: custom visibility requires an audience", models.ErrInvalidAudience)
+	}
+	if len(users)+len(groups) > models.MaxAudienceEntries {
+		return nil, fmt.Errorf("%w: too many entries", models.ErrInvalidAudience)
+	}
+	return &models.PostAudience{UserIDs: users, GroupIDs: groups}, nil
 }
 
 // สร้างโพสต์ใหม่
@@ -69,14 +206,38 @@ func (s *postService) CreatePost(post *models.Post, tags []string) (int, error)
 	}
 	post.Visibility = vis
 
+	post.Audience, err = s.audienceFor(post, "")
+	if err != nil {
+		return 0, err
+	}
+
+	post.Status, post.PublishAt, err = normalizePublishState(post.Status, post.PublishAt, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
 	normTags := normalizeTags(tags)
 	postID, err := s.postRepo.CreatePost(post, normTags)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create post: %w", err)
 	}
+	if post.Status == models.StatusPublished {
+		go s.onPublished(postID, post.AuthorUserID, post.DocumentID)
+	}
 	return postID, nil
 }
 
+// audienceFor ผู้ชมที่จะบันทึก: ไม่ใช่ custom = nil, custom ที่เดิมเป็น custom อยู่แล้วไม่ส่งมา = คงเดิม (nil)
+func (s *postService) audienceFor(post *models.Post, prevVisibility string) (*models.PostAudience, error) {
+	if post.Visibility != models.VisibilityCustom {
+		return nil, nil
+	}
+	if post.Audience == nil && prevVisibility == models.VisibilityCustom {
+		return nil, nil
+	}
+	return normalizeAudience(post.Audience, post.AuthorUserID)
+}
+
 func (s *postService) UpdatePost(post *models.Post, tags []string) error {
 	if post.PostID <= 0 {
 		return fmt.Errorf("invalid post_id")
@@ -85,15 +246,17 @@ func (s *postService) UpdatePost(post *models.Post, tags []string) error {
 		return fmt.Errorf("post_title is required")
 	}
 
+	existing, err := s.postRepo.GetPostByID(post.PostID)
+	if err != nil {
+		return fmt.Errorf("get existing post: %w", err)
+	}
+	if existing == nil {
+		return fmt.Errorf("post not found")
+	}
+	post.AuthorUserID = existing.AuthorID
+
 	visInput := strings.TrimSpace(post.Visibility)
 	if visInput == "" {
-		existing, err := s.postRepo.GetPostByID(post.PostID)
-		if err != nil {
-			return fmt.Errorf("get existing post: %w", err)
-		}
-		if existing == nil {
-			return fmt.Errorf("post not found")
-		}
 		visInput = existing.Visibility
 	}
 
@@ -103,50 +266,109 @@ func (s *postService) UpdatePost(post *models.Post, tags []string) error {
 	}
 	post.Visibility = vis
 
+	post.Audience, err = s.audienceFor(post, existing.Visibility)
+	if err != nil {
+		return err
+	}
+
+	// ไม่ส่ง status/publish_at มา = คงสถานะเดิม
+	if post.Status == "" && post.PublishAt == nil {
+		post.Status, post.PublishAt = existing.Status, existing.PublishAt
+	} else {
+		post.Status, post.PublishAt, err = normalizePublishState(post.Status, post.PublishAt, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	if existing.Status == models.StatusPublished && post.Status != models.StatusPublished {
+		return fmt.Errorf("%w: a published post cannot go back to %s", ErrInvalidPublishState, post.Status)
+	}
+
 	var normTags []string
 	if tags != nil {
 		normTags = normalizeTags(tags)
 	}
 
-	if err := s.postRepo.UpdatePost(post, normTags); err != nil {
+	published, err := s.postRepo.UpdatePost(post, normTags)
+	if errors.Is(err, models.ErrAlreadyPublished) {
+		return fmt.Errorf("%w: a published post cannot go back to %s", ErrInvalidPublishState, post.Status)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to update post: %w", err)
 	}
+	// published = การแก้ไขนี้เป็นตัวเผยแพร่จริง ถ้า scheduler ชิงไปก่อนฝั่งนั้นส่งแจ้งเตือนแล้ว
+	if published {
+		go s.onPublished(post.PostID, existing.AuthorID, existing.DocumentID)
+	}
+	return nil
+}
+
+// PublishPost เผยแพร่ฉบับร่าง/โพสต์ที่ตั้งเวลาไว้ทันที (เฉพาะเจ้าของ)
+func (s *postService) PublishPost(authorID, postID int) error {
+	existing, err := s.postRepo.GetPostByID(postID)
+	if err != nil {
+		return fmt.Errorf("get existing post: %w", err)
+	}
+	if existing == nil || existing.AuthorID != authorID {
+		return ErrPostNotFound
+	}
+	if existing.Status == models.StatusPublished {
+		return fmt.Errorf("%w: post is already published", ErrInvalidPublishState)
+	}
+
+	// เผยแพร่แบบมีเงื่อนไข: ถ้า scheduler ชิงเผยแพร่ไปก่อน ได้ false และไม่แจ้งเตือนซ้ำ
+	ok, err := s.postRepo.PublishPost(postID, authorID)
+	if err != nil {
+		return fmt.Errorf("publish post: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: post is already published", ErrInvalidPublishState)
+	}
+	go s.onPublished(postID, authorID, existing.DocumentID)
 	return nil
 }
 
+func (s *postService) GetDrafts(authorID int, cursor string, size int) (*models.PostPage, error) {
+	if authorID <= 0 {
+		return nil, fmt.Errorf("invalid author id")
+	}
+	return paginate(cursor, size, func(c *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+		return s.postRepo.GetDrafts(authorID, c, limit)
+	})
+}
+
+// normalizeTag ตัด # และช่องว่าง แปลงเป็นตัวเล็ก ใช้ได้แค่ a-z 0-9 _ -
+func normalizeTag(t string) (string, bool) {
+	tag := strings.TrimSpace(t)
+	tag = strings.TrimPrefix(tag, "#")
+	tag = strings.ToLower(tag)
+
+	if tag == "" || len(tag) > models.MaxTagLength {
+		return "", false
+	}
+	for _, r := range tag {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_' && r != '-' {
+			return "", false
+		}
+	}
+	return tag, true
+}
+
 func normalizeTags(in []string) []string {
 	seen := make(map[string]struct{}, len(in))
 	out := make([]string, 0, len(in))
 
-	const (
-		maxTags = 10
-		maxLen  = 30
-	)
 	for _, t := range in {
-		tag := strings.TrimSpace(t)
-		tag = strings.TrimPrefix(tag, "#")
-		tag = strings.ToLower(tag)
-
-		if tag == "" || len(tag) > maxLen {
-			continue
-		}
-		valid := true
-		for _, r := range tag {
-			if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '_' && r != '-' {
-				valid = false
-				break
-			}
-		}
-		if !valid {
+		tag, ok := normalizeTag(t)
+		if !ok {
 			continue
 		}
-
 		if _, dup := seen[tag]; dup {
 			continue
 		}
 		seen[tag] = struct{}{}
 		out = append(out, tag)
-		if len(out) >= maxTags {
+		if len(out) >= models.MaxTagsPerPost {
 			break
 		}
 	}
@@ -175,8 +397,79 @@ func (s *postService) GetAllPosts() ([]models.PostResponse, error) {
 	return s.postRepo.GetAllPosts()
 }
 
-func (s *postService) GetFeedPosts(viewerID int) ([]models.PostResponse, error) {
-	return s.postRepo.GetFeedPosts(viewerID)
+// GetFeedPosts ฟีดหน้าแรกทีละหน้า (cursor ว่าง = หน้าแรก)
+func (s *postService) GetFeedPosts(viewerID int, mode, cursor string, size int) (*models.PostPage, error) {
+	// ไม่ระบุ mode = latest เหมือนเดิม ฟีดจัดอันดับต้องขอ mode=personal เอง
+	switch mode {
+	case models.FeedModePersonal:
+		return s.getPersonalFeed(viewerID, cursor, size)
+	case "", models.FeedModeLatest:
+		return paginate(cursor, size, func(c *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+			return s.postRepo.GetFeedPosts(viewerID, c, limit)
+		})
+	default:
+		return nil, ErrInvalidFeedMode
+	}
+}
+
+// maxFeedSeen post_id ล่าสุดที่ cursor ของฟีด personal จำไว้กันโพสต์ซ้ำข้ามหน้า (ราว 10 หน้าของ default size)
+const maxFeedSeen = 200
+
+// getPersonalFeed ตรึงเวลา asOf ไว้ใน cursor ให้คะแนนทุกหน้าคิดจากเวลาเดียวกัน
+// คะแนนยังขยับได้ (unlike, recommendation ถูกคำนวณใหม่) โพสต์ที่ส่งไปแล้วจึงถูกจำใน cursor และตัดออกจากหน้าถัดไป
+func (s *postService) getPersonalFeed(viewerID int, cursor string, size int) (*models.PostPage, error) {
+	c, err := DecodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	asOf := time.Now().UTC()
+	if c != nil {
+		if c.Score == nil || c.AsOf == nil {
+			return nil, ErrInvalidCursor
+		}
+		asOf = *c.AsOf
+	}
+
+	cursorOf := func(p models.PostResponse) models.FeedCursor {
+		return models.FeedCursor{CreatedAt: p.CreatedAt, PostID: p.PostID, Score: p.FeedScore, AsOf: &asOf}
+	}
+	page, err := paginateFrom(c, size, cursorOf, func(c *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+		return s.postRepo.GetPersonalFeed(viewerID, s.feedRanking, asOf, c, limit)
+	})
+	if err != nil || page.NextCursor == nil {
+		return page, err
+	}
+
+	next := cursorOf(page.Items[len(page.Items)-1])
+	next.Seen = seenAfter(c, page.Items)
+	encoded := EncodeCursor(next)
+	page.NextCursor = &encoded
+	return page, nil
+}
+
+// seenAfter post_id ที่ส่งไปแล้วรวมหน้านี้ เก็บแค่ maxFeedSeen ตัวล่าสุด
+func seenAfter(c *models.FeedCursor, items []models.PostResponse) []int {
+	var seen []int
+	if c != nil {
+		seen = append(seen, c.Seen...)
+	}
+	for _, p := range items {
+		seen = append(seen, p.PostID)
+	}
+	if len(seen) > maxFeedSeen {
+		seen = seen[len(seen)-maxFeedSeen:]
+	}
+	return seen
+}
+
+// GetUserPosts โพสต์ในหน้าโปรไฟล์ของ authorID ตามสิทธิ์ของ viewer
+func (s *postService) GetUserPosts(viewerID, authorID int, cursor string, size int) (*models.PostPage, error) {
+	if authorID <= 0 {
+		return nil, fmt.Errorf("invalid author")
+	}
+	return paginate(cursor, size, func(c *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+		return s.postRepo.GetUserPosts(viewerID, authorID, c, limit)
+	})
 }
 
 // each post by ID
@@ -184,8 +477,18 @@ func (s *postService) GetPostByID(postID int) (*models.PostResponse, error) {
 	return s.postRepo.GetPostByID(postID)
 }
 
+// GetPostByIDForViewer เจ้าของโพสต์ custom จะได้รายชื่อผู้ชมไปด้วย (ไว้แก้ไข)
 func (s *postService) GetPostByIDForViewer(viewerID, postID int) (*models.PostResponse, error) {
-	return s.postRepo.GetPostByIDForViewer(viewerID, postID)
+	post, err := s.postRepo.GetPostByIDForViewer(viewerID, postID)
+	if err != nil || post == nil {
+		return post, err
+	}
+	if post.AuthorID == viewerID && post.Visibility == models.VisibilityCustom {
+		if post.Audience, err = s.postRepo.GetAudience(postID); err != nil {
+			return nil, err
+		}
+	}
+	return post, nil
 }
 
 func (s *postService) CountByUserID(userID int) (int, error) {
@@ -200,8 +503,15 @@ func (s *postService) IsOwner(postID int, userID int) (bool, error) {
 	return ownerID == userID, nil
 }
 
+func (s *postService) GetOwnerID(postID int) (int, error) {
+	return s.postRepo.GetPostOwnerID(postID)
+}
+
 func (s *postService) ViewPost(viewerID, postID int) (bool, string, error) {
 	post, err := s.GetPostByID(postID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, "not_found", nil
+	}
 	if err != nil {
 		return false, "error", fmt.Errorf("get post: %w", err)
 	}
@@ -217,6 +527,9 @@ func (s *postService) ViewPost(viewerID, postID int) (bool, string, error) {
 	if viewerID == authorID {
 		return true, "owner", nil
 	}
+	if post.Status != models.StatusPublished {
+		return false, "not_found", nil
+	}
 
 	switch vis {
 	case models.VisibilityPublic:
@@ -230,11 +543,52 @@ func (s *postService) ViewPost(viewerID, postID int) (bool, string, error) {
 			return true, "friends", nil
 		}
 		return false, "friends_only", nil
+	case models.VisibilityPrivate:
+		return false, "private", nil
+	case models.VisibilityCustom:
+		ok, err := s.postRepo.CanView(viewerID, postID)
+		if err != nil {
+			return false, "error", err
+		}
+		if ok {
+			return true, "audience", nil
+		}
+		return false, "not_in_audience", nil
 	default:
 		return false, "denied", nil
 	}
 }
 
+// CanViewDocument เจ้าของไฟล์ หรือดูโพสต์ที่แนบไฟล์นี้ได้อย่างน้อย 1 โพสต์ (กติกาเดียวกับ ViewPost)
+func (s *postService) CanViewDocument(viewerID, documentID int) (bool, error) {
+	if viewerID <= 0 || documentID <= 0 {
+		return false, fmt.Errorf("invalid viewer or document id")
+	}
+
+	ownerID, err := s.fileSvc.GetDocumentOwnerID(documentID)
+	if err != nil {
+		return false, err
+	}
+	if ownerID == viewerID {
+		return true, nil
+	}
+
+	postIDs, err := s.postRepo.ListPostIDsByDocumentID(documentID)
+	if err != nil {
+		return false, err
+	}
+	for _, postID := range postIDs {
+		ok, _, err := s.ViewPost(viewerID, postID)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (s *postService) Friends(viewerID, authorID int) (bool, error) {
 	if viewerID <= 0 || authorID <= 0 {
 		return false, fmt.Errorf("invalid user id")
@@ -251,29 +605,69 @@ func (s *postService) Friends(viewerID, authorID int) (bool, error) {
 	return ok, nil
 }
 
-func (s *postService) GetSavedPosts(userID int) ([]models.PostResponse, error) {
-	return s.postRepo.GetSavedPosts(userID)
+func (s *postService) GetSavedPosts(userID int, cursor string, size int) (*models.PostPage, error) {
+	return paginate(cursor, size, func(c *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+		return s.postRepo.GetSavedPosts(userID, c, limit)
+	})
+}
+
+func normalizeTrendingWindow(w string) (string, error) {
+	window := strings.ToLower(strings.TrimSpace(w))
+	switch window {
+	case "":
+		return models.TrendingWeek, nil
+	case models.TrendingDay, models.TrendingWeek, models.TrendingAll:
+		return window, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidTrendingWindow, w)
 }
 
-func (s *postService) GetPopularPosts(viewerID, limit int) ([]models.PostResponse, error) {
+func (s *postService) GetPopularPosts(viewerID int, window string, limit int) ([]models.PostResponse, error) {
 	if viewerID <= 0 {
 		return nil, fmt.Errorf("invalid viewer id")
 	}
+	window, err := normalizeTrendingWindow(window)
+	if err != nil {
+		return nil, err
+	}
 	if limit <= 0 {
 		limit = 3
 	}
 	if limit > 20 {
 		limit = 20
 	}
-	return s.postRepo.GetPopularPosts(viewerID, limit)
+	posts, err := s.postRepo.GetPopularPosts(viewerID, window, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get popular posts: %w", err)
+	}
+	if posts == nil {
+		posts = []models.PostResponse{}
+	}
+	return posts, nil
+}
+
+func normalizeSearchMode(m string) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(m))
+	switch mode {
+	case "", models.SearchModeKeyword:
+		return models.SearchModeKeyword, nil
+	case models.SearchModeSemantic, models.SearchModeHybrid:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported search mode: %s", m)
+	}
 }
 
-func (s *postService) SearchPosts(viewerID int, search string, page, size int) ([]models.PostResponse, int, error) {
+func (s *postService) SearchPosts(viewerID int, search, mode string, page, size int) ([]models.PostResponse, int, error) {
 	if viewerID <= 0 {
 		return nil, 0, fmt.Errorf("invalid viewer id")
 	}
 
 	search = strings.TrimSpace(search)
+	mode, err := normalizeSearchMode(mode)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	if page < 1 {
 		page = 1
@@ -281,5 +675,28 @@ func (s *postService) SearchPosts(viewerID int, search string, page, size int) (
 	if size <= 0 || size > 100 {
 		size = 20
 	}
-	return s.postRepo.SearchPosts(viewerID, search, page, size)
+
+	if mode == models.SearchModeKeyword {
+		return s.postRepo.SearchPosts(viewerID, search, page, size)
+	}
+
+	var emb []float64
+	if s.embedder != nil {
+		emb, err = s.embedder.EmbedQuery(search)
+	} else {
+		err = ErrSemanticUnavailable
+	}
+	if err != nil {
+		// hybrid ยังตอบได้ด้วย keyword อย่างเดียว ส่วน semantic ต้องแจ้ง error
+		if mode == models.SearchModeHybrid {
+			log.Printf("[SEARCH] embed failed, fallback to keyword: %v", err)
+			return s.postRepo.SearchPosts(viewerID, search, page, size)
+		}
+		return nil, 0, fmt.Errorf("%w: %v", ErrSemanticUnavailable, err)
+	}
+
+	if mode == models.SearchModeSemantic {
+		return s.postRepo.SearchPostsSemantic(viewerID, emb, semanticMaxDistance, page, size)
+	}
+	return s.postRepo.SearchPostsHybrid(viewerID, search, emb, hybridKeywordWeight, hybridMinSimilarity, page, size)
 }
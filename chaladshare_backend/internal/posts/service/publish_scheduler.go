@@ -0,0 +1,79 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	notimodels "chaladshare_backend/internal/notifications/models"
+)
+
+const (
+	publishBatchSize        = 100
+	maxNewPostNotifications = 1000 // ผู้ติดตามที่ได้แจ้งเตือนต่อหนึ่งโพสต์
+)
+
+// StartPublishScheduler เผยแพร่โพสต์ scheduled ที่ถึงเวลาทุก interval (เรียกครั้งเดียวตอน start)
+func (s *postService) StartPublishScheduler(interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	go func() {
+		s.publishDue()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.publishDue()
+		}
+	}()
+	log.Printf("[PUBLISH-SCHEDULER] started interval=%s", interval)
+}
+
+// publishDue ทำทีละชุดจนหมดโพสต์ที่ถึงเวลา
+func (s *postService) publishDue() {
+	for {
+		posts, err := s.postRepo.PublishDue(publishBatchSize)
+		if err != nil {
+			log.Printf("[PUBLISH-SCHEDULER] %v", err)
+			return
+		}
+		for _, p := range posts {
+			log.Printf("[PUBLISH-SCHEDULER] published post=%d author=%d", p.PostID, p.AuthorID)
+			s.onPublished(p.PostID, p.AuthorID, p.DocumentID)
+		}
+		if len(posts) < publishBatchSize {
+			return
+		}
+	}
+}
+
+// onPublished งานหลังโพสต์เผยแพร่ เรียกครั้งเดียวต่อโพสต์จากฝั่งที่เปลี่ยนสถานะได้จริง (ทันทีหรือ scheduler)
+// ฟีด/ค้นหา/ยอดนิยมอ่าน post_status ตรง ๆ จึงเห็นเอง แต่ cluster ของเอกสารและ recommendation
+// ที่คำนวณเก็บไว้ไม่ขยับเอง ต้องสั่งที่นี่: เข้าคิว feature, แจ้งผู้ติดตาม, คำนวณ recommendation ของผู้ติดตามใหม่
+func (s *postService) onPublished(postID, authorID int, documentID *int) {
+	if documentID != nil && s.features != nil {
+		s.features.OnDocumentPublished(*documentID)
+	}
+	if s.notifier == nil && s.recommend == nil {
+		return
+	}
+	followers, err := s.postRepo.ListFollowersWhoCanView(postID, maxNewPostNotifications)
+	if err != nil {
+		log.Printf("[PUBLISH] list followers for post %d: %v", postID, err)
+		return
+	}
+	if s.recommend != nil {
+		s.recommend.OnPostPublished(postID, followers)
+	}
+	if s.notifier == nil {
+		return
+	}
+	for _, uid := range followers {
+		pid := postID
+		s.notifier.Notify(notimodels.Event{
+			Type:        notimodels.TypeNewPost,
+			RecipientID: uid,
+			ActorID:     authorID,
+			PostID:      &pid,
+		})
+	}
+}
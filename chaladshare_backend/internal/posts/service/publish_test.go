@@ -0,0 +1,174 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	notimodels "chaladshare_backend/internal/notifications/models"
+	"chaladshare_backend/internal/posts/models"
+	"chaladshare_backend/internal/posts/repository"
+)
+
+func TestNormalizePublishState(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+
+	cases := []struct {
+		name       string
+		status     string
+		publishAt  *time.Time
+		wantStatus string
+		wantAt     *time.Time
+		wantErr    bool
+	}{
+		{"empty without publish_at", "", nil, models.StatusPublished, nil, false},
+		{"empty with future", "", &future, models.StatusScheduled, &future, false},
+		{"empty with past", "", &past, models.StatusPublished, nil, false},
+		{"published", "published", nil, models.StatusPublished, nil, false},
+		{"published with past", "published", &past, models.StatusPublished, nil, false},
+		{"published with future", "published", &future, "", nil, true},
+		{"scheduled with future", "scheduled", &future, models.StatusScheduled, &future, false},
+		{"scheduled without publish_at", "scheduled", nil, "", nil, true},
+		{"scheduled with past", "scheduled", &past, "", nil, true},
+		{"scheduled at now", "scheduled", &now, "", nil, true},
+		{"draft", "draft", nil, models.StatusDraft, nil, false},
+		{"draft with publish_at", "draft", &future, "", nil, true},
+		{"case and spaces", "  Draft ", nil, models.StatusDraft, nil, false},
+		{"unknown", "archived", nil, "", nil, true},
+	}
+	for _, tc := range cases {
+		st, at, err := normalizePublishState(tc.status, tc.publishAt, now)
+		if tc.wantErr {
+			if !errors.Is(err, ErrInvalidPublishState) {
+				t.Errorf("%s: err = %v, want ErrInvalidPublishState", tc.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if st != tc.wantStatus {
+			t.Errorf("%s: status = %q, want %q", tc.name, st, tc.wantStatus)
+		}
+		if (at == nil) != (tc.wantAt == nil) || (at != nil && !at.Equal(*tc.wantAt)) {
+			t.Errorf("%s: publish_at = %v, want %v", tc.name, at, tc.wantAt)
+		}
+	}
+}
+
+// fakePublishRepo จำลองการเผยแพร่แบบมีเงื่อนไข: เปลี่ยนสถานะได้ครั้งเดียว
+type fakePublishRepo struct {
+	repository.PostRepository
+
+	mu        sync.Mutex
+	status    string
+	stale     string // สถานะที่ GetPostByID เห็น (จำลองอ่านก่อน scheduler เผยแพร่)
+	authorID  int
+	docID     *int
+	followers []int
+}
+
+func (r *fakePublishRepo) GetPostByID(postID int) (*models.PostResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := r.status
+	if r.stale != "" {
+		st = r.stale
+	}
+	return &models.PostResponse{PostID: postID, AuthorID: r.authorID, Status: st, DocumentID: r.docID}, nil
+}
+
+func (r *fakePublishRepo) PublishPost(postID, authorID int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status == models.StatusPublished || authorID != r.authorID {
+		return false, nil
+	}
+	r.status = models.StatusPublished
+	return true, nil
+}
+
+func (r *fakePublishRepo) PublishDue(limit int) ([]models.PostResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.status != models.StatusScheduled {
+		return nil, nil
+	}
+	r.status = models.StatusPublished
+	return []models.PostResponse{{PostID: 1, AuthorID: r.authorID, DocumentID: r.docID}}, nil
+}
+
+func (r *fakePublishRepo) ListFollowersWhoCanView(postID, limit int) ([]int, error) {
+	return r.followers, nil
+}
+
+type publishRecorder struct {
+	mu        sync.Mutex
+	notified  []int
+	docs      []int
+	recompute [][]int
+}
+
+func (p *publishRecorder) Notify(ev notimodels.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.notified = append(p.notified, ev.RecipientID)
+}
+
+func (p *publishRecorder) Retract(ev notimodels.Event) {}
+
+func (p *publishRecorder) OnDocumentPublished(documentID int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.docs = append(p.docs, documentID)
+}
+
+func (p *publishRecorder) OnPostPublished(postID int, viewerIDs []int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recompute = append(p.recompute, viewerIDs)
+}
+
+func newPublishService(status string) (*postService, *fakePublishRepo, *publishRecorder) {
+	doc := 7
+	repo := &fakePublishRepo{status: status, authorID: 10, docID: &doc, followers: []int{20, 21}}
+	rec := &publishRecorder{}
+	return &postService{postRepo: repo, notifier: rec, features: rec, recommend: rec}, repo, rec
+}
+
+func TestPublishDueRunsAllPublishSteps(t *testing.T) {
+	s, _, rec := newPublishService(models.StatusScheduled)
+
+	s.publishDue()
+
+	if len(rec.notified) != 2 {
+		t.Errorf("notified %v, want both followers", rec.notified)
+	}
+	if len(rec.docs) != 1 || rec.docs[0] != 7 {
+		t.Errorf("feature hook docs = %v, want [7]", rec.docs)
+	}
+	if len(rec.recompute) != 1 || len(rec.recompute[0]) != 2 {
+		t.Errorf("recommend hook calls = %v, want one call with both followers", rec.recompute)
+	}
+}
+
+func TestPublishPostAfterSchedulerDoesNotNotifyAgain(t *testing.T) {
+	s, repo, rec := newPublishService(models.StatusScheduled)
+	// PublishPost อ่านได้ scheduled แต่ scheduler เผยแพร่ไปก่อน UPDATE ของ PublishPost
+	repo.stale = models.StatusScheduled
+
+	s.publishDue()
+	err := s.PublishPost(10, 1)
+	if !errors.Is(err, ErrInvalidPublishState) {
+		t.Fatalf("PublishPost = %v, want ErrInvalidPublishState", err)
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.notified) != 2 {
+		t.Fatalf("notified %d times, want 2 (scheduler only)", len(rec.notified))
+	}
+}
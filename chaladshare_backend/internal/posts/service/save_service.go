@@ -9,10 +9,11 @@ type SaveService interface {
 
 type saveService struct {
 	saveRepo repository.SaveRepository
+	postSvc  PostService
 }
 
-func NewSaveService(saveRepo repository.SaveRepository) SaveService {
-	return &saveService{saveRepo: saveRepo}
+func NewSaveService(saveRepo repository.SaveRepository, postSvc PostService) SaveService {
+	return &saveService{saveRepo: saveRepo, postSvc: postSvc}
 }
 
 func (s *saveService) ToggleSave(userID, postID int) (bool, int, error) {
@@ -29,7 +30,10 @@ func (s *saveService) ToggleSave(userID, postID int) (bool, int, error) {
 		}
 		saved = false
 	} else {
-		// ถ้ายังไม่เคย save → save ใหม่
+		// ถ้ายังไม่เคย save → save ใหม่ (ต้องมองเห็นโพสต์ก่อน ส่วน unsave ทำได้เสมอ)
+		if err := s.checkVisible(userID, postID); err != nil {
+			return false, 0, err
+		}
 		if err := s.saveRepo.SavePost(userID, postID); err != nil {
 			return false, 0, err
 		}
@@ -45,6 +49,21 @@ func (s *saveService) ToggleSave(userID, postID int) (bool, int, error) {
 	return saved, count, nil
 }
 
+// checkVisible กติกาเดียวกับ ViewPost
+func (s *saveService) checkVisible(viewerID, postID int) error {
+	ok, reason, err := s.postSvc.ViewPost(viewerID, postID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if reason == "not_found" {
+			return ErrPostNotFound
+		}
+		return ErrPostNotVisible
+	}
+	return nil
+}
+
 //ตรวจสอบ
 func (s *saveService) IsPostSaved(userID, postID int) (bool, error) {
 	return s.saveRepo.IsPostSaved(userID, postID)
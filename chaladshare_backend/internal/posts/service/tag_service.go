@@ -0,0 +1,119 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"chaladshare_backend/internal/posts/models"
+	"chaladshare_backend/internal/posts/repository"
+)
+
+type TagService interface {
+	SuggestTags(viewerID int, prefix string, limit int) ([]models.TagSummary, error)
+	GetTag(viewerID int, name string) (*models.TagDetail, error)
+	GetTagPosts(viewerID int, name, cursor string, size int) (*models.PostPage, error)
+
+	FollowTag(userID int, name string) (*models.FollowedTag, error)
+	UnfollowTag(userID int, name string) error
+	ListFollowedTags(userID int) ([]models.FollowedTag, error)
+}
+
+type tagService struct {
+	tagRepo  repository.TagRepository
+	postRepo repository.PostRepository
+}
+
+func NewTagService(tagRepo repository.TagRepository, postRepo repository.PostRepository) TagService {
+	return &tagService{tagRepo: tagRepo, postRepo: postRepo}
+}
+
+// tagName ใช้กติกาเดียวกับแท็กตอนสร้างโพสต์ (#Go กับ go คือแท็กเดียวกัน)
+func tagName(name string) (string, error) {
+	tag, ok := normalizeTag(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", models.ErrInvalidTag, name)
+	}
+	return tag, nil
+}
+
+func (s *tagService) SuggestTags(viewerID int, prefix string, limit int) ([]models.TagSummary, error) {
+	if viewerID <= 0 {
+		return nil, fmt.Errorf("invalid viewer id")
+	}
+	if limit <= 0 || limit > models.MaxTagSuggestion {
+		limit = 10
+	}
+
+	// prefix ว่าง = แท็กยอดนิยม; prefix ที่มีอักขระใช้ไม่ได้ไม่มีทางตรงกับแท็กไหน
+	p := strings.TrimPrefix(strings.TrimSpace(prefix), "#")
+	if p != "" {
+		var ok bool
+		if p, ok = normalizeTag(p); !ok {
+			return []models.TagSummary{}, nil
+		}
+	}
+
+	tags, err := s.tagRepo.SuggestTags(viewerID, p, limit)
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = []models.TagSummary{}
+	}
+	return tags, nil
+}
+
+func (s *tagService) GetTag(viewerID int, name string) (*models.TagDetail, error) {
+	tag, err := tagName(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.tagRepo.GetTag(viewerID, tag)
+}
+
+// GetTagPosts แท็กที่ไม่มีอยู่ได้หน้าว่าง (ไม่ตอบ 404 เพื่อให้ลิงก์แท็กใช้ได้เสมอ)
+func (s *tagService) GetTagPosts(viewerID int, name, cursor string, size int) (*models.PostPage, error) {
+	tag, err := tagName(name)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(cursor, size, func(c *models.FeedCursor, limit int) ([]models.PostResponse, error) {
+		return s.postRepo.GetTagPosts(viewerID, tag, c, limit)
+	})
+}
+
+func (s *tagService) FollowTag(userID int, name string) (*models.FollowedTag, error) {
+	if userID <= 0 {
+		return nil, fmt.Errorf("invalid user id")
+	}
+	tag, err := tagName(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.tagRepo.FollowTag(userID, tag)
+}
+
+func (s *tagService) UnfollowTag(userID int, name string) error {
+	if userID <= 0 {
+		return fmt.Errorf("invalid user id")
+	}
+	tag, err := tagName(name)
+	if err != nil {
+		return err
+	}
+	return s.tagRepo.UnfollowTag(userID, tag)
+}
+
+func (s *tagService) ListFollowedTags(userID int) ([]models.FollowedTag, error) {
+	if userID <= 0 {
+		return nil, fmt.Errorf("invalid user id")
+	}
+	tags, err := s.tagRepo.ListFollowedTags(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = []models.FollowedTag{}
+	}
+	return tags, nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"chaladshare_backend/internal/posts/models"
+)
+
+// trendingSince จุดเริ่มของช่วง window (nil = ไม่จำกัด)
+func trendingSince(window string, now time.Time) *time.Time {
+	var d time.Duration
+	switch window {
+	case models.TrendingDay:
+		d = 24 * time.Hour
+	case models.TrendingWeek:
+		d = 7 * 24 * time.Hour
+	default:
+		return nil
+	}
+	t := now.Add(-d)
+	return &t
+}
+
+// StartTrendingRefresher คำนวณตาราง post_trending ทันทีหนึ่งรอบ แล้วทำซ้ำทุก interval จน ctx ถูกยกเลิก
+// เรียกครั้งเดียวตอน start
+func (s *postService) StartTrendingRefresher(ctx context.Context, cfg models.TrendingConfig, interval time.Duration) {
+	if cfg.HalfLifeHours <= 0 {
+		cfg.HalfLifeHours = 24
+	}
+	if cfg.SaveWeight <= 0 {
+		cfg.SaveWeight = 2
+	}
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+
+	go s.runTrendingRefresher(ctx, cfg, interval)
+
+	log.Printf("[TRENDING] refresher started half_life=%.1fh save_weight=%.1f interval=%s",
+		cfg.HalfLifeHours, cfg.SaveWeight, interval)
+}
+
+func (s *postService) runTrendingRefresher(ctx context.Context, cfg models.TrendingConfig, interval time.Duration) {
+	s.refreshTrending(ctx, cfg)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[TRENDING] refresher stopped")
+			return
+		case <-ticker.C:
+			s.refreshTrending(ctx, cfg)
+		}
+	}
+}
+
+// refreshTrending log หนึ่งบรรทัดต่อรอบ (error ของแต่ละ window log แยก)
+// window ที่ instance อื่นกำลังคำนวณอยู่ข้ามไปเงียบ ๆ
+func (s *postService) refreshTrending(ctx context.Context, cfg models.TrendingConfig) {
+	now := time.Now()
+	var done []string
+	for _, window := range models.TrendingWindows {
+		if ctx.Err() != nil {
+			return
+		}
+		n, err := s.postRepo.RefreshTrending(window, trendingSince(window, now), cfg)
+		if errors.Is(err, models.ErrTrendingBusy) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[TRENDING] refresh %s: %v", window, err)
+			continue
+		}
+		done = append(done, fmt.Sprintf("%s=%d", window, n))
+	}
+	if len(done) > 0 {
+		log.Printf("[TRENDING] refreshed %s in %s", strings.Join(done, " "), time.Since(now).Round(time.Millisecond))
+	}
+}
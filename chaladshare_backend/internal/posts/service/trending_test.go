@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"chaladshare_backend/internal/posts/models"
+	"chaladshare_backend/internal/posts/repository"
+)
+
+// fakeTrendingRepo นับรอบที่ refresh และจำลอง window ที่ instance อื่นถือ lock อยู่
+type fakeTrendingRepo struct {
+	repository.PostRepository
+
+	mu     sync.Mutex
+	busy   map[string]bool
+	fail   map[string]error
+	calls  []string
+	since  map[string]*time.Time
+	called chan struct{}
+}
+
+func (r *fakeTrendingRepo) RefreshTrending(window string, since *time.Time, cfg models.TrendingConfig) (int, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, window)
+	if r.since == nil {
+		r.since = make(map[string]*time.Time)
+	}
+	r.since[window] = since
+	r.mu.Unlock()
+	if r.called != nil {
+		select {
+		case r.called <- struct{}{}:
+		default:
+		}
+	}
+	if r.busy[window] {
+		return 0, models.ErrTrendingBusy
+	}
+	if err := r.fail[window]; err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (r *fakeTrendingRepo) callCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+func TestNormalizeTrendingWindow(t *testing.T) {
+	cases := map[string]string{"": models.TrendingWeek, " Day ": models.TrendingDay, "week": models.TrendingWeek, "all": models.TrendingAll}
+	for in, want := range cases {
+		got, err := normalizeTrendingWindow(in)
+		if err != nil || got != want {
+			t.Errorf("normalizeTrendingWindow(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := normalizeTrendingWindow("month"); !errors.Is(err, ErrInvalidTrendingWindow) {
+		t.Errorf("month: err = %v, want ErrInvalidTrendingWindow", err)
+	}
+}
+
+func TestRefreshTrendingCoversEveryWindow(t *testing.T) {
+	repo := &fakeTrendingRepo{
+		busy: map[string]bool{models.TrendingDay: true},
+		fail: map[string]error{models.TrendingWeek: errors.New("boom")},
+	}
+	s := &postService{postRepo: repo}
+
+	s.refreshTrending(context.Background(), models.TrendingConfig{HalfLifeHours: 24, SaveWeight: 2})
+
+	// window ที่ busy หรือ error ต้องไม่ทำให้ window ถัดไปถูกข้าม
+	if len(repo.calls) != len(models.TrendingWindows) {
+		t.Fatalf("refreshed %v, want every window", repo.calls)
+	}
+	if repo.since[models.TrendingDay] == nil || repo.since[models.TrendingWeek] == nil {
+		t.Fatal("day/week refreshed without a start time")
+	}
+	if repo.since[models.TrendingAll] != nil {
+		t.Fatalf("all window since = %v, want nil", repo.since[models.TrendingAll])
+	}
+}
+
+func TestTrendingRefresherStopsOnCancel(t *testing.T) {
+	repo := &fakeTrendingRepo{called: make(chan struct{}, 1)}
+	s := &postService{postRepo: repo}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan struct{})
+	go func() {
+		s.runTrendingRefresher(ctx, models.TrendingConfig{HalfLifeHours: 24, SaveWeight: 2}, time.Millisecond)
+		close(stopped)
+	}()
+
+	<-repo.called
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("refresher still running after cancel")
+	}
+
+	n := repo.callCount()
+	time.Sleep(10 * time.Millisecond)
+	if repo.callCount() != n {
+		t.Fatal("refresher kept refreshing after it stopped")
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+const memorySweepInterval = time.Minute
+
+type memoryEntry struct {
+	count   int
+	resetAt time.Time
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{entries: make(map[string]memoryEntry), lastSweep: time.Now()}
+}
+
+func (s *memoryStore) Incr(key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweepLocked(now)
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.resetAt) {
+		e = memoryEntry{resetAt: now.Add(window)}
+	}
+	e.count++
+	s.entries[key] = e
+	return e.count, e.resetAt, nil
+}
+
+func (s *memoryStore) Get(key string) (int, time.Time, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.resetAt) {
+		return 0, time.Time{}, nil
+	}
+	return e.count, e.resetAt, nil
+}
+
+func (s *memoryStore) Reset(key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// sweepLocked ลบ key ที่หมดหน้าต่างแล้ว ไม่ให้ map โตไม่หยุด (ต้องถือ lock อยู่)
+func (s *memoryStore) sweepLocked(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	for k, e := range s.entries {
+		if !now.Before(e.resetAt) {
+			delete(s.entries, k)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore(), 100*time.Millisecond)
+}
+
+func TestMemoryStoreConcurrentIncr(t *testing.T) {
+	s := NewMemoryStore()
+	const n = 50
+
+	var wg sync.WaitGroup
+	seen := make([]bool, n+1)
+	var mu sync.Mutex
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, _, _ := s.Incr("k", time.Minute)
+			mu.Lock()
+			seen[c] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	// ทุกคำขอได้ลำดับไม่ซ้ำกัน 1..n
+	for i := 1; i <= n; i++ {
+		if !seen[i] {
+			t.Fatalf("count %d never returned", i)
+		}
+	}
+}
+
+func TestMemoryStoreSweepsExpired(t *testing.T) {
+	s := NewMemoryStore().(*memoryStore)
+	if _, _, err := s.Incr("old", time.Millisecond); err != nil {
+		t.Fatalf("Incr: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	s.mu.Lock()
+	s.lastSweep = time.Now().Add(-memorySweepInterval)
+	s.mu.Unlock()
+	if _, _, err := s.Incr("new", time.Minute); err != nil {
+		t.Fatalf("Incr: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries["old"]; ok {
+		t.Fatal("expired key not swept")
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const postgresSweepInterval = 10 * time.Minute
+
+type postgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore เริ่ม goroutine ลบแถวที่หมดหน้าต่างแล้วเป็นระยะด้วย
+func NewPostgresStore(db *sql.DB) Store {
+	s := &postgresStore{db: db}
+	go func() {
+		ticker := time.NewTicker(postgresSweepInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.deleteExpired()
+		}
+	}()
+	return s
+}
+
+func (s *postgresStore) Incr(key string, window time.Duration) (int, time.Time, error) {
+	var (
+		count   int
+		resetAt time.Time
+	)
+	err := s.db.QueryRow(`
+		INSERT INTO rate_limits (rl_key, rl_count, rl_reset_at)
+		VALUES ($1, 1, now() + make_interval(secs => $2))
+		ON CONFLICT (rl_key) DO UPDATE SET
+			rl_count = CASE WHEN rate_limits.rl_reset_at <= now() THEN 1
+			                ELSE rate_limits.rl_count + 1 END,
+			rl_reset_at = CASE WHEN rate_limits.rl_reset_at <= now() THEN EXCLUDED.rl_reset_at
+			                   ELSE rate_limits.rl_reset_at END
+		RETURNING rl_count, rl_reset_at
+	`, key, window.Seconds()).Scan(&count, &resetAt)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("rate limit incr: %w", err)
+	}
+	return count, resetAt, nil
+}
+
+func (s *postgresStore) Get(key string) (int, time.Time, error) {
+	var (
+		count   int
+		resetAt time.Time
+	)
+	err := s.db.QueryRow(`
+		SELECT rl_count, rl_reset_at
+		FROM rate_limits
+		WHERE rl_key = $1 AND rl_reset_at > now()
+	`, key).Scan(&count, &resetAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("rate limit get: %w", err)
+	}
+	return count, resetAt, nil
+}
+
+func (s *postgresStore) Reset(key string) error {
+	if _, err := s.db.Exec(`DELETE FROM rate_limits WHERE rl_key = $1`, key); err != nil {
+		return fmt.Errorf("rate limit reset: %w", err)
+	}
+	return nil
+}
+
+func (s *postgresStore) deleteExpired() {
+	if _, err := s.db.Exec(`DELETE FROM rate_limits WHERE rl_reset_at <= now()`); err != nil {
+		log.Printf("[RATE-LIMIT] delete expired: %v", err)
+	}
+}
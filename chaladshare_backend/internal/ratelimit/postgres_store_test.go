@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// ต้องมี Postgres จริง: RATE_LIMIT_TEST_DATABASE_URL=postgres://... go test ./internal/ratelimit/
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("RATE_LIMIT_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("RATE_LIMIT_TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS rate_limits (
+			rl_key      varchar(300) primary key,
+			rl_count    integer not null default 0,
+			rl_reset_at timestamptz not null
+		)
+	`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	t.Cleanup(func() { _, _ = db.Exec(`DELETE FROM rate_limits WHERE rl_key LIKE 'test:%'`) })
+
+	testStore(t, NewPostgresStore(db), time.Second)
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Store ตัวนับแบบ fixed window ต่อ key
+type Store interface {
+	// Incr นับเพิ่ม 1 (เริ่มหน้าต่างใหม่ถ้าหมดอายุแล้ว) คืนจำนวนหลังนับและเวลาหมดหน้าต่าง
+	Incr(key string, window time.Duration) (int, time.Time, error)
+	// Get จำนวนในหน้าต่างปัจจุบัน (0 ถ้าไม่มี/หมดอายุ)
+	Get(key string) (int, time.Time, error)
+	Reset(key string) error
+}
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+)
+
+// NewStore เลือก store ตาม config (memory = instance เดียว, postgres = นับร่วมกันทุก instance)
+func NewStore(kind string, db *sql.DB) (Store, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", StoreMemory:
+		return NewMemoryStore(), nil
+	case StorePostgres:
+		if db == nil {
+			return nil, fmt.Errorf("postgres rate limit store requires a database")
+		}
+		return NewPostgresStore(db), nil
+	}
+	return nil, fmt.Errorf("unsupported rate limit store: %s", kind)
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testStore ชุดทดสอบร่วมของทุก Store (window ต้องสั้นพอให้รอหมดได้ในเทสต์)
+func testStore(t *testing.T, s Store, window time.Duration) {
+	t.Helper()
+	prefix := fmt.Sprintf("test:%d:", time.Now().UnixNano())
+
+	t.Run("counts within window", func(t *testing.T) {
+		key := prefix + "count"
+		var firstReset time.Time
+		for i := 1; i <= 3; i++ {
+			n, resetAt, err := s.Incr(key, window)
+			if err != nil {
+				t.Fatalf("Incr: %v", err)
+			}
+			if n != i {
+				t.Fatalf("Incr #%d = %d", i, n)
+			}
+			if i == 1 {
+				firstReset = resetAt
+			} else if !resetAt.Equal(firstReset) {
+				t.Fatalf("resetAt moved within window: %v -> %v", firstReset, resetAt)
+			}
+		}
+		n, resetAt, err := s.Get(key)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if n != 3 || !resetAt.Equal(firstReset) {
+			t.Fatalf("Get = %d, %v; want 3, %v", n, resetAt, firstReset)
+		}
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		if _, _, err := s.Incr(prefix+"a", window); err != nil {
+			t.Fatalf("Incr: %v", err)
+		}
+		n, _, err := s.Get(prefix + "b")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if n != 0 {
+			t.Fatalf("Get(other key) = %d, want 0", n)
+		}
+	})
+
+	t.Run("reset clears", func(t *testing.T) {
+		key := prefix + "reset"
+		for i := 0; i < 2; i++ {
+			if _, _, err := s.Incr(key, window); err != nil {
+				t.Fatalf("Incr: %v", err)
+			}
+		}
+		if err := s.Reset(key); err != nil {
+			t.Fatalf("Reset: %v", err)
+		}
+		if n, _, _ := s.Get(key); n != 0 {
+			t.Fatalf("Get after Reset = %d, want 0", n)
+		}
+		if n, _, _ := s.Incr(key, window); n != 1 {
+			t.Fatalf("Incr after Reset = %d, want 1", n)
+		}
+		// ล้าง key ที่ไม่มีต้องไม่ error
+		if err := s.Reset(prefix + "missing"); err != nil {
+			t.Fatalf("Reset(missing): %v", err)
+		}
+	})
+
+	t.Run("window expiry starts a new window", func(t *testing.T) {
+		key := prefix + "expiry"
+		for i := 0; i < 3; i++ {
+			if _, _, err := s.Incr(key, window); err != nil {
+				t.Fatalf("Incr: %v", err)
+			}
+		}
+		time.Sleep(window + window/2)
+
+		if n, _, _ := s.Get(key); n != 0 {
+			t.Fatalf("Get after window = %d, want 0", n)
+		}
+		n, resetAt, err := s.Incr(key, window)
+		if err != nil {
+			t.Fatalf("Incr: %v", err)
+		}
+		if n != 1 {
+			t.Fatalf("Incr after window = %d, want 1", n)
+		}
+		if !resetAt.After(time.Now()) {
+			t.Fatalf("new resetAt %v not in the future", resetAt)
+		}
+	})
+}
+
+func TestNewStore(t *testing.T) {
+	for _, kind := range []string{"", "memory", " Memory "} {
+		if _, err := NewStore(kind, nil); err != nil {
+			t.Errorf("NewStore(%q): %v", kind, err)
+		}
+	}
+	if _, err := NewStore(StorePostgres, nil); err == nil {
+		t.Error("postgres store without db should fail")
+	}
+	if _, err := NewStore("redis", nil); err == nil {
+		t.Error("unknown store should fail")
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"chaladshare_backend/internal/realtime/models"
+	"chaladshare_backend/internal/realtime/service"
+)
+
+const streamKeepAlive = 15 * time.Second
+
+// PostViewer ตรวจว่าผู้ใช้ดูโพสต์ได้ไหม ก่อนส่งตัวเลขของโพสต์นั้นให้ (posts service)
+type PostViewer interface {
+	ViewPost(viewerID, postID int) (bool, string, error)
+}
+
+type RealtimeHandler struct {
+	hub        *service.Hub
+	postViewer PostViewer
+}
+
+func NewRealtimeHandler(hub *service.Hub, postViewer PostViewer) *RealtimeHandler {
+	return &RealtimeHandler{hub: hub, postViewer: postViewer}
+}
+
+// visiblePosts ตัดโพสต์ที่ดูไม่ได้หรือไม่มีอยู่ทิ้ง
+func (h *RealtimeHandler) visiblePosts(viewerID int, postIDs []int) ([]int, error) {
+	if len(postIDs) > service.MaxWatchedPosts {
+		postIDs = postIDs[:service.MaxWatchedPosts]
+	}
+	out := make([]int, 0, len(postIDs))
+	for _, id := range postIDs {
+		if id <= 0 {
+			continue
+		}
+		ok, _, err := h.postViewer.ViewPost(viewerID, id)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+func parsePostIDs(raw string) ([]int, bool) {
+	var ids []int
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		id, err := strconv.Atoi(p)
+		if err != nil || id <= 0 {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// GET /realtime/stream?posts=1,2,3
+// event แรกคือ ready พร้อม connection_id สำหรับเปลี่ยนโพสต์ที่ดูผ่าน PUT /realtime/watch
+func (h *RealtimeHandler) Stream(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	postIDs, ok := parsePostIDs(c.Query("posts"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid posts"})
+		return
+	}
+	postIDs, err := h.visiblePosts(uid, postIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sub := h.hub.Subscribe(uid)
+	defer sub.Close()
+	sub.Watch(postIDs)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent(models.EventReady, models.ReadyData{ConnectionID: sub.ID})
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(streamKeepAlive)
+	defer ticker.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, open := <-sub.Events:
+			if !open {
+				return
+			}
+			c.SSEvent(ev.Type, ev.Data)
+			c.Writer.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// PUT /realtime/watch แทนที่รายการโพสต์ที่การเชื่อมต่อนี้กำลังดู
+func (h *RealtimeHandler) Watch(c *gin.Context) {
+	uid := c.GetInt("user_id")
+	if uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req models.WatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	postIDs, err := h.visiblePosts(uid, req.PostIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.hub.Watch(uid, req.ConnectionID, postIDs); err != nil {
+		if errors.Is(err, service.ErrConnectionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"post_ids": postIDs})
+}
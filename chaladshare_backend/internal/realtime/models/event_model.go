@@ -0,0 +1,40 @@
+package models
+
+const (
+	EventReady         = "ready"          // ส่งครั้งแรก พร้อม connection_id
+	EventNotification  = "notification"   // แจ้งเตือนใหม่/ถูกรวมกลุ่ม
+	EventUnreadCount   = "unread_count"   // จำนวนยังไม่อ่านเปลี่ยน
+	EventFriendRequest = "friend_request" // มีคำขอเป็นเพื่อนเข้ามา
+	EventPostStats     = "post_stats"     // like_count/save_count ของโพสต์ที่กำลังดู
+)
+
+// Event หนึ่งข้อความใน stream (Type = ชื่อ event ของ SSE)
+type Event struct {
+	Type string
+	Data any
+}
+
+type ReadyData struct {
+	ConnectionID string `json:"connection_id"`
+}
+
+type UnreadCountData struct {
+	UnreadCount int `json:"unread_count"`
+}
+
+type FriendRequestData struct {
+	RequestID  int `json:"request_id"`
+	FromUserID int `json:"from_user_id"`
+}
+
+// PostStatsData ส่งเฉพาะตัวเลขที่เปลี่ยน
+type PostStatsData struct {
+	PostID    int  `json:"post_id"`
+	LikeCount *int `json:"like_count,omitempty"`
+	SaveCount *int `json:"save_count,omitempty"`
+}
+
+type WatchRequest struct {
+	ConnectionID string `json:"connection_id" binding:"required"`
+	PostIDs      []int  `json:"post_ids"`
+}
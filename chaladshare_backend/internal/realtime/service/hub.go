@@ -0,0 +1,181 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"chaladshare_backend/internal/realtime/models"
+)
+
+const (
+	subscriberBuffer = 16  // event ค้างได้ต่อการเชื่อมต่อ เกินนี้ถือว่าผู้ฟังช้าและข้าม
+	MaxWatchedPosts  = 100 // โพสต์ที่ดูพร้อมกันได้ต่อการเชื่อมต่อ
+)
+
+var ErrConnectionNotFound = errors.New("connection not found")
+
+// Publisher ให้โมดูลอื่นส่ง event เข้ามาโดยไม่ต้องรู้จัก Hub
+type Publisher interface {
+	PublishToUser(userID int, ev models.Event)
+	PublishToPost(postID int, ev models.Event)
+}
+
+// Hub กระจาย event ให้ทุกการเชื่อมต่อของผู้ใช้ (หลายแท็บ/หลายเครื่อง) ภายใน process เดียวกัน
+type Hub struct {
+	mu       sync.Mutex
+	nextID   uint64
+	users    map[int]map[uint64]*subscriber
+	watchers map[int]map[uint64]*subscriber // post_id → การเชื่อมต่อที่กำลังดูโพสต์นั้น
+}
+
+type subscriber struct {
+	id     uint64
+	userID int
+	ch     chan models.Event
+	posts  map[int]struct{}
+	closed bool
+}
+
+// Subscription การเชื่อมต่อหนึ่งครั้ง ต้องเรียก Close เมื่อเลิกฟัง
+type Subscription struct {
+	ID     string
+	Events <-chan models.Event
+
+	hub  *Hub
+	sub  *subscriber
+	once sync.Once
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		users:    make(map[int]map[uint64]*subscriber),
+		watchers: make(map[int]map[uint64]*subscriber),
+	}
+}
+
+func (h *Hub) Subscribe(userID int) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	sub := &subscriber{
+		id:     h.nextID,
+		userID: userID,
+		ch:     make(chan models.Event, subscriberBuffer),
+		posts:  make(map[int]struct{}),
+	}
+	if h.users[userID] == nil {
+		h.users[userID] = make(map[uint64]*subscriber)
+	}
+	h.users[userID][sub.id] = sub
+
+	return &Subscription{
+		ID:     strconv.FormatUint(sub.id, 10),
+		Events: sub.ch,
+		hub:    h,
+		sub:    sub,
+	}
+}
+
+// Close เลิกฟังและปิด channel (เรียกซ้ำได้)
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.unwatchLocked(s.sub)
+		delete(h.users[s.sub.userID], s.sub.id)
+		if len(h.users[s.sub.userID]) == 0 {
+			delete(h.users, s.sub.userID)
+		}
+		s.sub.closed = true
+		close(s.sub.ch)
+	})
+}
+
+// Watch แทนที่รายการโพสต์ที่การเชื่อมต่อนี้กำลังดู
+func (s *Subscription) Watch(postIDs []int) {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !s.sub.closed {
+		h.watchLocked(s.sub, postIDs)
+	}
+}
+
+// Watch เหมือน Subscription.Watch แต่อ้างด้วย connection_id (ต้องเป็นของ userID)
+func (h *Hub) Watch(userID int, connectionID string, postIDs []int) error {
+	id, err := strconv.ParseUint(connectionID, 10, 64)
+	if err != nil {
+		return ErrConnectionNotFound
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sub, ok := h.users[userID][id]
+	if !ok {
+		return ErrConnectionNotFound
+	}
+	h.watchLocked(sub, postIDs)
+	return nil
+}
+
+func (h *Hub) watchLocked(sub *subscriber, postIDs []int) {
+	h.unwatchLocked(sub)
+	for _, postID := range postIDs {
+		if len(sub.posts) >= MaxWatchedPosts {
+			break
+		}
+		if postID <= 0 {
+			continue
+		}
+		sub.posts[postID] = struct{}{}
+		if h.watchers[postID] == nil {
+			h.watchers[postID] = make(map[uint64]*subscriber)
+		}
+		h.watchers[postID][sub.id] = sub
+	}
+}
+
+func (h *Hub) unwatchLocked(sub *subscriber) {
+	for postID := range sub.posts {
+		delete(h.watchers[postID], sub.id)
+		if len(h.watchers[postID]) == 0 {
+			delete(h.watchers, postID)
+		}
+	}
+	sub.posts = make(map[int]struct{})
+}
+
+func (h *Hub) PublishToUser(userID int, ev models.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range h.users[userID] {
+		send(sub, ev)
+	}
+}
+
+func (h *Hub) PublishToPost(postID int, ev models.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range h.watchers[postID] {
+		send(sub, ev)
+	}
+}
+
+// Connections จำนวนการเชื่อมต่อที่เปิดอยู่ของผู้ใช้
+func (h *Hub) Connections(userID int) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.users[userID])
+}
+
+func send(sub *subscriber, ev models.Event) {
+	select {
+	case sub.ch <- ev:
+	default:
+		// ผู้ฟังช้า ข้ามไป (client ดึงค่าล่าสุดใหม่ได้ตอนเชื่อมต่อใหม่)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"chaladshare_backend/internal/realtime/models"
+)
+
+func recv(t *testing.T, sub *Subscription) models.Event {
+	t.Helper()
+	select {
+	case ev, ok := <-sub.Events:
+		if !ok {
+			t.Fatalf("connection %s: channel closed", sub.ID)
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatalf("connection %s: no event", sub.ID)
+	}
+	return models.Event{}
+}
+
+func assertNoEvent(t *testing.T, sub *Subscription) {
+	t.Helper()
+	select {
+	case ev := <-sub.Events:
+		t.Fatalf("connection %s: unexpected event %q", sub.ID, ev.Type)
+	default:
+	}
+}
+
+func TestPublishToUserFansOutToEveryConnection(t *testing.T) {
+	h := NewHub()
+	a1 := h.Subscribe(1)
+	a2 := h.Subscribe(1)
+	b := h.Subscribe(2)
+	defer a1.Close()
+	defer a2.Close()
+	defer b.Close()
+
+	if got := h.Connections(1); got != 2 {
+		t.Fatalf("Connections(1) = %d, want 2", got)
+	}
+
+	h.PublishToUser(1, models.Event{Type: models.EventUnreadCount, Data: models.UnreadCountData{UnreadCount: 3}})
+
+	for _, sub := range []*Subscription{a1, a2} {
+		ev := recv(t, sub)
+		if ev.Type != models.EventUnreadCount {
+			t.Fatalf("connection %s: type = %q", sub.ID, ev.Type)
+		}
+	}
+	assertNoEvent(t, b)
+}
+
+func TestWatchReplacesPostSet(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(1)
+	defer sub.Close()
+
+	if err := h.Watch(1, sub.ID, []int{10, 11}); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if err := h.Watch(1, sub.ID, []int{12}); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	h.PublishToPost(10, models.Event{Type: models.EventPostStats})
+	h.PublishToPost(11, models.Event{Type: models.EventPostStats})
+	assertNoEvent(t, sub)
+
+	h.PublishToPost(12, models.Event{Type: models.EventPostStats, Data: models.PostStatsData{PostID: 12}})
+	ev := recv(t, sub)
+	if data, ok := ev.Data.(models.PostStatsData); !ok || data.PostID != 12 {
+		t.Fatalf("event data = %#v, want post 12", ev.Data)
+	}
+}
+
+func TestWatchRejectsOtherUsersConnection(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(1)
+	defer sub.Close()
+
+	cases := []struct {
+		name   string
+		userID int
+		connID string
+	}{
+		{"other user", 2, sub.ID},
+		{"not a number", 1, "abc"},
+		{"unknown id", 1, "999"},
+	}
+	for _, tc := range cases {
+		if err := h.Watch(tc.userID, tc.connID, []int{10}); !errors.Is(err, ErrConnectionNotFound) {
+			t.Fatalf("%s: Watch = %v, want ErrConnectionNotFound", tc.name, err)
+		}
+	}
+
+	h.PublishToPost(10, models.Event{Type: models.EventPostStats})
+	assertNoEvent(t, sub)
+}
+
+func TestSlowSubscriberDropsEventsWithoutBlocking(t *testing.T) {
+	h := NewHub()
+	slow := h.Subscribe(1)
+	fast := h.Subscribe(1)
+	defer slow.Close()
+	defer fast.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subscriberBuffer*3; i++ {
+			h.PublishToUser(1, models.Event{Type: models.EventNotification, Data: i})
+			// อ่านฝั่ง fast ทุกครั้ง ให้เห็นว่าคนอื่นยังได้ event ครบ
+			<-fast.Events
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("publish blocked on a slow subscriber")
+	}
+
+	if got := len(slow.Events); got != subscriberBuffer {
+		t.Fatalf("slow buffered %d events, want %d", got, subscriberBuffer)
+	}
+	// ได้ event แรก ๆ ที่ยังพอดี buffer ส่วนที่เกินถูกทิ้ง
+	if ev := recv(t, slow); ev.Data != 0 {
+		t.Fatalf("first buffered event = %v, want 0", ev.Data)
+	}
+}
+
+func TestCloseIsIdempotent(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(1)
+	if err := h.Watch(1, sub.ID, []int{10}); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	sub.Close()
+	sub.Close()
+
+	if _, ok := <-sub.Events; ok {
+		t.Fatal("channel still open after Close")
+	}
+	if got := h.Connections(1); got != 0 {
+		t.Fatalf("Connections(1) = %d after Close, want 0", got)
+	}
+	if err := h.Watch(1, sub.ID, []int{10}); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Watch after Close = %v, want ErrConnectionNotFound", err)
+	}
+
+	// ส่งหลังปิดต้องไม่ panic และ Watch ผ่าน Subscription ไม่มีผล
+	sub.Watch([]int{11})
+	h.PublishToUser(1, models.Event{Type: models.EventNotification})
+	h.PublishToPost(10, models.Event{Type: models.EventPostStats})
+	h.PublishToPost(11, models.Event{Type: models.EventPostStats})
+}
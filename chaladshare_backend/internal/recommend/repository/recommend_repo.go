@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	postmodels "chaladshare_backend/internal/posts/models"
+	postrepo "chaladshare_backend/internal/posts/repository"
 	recmodels "chaladshare_backend/internal/recommend/models"
 
 	"github.com/lib/pq"
@@ -68,7 +69,8 @@ SELECT
   df.cluster_id,
   df.style_vector_v16
 FROM liked_docs ld
-JOIN document_features df ON df.document_id = ld.document_id
+JOIN documents fd ON fd.document_id = ld.document_id
+JOIN document_features df ON df.document_id = COALESCE(fd.canonical_document_id, fd.document_id)
 WHERE df.feature_status = 'done'
   AND df.style_vector_v16 IS NOT NULL
   AND df.cluster_id IS NOT NULL
@@ -163,7 +165,8 @@ SELECT
   df.cluster_id,
   df.style_vector_v16
 FROM posts p
-JOIN document_features df ON df.document_id = p.post_document_id
+JOIN documents fd ON fd.document_id = p.post_document_id
+JOIN document_features df ON df.document_id = COALESCE(fd.canonical_document_id, fd.document_id)
 WHERE p.post_document_id IS NOT NULL
   AND df.feature_status = 'done'
   AND df.style_vector_v16 IS NOT NULL
@@ -176,17 +179,7 @@ WHERE p.post_document_id IS NOT NULL
     WHERE l2.like_user_id = $1
       AND l2.like_post_id = p.post_id
   )
-  AND (
-    p.post_visibility = 'public'
-    OR (
-      p.post_visibility = 'friends'
-      AND EXISTS (
-        SELECT 1 FROM friendships f
-        WHERE f.user_id = LEAST($1, p.post_author_user_id)
-          AND f.friend_id = GREATEST($1, p.post_author_user_id)
-      )
-    )
-  )
+  AND ` + postrepo.VisibleToViewerSQL + `
   AND EXISTS (
     SELECT 1 FROM seed_labels sl
     WHERE sl.style_label = df.style_label
@@ -300,12 +293,13 @@ SELECT
 
     COALESCE(ps.post_like_count, 0) AS post_like_count,
     COALESCE(ps.post_save_count, 0) AS post_save_count,
+    COALESCE(ps.post_comment_count, 0) AS post_comment_count,
 
-    d.document_url  AS document_file_url,
+    ('/api/v1/files/' || p.post_document_id || '/download')  AS document_file_url,
     d.document_name AS document_name,
 
-    p.post_cover_url,
-    up.avatar_url,
+    COALESCE(p.post_cover_thumb_url, p.post_cover_url) AS post_cover_url,
+    COALESCE(up.avatar_thumb_url, up.avatar_url)       AS avatar_url,
 
     ARRAY_REMOVE(ARRAY_AGG(DISTINCT t.tag_name), NULL) AS tags,
 
@@ -327,27 +321,14 @@ LEFT JOIN tags t ON t.tag_id = pt.post_tag_tag_id
 LEFT JOIN documents d ON d.document_id = p.post_document_id
 LEFT JOIN user_profiles up ON up.profile_user_id = u.user_id
 
-WHERE
-    (
-        p.post_author_user_id = $1
-        OR p.post_visibility = 'public'
-        OR (
-            p.post_visibility = 'friends'
-            AND EXISTS (
-                SELECT 1
-                FROM friendships f
-                WHERE
-                    f.user_id  = LEAST(p.post_author_user_id, $1)
-                    AND f.friend_id = GREATEST(p.post_author_user_id, $1)
-            )
-        )
-    )
+WHERE ` + postrepo.VisibleToViewerSQL + `
 
 GROUP BY
     p.post_id, u.username,
-    ps.post_like_count, ps.post_save_count,
+    ps.post_like_count, ps.post_save_count, ps.post_comment_count,
     d.document_url, d.document_name,
     p.post_cover_url, up.avatar_url,
+    p.post_cover_thumb_url, up.avatar_thumb_url,
     rec.score, rec.created_at
 
 ORDER BY rec.score DESC, rec.created_at DESC;
@@ -378,7 +359,7 @@ ORDER BY rec.score DESC, rec.created_at DESC;
 			&p.PostID, &p.AuthorID, &p.AuthorName,
 			&p.Title, &p.Description, &p.Visibility,
 			&docID, &p.CreatedAt, &p.UpdatedAt,
-			&p.LikeCount, &p.SaveCount,
+			&p.LikeCount, &p.SaveCount, &p.CommentCount,
 			&fileURL, &docName, &coverURL, &avatarURL, &tags,
 			&isLiked, &isSaved,
 		); err != nil {
@@ -16,11 +16,13 @@ const (
 	topK                 = 10
 	boostSameCluster     = 0.05
 	maxPerCluster        = 4
+	maxPublishRecompute  = 200 // ผู้ใช้ที่คำนวณใหม่ต่อหนึ่งโพสต์ที่เผยแพร่
 )
 
 type RecommendService interface {
 	RecomputeFromLikes(userID int) error
 	OnLikeHook(userID int)
+	OnPostPublished(postID int, viewerIDs []int)
 }
 
 type svc struct {
@@ -40,6 +42,24 @@ func (s *svc) OnLikeHook(userID int) {
 	}()
 }
 
+// OnPostPublished คำนวณ recommendation ใหม่ให้ผู้ที่น่าจะสนใจโพสต์ใหม่ (ผู้ติดตามที่มองเห็นได้)
+// ทำทีละคนใน goroutine เดียว ไม่ยิง AI พร้อมกันเป็นพันครั้ง; คนที่ไลก์ไม่ถึงเกณฑ์จบที่ CountUserLikes
+func (s *svc) OnPostPublished(postID int, viewerIDs []int) {
+	if len(viewerIDs) > maxPublishRecompute {
+		viewerIDs = viewerIDs[:maxPublishRecompute]
+	}
+	if s.aiClient == nil || len(viewerIDs) == 0 {
+		return
+	}
+	go func() {
+		for _, uid := range viewerIDs {
+			if err := s.RecomputeFromLikes(uid); err != nil {
+				log.Printf("[RECOMMEND] recompute after publish post=%d user=%d: %v", postID, uid, err)
+			}
+		}
+	}()
+}
+
 func (s *svc) RecomputeFromLikes(userID int) error {
 	if s.aiClient == nil {
 		return fmt.Errorf("ai client is nil")
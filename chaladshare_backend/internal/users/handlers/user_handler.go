@@ -15,13 +15,14 @@ import (
 )
 
 type UserHandler struct {
-	userSvc    service.UserService
-	postSvc    postsvc.PostService
-	friendsSvc friendservice.FriendService
+	userSvc           service.UserService
+	postSvc           postsvc.PostService
+	friendsSvc        friendservice.FriendService
+	refreshCookieName string
 }
 
-func NewUserHandler(s service.UserService, p postsvc.PostService, f friendservice.FriendService) *UserHandler {
-	return &UserHandler{userSvc: s, postSvc: p, friendsSvc: f}
+func NewUserHandler(s service.UserService, p postsvc.PostService, f friendservice.FriendService, refreshCookieName string) *UserHandler {
+	return &UserHandler{userSvc: s, postSvc: p, friendsSvc: f, refreshCookieName: refreshCookieName}
 }
 
 func getUID(c *gin.Context) (int, bool) {
@@ -217,7 +218,9 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.userSvc.ChangePassword(c.Request.Context(), uid, req.CurrentPassword, req.NewPassword); err != nil {
+	// refresh ของเครื่องนี้ ใช้ระบุ session ที่ไม่ต้องปิด
+	currentRefresh, _ := c.Cookie(h.refreshCookieName)
+	if err := h.userSvc.ChangePassword(c.Request.Context(), uid, req.CurrentPassword, req.NewPassword, currentRefresh); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -115,6 +115,9 @@ func (r *userRepo) UpdateOwnProfile(ctx context.Context, userID int, req *models
 			UPDATE user_profiles
 			SET
 				avatar_url     = COALESCE($1, avatar_url),
+				-- เปลี่ยน avatar แล้วต้องเปลี่ยน thumbnail ตาม (ถ้าไม่ได้อัปผ่าน /files/avatar จะเป็น NULL)
+				avatar_thumb_url = CASE WHEN $1::text IS NULL THEN avatar_thumb_url
+				                   ELSE (SELECT iu.image_thumb_url FROM image_uploads iu WHERE iu.image_url = $1) END,
 				avatar_storage = COALESCE($2, avatar_storage),
 				bio            = COALESCE($3, bio),
 				updated_at     = now()
@@ -4,10 +4,12 @@ package service
 import (
 	"context"
 	"errors"
+	"log"
 	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
 
+	authservice "chaladshare_backend/internal/auth/service"
 	"chaladshare_backend/internal/users/models"
 	"chaladshare_backend/internal/users/repository"
 )
@@ -16,15 +18,17 @@ type UserService interface {
 	GetOwnProfile(ctx context.Context, userID int) (*models.OwnProfileResponse, error)
 	GetViewedUserProfile(ctx context.Context, userID int) (*models.ViewedUserProfileResponse, error)
 	UpdateOwnProfile(ctx context.Context, userID int, req *models.UpdateOwnProfileRequest) error
-	ChangePassword(ctx context.Context, userID int, currentPwd, newPwd string) error
+	// currentRefresh = refresh token ของเครื่องที่เปลี่ยนรหัส (session อื่นจะถูกปิดหมด)
+	ChangePassword(ctx context.Context, userID int, currentPwd, newPwd, currentRefresh string) error
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo     repository.UserRepository
+	sessions authservice.SessionRevoker
 }
 
-func NewUserService(r repository.UserRepository) UserService {
-	return &userService{repo: r}
+func NewUserService(r repository.UserRepository, sessions authservice.SessionRevoker) UserService {
+	return &userService{repo: r, sessions: sessions}
 }
 
 func (s *userService) GetOwnProfile(ctx context.Context, userID int) (*models.OwnProfileResponse, error) {
@@ -54,7 +58,7 @@ func (s *userService) UpdateOwnProfile(ctx context.Context, userID int, req *mod
 	return s.repo.UpdateOwnProfile(ctx, userID, req)
 }
 
-func (s *userService) ChangePassword(ctx context.Context, userID int, current string, newPwd string) error {
+func (s *userService) ChangePassword(ctx context.Context, userID int, current string, newPwd string, currentRefresh string) error {
 	if len(current) == 0 || len(newPwd) == 0 {
 		return errors.New("กรุณากรอกรหัสผ่านให้ครบ")
 	}
@@ -81,5 +85,12 @@ func (s *userService) ChangePassword(ctx context.Context, userID int, current st
 	if err := s.repo.UpdatePasswordHash(ctx, userID, string(newHash)); err != nil {
 		return errors.New("อัปเดตรหัสผ่านไม่สำเร็จ")
 	}
+
+	// เปลี่ยนรหัสแล้วอุปกรณ์อื่นต้อง login ใหม่
+	if s.sessions != nil {
+		if _, err := s.sessions.RevokeOtherSessions(userID, currentRefresh); err != nil {
+			log.Printf("[CHANGE-PASSWORD] revoke other sessions of user %d: %v", userID, err)
+		}
+	}
 	return nil
 }
@@ -16,6 +16,9 @@ create table if not exists users (
 -- สร้าง unique index สำหรับ username_ci กันซ้ำแบบ case-insensitive
 create unique index if not exists users_username_ci_uq on users(username_ci);
 
+-- เพิ่มเมื่อต้องการยกเลิก access token เดิมทั้งหมด (เช่นรีเซ็ตรหัสผ่าน)
+alter table users add column if not exists user_token_version integer not null default 1;
+
 -------------------------------------------------------------------------------
 -- ตารางโปรไฟล์ผู้ใช้
 create table if not exists user_profiles (
@@ -44,6 +47,9 @@ before update on user_profiles
 for each row
 execute function set_updated_at();
 
+-- avatar ขนาดเล็กสำหรับรายการ/ฟีด (ได้จาก image_uploads ตอนอัปเดตโปรไฟล์)
+alter table user_profiles add column if not exists avatar_thumb_url text;
+
 -- ตารางเก็บ session การล็อกอิน (ใช้ refresh token)
 create table if not exists auth_sessions (
     session_id          serial primary key,
@@ -56,6 +62,12 @@ create table if not exists auth_sessions (
     replaced_by_session_id integer references auth_sessions(session_id)
 );
 
+-- ข้อมูลอุปกรณ์สำหรับหน้ารายการ session
+alter table auth_sessions
+  add column if not exists session_started_at timestamptz not null default now(), -- เวลา login ครั้งแรก (คงเดิมเมื่อ rotate)
+  add column if not exists session_user_agent varchar(512),                       -- อุปกรณ์/เบราว์เซอร์ล่าสุด
+  add column if not exists session_ip         varchar(64);                        -- IP ล่าสุด
+
 -- session ที่ยัง active ของ user
 create index if not exists ix_auth_sessions_user_active
   on auth_sessions(session_user_id)
@@ -64,6 +76,47 @@ create index if not exists ix_auth_sessions_user_active
 CREATE UNIQUE INDEX IF NOT EXISTS ux_auth_sessions_refresh_hash
 ON auth_sessions (refresh_token_hash);
 
+-- ใช้ไล่ chain ของ session ที่ rotate ต่อกัน
+create index if not exists ix_auth_sessions_replaced_by
+  on auth_sessions(replaced_by_session_id)
+  where replaced_by_session_id is not null;
+
+-- เหตุการณ์ด้านความปลอดภัยของบัญชี (เช่น refresh token ที่ rotate แล้วถูกใช้ซ้ำ)
+create table if not exists security_events (
+    event_id          serial primary key,
+    event_user_id     integer not null references users(user_id) on delete cascade,
+    event_type        varchar(40) not null,                 -- refresh_token_reuse, two_factor_disabled
+    event_session_id  integer references auth_sessions(session_id) on delete set null,
+    event_ip          varchar(64),
+    event_user_agent  varchar(512),
+    event_created_at  timestamptz not null default now()
+);
+
+create index if not exists ix_security_events_user
+  on security_events(event_user_id, event_created_at desc);
+
+-- TOTP 2FA (RFC 6238) หนึ่งแถวต่อผู้ใช้
+create table if not exists user_totp (
+    totp_user_id       integer primary key references users(user_id) on delete cascade,
+    totp_secret        varchar(255) not null,            -- secret เข้ารหัส AES-GCM (base64)
+    totp_enabled_at    timestamptz,                      -- null = enroll แล้วแต่ยังไม่ยืนยัน
+    totp_last_counter  bigint not null default 0,        -- time step ล่าสุดที่ใช้ไป กันใช้รหัสเดิมซ้ำ
+    totp_failures      integer not null default 0,       -- กรอกผิดติดกัน
+    totp_locked_until  timestamptz,                      -- ผิดครบแล้วล็อกถึงเวลานี้
+    totp_created_at    timestamptz not null default now()
+);
+
+-- recovery code ของ 2FA (เก็บเป็น sha256 ใช้ได้ครั้งเดียว)
+create table if not exists user_recovery_codes (
+    recovery_id        serial primary key,
+    recovery_user_id   integer not null references users(user_id) on delete cascade,
+    recovery_code_hash varchar(64) not null,
+    recovery_used_at   timestamptz
+);
+
+create unique index if not exists ux_user_recovery_codes_hash
+  on user_recovery_codes(recovery_user_id, recovery_code_hash);
+
 -- ตารางเก็บการ reset password (otp หรือโค้ดชั่วคราว)
 create table if not exists password_resets (
     reset_pass_id         serial primary key,                      -- id auto increment
@@ -73,6 +126,9 @@ create table if not exists password_resets (
     reset_pass_expires_at timestamptz not null,                    -- เวลาหมดอายุของการ reset
     used_at               timestamptz                              -- เวลาใช้ reset ไปแล้ว
 );
+-- จำนวนครั้งที่กรอก OTP ผิด (ครบแล้ว OTP ใช้ไม่ได้)
+alter table password_resets add column if not exists reset_pass_attempts integer not null default 0;
+
 -- ตารางยืนยันอีเมลก่อนสมัครสมาชิก (OTP) 888
 create table if not exists email_verifications (
     verify_id         serial primary key,
@@ -82,6 +138,8 @@ create table if not exists email_verifications (
     used_at           timestamptz,
     created_at        timestamptz default now()
 );
+-- จำนวนครั้งที่กรอก OTP ผิด
+alter table email_verifications add column if not exists verify_attempts integer not null default 0;
 
 -- กัน query ช้า + กันเคสตัวเล็กใหญ่
 create index if not exists ix_email_verifications_email_ci
@@ -91,6 +149,16 @@ create index if not exists ix_email_verifications_active
   on email_verifications (lower(email), expires_at)
   where used_at is null;
 
+-- ตัวนับ rate limit แบบ fixed window (ใช้เมื่อ RATE_LIMIT_STORE=postgres ให้หลาย instance นับร่วมกัน)
+create table if not exists rate_limits (
+    rl_key      varchar(300) primary key,        -- เช่น rl:login:email:a@b.com
+    rl_count    integer not null default 0,
+    rl_reset_at timestamptz not null              -- หมดหน้าต่างแล้วเริ่มนับใหม่
+);
+
+create index if not exists ix_rate_limits_reset_at
+  on rate_limits (rl_reset_at);
+
 --------------------------------------------------------------------------------------------------------------
 -- เพิ่มตารางหัวข้อให้มาก่อน user_interests
 create table if not exists topics (
@@ -171,6 +239,27 @@ create table if not exists documents (
     uploaded_at       timestamptz default now()                            -- เวลาอัปโหลด
 );
 
+-- object key ใน storage (ใช้ลบไฟล์/สร้าง signed URL โดยไม่ต้องถอดจาก URL)
+alter table documents add column if not exists document_path text;
+
+-- dedup: ไฟล์เนื้อหาเดียวกัน (sha256 ตรงกัน) ใช้ object และ document_features ของเอกสารต้นทาง (canonical) ร่วมกัน
+-- แถวซ้ำจะมี canonical_document_id ชี้ไปเอกสารต้นทาง และไม่มีแถว document_features/summaries ของตัวเอง
+alter table documents add column if not exists content_sha256 char(64);
+alter table documents add column if not exists canonical_document_id integer;
+-- ไม่ใช้ set null: ลบ canonical ผ่าน cascade (เช่นลบ user) แล้วไฟล์ซ้ำจะกลายเป็น canonical หลายแถว/ไม่มี feature
+-- deferred ให้ trigger trg_documents_promote_duplicate ย้ายไฟล์ซ้ำไปตัวใหม่ก่อนตรวจตอน commit
+alter table documents drop constraint if exists documents_canonical_document_id_fkey;
+alter table documents add constraint documents_canonical_document_id_fkey
+    foreign key (canonical_document_id) references documents(document_id)
+    on delete no action deferrable initially deferred;
+
+create index if not exists ix_documents_content_sha256 on documents(content_sha256);
+create index if not exists ix_documents_canonical on documents(canonical_document_id);
+-- canonical ได้แค่หนึ่งแถวต่อ hash
+create unique index if not exists ux_documents_canonical_sha256
+    on documents(content_sha256)
+    where canonical_document_id is null and content_sha256 is not null;
+
 -- ตารางเก็บสรุป (summaries)
 create table if not exists summaries (
     summary_id            serial primary key,
@@ -189,6 +278,19 @@ create table if not exists summaries (
 
 create index if not exists ix_summaries_document_id on summaries(summary_document_id);
 
+-- retry/backoff ของ summary worker
+alter table summaries
+    add column if not exists summary_attempt_count integer not null default 0,
+    add column if not exists summary_next_attempt_at timestamptz;
+
+-- claim ที่ถืองานอยู่ (ออกใหม่ทุกครั้งที่ claim) worker ที่ถูก reclaim/สั่งสรุปใหม่ไปแล้วเขียนผลทับไม่ได้
+create sequence if not exists summaries_claim_seq;
+alter table summaries add column if not exists summary_claim_id bigint;
+
+create index if not exists ix_summaries_queue
+    on summaries(coalesce(summary_next_attempt_at, summary_created_at), summary_id)
+    where summary_status = 'queued';
+
 -- ตารางโพสต์
 create table if not exists posts (
     post_id             serial primary key,
@@ -204,6 +306,77 @@ create table if not exists posts (
 
 create index if not exists ix_posts_document_id on posts(post_document_id);
 
+-- keyset pagination ของฟีด/โปรไฟล์: (post_created_at, post_id) ใหม่ไปเก่า
+create index if not exists ix_posts_created_id on posts(post_created_at desc, post_id desc);
+create index if not exists ix_posts_author_created_id on posts(post_author_user_id, post_created_at desc, post_id desc);
+
+-- cover ขนาดเล็กสำหรับฟีด (ได้จาก image_uploads ตอนสร้างโพสต์)
+alter table posts add column if not exists post_cover_thumb_url text;
+
+-- การมองเห็นเพิ่ม private (เฉพาะเจ้าของ) และ custom (เฉพาะผู้ใช้/กลุ่มเพื่อนที่เลือก)
+alter table posts drop constraint if exists posts_post_visibility_check;
+alter table posts add constraint posts_post_visibility_check
+    check (post_visibility in ('public','friends','private','custom'));
+
+-- ฉบับร่าง/ตั้งเวลาเผยแพร่: เห็นได้เฉพาะเจ้าของ จนกว่าจะเป็น published
+-- ตอนเผยแพร่ post_created_at จะถูกตั้งเป็นเวลาเผยแพร่ (ฟีดเรียงตามเวลานี้)
+alter table posts add column if not exists post_status varchar(10) not null default 'published';
+alter table posts add column if not exists post_publish_at timestamptz; -- เวลาที่ตั้งไว้ (scheduled)
+alter table posts drop constraint if exists posts_post_status_check;
+alter table posts add constraint posts_post_status_check
+    check (post_status in ('draft','scheduled','published'));
+alter table posts drop constraint if exists posts_post_publish_at_check;
+alter table posts add constraint posts_post_publish_at_check
+    check (post_status <> 'scheduled' or post_publish_at is not null);
+create index if not exists ix_posts_scheduled_due
+    on posts(post_publish_at) where post_status = 'scheduled';
+create index if not exists ix_posts_author_unpublished
+    on posts(post_author_user_id, post_created_at desc, post_id desc) where post_status <> 'published';
+
+-- กลุ่มเพื่อนที่บันทึกไว้ (ใช้เป็นผู้ชมของโพสต์ custom)
+create table if not exists friend_groups (
+    group_id            serial primary key,
+    group_owner_user_id integer not null references users(user_id) on delete cascade,
+    group_name          varchar(50) not null,
+    group_created_at    timestamptz not null default now(),
+    group_updated_at    timestamptz not null default now(),
+    unique (group_owner_user_id, group_name)
+);
+
+create table if not exists friend_group_members (
+    group_id       integer not null references friend_groups(group_id) on delete cascade,
+    member_user_id integer not null references users(user_id) on delete cascade,
+    added_at       timestamptz not null default now(),
+    primary key (group_id, member_user_id)
+);
+create index if not exists ix_friend_group_members_user on friend_group_members(member_user_id);
+
+-- ผู้ชมของโพสต์ custom: ระบุรายคน และ/หรือ ทั้งกลุ่ม (สมาชิกกลุ่มอ่านสด ณ ตอนดู)
+create table if not exists post_audience_users (
+    audience_post_id integer not null references posts(post_id) on delete cascade,
+    audience_user_id integer not null references users(user_id) on delete cascade,
+    primary key (audience_post_id, audience_user_id)
+);
+create index if not exists ix_post_audience_users_user on post_audience_users(audience_user_id);
+
+create table if not exists post_audience_groups (
+    audience_post_id  integer not null references posts(post_id) on delete cascade,
+    audience_group_id integer not null references friend_groups(group_id) on delete cascade,
+    primary key (audience_post_id, audience_group_id)
+);
+create index if not exists ix_post_audience_groups_group on post_audience_groups(audience_group_id);
+
+-- รูป avatar/cover ที่ย่อขนาดแล้ว: image_url = ขนาดใหญ่สุดที่ส่งให้ client
+create table if not exists image_uploads (
+    image_url         text primary key,
+    image_user_id     integer references users(user_id) on delete cascade,
+    image_kind        varchar(20) not null check (image_kind in ('avatars','covers')),
+    image_medium_url  text not null,
+    image_thumb_url   text not null,
+    image_storage     varchar(50),
+    image_created_at  timestamptz default now()
+);
+
 -- ตารางแท็ก (tags)
 create table if not exists tags (
     tag_id   serial primary key,
@@ -241,6 +414,56 @@ create table if not exists post_stats (
     post_last_activity_at timestamptz default now() -- เวลากิจกรรมล่าสุด
 );
 
+alter table post_stats add column if not exists post_comment_count integer default 0; -- จำนวนความคิดเห็น (รวมตอบกลับ)
+
+-- ตารางความคิดเห็น: comment_parent_id = null คือความคิดเห็นหลัก, มีค่าคือตอบกลับ (ลึกได้ชั้นเดียว)
+create table if not exists comments (
+    comment_id         serial primary key,
+    comment_post_id    integer not null references posts(post_id) on delete cascade,
+    comment_user_id    integer not null references users(user_id) on delete cascade,
+    comment_parent_id  integer references comments(comment_id) on delete cascade,
+    comment_body       text not null check (char_length(comment_body) between 1 and 2000),
+    comment_created_at timestamptz not null default now(),
+    comment_updated_at timestamptz not null default now()
+);
+
+create index if not exists ix_comments_post_top
+    on comments(comment_post_id, comment_created_at, comment_id)
+    where comment_parent_id is null;
+create index if not exists ix_comments_parent
+    on comments(comment_parent_id, comment_created_at, comment_id);
+
+-- การแจ้งเตือน: เหตุการณ์ชนิดเดียวกันบนเป้าหมายเดียวกันที่ยังไม่อ่านจะถูกรวมเป็นแถวเดียว (notif_group_key)
+create table if not exists notifications (
+    notification_id    serial primary key,
+    recipient_user_id  integer not null references users(user_id) on delete cascade, -- ผู้รับ
+    notif_type         varchar(30) not null,                                     -- post_liked / followed / friend_request ...
+    notif_group_key    varchar(100) not null,                                    -- เช่น post_liked:post:12
+    notif_post_id      integer references posts(post_id) on delete cascade,
+    notif_comment_id   integer references comments(comment_id) on delete cascade,
+    notif_request_id   integer references friend_requests(request_id) on delete cascade,
+    actor_count        integer not null default 0,                               -- จำนวนคนที่ทำ (ไม่ซ้ำ)
+    last_actor_user_id integer references users(user_id) on delete set null,
+    read_at            timestamptz,                                              -- null = ยังไม่อ่าน
+    notif_created_at   timestamptz not null default now(),
+    notif_updated_at   timestamptz not null default now()
+);
+
+-- ยังไม่อ่านได้แค่แถวเดียวต่อกลุ่ม
+create unique index if not exists ux_notifications_unread_group
+    on notifications(recipient_user_id, notif_group_key)
+    where read_at is null;
+create index if not exists ix_notifications_recipient_updated
+    on notifications(recipient_user_id, notif_updated_at desc, notification_id desc);
+
+-- คนที่ทำให้เกิดการแจ้งเตือนแต่ละแถว (ใช้นับและแสดงชื่อล่าสุด)
+create table if not exists notification_actors (
+    notification_id integer not null references notifications(notification_id) on delete cascade,
+    actor_user_id   integer not null references users(user_id) on delete cascade,
+    acted_at        timestamptz not null default now(),
+    primary key (notification_id, actor_user_id)
+);
+
 
 CREATE INDEX IF NOT EXISTS idx_likes_post_id
 ON likes (like_post_id);
@@ -248,6 +471,71 @@ ON likes (like_post_id);
 CREATE INDEX IF NOT EXISTS idx_saved_posts_post_id
 ON saved_posts (save_post_id);
 
+-- trending: สแกน like/save ตามช่วงเวลา
+create index if not exists ix_likes_created_at on likes(like_created_at);
+create index if not exists ix_saved_posts_created_at on saved_posts(save_created_at);
+
+-- คะแนน trending ที่คำนวณไว้ (refresh เป็นระยะ) แยกตามช่วง day / week / all
+create table if not exists post_trending (
+    trending_post_id     integer not null references posts(post_id) on delete cascade,
+    trending_window      varchar(8) not null check (trending_window in ('day','week','all')),
+    trending_score       double precision not null,
+    trending_like_count  integer not null default 0,   -- like ในช่วงนี้
+    trending_save_count  integer not null default 0,   -- save ในช่วงนี้
+    trending_computed_at timestamptz not null default now(),
+    primary key (trending_window, trending_post_id)
+);
+create index if not exists ix_post_trending_window_score
+    on post_trending(trending_window, trending_score desc, trending_post_id desc);
+
+-- แท็ก: autocomplete ด้วย prefix และหาโพสต์จากแท็ก
+create index if not exists ix_tags_name_prefix on tags(tag_name varchar_pattern_ops);
+create index if not exists ix_post_tags_tag_id on post_tags(post_tag_tag_id, post_tag_post_id);
+
+-- ผู้ใช้ติดตามแท็ก (โพสต์ใหม่ของแท็กจะถูกดันขึ้นในฟีด)
+create table if not exists tag_follows (
+    tag_follow_user_id integer not null references users(user_id) on delete cascade,
+    tag_follow_tag_id  integer not null references tags(tag_id) on delete cascade,
+    tag_followed_at    timestamptz not null default now(),
+    primary key (tag_follow_user_id, tag_follow_tag_id)
+);
+create index if not exists ix_tag_follows_tag_id on tag_follows(tag_follow_tag_id);
+
+-- คอลเลกชัน (โฟลเดอร์) ของโพสต์ที่บันทึกไว้ เรียงตาม collection_position
+create table if not exists collections (
+    collection_id            serial primary key,
+    collection_owner_user_id integer not null references users(user_id) on delete cascade,
+    collection_name          varchar(80) not null,
+    collection_description   text,
+    collection_visibility    varchar(10) not null default 'private'
+        check (collection_visibility in ('private','friends','public')),
+    collection_position      integer not null default 0,
+    collection_created_at    timestamptz not null default now(),
+    collection_updated_at    timestamptz not null default now(),
+    unique (collection_owner_user_id, collection_name),
+    unique (collection_id, collection_owner_user_id) -- ให้ collection_items อ้างเจ้าของได้
+);
+create index if not exists ix_collections_owner_position
+    on collections(collection_owner_user_id, collection_position, collection_id);
+
+-- โพสต์ในคอลเลกชัน: ต้องเป็นโพสต์ที่เจ้าของคอลเลกชันบันทึกไว้ (ยกเลิกบันทึก = ออกจากทุกคอลเลกชัน)
+create table if not exists collection_items (
+    item_collection_id integer not null,
+    item_user_id       integer not null,
+    item_post_id       integer not null,
+    item_position      integer not null default 0,
+    item_added_at      timestamptz not null default now(),
+    primary key (item_collection_id, item_post_id),
+    foreign key (item_collection_id, item_user_id)
+        references collections(collection_id, collection_owner_user_id) on delete cascade,
+    foreign key (item_user_id, item_post_id)
+        references saved_posts(save_user_id, save_post_id) on delete cascade
+);
+create index if not exists ix_collection_items_position
+    on collection_items(item_collection_id, item_position, item_post_id);
+create index if not exists ix_collection_items_saved
+    on collection_items(item_user_id, item_post_id);
+
 
 CREATE OR REPLACE FUNCTION set_updated_at()
 RETURNS trigger AS $$
@@ -285,9 +573,28 @@ BEFORE UPDATE ON document_features
 FOR EACH ROW
 EXECUTE FUNCTION set_updated_at();
 
+-- เวลาเริ่ม processing ใช้คืนงานที่ค้างเกิน lease กลับเข้าคิว
+ALTER TABLE document_features
+  ADD COLUMN IF NOT EXISTS processing_started_at timestamptz;
+
+-- retry/backoff: failed = เลิกลองแล้ว (ถาวรหรือครบจำนวนครั้ง)
+ALTER TABLE document_features
+  ADD COLUMN IF NOT EXISTS attempt_count integer NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz;
+
+-- claim ที่ถืองานอยู่ (ออกใหม่ทุกครั้งที่ claim) worker ที่ถูก reclaim ไปแล้วเขียนผลทับไม่ได้
+CREATE SEQUENCE IF NOT EXISTS document_features_claim_seq;
+ALTER TABLE document_features
+  ADD COLUMN IF NOT EXISTS feature_claim_id bigint;
+
 CREATE INDEX IF NOT EXISTS ix_document_features_status
   ON document_features(feature_status);
 
+-- คิวงาน queued เรียงตามเวลาเข้าคิว
+CREATE INDEX IF NOT EXISTS ix_document_features_queue
+  ON document_features(COALESCE(next_attempt_at, created_at), document_id)
+  WHERE feature_status = 'queued';
+
 CREATE INDEX IF NOT EXISTS ix_document_features_style_label
   ON document_features(style_label);
 
@@ -304,6 +611,53 @@ CREATE INDEX IF NOT EXISTS ix_document_features_stylevec_hnsw
   USING hnsw (style_vector_v16 vector_cosine_ops)
   WHERE style_vector_v16 IS NOT NULL;
 
+-- pgvector HNSW index (content embedding) ใช้กับ /posts/search?mode=semantic|hybrid
+CREATE INDEX IF NOT EXISTS ix_document_features_content_hnsw
+  ON document_features
+  USING hnsw (content_embedding vector_cosine_ops)
+  WHERE content_embedding IS NOT NULL;
+
+-- ลบเอกสาร canonical ที่ยังมีไฟล์ซ้ำโดยไม่ผ่าน PromoteDuplicate (เช่น cascade จากลบ user)
+-- ยกไฟล์ซ้ำที่เก่าที่สุดขึ้นเป็น canonical แล้วเข้าคิวสกัด feature/สรุปใหม่ (ของเดิมถูก cascade ลบไปแล้ว)
+-- เป็น AFTER trigger เพราะไฟล์ซ้ำอาจถูกลบในคำสั่งเดียวกัน แก้แถวพวกนั้นใน BEFORE ไม่ได้
+CREATE OR REPLACE FUNCTION promote_duplicate_document()
+RETURNS trigger AS $$
+DECLARE
+  new_id integer;
+BEGIN
+  SELECT document_id INTO new_id
+  FROM documents
+  WHERE canonical_document_id = OLD.document_id
+  ORDER BY uploaded_at ASC, document_id ASC
+  LIMIT 1;
+
+  IF new_id IS NULL THEN
+    RETURN NULL;
+  END IF;
+
+  UPDATE documents SET canonical_document_id = NULL WHERE document_id = new_id;
+  UPDATE documents SET canonical_document_id = new_id WHERE canonical_document_id = OLD.document_id;
+
+  INSERT INTO document_features (document_id, feature_status)
+  VALUES (new_id, 'queued')
+  ON CONFLICT (document_id) DO NOTHING;
+
+  INSERT INTO summaries (summary_document_id, summary_status)
+  SELECT new_id, 'queued'
+  WHERE NOT EXISTS (SELECT 1 FROM summaries WHERE summary_document_id = new_id);
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- PromoteDuplicate ล็อกแล้วปลด content_sha256 ของตัวเก่าก่อนลบเสมอ จึงไม่เข้า trigger ซ้ำและไม่มีไฟล์ซ้ำใหม่ลิงก์เข้ามา
+DROP TRIGGER IF EXISTS trg_documents_promote_duplicate ON documents;
+CREATE TRIGGER trg_documents_promote_duplicate
+AFTER DELETE ON documents
+FOR EACH ROW
+WHEN (OLD.canonical_document_id IS NULL AND OLD.content_sha256 IS NOT NULL)
+EXECUTE FUNCTION promote_duplicate_document();
+
 CREATE TABLE IF NOT EXISTS recommendations (
   rec_user_id   integer references users(user_id) on delete cascade,
   rec_post_id   integer references posts(post_id) on delete cascade,
@@ -26,7 +26,10 @@ const mapToCardPost = (p) => {
   const coverImg = coverRaw ? toAbsUrl(coverRaw) : PdfPlaceholder;
 
   const rawUrl = p.file_url || p.document_url || "";
-  const isPdf = /\.pdf$/i.test(rawUrl || "");
+  // file_url เป็น /api/v1/files/:id/download ไม่มี .pdf ท้าย URL แล้ว
+  const isPdf =
+    Boolean(p.post_document_id ?? p.document_id) ||
+    /\.pdf$/i.test(rawUrl || "");
 
   const avatarRaw = p.avatar_url || p.author_img || "";
   const authorImg = avatarRaw ? toAbsUrl(avatarRaw) : Avatar;
@@ -86,6 +89,8 @@ const Home = () => {
   const [allPosts, setAllPosts] = useState([]);
   const [loadingAll, setLoadingAll] = useState(true);
   const [allErr, setAllErr] = useState("");
+  const [allCursor, setAllCursor] = useState(null); // next_cursor ของฟีด null = หมดแล้ว
+  const [loadingMoreAll, setLoadingMoreAll] = useState(false);
 
   const navigate = useNavigate();
 
@@ -266,7 +271,10 @@ const Home = () => {
 
         const mapped = rows.map(mapToCardPost);
 
-        if (!cancelled) setAllPosts(mapped);
+        if (!cancelled) {
+          setAllPosts(mapped);
+          setAllCursor(res?.data?.next_cursor ?? null);
+        }
       } catch (e) {
         if (!cancelled) {
           if (e?.response?.status === 401) {
@@ -303,6 +311,36 @@ const Home = () => {
     fetchSearchPosts(search, searchPage);
   }, [searchPage, search, fetchSearchPosts]);
 
+  // หน้าถัดไปของโพสต์ทั้งหมด ส่ง next_cursor ของหน้าก่อนกลับไป
+  const loadMoreAll = async () => {
+    if (!allCursor || loadingMoreAll) return;
+    setLoadingMoreAll(true);
+    try {
+      const res = await axios.get("/posts", {
+        params: { cursor: allCursor },
+        withCredentials: true,
+      });
+      const rows = Array.isArray(res?.data?.data) ? res.data.data : [];
+      const mapped = rows.map(mapToCardPost);
+
+      setAllPosts((prev) => {
+        const seen = new Set(prev.map((p) => p.id));
+        return [...prev, ...mapped.filter((p) => !seen.has(p.id))];
+      });
+      setAllCursor(res?.data?.next_cursor ?? null);
+    } catch (e) {
+      if (e?.response?.status === 401) {
+        navigate("/", { replace: true });
+        return;
+      }
+      setAllErr(
+        e?.response?.data?.error || e.message || "โหลดโพสต์เพิ่มล้มเหลว",
+      );
+    } finally {
+      setLoadingMoreAll(false);
+    }
+  };
+
   const goToPostDetail = (post) => {
     if (post?.id) navigate(`/posts/${post.id}`);
   };
@@ -491,6 +529,14 @@ const Home = () => {
                     </div>
                   ))}
               </div>
+
+              {!loadingAll && !allErr && allCursor && (
+                <div className="search-pagination">
+                  <button disabled={loadingMoreAll} onClick={loadMoreAll}>
+                    {loadingMoreAll ? "กำลังโหลด..." : "โหลดเพิ่ม"}
+                  </button>
+                </div>
+              )}
             </>
           )}
         </div>
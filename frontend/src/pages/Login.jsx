@@ -2,7 +2,11 @@ import React, { useState } from "react";
 import { useNavigate, Link } from "react-router-dom";
 import axios from "axios";
 
-import { MdOutlineAlternateEmail, MdLockOutline } from "react-icons/md";
+import {
+  MdOutlineAlternateEmail,
+  MdLockOutline,
+  MdOutlineSecurity,
+} from "react-icons/md";
 import { VscEye, VscEyeClosed } from "react-icons/vsc";
 
 import "../component/Login.css";
@@ -14,6 +18,10 @@ const Login = () => {
   const [showPassword, setShowPassword] = useState(false);
   const [error, setError] = useState("");
   const [loading, setLoading] = useState(false);
+  // ขั้นที่สอง: รหัสผ่านถูกแล้วแต่บัญชีเปิด 2FA ไว้ (login ตอบ 202 พร้อม two_factor_token)
+  const [twoFactorToken, setTwoFactorToken] = useState("");
+  const [twoFactorCode, setTwoFactorCode] = useState("");
+  const [useRecoveryCode, setUseRecoveryCode] = useState(false);
   const navigate = useNavigate();
 
   const validateEmail = (email) => /^[^\s@]+@[^\s@]+\.[^\s@]+$/.test(email);
@@ -68,6 +76,13 @@ const Login = () => {
         },
       );
 
+      if (res.status === 202 && res.data?.two_factor_required) {
+        setTwoFactorToken(res.data.two_factor_token);
+        setTwoFactorCode("");
+        setUseRecoveryCode(false);
+        return;
+      }
+
       if (res.status === 200) {
         navigate("/home");
       }
@@ -91,6 +106,78 @@ const Login = () => {
     }
   };
 
+  const resetTwoFactor = () => {
+    setTwoFactorToken("");
+    setTwoFactorCode("");
+    setUseRecoveryCode(false);
+    setError("");
+  };
+
+  const handleTwoFactorSubmit = async (e) => {
+    e.preventDefault();
+    if (loading) return;
+
+    setError("");
+
+    const code = twoFactorCode.trim();
+    if (!code) {
+      setError(
+        useRecoveryCode ? "กรุณากรอก recovery code" : "กรุณากรอกรหัส 6 หลัก",
+      );
+      return;
+    }
+
+    try {
+      setLoading(true);
+
+      const res = await axios.post(
+        "/auth/2fa/verify",
+        useRecoveryCode
+          ? { two_factor_token: twoFactorToken, recovery_code: code }
+          : { two_factor_token: twoFactorToken, code },
+        {
+          headers: { "Content-Type": "application/json" },
+          withCredentials: true,
+          timeout: 15000,
+        },
+      );
+
+      if (res.status === 200) {
+        navigate("/home");
+      }
+    } catch (err) {
+      const status = err?.response?.status;
+
+      if (status === 401) {
+        // token รอ 2FA หมดอายุ (5 นาที) ให้กรอกรหัสผ่านใหม่
+        resetTwoFactor();
+        setError("หมดเวลายืนยันตัวตน กรุณาเข้าสู่ระบบอีกครั้ง");
+        return;
+      }
+
+      if (status === 429) {
+        const secs = Number(err?.response?.data?.retry_after) || 0;
+        const mins = Math.max(1, Math.ceil(secs / 60));
+        setError(`กรอกรหัสผิดหลายครั้ง กรุณาลองใหม่ในอีก ${mins} นาที`);
+        return;
+      }
+
+      if (status === 400) {
+        setError("รหัสยืนยันไม่ถูกต้อง");
+        return;
+      }
+
+      const raw =
+        err?.response?.data?.error ||
+        err?.message ||
+        "ยืนยันตัวตนไม่สำเร็จ";
+
+      setError(raw);
+    } finally {
+      setLoading(false);
+    }
+  };
+
   return (
     <div className="login-page">
       <div
@@ -106,58 +193,125 @@ const Login = () => {
           <img src={logo} alt="Logo" />
           <h2>เข้าสู่ระบบ</h2>
 
-          {/* ปิด browser validation */}
-          <form onSubmit={handleSubmit} noValidate>
-            <div className="input-group">
-              <span className="icon">
-                <MdOutlineAlternateEmail />
-              </span>
-              <input
-                type="text" // เปลี่ยนจาก email เพื่อกัน popup อังกฤษ
-                name="email"
-                value={formData.email}
-                onChange={handleChange}
-                placeholder="Email"
-                autoComplete="email"
-              />
-            </div>
-
-            <div className="input-group">
-              <span className="icon">
-                <MdLockOutline />
-              </span>
-              <input
-                type={showPassword ? "text" : "password"}
-                name="password"
-                value={formData.password}
-                onChange={handleChange}
-                placeholder="Password"
-                autoComplete="current-password"
-              />
-              <span
-                className="icon-right"
-                onClick={() => setShowPassword((s) => !s)}
-                role="button"
-                tabIndex={0}
-              >
-                {showPassword ? <VscEyeClosed /> : <VscEye />}
-              </span>
-            </div>
-
-            <div className="forgot-password">
-              <Link to="/forgot_password">ลืมรหัสผ่าน?</Link>
-            </div>
-
-            <button type="submit" disabled={loading}>
-              {loading ? "กำลังเข้าสู่ระบบ..." : "เข้าสู่ระบบ"}
-            </button>
-
-            {error && (
-              <p style={{ color: "red", fontSize: "15px", marginTop: "10px" }}>
-                {error}
+          {twoFactorToken ? (
+            <form onSubmit={handleTwoFactorSubmit} noValidate>
+              <p>
+                {useRecoveryCode
+                  ? "กรอก recovery code ที่ได้ตอนเปิดใช้การยืนยันสองขั้นตอน"
+                  : "กรอกรหัส 6 หลักจากแอป Authenticator"}
               </p>
-            )}
-          </form>
+
+              <div className="input-group">
+                <span className="icon">
+                  <MdOutlineSecurity />
+                </span>
+                <input
+                  type="text"
+                  name="two_factor_code"
+                  value={twoFactorCode}
+                  onChange={(e) => {
+                    setTwoFactorCode(e.target.value);
+                    if (error) setError("");
+                  }}
+                  placeholder={useRecoveryCode ? "xxxxx-xxxxx" : "123456"}
+                  inputMode={useRecoveryCode ? "text" : "numeric"}
+                  autoComplete="one-time-code"
+                  autoFocus
+                />
+              </div>
+
+              <div className="forgot-password">
+                <span
+                  onClick={() => {
+                    setUseRecoveryCode((v) => !v);
+                    setTwoFactorCode("");
+                    setError("");
+                  }}
+                  role="button"
+                  tabIndex={0}
+                  style={{ cursor: "pointer" }}
+                >
+                  {useRecoveryCode
+                    ? "ใช้รหัสจากแอปแทน"
+                    : "ใช้ recovery code แทน"}
+                </span>
+              </div>
+
+              <button type="submit" disabled={loading}>
+                {loading ? "กำลังยืนยัน..." : "ยืนยัน"}
+              </button>
+
+              {error && (
+                <p style={{ color: "red", fontSize: "15px", marginTop: "10px" }}>
+                  {error}
+                </p>
+              )}
+
+              <div className="forgot-password">
+                <span
+                  onClick={resetTwoFactor}
+                  role="button"
+                  tabIndex={0}
+                  style={{ cursor: "pointer" }}
+                >
+                  กลับไปหน้าเข้าสู่ระบบ
+                </span>
+              </div>
+            </form>
+          ) : (
+            /* ปิด browser validation */
+            <form onSubmit={handleSubmit} noValidate>
+              <div className="input-group">
+                <span className="icon">
+                  <MdOutlineAlternateEmail />
+                </span>
+                <input
+                  type="text" // เปลี่ยนจาก email เพื่อกัน popup อังกฤษ
+                  name="email"
+                  value={formData.email}
+                  onChange={handleChange}
+                  placeholder="Email"
+                  autoComplete="email"
+                />
+              </div>
+
+              <div className="input-group">
+                <span className="icon">
+                  <MdLockOutline />
+                </span>
+                <input
+                  type={showPassword ? "text" : "password"}
+                  name="password"
+                  value={formData.password}
+                  onChange={handleChange}
+                  placeholder="Password"
+                  autoComplete="current-password"
+                />
+                <span
+                  className="icon-right"
+                  onClick={() => setShowPassword((s) => !s)}
+                  role="button"
+                  tabIndex={0}
+                >
+                  {showPassword ? <VscEyeClosed /> : <VscEye />}
+                </span>
+              </div>
+
+              <div className="forgot-password">
+                <Link to="/forgot_password">ลืมรหัสผ่าน?</Link>
+              </div>
+
+              <button type="submit" disabled={loading}>
+                {loading ? "กำลังเข้าสู่ระบบ..." : "เข้าสู่ระบบ"}
+              </button>
+
+              {error && (
+                <p style={{ color: "red", fontSize: "15px", marginTop: "10px" }}>
+                  {error}
+                </p>
+              )}
+            </form>
+          )}
 
           <div className="ClickToRegis">
             <p>มีบัญชีแล้วหรือยัง?</p>
@@ -18,6 +18,18 @@ const toAbsUrl = (p) => {
   return `${API_HOST}${clean.startsWith("/") ? clean : `/${clean}`}`;
 };
 
+// หนึ่งหน้าของ PostPage ({ data, next_cursor }) next = null คือหมดแล้ว
+const pageOf = (res) => ({
+  rows: Array.isArray(res?.data?.data) ? res.data.data : [],
+  next: res?.data?.next_cursor ?? null,
+});
+
+// ต่อหน้าถัดไปท้ายรายการเดิม ข้ามโพสต์ที่มีอยู่แล้ว
+const appendUnique = (list, more) => {
+  const seen = new Set(list.map((p) => p.id));
+  return [...list, ...more.filter((p) => !seen.has(p.id))];
+};
+
 const Profile = () => {
   const { id } = useParams();
   const navigate = useNavigate();
@@ -198,6 +210,9 @@ const Profile = () => {
 
   const [posts, setPosts] = useState([]);
   const [savedPosts, setSavedPosts] = useState([]);
+  const [postsCursor, setPostsCursor] = useState(null);
+  const [savedCursor, setSavedCursor] = useState(null);
+  const [loadingMore, setLoadingMore] = useState(false);
   const [loading, setLoading] = useState(true);
   const [err, setErr] = useState("");
   const [followStatus, setFollowStatus] = useState("idle");
@@ -288,6 +303,51 @@ const Profile = () => {
     };
   }, [navigate]);
 
+  const formatPosts = (list) =>
+    Array.isArray(list)
+      ? list.map((p) => {
+          const fileRaw = p.file_url || "";
+          const coverRaw = p.cover_url || "";
+          // file_url เป็น /api/v1/files/:id/download ไม่มี .pdf ท้าย URL แล้ว
+          const isPdf = Boolean(p.post_document_id) || /\.pdf$/i.test(fileRaw);
+
+          const imgSrc = coverRaw
+            ? toAbsUrl(coverRaw)
+            : !fileRaw || isPdf
+              ? "/img/pdf-placeholder.jpg"
+              : toAbsUrl(fileRaw);
+
+          const rawAuthorAvatar = p.avatar_url || "";
+          const authorImg = rawAuthorAvatar ? toAbsUrl(rawAuthorAvatar) : Avatar;
+
+          const authorName =
+            p.author_name ||
+            p.username ||
+            (isOwn && profile.name) ||
+            "ผู้ใช้";
+
+          return {
+            id: p.post_id,
+            post: p.post_id,
+            img: imgSrc,
+            isPdf,
+            likes: p.like_count ?? 0,
+            like_count: p.like_count ?? 0,
+            is_liked: !!p.is_liked,
+            is_saved: !!p.is_saved,
+            title: p.post_title,
+            tags: Array.isArray(p.tags)
+              ? p.tags
+                  .map((t) => (t.startsWith("#") ? t : `#${t}`))
+                  .join(" ")
+              : "",
+            authorId: p.author_id ?? p.post_user_id ?? p.user_id,
+            authorName,
+            authorImg,
+          };
+        })
+      : [];
+
   useEffect(() => {
     if (isOwn == null || !ownerId) return;
     setLoading(true);
@@ -309,9 +369,7 @@ const Profile = () => {
         const statsRes = await axios.get(`/social/stats/${statsUserId}`);
         const stats = statsRes?.data ?? {};
 
-        const postsRes = isOwn
-          ? await axios.get("/posts", { params: { mine: 1 } })
-          : await axios.get("/posts", { params: { user_id: ownerId } });
+        const postsRes = await axios.get(`/profile/${ownerId}/posts`);
 
         let savedRes = { data: [] };
         if (isOwn) {
@@ -322,52 +380,6 @@ const Profile = () => {
 
         const rawAvatar = prof?.data?.avatar_url || "";
 
-        const format = (list) =>
-          Array.isArray(list)
-            ? list.map((p) => {
-                const fileRaw = p.file_url || "";
-                const coverRaw = p.cover_url || "";
-                const isPdf = /\.pdf$/i.test(fileRaw);
-
-                const imgSrc = coverRaw
-                  ? toAbsUrl(coverRaw)
-                  : !fileRaw || isPdf
-                    ? "/img/pdf-placeholder.jpg"
-                    : toAbsUrl(fileRaw);
-
-                const rawAuthorAvatar = p.avatar_url || "";
-                const authorImg = rawAuthorAvatar
-                  ? toAbsUrl(rawAuthorAvatar)
-                  : Avatar;
-
-                const authorName =
-                  p.author_name ||
-                  p.username ||
-                  (isOwn && profile.name) ||
-                  "ผู้ใช้";
-
-                return {
-                  id: p.post_id,
-                  post: p.post_id,
-                  img: imgSrc,
-                  isPdf,
-                  likes: p.like_count ?? 0,
-                  like_count: p.like_count ?? 0,
-                  is_liked: !!p.is_liked,
-                  is_saved: !!p.is_saved,
-                  title: p.post_title,
-                  tags: Array.isArray(p.tags)
-                    ? p.tags
-                        .map((t) => (t.startsWith("#") ? t : `#${t}`))
-                        .join(" ")
-                    : "",
-                  authorId: p.author_id ?? p.post_user_id ?? p.user_id,
-                  authorName,
-                  authorImg,
-                };
-              })
-            : [];
-
         setProfile((prev) => {
           const avatarFull = rawAvatar
             ? toAbsUrl(rawAvatar)
@@ -384,25 +396,13 @@ const Profile = () => {
           };
         });
 
-        const postRows = Array.isArray(postsRes?.data?.data)
-          ? postsRes.data.data
-          : Array.isArray(postsRes?.data)
-            ? postsRes.data
-            : [];
-        const savedRows = Array.isArray(savedRes?.data?.data)
-          ? savedRes.data.data
-          : Array.isArray(savedRes?.data)
-            ? savedRes.data
-            : [];
-
-        const ownerRows = postRows.filter(
-          (p) =>
-            String(p.author_id ?? p.post_user_id ?? p.user_id) ===
-            String(ownerId),
-        );
+        const postPage = pageOf(postsRes);
+        const savedPage = pageOf(savedRes);
 
-        setPosts(format(ownerRows));
-        setSavedPosts(isOwn ? format(savedRows) : []);
+        setPosts(formatPosts(postPage.rows));
+        setPostsCursor(postPage.next);
+        setSavedPosts(isOwn ? formatPosts(savedPage.rows) : []);
+        setSavedCursor(isOwn ? savedPage.next : null);
 
         if (!isOwn) {
           const rel = prof?.data ?? {};
@@ -681,6 +681,33 @@ const Profile = () => {
     () => (activeTab === "posts" ? posts : savedPosts),
     [activeTab, posts, savedPosts],
   );
+  const showingCursor = activeTab === "posts" ? postsCursor : savedCursor;
+
+  // หน้าถัดไปของแท็บที่เปิดอยู่ ส่ง next_cursor ของหน้าก่อนกลับไป
+  const loadMore = async () => {
+    if (!showingCursor || loadingMore) return;
+    const saved = activeTab === "saved";
+    setLoadingMore(true);
+    try {
+      const res = saved
+        ? await axios.get("/posts/save", { params: { cursor: showingCursor } })
+        : await axios.get(`/profile/${ownerId}/posts`, {
+            params: { cursor: showingCursor },
+          });
+      const { rows, next } = pageOf(res);
+      if (saved) {
+        setSavedPosts((list) => appendUnique(list, formatPosts(rows)));
+        setSavedCursor(next);
+      } else {
+        setPosts((list) => appendUnique(list, formatPosts(rows)));
+        setPostsCursor(next);
+      }
+    } catch (e) {
+      notifyError(e?.response?.data?.error || "โหลดโพสต์เพิ่มไม่สำเร็จ", 2500);
+    } finally {
+      setLoadingMore(false);
+    }
+  };
 
   if (isOwn == null) {
     return (
@@ -1064,6 +1091,19 @@ const Profile = () => {
                   ))}
                 </div>
               )}
+
+              {!loading && !err && showingCursor && (
+                <div className="profile-load-more">
+                  <button
+                    type="button"
+                    className="profile-manage-btn profile-manage-btn-edit"
+                    onClick={loadMore}
+                    disabled={loadingMore}
+                  >
+                    {loadingMore ? "กำลังโหลด..." : "โหลดเพิ่ม"}
+                  </button>
+                </div>
+              )}
             </section>
 
             {deleteOpen && (